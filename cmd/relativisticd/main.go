@@ -2,8 +2,10 @@ package main
 import (
         "context"
         "log"
+        "net"
         "os"
         "os/signal"
+        "strconv"
         "syscall"
         "net/http"
         "go.uber.org/zap"
@@ -14,6 +16,7 @@ import (
         "github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/metrics"
         "github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/network"
         "github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/security"
+        "github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
 )
 func main() {
         cfg, err := config.Load()
@@ -31,26 +34,191 @@ func main() {
         
         core.NewRelativisticEngine(topology, latencyMonitor, logger) 
         engineWrapper := core.NewEngine(topology, latencyMonitor, logger) 
+        positionVerifier := network.NewPositionVerifier(topology, network.DefaultPositionVerifierConfig(), logger)
+        engineWrapper.AttachPositionVerifier(positionVerifier)
 
-        timingManager := consensus.NewTimingManager(topology, logger)
+        consensusManager := consensus.NewConsensusManager(topology, logger)
+        consensusManager.SetDriftBudget(cfg.Consensus.MaxDriftPPM)
+        consensusManager.SetAggregationMethod(consensus.AggregationMethod(cfg.Consensus.AggregationMethod))
+        consensusManager.AttachLatencyMonitor(latencyMonitor)
+        eventManager := network.NewEventManager(logger)
+        consensusManager.AttachEventManager(eventManager)
+        consensusManager.OnDriftAlert(func(alert *consensus.DriftAlert) {
+                eventType := types.EventTypeAlertTriggered
+                severity := alert.Severity
+                if alert.Resolved {
+                        eventType = types.EventTypeAlertResolved
+                        severity = types.AlertSeverityInfo
+                }
+                eventManager.EmitEvent(eventType, "drift_tracker", map[string]interface{}{
+                        "alert_id":   alert.ID,
+                        "alert_type": string(alert.Type),
+                        "node_id":    alert.NodeID,
+                        "drift_ppm":  alert.DriftPPM,
+                        "budget_ppm": alert.BudgetPPM,
+                        "message":    alert.Message,
+                }, severity)
+        })
+        advertiseAddress := cfg.Network.AdvertiseAddress
+        if advertiseAddress == "" && cfg.Network.ExternalIP != "" {
+                if _, port, err := net.SplitHostPort(cfg.Network.ListenAddress); err == nil {
+                        advertiseAddress = net.JoinHostPort(cfg.Network.ExternalIP, port)
+                }
+        }
+        if advertiseAddress == "" {
+                logger.Warn("No advertise address configured, peers cannot dial this node",
+                        zap.String("listen_address", cfg.Network.ListenAddress),
+                )
+        }
+        peeringConfig := &network.PeeringConfig{
+                NodeID:        cfg.Network.NodeID,
+                ListenAddress: cfg.Network.ListenAddress,
+                MaxPeers:      cfg.Network.MaxPeers,
+        }
+        if cfg.Network.Region != "" || cfg.Network.Latitude != 0 || cfg.Network.Longitude != 0 {
+                peeringConfig.Node = &types.Node{
+                        Position: types.Position{
+                                Latitude:  cfg.Network.Latitude,
+                                Longitude: cfg.Network.Longitude,
+                                Altitude:  cfg.Network.Altitude,
+                        },
+                        Address: advertiseAddress,
+                        Metadata: types.Metadata{
+                                Region:  cfg.Network.Region,
+                                Version: "1.0.0",
+                        },
+                }
+        }
+        if cfg.Network.IdentityFile != "" {
+                identity, err := network.LoadOrCreateIdentity(cfg.Network.IdentityFile)
+                if err != nil {
+                        log.Fatalf("Failed to load node identity: %v", err)
+                }
+                peeringConfig.Identity = identity
+                logger.Info("Loaded node identity", zap.String("node_id", identity.ID))
+        }
+        discovery := network.NewDiscoveryService(topology, logger)
+        discovery.AddSource(network.NewStaticSource(cfg.Network.BootstrapNodes, logger))
+        if cfg.Network.PeersFile != "" {
+                discovery.AddSource(network.NewFileSource(cfg.Network.PeersFile))
+        }
+        if cfg.Network.PeerDiscovery {
+                for _, seed := range cfg.Network.DNSSeeds {
+                        discovery.AddSource(network.NewDNSSource(seed, nil))
+                }
+        }
+        peering := network.NewPeeringManager(discovery, topology, peeringConfig, logger)
+        peering.AttachPositionVerifier(positionVerifier)
+        probeConfig := network.DefaultProbeConfig()
+        probeConfig.Interval = cfg.Network.ProbeInterval
+        probeConfig.Budget = cfg.Network.ProbeBudget
+        latencyMonitor.AttachPeering(peering, probeConfig)
+        consensusManager.AttachPeering(peering)
+        gossipConfig := network.DefaultGossipConfig()
+        gossipConfig.StrictSigning = peeringConfig.Identity != nil
+        gossipRouter := network.NewGossipRouter(peering, gossipConfig, logger)
+        peering.AttachGossip(gossipRouter)
+        consensusManager.AttachGossip(gossipRouter)
+        engineWrapper.AttachGossip(gossipRouter)
+        connConfig := network.DefaultConnectionManagerConfig()
+        connConfig.TargetOutbound = cfg.Network.TargetOutboundPeers
+        connConfig.MaxInbound = cfg.Network.MaxInboundPeers
+        connConfig.MinOutsideRegion = cfg.Network.MinPeersOutsideRegion
+        connManager := network.NewConnectionManager(peering, discovery, connConfig, logger)
+        connManager.AttachGossip(gossipRouter)
+        keyManager := security.NewKeyManager(logger)
+        if cfg.Consensus.ValidatorKeyFile != "" {
+                validatorKey, err := security.LoadOrCreateSigningKey(cfg.Consensus.ValidatorKeyFile, security.KeyType(cfg.Consensus.ValidatorKeyType))
+                if err != nil {
+                        log.Fatalf("Failed to load validator key: %v", err)
+                }
+                keyManager.SetLocalKey(peering.LocalPeerID(), validatorKey)
+                logger.Info("Loaded validator key",
+                        zap.String("validator_id", peering.LocalPeerID()),
+                        zap.String("public_key", validatorKey.Public().String()),
+                )
+        }
+        consensusManager.AttachKeyManager(keyManager)
+        if err := consensusManager.SetBlockIntervalBounds(cfg.Consensus.MinBlockInterval, cfg.Consensus.MaxBlockInterval); err != nil {
+                log.Fatalf("Failed to configure block interval: %v", err)
+        }
         securityValidator := security.NewSecurityValidator(logger) 
         metricsCollector := metrics.NewMetricsCollector(logger) 
 
         healthMonitor := api.NewHealthMonitor(logger) 
         webSocketManager := api.NewWebSocketManager(engineWrapper, topology, logger) 
+        webSocketManager.AttachEventManager(eventManager)
+        webSocketManager.Start()
         
         metricsCollector.StartCollection()
         ctx, cancel := context.WithCancel(context.Background())
         defer cancel()
         
         go latencyMonitor.StartMonitoring(ctx)
+        go positionVerifier.Start(ctx)
+        anomalyFlagger := core.NewAnomalyFlagger(engineWrapper, consensusManager, core.DefaultAnomalyFlaggerConfig(), logger)
+        go anomalyFlagger.Start(ctx)
         go metricsCollector.StartCollection()
+        go engineWrapper.GetAdmissionController().Start(ctx)
         securityValidator.StartCleanup()
+        if err := consensusManager.Start(ctx); err != nil {
+                log.Fatalf("Failed to start consensus manager: %v", err)
+        }
+        if err := peering.Start(ctx); err != nil {
+                log.Fatalf("Failed to start peering manager: %v", err)
+        }
+        go gossipRouter.Start(ctx)
+        if cfg.Network.PeerDiscovery && cfg.Network.EnableMDNS {
+                var local *types.Node
+                if peeringConfig.Node != nil {
+                        node := *peeringConfig.Node
+                        node.ID = peering.LocalPeerID()
+                        local = &node
+                }
+                // Without a listener there is nothing to answer for, but
+                // peers on the link can still be found.
+                port := 0
+                if addr := peering.ListenAddr(); addr != nil {
+                        _, portStr, err := net.SplitHostPort(addr.String())
+                        if err == nil {
+                                port, err = strconv.Atoi(portStr)
+                        }
+                        if err != nil {
+                                logger.Warn("Failed to read peering port for mDNS",
+                                        zap.String("address", addr.String()),
+                                        zap.Error(err),
+                                )
+                                port = 0
+                        }
+                }
+                mdns := network.NewMDNSSource(local, port, logger)
+                discovery.AddSource(mdns)
+                if local != nil && port > 0 {
+                        if err := mdns.Serve(ctx); err != nil {
+                                logger.Warn("Failed to start mDNS responder", zap.Error(err))
+                        }
+                }
+        }
+        var dht *network.DHT
+        if cfg.Network.PeerDiscovery && cfg.Network.EnableDHT {
+                dhtConfig := network.DefaultDHTConfig()
+                dhtConfig.PreferLowLatency = cfg.Network.DHTPreferLowLatency
+                dhtConfig.AdvertiseAddress = advertiseAddress
+                dht = network.NewDHT(peering, dhtConfig, logger)
+                discovery.AttachDHT(dht)
+                go dht.Start(ctx)
+        }
+        if err := discovery.StartDiscovery(ctx); err != nil {
+                log.Fatalf("Failed to start peer discovery: %v", err)
+        }
+        go connManager.Start(ctx)
         
         server := api.NewServer(
             engineWrapper,
             topology,
-            timingManager,
+            consensusManager.GetTimingManager(),
+            consensusManager,
+            keyManager,
             securityValidator,
             logger,
             healthMonitor,
@@ -75,6 +243,14 @@ cfg.Server.ShutdownTimeout)
         if err := server.Shutdown(shutdownCtx); err != nil {
                 logger.Error("Server shutdown error", zap.Error(err))
         }
+        gossipRouter.Stop()
+        if dht != nil {
+                dht.Stop()
+        }
+        connManager.Stop()
+        discovery.Stop()
+        peering.Stop()
+        consensusManager.Stop()
         latencyMonitor.Stop()
         metricsCollector.StopCollection()
         securityValidator.StopCleanup()
@@ -121,7 +121,7 @@ func (s *Server) getEndpoints() []APIEndpoint {
 		{
 			Method:        "GET",
 			Path:          "/api/v1/consensus/offsets",
-			Description:   "Get node offsets",
+			Description:   "Get node offsets; version=2 adds snapshots and the aggregation report",
 			AuthRequired:  false,
 			AdminRequired: false,
 		},
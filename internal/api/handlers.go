@@ -1,9 +1,17 @@
 package api
 import (
+        "errors"
+        "fmt"
         "net/http"
+        "strconv"
+        "strings"
         "time"
         "github.com/gin-gonic/gin"
         "go.uber.org/zap"
+        "github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/consensus"
+        "github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/core"
+        "github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/network"
+        "github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/security"
         "github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
 )
 func (s *Server) metricsHandler(c *gin.Context) {
@@ -159,7 +167,7 @@ func (s *Server) getConsensusTimingHandler(c *gin.Context) {
 func (s *Server) getOffsetsHandler(c *gin.Context) {
         nodeID := c.Query("node_id")
         if nodeID != "" {
-                offset, err := s.timingManager.GetNodeOffset(nodeID)
+                offset, err := s.consensusManager.GetNodeOffset(nodeID)
                 if err != nil {
                         c.JSON(http.StatusNotFound, gin.H{"error": "Offset not found"})
                         return
@@ -167,8 +175,446 @@ func (s *Server) getOffsetsHandler(c *gin.Context) {
                 c.JSON(http.StatusOK, offset)
                 return
         }
-        offsets := s.timingManager.GetAllOffsets()
-        c.JSON(http.StatusOK, offsets)
+        // Version 1 maps node IDs to their offset and nothing else, so no
+        // node ID can collide with another key. Version 2 keeps the full
+        // snapshots under one key next to the aggregation report.
+        offsets := s.consensusManager.GetAllOffsets()
+        switch c.DefaultQuery("version", "1") {
+        case "1":
+                response := make(map[string]time.Duration, len(offsets))
+                for nodeID, offset := range offsets {
+                        response[nodeID] = offset.Offset
+                }
+                c.JSON(http.StatusOK, response)
+        case "2":
+                c.JSON(http.StatusOK, gin.H{
+                        "version":     2,
+                        "offsets":     offsets,
+                        "aggregation": s.consensusManager.GetAggregationReport(),
+                        "timestamp":   time.Now().UTC(),
+                })
+        default:
+                c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported version"})
+        }
+}
+func (s *Server) getSyncStatusHandler(c *gin.Context) {
+        c.JSON(http.StatusOK, gin.H{
+                "stats": s.consensusManager.GetSyncStats(),
+                "nodes": s.consensusManager.GetSyncStatus(),
+        })
+}
+func (s *Server) getDriftAlertsHandler(c *gin.Context) {
+        alerts := s.consensusManager.GetDriftAlerts()
+        c.JSON(http.StatusOK, gin.H{
+                "alerts":    alerts,
+                "count":     len(alerts),
+                "timestamp": time.Now().UTC(),
+        })
+}
+func (s *Server) admitTransactionHandler(c *gin.Context) {
+        var request struct {
+                Transaction *types.Transaction `json:"transaction"`
+                OriginNode  string             `json:"origin_node"`
+        }
+        if err := c.ShouldBindJSON(&request); err != nil || request.Transaction == nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+                return
+        }
+        result, err := s.engine.AdmitTransaction(c.Request.Context(), request.Transaction, request.OriginNode)
+        if err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+                return
+        }
+        status := http.StatusOK
+        switch result.Decision {
+        case core.AdmissionParked:
+                status = http.StatusAccepted
+        case core.AdmissionRejected:
+                status = http.StatusUnprocessableEntity
+        }
+        c.JSON(status, result)
+}
+func (s *Server) nextTransactionBatchHandler(c *gin.Context) {
+        limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+        if err != nil || limit < 0 {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+                return
+        }
+        transactions := s.engine.GetAdmissionController().NextBatch(limit)
+        c.JSON(http.StatusOK, gin.H{
+                "transactions": transactions,
+                "count":        len(transactions),
+        })
+}
+func (s *Server) getMempoolMetricsHandler(c *gin.Context) {
+        c.JSON(http.StatusOK, s.engine.GetAdmissionController().GetMetrics())
+}
+func (s *Server) getFinalityHandler(c *gin.Context) {
+        request := &consensus.FinalityRequest{
+                OriginNode: c.Query("origin"),
+        }
+        if request.OriginNode == "" {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "Origin node is required"})
+                return
+        }
+        if height := c.Query("height"); height != "" {
+                h, err := strconv.ParseUint(height, 10, 64)
+                if err != nil {
+                        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid height"})
+                        return
+                }
+                request.Height = h
+        }
+        if proposedAt := c.Query("proposed_at"); proposedAt != "" {
+                t, err := time.Parse(time.RFC3339, proposedAt)
+                if err != nil {
+                        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid proposed_at, expected RFC3339"})
+                        return
+                }
+                request.ProposedAt = t
+        }
+        if validators := c.Query("validators"); validators != "" {
+                request.Validators = strings.Split(validators, ",")
+        }
+        estimate, err := s.consensusManager.EstimateFinality(request)
+        if err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+                return
+        }
+        c.JSON(http.StatusOK, estimate)
+}
+func (s *Server) getBlockIntervalHandler(c *gin.Context) {
+        limit := 100
+        if l := c.Query("limit"); l != "" {
+                parsed, err := strconv.Atoi(l)
+                if err != nil || parsed < 0 {
+                        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+                        return
+                }
+                limit = parsed
+        }
+        controller := s.consensusManager.GetBlockIntervalController()
+        c.JSON(http.StatusOK, gin.H{
+                "interval":    controller.GetInterval(),
+                "last_inputs": controller.GetLastInputs(),
+                "history":     controller.GetHistory(limit),
+                "timestamp":   time.Now().UTC(),
+        })
+}
+func (s *Server) registerValidatorKeyHandler(c *gin.Context) {
+        var request struct {
+                ValidatorID string `json:"validator_id" binding:"required"`
+                KeyType     string `json:"key_type" binding:"required"`
+                PublicKey   string `json:"public_key" binding:"required"`
+        }
+        if err := c.ShouldBindJSON(&request); err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+                return
+        }
+        if err := s.keyManager.RegisterValidatorKey(request.ValidatorID, security.KeyType(request.KeyType), request.PublicKey); err != nil {
+                status := http.StatusBadRequest
+                if errors.Is(err, security.ErrKeyConflict) {
+                        status = http.StatusConflict
+                }
+                c.JSON(status, gin.H{"error": err.Error()})
+                return
+        }
+        c.JSON(http.StatusOK, gin.H{"message": "Validator key registered successfully"})
+}
+func (s *Server) rotateValidatorKeyHandler(c *gin.Context) {
+        var request struct {
+                ValidatorID string `json:"validator_id" binding:"required"`
+                KeyType     string `json:"key_type" binding:"required"`
+                PublicKey   string `json:"public_key" binding:"required"`
+                Signature   string `json:"signature" binding:"required"`
+        }
+        if err := c.ShouldBindJSON(&request); err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+                return
+        }
+        if err := s.keyManager.RotateValidatorKey(request.ValidatorID, security.KeyType(request.KeyType), request.PublicKey, request.Signature); err != nil {
+                status := http.StatusBadRequest
+                if errors.Is(err, security.ErrInvalidSignature) {
+                        status = http.StatusForbidden
+                }
+                c.JSON(status, gin.H{"error": err.Error()})
+                return
+        }
+        c.JSON(http.StatusOK, gin.H{"message": "Validator key rotated successfully"})
+}
+func (s *Server) getValidatorSetHandler(c *gin.Context) {
+        registry := s.consensusManager.GetValidatorRegistry()
+        epoch := registry.CurrentEpoch()
+        if e := c.Query("epoch"); e != "" {
+                parsed, err := strconv.ParseUint(e, 10, 64)
+                if err != nil {
+                        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid epoch"})
+                        return
+                }
+                epoch = parsed
+        } else if h := c.Query("height"); h != "" {
+                height, err := strconv.ParseUint(h, 10, 64)
+                if err != nil {
+                        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid height"})
+                        return
+                }
+                epoch = registry.EpochForHeight(height)
+        }
+        set, err := s.consensusManager.GetValidatorSet(epoch)
+        if err != nil {
+                c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+                return
+        }
+        c.JSON(http.StatusOK, set)
+}
+func (s *Server) listValidatorEpochsHandler(c *gin.Context) {
+        registry := s.consensusManager.GetValidatorRegistry()
+        c.JSON(http.StatusOK, gin.H{
+                "epochs":        registry.ListEpochs(),
+                "current_epoch": registry.CurrentEpoch(),
+                "height":        registry.CurrentHeight(),
+                "timestamp":     time.Now().UTC(),
+        })
+}
+func (s *Server) getMembershipRequestsHandler(c *gin.Context) {
+        pending := s.consensusManager.GetValidatorRegistry().GetPendingRequests()
+        c.JSON(http.StatusOK, gin.H{
+                "pending":   pending,
+                "count":     len(pending),
+                "timestamp": time.Now().UTC(),
+        })
+}
+func (s *Server) bootstrapValidatorsHandler(c *gin.Context) {
+        var request struct {
+                Validators []consensus.ValidatorInfo `json:"validators" binding:"required"`
+        }
+        if err := c.ShouldBindJSON(&request); err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+                return
+        }
+        genesis, err := s.consensusManager.BootstrapValidators(request.Validators)
+        if err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+                return
+        }
+        c.JSON(http.StatusCreated, genesis)
+}
+func (s *Server) joinValidatorHandler(c *gin.Context) {
+        var request struct {
+                ValidatorID string  `json:"validator_id" binding:"required"`
+                KeyType     string  `json:"key_type"`
+                PublicKey   string  `json:"public_key"`
+                Height      *uint64 `json:"height"`
+        }
+        if err := c.ShouldBindJSON(&request); err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+                return
+        }
+        height := s.consensusManager.GetValidatorRegistry().CurrentHeight()
+        if request.Height != nil {
+                height = *request.Height
+        }
+        membership, err := s.consensusManager.RequestValidatorJoin(consensus.ValidatorInfo{
+                ID:        request.ValidatorID,
+                KeyType:   request.KeyType,
+                PublicKey: request.PublicKey,
+        }, height)
+        if err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+                return
+        }
+        c.JSON(http.StatusAccepted, membership)
+}
+func (s *Server) leaveValidatorHandler(c *gin.Context) {
+        var request struct {
+                ValidatorID string  `json:"validator_id" binding:"required"`
+                Height      *uint64 `json:"height"`
+        }
+        if err := c.ShouldBindJSON(&request); err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+                return
+        }
+        height := s.consensusManager.GetValidatorRegistry().CurrentHeight()
+        if request.Height != nil {
+                height = *request.Height
+        }
+        membership, err := s.consensusManager.RequestValidatorLeave(request.ValidatorID, height)
+        if err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+                return
+        }
+        c.JSON(http.StatusAccepted, membership)
+}
+func (s *Server) advanceHeightHandler(c *gin.Context) {
+        var request struct {
+                Height uint64 `json:"height" binding:"required"`
+        }
+        if err := c.ShouldBindJSON(&request); err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+                return
+        }
+        epochs, err := s.consensusManager.AdvanceHeight(request.Height)
+        if err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+                return
+        }
+        c.JSON(http.StatusOK, gin.H{
+                "height":     request.Height,
+                "new_epochs": epochs,
+        })
+}
+func (s *Server) ingestProposalHandler(c *gin.Context) {
+        var proposal types.Proposal
+        if err := c.ShouldBindJSON(&proposal); err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+                return
+        }
+        evidence, err := s.consensusManager.PublishProposal(&proposal)
+        if err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+                return
+        }
+        c.JSON(http.StatusOK, gin.H{
+                "equivocation": evidence != nil,
+                "evidence":     evidence,
+        })
+}
+func (s *Server) getMedianTimePastHandler(c *gin.Context) {
+        chainTime := s.consensusManager.GetChainTimeValidator()
+        c.JSON(http.StatusOK, gin.H{
+                "median_time_past": chainTime.MedianTimePast(),
+                "blocks":           chainTime.Len(),
+        })
+}
+func (s *Server) validateChainBlockHandler(c *gin.Context) {
+        var request struct {
+                Header *types.BlockHeader `json:"header"`
+                Accept bool               `json:"accept"`
+        }
+        if err := c.ShouldBindJSON(&request); err != nil || request.Header == nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+                return
+        }
+        result, err := s.consensusManager.ValidateChainTimestamp(request.Header, request.Accept)
+        if err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+                return
+        }
+        c.JSON(http.StatusOK, result)
+}
+func (s *Server) ingestVoteHandler(c *gin.Context) {
+        var vote types.Vote
+        if err := c.ShouldBindJSON(&vote); err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+                return
+        }
+        evidence, err := s.consensusManager.PublishVote(&vote)
+        if err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+                return
+        }
+        c.JSON(http.StatusOK, gin.H{
+                "equivocation": evidence != nil,
+                "evidence":     evidence,
+        })
+}
+func (s *Server) parseEvidenceFilter(c *gin.Context) (*consensus.EvidenceFilter, error) {
+        filter := &consensus.EvidenceFilter{
+                Offender: c.Query("offender"),
+                Type:     consensus.EvidenceType(c.Query("type")),
+                Status:   consensus.EvidenceStatus(c.Query("status")),
+        }
+        if from := c.Query("from_height"); from != "" {
+                h, err := strconv.ParseUint(from, 10, 64)
+                if err != nil {
+                        return nil, err
+                }
+                filter.FromHeight = h
+        }
+        if to := c.Query("to_height"); to != "" {
+                h, err := strconv.ParseUint(to, 10, 64)
+                if err != nil {
+                        return nil, err
+                }
+                filter.ToHeight = h
+        }
+        if limit := c.Query("limit"); limit != "" {
+                l, err := strconv.Atoi(limit)
+                if err != nil {
+                        return nil, err
+                }
+                filter.Limit = l
+        }
+        return filter, nil
+}
+func (s *Server) queryEvidenceHandler(c *gin.Context) {
+        filter, err := s.parseEvidenceFilter(c)
+        if err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid evidence filter"})
+                return
+        }
+        evidence := s.consensusManager.GetEquivocationDetector().QueryEvidence(filter)
+        c.JSON(http.StatusOK, gin.H{
+                "evidence":  evidence,
+                "count":     len(evidence),
+                "timestamp": time.Now().UTC(),
+        })
+}
+func (s *Server) getEvidenceHandler(c *gin.Context) {
+        evidence, err := s.consensusManager.GetEquivocationDetector().GetEvidence(c.Param("id"))
+        if err != nil {
+                c.JSON(http.StatusNotFound, gin.H{"error": "Evidence not found"})
+                return
+        }
+        c.JSON(http.StatusOK, evidence)
+}
+func (s *Server) exportEvidenceHandler(c *gin.Context) {
+        filter, err := s.parseEvidenceFilter(c)
+        if err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid evidence filter"})
+                return
+        }
+        data, err := s.consensusManager.GetEquivocationDetector().ExportEvidence(filter)
+        if err != nil {
+                c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+                return
+        }
+        c.Header("Content-Disposition", "attachment; filename=evidence.json")
+        c.Data(http.StatusOK, "application/json", data)
+}
+func (s *Server) listRoundsHandler(c *gin.Context) {
+        limit := 50
+        if l := c.Query("limit"); l != "" {
+                parsed, err := strconv.Atoi(l)
+                if err != nil || parsed < 0 {
+                        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+                        return
+                }
+                limit = parsed
+        }
+        rounds := s.consensusManager.GetRoundRecorder().ListRounds(limit)
+        c.JSON(http.StatusOK, gin.H{
+                "rounds":    rounds,
+                "count":     len(rounds),
+                "timestamp": time.Now().UTC(),
+        })
+}
+func (s *Server) getRoundHandler(c *gin.Context) {
+        timeline, err := s.consensusManager.GetRoundRecorder().GetTimeline(c.Param("id"))
+        if err != nil {
+                c.JSON(http.StatusNotFound, gin.H{"error": "Round not found"})
+                return
+        }
+        c.JSON(http.StatusOK, timeline)
+}
+func (s *Server) getRoundTraceHandler(c *gin.Context) {
+        trace, err := s.consensusManager.GetRoundRecorder().ChromeTrace(c.Param("id"))
+        if err != nil {
+                c.JSON(http.StatusNotFound, gin.H{"error": "Round not found"})
+                return
+        }
+        c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=round-%s.trace.json", c.Param("id")))
+        c.JSON(http.StatusOK, trace)
 }
 func (s *Server) validateConsensusHandler(c *gin.Context) {
         var request struct {
@@ -193,3 +639,74 @@ func (s *Server) getRegionDistribution(nodes []*types.Node) map[string]int {
         }
         return distribution
 }
+func (s *Server) positionVerifier(c *gin.Context) *network.PositionVerifier {
+        verifier := s.engine.GetPositionVerifier()
+        if verifier == nil {
+                c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Position verification is not enabled"})
+        }
+        return verifier
+}
+func (s *Server) getAnchorsHandler(c *gin.Context) {
+        verifier := s.positionVerifier(c)
+        if verifier == nil {
+                return
+        }
+        c.JSON(http.StatusOK, gin.H{"anchors": verifier.Anchors()})
+}
+func (s *Server) addAnchorHandler(c *gin.Context) {
+        verifier := s.positionVerifier(c)
+        if verifier == nil {
+                return
+        }
+        var request struct {
+                NodeID string `json:"node_id" binding:"required"`
+        }
+        if err := c.ShouldBindJSON(&request); err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+                return
+        }
+        if err := verifier.AddAnchor(request.NodeID); err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+                return
+        }
+        c.JSON(http.StatusOK, gin.H{"message": "Anchor added successfully"})
+}
+func (s *Server) recordAnchorRTTHandler(c *gin.Context) {
+        verifier := s.positionVerifier(c)
+        if verifier == nil {
+                return
+        }
+        var request struct {
+                AnchorID string        `json:"anchor_id" binding:"required"`
+                NodeID   string        `json:"node_id" binding:"required"`
+                RTT      time.Duration `json:"rtt_ns" binding:"required"`
+        }
+        if err := c.ShouldBindJSON(&request); err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+                return
+        }
+        if err := verifier.RecordRTT(request.AnchorID, request.NodeID, request.RTT); err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+                return
+        }
+        c.JSON(http.StatusOK, gin.H{"message": "Measurement recorded"})
+}
+func (s *Server) verifyPositionHandler(c *gin.Context) {
+        verifier := s.positionVerifier(c)
+        if verifier == nil {
+                return
+        }
+        result := verifier.GetVerification(c.Param("id"))
+        if result == nil {
+                c.JSON(http.StatusNotFound, gin.H{"error": "Node position has not been verified"})
+                return
+        }
+        c.JSON(http.StatusOK, result)
+}
+func (s *Server) getFlaggedPositionsHandler(c *gin.Context) {
+        verifier := s.positionVerifier(c)
+        if verifier == nil {
+                return
+        }
+        c.JSON(http.StatusOK, gin.H{"flagged": verifier.FlaggedNodes()})
+}
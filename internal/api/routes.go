@@ -34,10 +34,39 @@ func (s *Server) setupRoutes() {
                 validation.POST("/block", s.validateBlockHandler)
                 validation.POST("/batch", s.batchValidationHandler)
         }
+        mempool := api.Group("/mempool")
+        {
+                mempool.POST("/transactions", s.authMiddleware(), s.admitTransactionHandler)
+                mempool.POST("/batch", s.authMiddleware(), s.nextTransactionBatchHandler)
+                mempool.GET("/metrics", s.getMempoolMetricsHandler)
+        }
         consensus := api.Group("/consensus")
         {
                 consensus.GET("/timing", s.getConsensusTimingHandler)
                 consensus.GET("/offsets", s.getOffsetsHandler)
+                consensus.GET("/finality", s.getFinalityHandler)
+                consensus.GET("/sync", s.getSyncStatusHandler)
+                consensus.GET("/drift/alerts", s.getDriftAlertsHandler)
+                consensus.GET("/interval", s.getBlockIntervalHandler)
+                consensus.POST("/keys", s.authMiddleware(), s.registerValidatorKeyHandler)
+                consensus.POST("/keys/rotate", s.authMiddleware(), s.rotateValidatorKeyHandler)
+                consensus.GET("/validators", s.getValidatorSetHandler)
+                consensus.GET("/validators/epochs", s.listValidatorEpochsHandler)
+                consensus.GET("/validators/requests", s.getMembershipRequestsHandler)
+                consensus.POST("/validators/bootstrap", s.authMiddleware(), s.bootstrapValidatorsHandler)
+                consensus.POST("/validators/join", s.authMiddleware(), s.joinValidatorHandler)
+                consensus.POST("/validators/leave", s.authMiddleware(), s.leaveValidatorHandler)
+                consensus.POST("/validators/height", s.authMiddleware(), s.advanceHeightHandler)
+                consensus.GET("/chain/mtp", s.getMedianTimePastHandler)
+                consensus.POST("/chain/blocks", s.authMiddleware(), s.validateChainBlockHandler)
+                consensus.POST("/proposals", s.ingestProposalHandler)
+                consensus.POST("/votes", s.ingestVoteHandler)
+                consensus.GET("/evidence", s.queryEvidenceHandler)
+                consensus.GET("/evidence/export", s.exportEvidenceHandler)
+                consensus.GET("/evidence/:id", s.getEvidenceHandler)
+                consensus.GET("/rounds", s.listRoundsHandler)
+                consensus.GET("/rounds/:id", s.getRoundHandler)
+                consensus.GET("/rounds/:id/trace", s.getRoundTraceHandler)
                 consensus.POST("/validate", s.validateConsensusHandler)
                 consensus.GET("/health", s.consensusHealthHandler)
         }
@@ -47,6 +76,11 @@ func (s *Server) setupRoutes() {
                 network.GET("/latency", s.getLatencyHandler)
                 network.GET("/peers", s.getPeersHandler)
                 network.GET("/alerts", s.getAlertsHandler)
+                network.GET("/anchors", s.getAnchorsHandler)
+                network.POST("/anchors", s.authMiddleware(), s.addAnchorHandler)
+                network.POST("/anchors/measurements", s.authMiddleware(), s.recordAnchorRTTHandler)
+                network.GET("/positions/flagged", s.getFlaggedPositionsHandler)
+                network.GET("/positions/:id", s.verifyPositionHandler)
         }
         admin := api.Group("/admin")
         admin.Use(s.authMiddleware())
@@ -18,6 +18,8 @@ type Server struct {
         engine           *core.Engine // Diubah dari *core.RelativisticEngine
         topologyManager  *network.TopologyManager
         timingManager    *consensus.TimingManager
+        consensusManager *consensus.ConsensusManager
+        keyManager       *security.KeyManager
         securityValidator *security.SecurityValidator
         healthMonitor    HealthChecker
         startTime        time.Time
@@ -28,11 +30,13 @@ type Server struct {
 	websocketManager *WebSocketManager
 
 }
-func NewServer(engine *core.Engine, topology *network.TopologyManager, timing *consensus.TimingManager, securityValidator *security.SecurityValidator, logger *zap.Logger, healthMonitor HealthChecker, websocketManager *WebSocketManager) *Server {
+func NewServer(engine *core.Engine, topology *network.TopologyManager, timing *consensus.TimingManager, consensusManager *consensus.ConsensusManager, keyManager *security.KeyManager, securityValidator *security.SecurityValidator, logger *zap.Logger, healthMonitor HealthChecker, websocketManager *WebSocketManager) *Server {
         server := &Server{
                 engine:           engine,
                 topologyManager:  topology,
                 timingManager:    timing,
+                consensusManager: consensusManager,
+                keyManager:       keyManager,
                 securityValidator: securityValidator,
                 healthMonitor:    healthMonitor,
                 logger:           logger,
@@ -485,6 +485,24 @@ func (c *WebSocketClient) getAvailableChannels() []string {
 	}
 }
 
+// AttachEventManager forwards consensus change events, such as block
+// interval adjustments, to the consensus channel. Events are dropped rather
+// than blocking the emitter when the broadcast queue is full.
+func (wm *WebSocketManager) AttachEventManager(eventManager *network.EventManager) {
+	eventManager.Subscribe("websocket", types.EventTypeConsensusChange, func(event *network.Event) {
+		select {
+		case wm.broadcast <- WebSocketMessage{
+			Type:      string(event.Type),
+			Channel:   "consensus",
+			Data:      event,
+			Timestamp: time.Now().UTC(),
+		}:
+		default:
+			wm.logger.Debug("WebSocket broadcast queue full, dropping event", zap.String("event_id", event.ID))
+		}
+	})
+}
+
 func (wm *WebSocketManager) startBroadcasts() {
 	go wm.broadcastMetrics()
 	go wm.broadcastNodeUpdates()
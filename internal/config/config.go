@@ -5,13 +5,14 @@ import (
 )
 
 type Config struct {
-	Server   ServerConfig   `yaml:"server"`
-	Database DatabaseConfig `yaml:"database"`
-	Redis    RedisConfig    `yaml:"redis"`
-	Security SecurityConfig `yaml:"security"`
-	Metrics  MetricsConfig  `yaml:"metrics"`
-	Network  NetworkConfig  `yaml:"network"`
-	Logging  LoggingConfig  `yaml:"logging"`
+	Server    ServerConfig    `yaml:"server"`
+	Database  DatabaseConfig  `yaml:"database"`
+	Redis     RedisConfig     `yaml:"redis"`
+	Security  SecurityConfig  `yaml:"security"`
+	Metrics   MetricsConfig   `yaml:"metrics"`
+	Network   NetworkConfig   `yaml:"network"`
+	Consensus ConsensusConfig `yaml:"consensus"`
+	Logging   LoggingConfig   `yaml:"logging"`
 }
 
 type ServerConfig struct {
@@ -55,11 +56,61 @@ type MetricsConfig struct {
 }
 
 type NetworkConfig struct {
+	NodeID         string   `yaml:"node_id"`
+	IdentityFile   string   `yaml:"identity_file"`
 	BootstrapNodes []string `yaml:"bootstrap_nodes"`
 	PeerDiscovery  bool     `yaml:"peer_discovery"`
 	MaxPeers       int      `yaml:"max_peers"`
 	ListenAddress  string   `yaml:"listen_address"`
 	ExternalIP     string   `yaml:"external_ip"`
+
+	// AdvertiseAddress is the host:port peers dial to reach this node. When
+	// empty it is ExternalIP with the ListenAddress port.
+	AdvertiseAddress string `yaml:"advertise_address"`
+
+	// Discovery sources used alongside BootstrapNodes. DNSSeeds are domains
+	// publishing _relativistic._tcp SRV records; PeersFile is a JSON list of
+	// nodes; EnableMDNS browses and advertises on the local network. Seeds
+	// and mDNS are only used when PeerDiscovery is on.
+	DNSSeeds   []string `yaml:"dns_seeds"`
+	PeersFile  string   `yaml:"peers_file"`
+	EnableMDNS bool     `yaml:"enable_mdns"`
+
+	// EnableDHT finds peers through a Kademlia DHT over peer sessions
+	// instead of polling peers' HTTP peer lists. DHTPreferLowLatency lets
+	// lower-RTT contacts displace slower ones in full buckets.
+	EnableDHT           bool `yaml:"enable_dht"`
+	DHTPreferLowLatency bool `yaml:"dht_prefer_low_latency"`
+
+	// Connection management: outbound sessions kept dialled, the cap on
+	// inbound sessions, and how many peers must be outside Region.
+	TargetOutboundPeers   int `yaml:"target_outbound_peers"`
+	MaxInboundPeers       int `yaml:"max_inbound_peers"`
+	MinPeersOutsideRegion int `yaml:"min_peers_outside_region"`
+
+	// Latency probing over peer sessions: how often a round runs and how
+	// many links it may probe (0 probes every link).
+	ProbeInterval time.Duration `yaml:"probe_interval"`
+	ProbeBudget   int           `yaml:"probe_budget"`
+
+	// Position and region advertised to peers in the handshake. Peers'
+	// claimed positions are only checked when a position is configured.
+	Region    string  `yaml:"region"`
+	Latitude  float64 `yaml:"latitude"`
+	Longitude float64 `yaml:"longitude"`
+	Altitude  float64 `yaml:"altitude"`
+}
+
+type ConsensusConfig struct {
+	MaxDriftPPM       float64       `yaml:"max_drift_ppm"`
+	AggregationMethod string        `yaml:"aggregation_method"`
+	MinBlockInterval  time.Duration `yaml:"min_block_interval"`
+	MaxBlockInterval  time.Duration `yaml:"max_block_interval"`
+
+	// Validator signing key for votes, proposals and evidence. The file is
+	// created with a fresh key of ValidatorKeyType when it does not exist.
+	ValidatorKeyFile string `yaml:"validator_key_file"`
+	ValidatorKeyType string `yaml:"validator_key_type"`
 }
 
 type LoggingConfig struct {
@@ -100,13 +151,27 @@ func DefaultConfig() *Config {
 			PushInterval: 60 * time.Second,
 		},
 		Network: NetworkConfig{
-			BootstrapNodes: []string{
-				"bootstrap1.relativistic-sdk.com:8080",
-				"bootstrap2.relativistic-sdk.com:8080",
-			},
+			NodeID:        "local-node",
+			IdentityFile:  "data/node_identity.pem",
 			PeerDiscovery: true,
 			MaxPeers:      50,
-			ListenAddress: ":8080",
+			ListenAddress: ":7070",
+			EnableDHT:     true,
+
+			TargetOutboundPeers:   8,
+			MaxInboundPeers:       32,
+			MinPeersOutsideRegion: 2,
+
+			ProbeInterval: 30 * time.Second,
+			ProbeBudget:   32,
+		},
+		Consensus: ConsensusConfig{
+			MaxDriftPPM:       100,
+			AggregationMethod: "median",
+			MinBlockInterval:  2 * time.Second,
+			MaxBlockInterval:  60 * time.Second,
+			ValidatorKeyFile:  "data/validator_key.pem",
+			ValidatorKeyType:  "ed25519",
 		},
 		Logging: LoggingConfig{
 			Level:  "info",
@@ -22,6 +22,7 @@ func (el *EnvLoader) Load(config *Config) error {
 	el.loadSecurityConfig(config)
 	el.loadMetricsConfig(config)
 	el.loadNetworkConfig(config)
+	el.loadConsensusConfig(config)
 	el.loadLoggingConfig(config)
 
 	return nil
@@ -160,6 +161,14 @@ func (el *EnvLoader) loadMetricsConfig(config *Config) {
 }
 
 func (el *EnvLoader) loadNetworkConfig(config *Config) {
+	if nodeID := el.getEnv("NODE_ID"); nodeID != "" {
+		config.Network.NodeID = nodeID
+	}
+
+	if identityFile := el.getEnv("NODE_IDENTITY_FILE"); identityFile != "" {
+		config.Network.IdentityFile = identityFile
+	}
+
 	if nodes := el.getEnv("BOOTSTRAP_NODES"); nodes != "" {
 		config.Network.BootstrapNodes = strings.Split(nodes, ",")
 	}
@@ -168,6 +177,56 @@ func (el *EnvLoader) loadNetworkConfig(config *Config) {
 		config.Network.PeerDiscovery = strings.ToLower(discovery) == "true"
 	}
 
+	if seeds := el.getEnv("DNS_SEEDS"); seeds != "" {
+		config.Network.DNSSeeds = strings.Split(seeds, ",")
+	}
+
+	if peersFile := el.getEnv("PEERS_FILE"); peersFile != "" {
+		config.Network.PeersFile = peersFile
+	}
+
+	if mdns := el.getEnv("ENABLE_MDNS"); mdns != "" {
+		config.Network.EnableMDNS = strings.ToLower(mdns) == "true"
+	}
+
+	if dht := el.getEnv("ENABLE_DHT"); dht != "" {
+		config.Network.EnableDHT = strings.ToLower(dht) == "true"
+	}
+
+	if bias := el.getEnv("DHT_PREFER_LOW_LATENCY"); bias != "" {
+		config.Network.DHTPreferLowLatency = strings.ToLower(bias) == "true"
+	}
+
+	if target := el.getEnv("TARGET_OUTBOUND_PEERS"); target != "" {
+		if t, err := strconv.Atoi(target); err == nil {
+			config.Network.TargetOutboundPeers = t
+		}
+	}
+
+	if maxInbound := el.getEnv("MAX_INBOUND_PEERS"); maxInbound != "" {
+		if mi, err := strconv.Atoi(maxInbound); err == nil {
+			config.Network.MaxInboundPeers = mi
+		}
+	}
+
+	if outside := el.getEnv("MIN_PEERS_OUTSIDE_REGION"); outside != "" {
+		if o, err := strconv.Atoi(outside); err == nil {
+			config.Network.MinPeersOutsideRegion = o
+		}
+	}
+
+	if interval := el.getEnv("PROBE_INTERVAL"); interval != "" {
+		if duration, err := time.ParseDuration(interval); err == nil {
+			config.Network.ProbeInterval = duration
+		}
+	}
+
+	if budget := el.getEnv("PROBE_BUDGET"); budget != "" {
+		if b, err := strconv.Atoi(budget); err == nil {
+			config.Network.ProbeBudget = b
+		}
+	}
+
 	if maxPeers := el.getEnv("MAX_PEERS"); maxPeers != "" {
 		if mp, err := strconv.Atoi(maxPeers); err == nil {
 			config.Network.MaxPeers = mp
@@ -181,6 +240,64 @@ func (el *EnvLoader) loadNetworkConfig(config *Config) {
 	if ip := el.getEnv("EXTERNAL_IP"); ip != "" {
 		config.Network.ExternalIP = ip
 	}
+
+	if addr := el.getEnv("ADVERTISE_ADDRESS"); addr != "" {
+		config.Network.AdvertiseAddress = addr
+	}
+
+	if region := el.getEnv("NODE_REGION"); region != "" {
+		config.Network.Region = region
+	}
+
+	if lat := el.getEnv("NODE_LATITUDE"); lat != "" {
+		if l, err := strconv.ParseFloat(lat, 64); err == nil {
+			config.Network.Latitude = l
+		}
+	}
+
+	if lon := el.getEnv("NODE_LONGITUDE"); lon != "" {
+		if l, err := strconv.ParseFloat(lon, 64); err == nil {
+			config.Network.Longitude = l
+		}
+	}
+
+	if alt := el.getEnv("NODE_ALTITUDE"); alt != "" {
+		if a, err := strconv.ParseFloat(alt, 64); err == nil {
+			config.Network.Altitude = a
+		}
+	}
+}
+
+func (el *EnvLoader) loadConsensusConfig(config *Config) {
+	if drift := el.getEnv("CONSENSUS_MAX_DRIFT_PPM"); drift != "" {
+		if d, err := strconv.ParseFloat(drift, 64); err == nil {
+			config.Consensus.MaxDriftPPM = d
+		}
+	}
+
+	if method := el.getEnv("CONSENSUS_AGGREGATION_METHOD"); method != "" {
+		config.Consensus.AggregationMethod = strings.ToLower(method)
+	}
+
+	if interval := el.getEnv("CONSENSUS_MIN_BLOCK_INTERVAL"); interval != "" {
+		if duration, err := time.ParseDuration(interval); err == nil {
+			config.Consensus.MinBlockInterval = duration
+		}
+	}
+
+	if interval := el.getEnv("CONSENSUS_MAX_BLOCK_INTERVAL"); interval != "" {
+		if duration, err := time.ParseDuration(interval); err == nil {
+			config.Consensus.MaxBlockInterval = duration
+		}
+	}
+
+	if keyFile := el.getEnv("VALIDATOR_KEY_FILE"); keyFile != "" {
+		config.Consensus.ValidatorKeyFile = keyFile
+	}
+
+	if keyType := el.getEnv("VALIDATOR_KEY_TYPE"); keyType != "" {
+		config.Consensus.ValidatorKeyType = keyType
+	}
 }
 
 func (el *EnvLoader) loadLoggingConfig(config *Config) {
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/go-viper/mapstructure/v2"
 	"github.com/spf13/viper"
 )
 
@@ -32,13 +33,17 @@ func (cl *ConfigLoader) Load(configPath string) (*Config, error) {
 		}
 	}
 
+	// Keys follow the yaml tags, which viper would otherwise ignore for
+	// multi-word names like jwt_secret.
 	var config Config
-	if err := cl.viper.Unmarshal(&config); err != nil {
+	if err := cl.viper.Unmarshal(&config, func(dc *mapstructure.DecoderConfig) {
+		dc.TagName = "yaml"
+	}); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
-	if err := cl.validateConfig(&config); err != nil {
-		return nil, fmt.Errorf("config validation failed: %w", err)
+	if err := Validate(&config); err != nil {
+		return nil, err
 	}
 
 	return &config, nil
@@ -94,40 +99,40 @@ func (cl *ConfigLoader) setupDefaults() {
 	cl.viper.SetDefault("metrics.path", defaultConfig.Metrics.Path)
 	cl.viper.SetDefault("metrics.push_interval", defaultConfig.Metrics.PushInterval)
 
+	cl.viper.SetDefault("network.node_id", defaultConfig.Network.NodeID)
+	cl.viper.SetDefault("network.identity_file", defaultConfig.Network.IdentityFile)
 	cl.viper.SetDefault("network.bootstrap_nodes", defaultConfig.Network.BootstrapNodes)
 	cl.viper.SetDefault("network.peer_discovery", defaultConfig.Network.PeerDiscovery)
+	cl.viper.SetDefault("network.dns_seeds", defaultConfig.Network.DNSSeeds)
+	cl.viper.SetDefault("network.peers_file", defaultConfig.Network.PeersFile)
+	cl.viper.SetDefault("network.enable_mdns", defaultConfig.Network.EnableMDNS)
+	cl.viper.SetDefault("network.enable_dht", defaultConfig.Network.EnableDHT)
+	cl.viper.SetDefault("network.dht_prefer_low_latency", defaultConfig.Network.DHTPreferLowLatency)
+	cl.viper.SetDefault("network.target_outbound_peers", defaultConfig.Network.TargetOutboundPeers)
+	cl.viper.SetDefault("network.max_inbound_peers", defaultConfig.Network.MaxInboundPeers)
+	cl.viper.SetDefault("network.min_peers_outside_region", defaultConfig.Network.MinPeersOutsideRegion)
+	cl.viper.SetDefault("network.probe_interval", defaultConfig.Network.ProbeInterval)
+	cl.viper.SetDefault("network.probe_budget", defaultConfig.Network.ProbeBudget)
 	cl.viper.SetDefault("network.max_peers", defaultConfig.Network.MaxPeers)
 	cl.viper.SetDefault("network.listen_address", defaultConfig.Network.ListenAddress)
+	cl.viper.SetDefault("network.advertise_address", defaultConfig.Network.AdvertiseAddress)
+	cl.viper.SetDefault("network.region", defaultConfig.Network.Region)
+	cl.viper.SetDefault("network.latitude", defaultConfig.Network.Latitude)
+	cl.viper.SetDefault("network.longitude", defaultConfig.Network.Longitude)
+	cl.viper.SetDefault("network.altitude", defaultConfig.Network.Altitude)
+
+	cl.viper.SetDefault("consensus.max_drift_ppm", defaultConfig.Consensus.MaxDriftPPM)
+	cl.viper.SetDefault("consensus.aggregation_method", defaultConfig.Consensus.AggregationMethod)
+	cl.viper.SetDefault("consensus.min_block_interval", defaultConfig.Consensus.MinBlockInterval)
+	cl.viper.SetDefault("consensus.max_block_interval", defaultConfig.Consensus.MaxBlockInterval)
+	cl.viper.SetDefault("consensus.validator_key_file", defaultConfig.Consensus.ValidatorKeyFile)
+	cl.viper.SetDefault("consensus.validator_key_type", defaultConfig.Consensus.ValidatorKeyType)
 
 	cl.viper.SetDefault("logging.level", defaultConfig.Logging.Level)
 	cl.viper.SetDefault("logging.format", defaultConfig.Logging.Format)
 	cl.viper.SetDefault("logging.output", defaultConfig.Logging.Output)
 }
 
-func (cl *ConfigLoader) validateConfig(config *Config) error {
-	if config.Server.Address == "" {
-		return fmt.Errorf("server address is required")
-	}
-
-	if config.Security.JWTSecret == "" {
-		return fmt.Errorf("JWT secret is required")
-	}
-
-	if len(config.Security.JWTSecret) < 32 {
-		return fmt.Errorf("JWT secret must be at least 32 characters")
-	}
-
-	if config.Database.Host == "" {
-		return fmt.Errorf("database host is required")
-	}
-
-	if config.Redis.Address == "" {
-		return fmt.Errorf("redis address is required")
-	}
-
-	return nil
-}
-
 func Load() (*Config, error) {
 	loader := NewConfigLoader()
 
@@ -24,6 +24,10 @@ func (cv *ConfigValidator) Validate(config *Config) error {
 	cv.validateSecurityConfig(&config.Security)
 	cv.validateMetricsConfig(&config.Metrics)
 	cv.validateNetworkConfig(&config.Network)
+	if config.Network.ListenAddress == config.Server.Address {
+		cv.addError("network listen address must differ from the server address")
+	}
+	cv.validateConsensusConfig(&config.Consensus)
 	cv.validateLoggingConfig(&config.Logging)
 
 	if len(cv.errors) > 0 {
@@ -149,21 +153,94 @@ func (cv *ConfigValidator) validateMetricsConfig(config *MetricsConfig) {
 }
 
 func (cv *ConfigValidator) validateNetworkConfig(config *NetworkConfig) {
+	if config.NodeID == "" {
+		cv.addError("network node ID is required")
+	}
+
 	if config.ListenAddress == "" {
 		cv.addError("network listen address is required")
 	}
 
+	for _, node := range config.BootstrapNodes {
+		peerID, hostPort, found := strings.Cut(node, "@")
+		if _, _, err := net.SplitHostPort(hostPort); !found || peerID == "" || err != nil {
+			cv.addError("invalid bootstrap node " + node + ", expected node-id@host:port")
+		}
+	}
+
+	if config.AdvertiseAddress != "" {
+		if host, _, err := net.SplitHostPort(config.AdvertiseAddress); err != nil || host == "" {
+			cv.addError("network advertise address must be host:port")
+		}
+	}
+
 	if config.MaxPeers <= 0 {
 		cv.addError("max peers must be positive")
 	}
 
-	for _, node := range config.BootstrapNodes {
-		if _, _, err := net.SplitHostPort(node); err != nil {
-			cv.addError("invalid bootstrap node address: " + node)
+	if config.TargetOutboundPeers < 0 || config.MaxInboundPeers < 0 || config.MinPeersOutsideRegion < 0 {
+		cv.addError("peer connection limits must not be negative")
+	}
+
+	if config.TargetOutboundPeers > config.MaxPeers {
+		cv.addError("target outbound peers must not exceed max peers")
+	}
+
+	if config.ProbeInterval <= 0 {
+		cv.addError("latency probe interval must be positive")
+	}
+
+	if config.ProbeBudget < 0 {
+		cv.addError("latency probe budget must not be negative")
+	}
+
+	if config.Latitude < -90 || config.Latitude > 90 {
+		cv.addError("network latitude must be between -90 and 90")
+	}
+
+	if config.Longitude < -180 || config.Longitude > 180 {
+		cv.addError("network longitude must be between -180 and 180")
+	}
+
+	if config.Altitude < 0 {
+		cv.addError("network altitude must not be negative")
+	}
+
+	for _, seed := range config.DNSSeeds {
+		if seed == "" || strings.ContainsAny(seed, ":/ ") {
+			cv.addError("invalid DNS seed domain: " + seed)
 		}
 	}
 }
 
+func (cv *ConfigValidator) validateConsensusConfig(config *ConsensusConfig) {
+	if config.MaxDriftPPM <= 0 {
+		cv.addError("consensus max drift ppm must be positive")
+	}
+
+	validMethods := map[string]bool{
+		"mean":         true,
+		"median":       true,
+		"trimmed_mean": true,
+		"marzullo":     true,
+	}
+	if !validMethods[config.AggregationMethod] {
+		cv.addError("invalid consensus aggregation method: " + config.AggregationMethod)
+	}
+
+	if config.MinBlockInterval <= 0 {
+		cv.addError("consensus min block interval must be positive")
+	}
+
+	if config.MaxBlockInterval < config.MinBlockInterval {
+		cv.addError("consensus max block interval must not be less than min block interval")
+	}
+
+	if config.ValidatorKeyType != "ed25519" && config.ValidatorKeyType != "secp256k1" {
+		cv.addError("invalid validator key type: " + config.ValidatorKeyType)
+	}
+}
+
 func (cv *ConfigValidator) validateLoggingConfig(config *LoggingConfig) {
 	validLevels := map[string]bool{
 		"debug": true,
@@ -0,0 +1,274 @@
+package consensus
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+type AggregationMethod string
+
+const (
+	AggregationMean        AggregationMethod = "mean"
+	AggregationMedian      AggregationMethod = "median"
+	AggregationTrimmedMean AggregationMethod = "trimmed_mean"
+	AggregationMarzullo    AggregationMethod = "marzullo"
+)
+
+type ExclusionReason string
+
+const (
+	ExclusionFlagged       ExclusionReason = "flagged"
+	ExclusionDriftAlert    ExclusionReason = "drift_budget_exceeded"
+	ExclusionLowConfidence ExclusionReason = "low_confidence"
+	ExclusionOutlier       ExclusionReason = "outlier"
+	ExclusionFalseticker   ExclusionReason = "falseticker"
+)
+
+type AggregationConfig struct {
+	Method            AggregationMethod `json:"method"`
+	MinConfidence     float64           `json:"min_confidence"`
+	TrimFraction      float64           `json:"trim_fraction"`
+	OutlierThreshold  float64           `json:"outlier_threshold"`
+	MinIntervalWidth  time.Duration     `json:"min_interval_width"`
+	ExcludeDriftAlert bool              `json:"exclude_drift_alert"`
+}
+
+func DefaultAggregationConfig() *AggregationConfig {
+	return &AggregationConfig{
+		Method:            AggregationMedian,
+		MinConfidence:     0.5,
+		TrimFraction:      0.2,
+		OutlierThreshold:  3.5,
+		MinIntervalWidth:  10 * time.Millisecond,
+		ExcludeDriftAlert: true,
+	}
+}
+
+type ExcludedNode struct {
+	NodeID string          `json:"node_id"`
+	Reason ExclusionReason `json:"reason"`
+	Detail string          `json:"detail,omitempty"`
+	Offset time.Duration   `json:"offset"`
+}
+
+type AggregationReport struct {
+	Method       AggregationMethod `json:"method"`
+	GlobalOffset time.Duration     `json:"global_offset"`
+	Lower        time.Duration     `json:"lower"`
+	Upper        time.Duration     `json:"upper"`
+	Included     []string          `json:"included"`
+	Excluded     []ExcludedNode    `json:"excluded"`
+	Timestamp    time.Time         `json:"timestamp"`
+}
+
+func (r *AggregationReport) IsExcluded(nodeID string) (ExcludedNode, bool) {
+	for _, excluded := range r.Excluded {
+		if excluded.NodeID == nodeID {
+			return excluded, true
+		}
+	}
+	return ExcludedNode{}, false
+}
+
+func (r *AggregationReport) exclude(offset *NodeOffset, reason ExclusionReason, detail string) {
+	r.Excluded = append(r.Excluded, ExcludedNode{
+		NodeID: offset.NodeID,
+		Reason: reason,
+		Detail: detail,
+		Offset: offset.Offset,
+	})
+}
+
+// AggregateOffsets combines node offsets into a single network reference.
+// Low-confidence nodes and median-absolute-deviation outliers are dropped
+// before the configured method runs, so one faulty clock cannot drag the
+// result regardless of method. Nodes already excluded by the caller should
+// not be passed in.
+func AggregateOffsets(offsets []*NodeOffset, config *AggregationConfig) *AggregationReport {
+	if config == nil {
+		config = DefaultAggregationConfig()
+	}
+
+	report := &AggregationReport{
+		Method:    config.Method,
+		Timestamp: time.Now().UTC(),
+	}
+
+	sorted := make([]*NodeOffset, len(offsets))
+	copy(sorted, offsets)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].NodeID < sorted[j].NodeID
+	})
+
+	candidates := make([]*NodeOffset, 0, len(sorted))
+	for _, offset := range sorted {
+		if offset.Confidence < config.MinConfidence {
+			report.exclude(offset, ExclusionLowConfidence,
+				fmt.Sprintf("confidence %.2f below %.2f", offset.Confidence, config.MinConfidence))
+			continue
+		}
+		candidates = append(candidates, offset)
+	}
+
+	candidates = rejectOutliers(candidates, config.OutlierThreshold, report)
+
+	if len(candidates) == 0 {
+		return report
+	}
+
+	switch config.Method {
+	case AggregationMean:
+		report.GlobalOffset = weightedMeanOffset(candidates)
+	case AggregationTrimmedMean:
+		report.GlobalOffset = trimmedMeanOffset(candidates, config.TrimFraction)
+	case AggregationMarzullo:
+		candidates = marzulloOffset(candidates, config.MinIntervalWidth, report)
+	default:
+		report.GlobalOffset = medianOffset(candidates)
+	}
+
+	if config.Method != AggregationMarzullo {
+		report.Lower, report.Upper = report.GlobalOffset, report.GlobalOffset
+		for _, offset := range candidates {
+			if offset.Offset < report.Lower {
+				report.Lower = offset.Offset
+			}
+			if offset.Offset > report.Upper {
+				report.Upper = offset.Offset
+			}
+		}
+	}
+
+	for _, offset := range candidates {
+		report.Included = append(report.Included, offset.NodeID)
+	}
+
+	return report
+}
+
+// rejectOutliers applies the modified z-score test, scaling the median
+// absolute deviation to be comparable with a standard deviation.
+func rejectOutliers(offsets []*NodeOffset, threshold float64, report *AggregationReport) []*NodeOffset {
+	if len(offsets) < 3 || threshold <= 0 {
+		return offsets
+	}
+
+	median := medianOffset(offsets)
+
+	deviations := make([]float64, len(offsets))
+	for i, offset := range offsets {
+		deviations[i] = math.Abs(float64(offset.Offset - median))
+	}
+	sort.Float64s(deviations)
+	mad := median64(deviations) * 1.4826
+	if mad == 0 {
+		return offsets
+	}
+
+	kept := make([]*NodeOffset, 0, len(offsets))
+	for _, offset := range offsets {
+		score := math.Abs(float64(offset.Offset-median)) / mad
+		if score > threshold {
+			report.exclude(offset, ExclusionOutlier,
+				fmt.Sprintf("deviates %v from median %v (score %.1f)", offset.Offset-median, median, score))
+			continue
+		}
+		kept = append(kept, offset)
+	}
+	return kept
+}
+
+func weightedMeanOffset(offsets []*NodeOffset) time.Duration {
+	var total, weight float64
+	for _, offset := range offsets {
+		total += float64(offset.Offset) * offset.Confidence
+		weight += offset.Confidence
+	}
+	if weight == 0 {
+		return 0
+	}
+	return time.Duration(total / weight)
+}
+
+func medianOffset(offsets []*NodeOffset) time.Duration {
+	values := make([]float64, len(offsets))
+	for i, offset := range offsets {
+		values[i] = float64(offset.Offset)
+	}
+	sort.Float64s(values)
+	return time.Duration(median64(values))
+}
+
+func trimmedMeanOffset(offsets []*NodeOffset, fraction float64) time.Duration {
+	values := make([]time.Duration, len(offsets))
+	for i, offset := range offsets {
+		values[i] = offset.Offset
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+
+	trim := int(float64(len(values)) * fraction)
+	if 2*trim >= len(values) {
+		trim = (len(values) - 1) / 2
+	}
+	values = values[trim : len(values)-trim]
+
+	var total time.Duration
+	for _, v := range values {
+		total += v
+	}
+	return total / time.Duration(len(values))
+}
+
+// marzulloOffset treats each offset as an interval whose half-width is its
+// uncertainty divided by its confidence, so less trusted nodes need wider
+// agreement. Nodes outside the winning intersection are excluded.
+func marzulloOffset(offsets []*NodeOffset, minWidth time.Duration, report *AggregationReport) []*NodeOffset {
+	intervals := make([]OffsetInterval, len(offsets))
+	for i, offset := range offsets {
+		width := offset.Uncertainty
+		if width < minWidth {
+			width = minWidth
+		}
+		if offset.Confidence > 0 {
+			width = time.Duration(float64(width) / offset.Confidence)
+		}
+		intervals[i] = OffsetInterval{
+			Source: offset.NodeID,
+			Lower:  offset.Offset - width,
+			Upper:  offset.Offset + width,
+		}
+	}
+
+	result, agreeing := MarzulloIntersection(intervals)
+	report.Lower, report.Upper = result.Lower, result.Upper
+	report.GlobalOffset = (result.Lower + result.Upper) / 2
+
+	agreed := make(map[string]bool, len(agreeing))
+	for _, nodeID := range agreeing {
+		agreed[nodeID] = true
+	}
+
+	kept := make([]*NodeOffset, 0, len(agreeing))
+	for _, offset := range offsets {
+		if !agreed[offset.NodeID] {
+			report.exclude(offset, ExclusionFalseticker,
+				fmt.Sprintf("outside intersection [%v, %v]", result.Lower, result.Upper))
+			continue
+		}
+		kept = append(kept, offset)
+	}
+	return kept
+}
+
+func median64(sorted []float64) float64 {
+	n := len(sorted)
+	if n == 0 {
+		return 0
+	}
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
@@ -18,6 +18,7 @@ type ConsensusCalculator struct {
 }
 
 type CalculationResult struct {
+	ValidatorSetID   string                   `json:"validator_set_id"`
 	OptimalBlockTime time.Duration            `json:"optimal_block_time"`
 	MaxPropagation   time.Duration            `json:"max_propagation_delay"`
 	SafetyMargin     time.Duration            `json:"safety_margin"`
@@ -25,6 +26,8 @@ type CalculationResult struct {
 	Confidence       float64                  `json:"confidence"`
 	CalculatedAt     time.Time                `json:"calculated_at"`
 	ValidatorCount   int                      `json:"validator_count"`
+
+	timing *ConsensusTiming
 }
 
 func NewConsensusCalculator(timingManager *TimingManager, offsetManager *OffsetManager, logger *zap.Logger) *ConsensusCalculator {
@@ -41,35 +44,37 @@ func (cc *ConsensusCalculator) CalculateConsensusParameters(validatorNodes []str
 		return nil, fmt.Errorf("validator nodes list cannot be empty")
 	}
 
-	cacheKey := cc.generateCacheKey(validatorNodes)
+	// The timing comes from the shared cache; a cached result is only reused
+	// while it was derived from that same timing.
+	timing, err := cc.timingManager.CalculateConsensusTiming(validatorNodes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate timing: %w", err)
+	}
 
 	cc.mu.RLock()
-	if cached, exists := cc.cache[cacheKey]; exists {
-		if time.Since(cached.CalculatedAt) < 2*time.Minute {
+	if cached, exists := cc.cache[timing.ValidatorSetID]; exists {
+		if cached.timing == timing && time.Since(cached.CalculatedAt) < 2*time.Minute {
 			cc.mu.RUnlock()
 			return cached, nil
 		}
 	}
 	cc.mu.RUnlock()
 
-	result, err := cc.performCalculation(validatorNodes)
-	if err != nil {
-		return nil, err
-	}
+	result := cc.performCalculation(canonicalValidators(validatorNodes), timing)
 
 	cc.mu.Lock()
-	cc.cache[cacheKey] = result
+	for setID, cached := range cc.cache {
+		if time.Since(cached.CalculatedAt) >= 2*time.Minute {
+			delete(cc.cache, setID)
+		}
+	}
+	cc.cache[timing.ValidatorSetID] = result
 	cc.mu.Unlock()
 
 	return result, nil
 }
 
-func (cc *ConsensusCalculator) performCalculation(validatorNodes []string) (*CalculationResult, error) {
-	timing, err := cc.timingManager.CalculateConsensusTiming(validatorNodes)
-	if err != nil {
-		return nil, fmt.Errorf("failed to calculate timing: %w", err)
-	}
-
+func (cc *ConsensusCalculator) performCalculation(validatorNodes []string, timing *ConsensusTiming) *CalculationResult {
 	nodeOffsets := make(map[string]time.Duration)
 	totalConfidence := 0.0
 	validOffsets := 0
@@ -96,6 +101,7 @@ func (cc *ConsensusCalculator) performCalculation(validatorNodes []string) (*Cal
 	}
 
 	result := &CalculationResult{
+		ValidatorSetID:   timing.ValidatorSetID,
 		OptimalBlockTime: timing.BlockTime,
 		MaxPropagation:   timing.MaxPropagation,
 		SafetyMargin:     timing.SafetyMargin,
@@ -103,6 +109,7 @@ func (cc *ConsensusCalculator) performCalculation(validatorNodes []string) (*Cal
 		Confidence:       overallConfidence,
 		CalculatedAt:     time.Now().UTC(),
 		ValidatorCount:   len(validatorNodes),
+		timing:           timing,
 	}
 
 	cc.logger.Info("Consensus parameters calculated",
@@ -112,7 +119,7 @@ func (cc *ConsensusCalculator) performCalculation(validatorNodes []string) (*Cal
 		zap.Int("validators", result.ValidatorCount),
 	)
 
-	return result, nil
+	return result
 }
 
 func (cc *ConsensusCalculator) CalculateOptimalBlockInterval(validatorNodes []string, networkLoad float64) (time.Duration, error) {
@@ -244,10 +251,6 @@ func (cc *ConsensusCalculator) CalculateFaultTolerance(validatorNodes []string)
 	return tolerance, nil
 }
 
-func (cc *ConsensusCalculator) generateCacheKey(validatorNodes []string) string {
-	return fmt.Sprintf("calc:%v", validatorNodes)
-}
-
 func (cc *ConsensusCalculator) ClearCache() {
 	cc.mu.Lock()
 	defer cc.mu.Unlock()
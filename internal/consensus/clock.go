@@ -0,0 +1,467 @@
+package consensus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/network"
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
+)
+
+type OffsetEstimator struct {
+	peering       *network.PeeringManager
+	timingManager *TimingManager
+	logger        *zap.Logger
+	correction    func() time.Duration
+	mu            sync.RWMutex
+	pending       map[string]chan *timeSyncResult
+	samples       map[string]*ClockSample
+	exchanges     int
+	timeout       time.Duration
+	sampleTTL     time.Duration
+	requestSeq    uint64
+}
+
+type TimeSyncPayload struct {
+	RequestID    string    `json:"request_id"`
+	OriginTime   time.Time `json:"origin_time"`
+	ReceiveTime  time.Time `json:"receive_time"`
+	TransmitTime time.Time `json:"transmit_time"`
+	// Correction is the responder's applied clock correction, already
+	// included in ReceiveTime and TransmitTime.
+	Correction time.Duration `json:"correction,omitempty"`
+}
+
+// ClockSample is a single four-timestamp exchange with a peer. Offset is the
+// peer clock minus the local clock; Uncertainty is the half-width of the
+// interval the offset is known to lie in once both one-way delays are bounded
+// below by the light delay between the two positions. PeerCorrection is the
+// correction the peer reported having applied when it answered.
+type ClockSample struct {
+	PeerID         string        `json:"peer_id"`
+	Offset         time.Duration `json:"offset"`
+	PeerCorrection time.Duration `json:"peer_correction"`
+	RoundTrip      time.Duration `json:"round_trip"`
+	LightDelay     time.Duration `json:"light_delay"`
+	Uncertainty    time.Duration `json:"uncertainty"`
+	MeasuredAt     time.Time     `json:"measured_at"`
+}
+
+type timeSyncResult struct {
+	receiveTime  time.Time
+	transmitTime time.Time
+	arrivalTime  time.Time
+	correction   time.Duration
+}
+
+type OffsetInterval struct {
+	Source string        `json:"source"`
+	Lower  time.Duration `json:"lower"`
+	Upper  time.Duration `json:"upper"`
+}
+
+type OffsetEstimate struct {
+	NodeID       string        `json:"node_id"`
+	Skew         time.Duration `json:"skew"`
+	Uncertainty  time.Duration `json:"uncertainty"`
+	Confidence   float64       `json:"confidence"`
+	References   int           `json:"references"`
+	Agreeing     []string      `json:"agreeing"`
+	Falsetickers []string      `json:"falsetickers,omitempty"`
+	EstimatedAt  time.Time     `json:"estimated_at"`
+}
+
+func NewOffsetEstimator(peering *network.PeeringManager, timingManager *TimingManager, logger *zap.Logger) *OffsetEstimator {
+	oe := &OffsetEstimator{
+		peering:       peering,
+		timingManager: timingManager,
+		logger:        logger,
+		pending:       make(map[string]chan *timeSyncResult),
+		samples:       make(map[string]*ClockSample),
+		exchanges:     8,
+		timeout:       5 * time.Second,
+		sampleTTL:     30 * time.Minute,
+	}
+
+	peering.RegisterHandler(network.MessageTypeTimeRequest, oe.handleTimeRequest)
+	peering.RegisterHandler(network.MessageTypeTimeResponse, oe.handleTimeResponse)
+
+	return oe
+}
+
+// SetClockCorrection sets where the local clock correction comes from. All
+// four exchange timestamps are read from the corrected clock, so an applied
+// correction shows up in every later measurement of this node.
+func (oe *OffsetEstimator) SetClockCorrection(correction func() time.Duration) {
+	oe.mu.Lock()
+	defer oe.mu.Unlock()
+	oe.correction = correction
+}
+
+func (oe *OffsetEstimator) localCorrection() time.Duration {
+	oe.mu.RLock()
+	correction := oe.correction
+	oe.mu.RUnlock()
+
+	if correction == nil {
+		return 0
+	}
+	return correction()
+}
+
+// ComputeClockSample applies the NTP on-wire algorithm to one exchange:
+// t1 request sent (local clock), t2 request received (peer clock), t3 response
+// sent (peer clock), t4 response received (local clock).
+func ComputeClockSample(t1, t2, t3, t4 time.Time, lightDelay time.Duration) (*ClockSample, error) {
+	roundTrip := t4.Sub(t1) - t3.Sub(t2)
+	if roundTrip < 0 {
+		return nil, fmt.Errorf("negative round trip: %v", roundTrip)
+	}
+	if roundTrip < 2*lightDelay {
+		return nil, fmt.Errorf("round trip %v is shorter than the light delay bound %v", roundTrip, 2*lightDelay)
+	}
+
+	offset := (t2.Sub(t1) + t3.Sub(t4)) / 2
+
+	return &ClockSample{
+		Offset:      offset,
+		RoundTrip:   roundTrip,
+		LightDelay:  lightDelay,
+		Uncertainty: roundTrip/2 - lightDelay,
+		MeasuredAt:  t4.UTC(),
+	}, nil
+}
+
+// MarzulloIntersection returns the smallest interval consistent with the
+// largest number of sources, along with the sources that agree with it.
+func MarzulloIntersection(intervals []OffsetInterval) (OffsetInterval, []string) {
+	if len(intervals) == 0 {
+		return OffsetInterval{}, nil
+	}
+
+	type edge struct {
+		value time.Duration
+		kind  int
+	}
+
+	edges := make([]edge, 0, len(intervals)*2)
+	for _, interval := range intervals {
+		edges = append(edges, edge{value: interval.Lower, kind: -1})
+		edges = append(edges, edge{value: interval.Upper, kind: 1})
+	}
+
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].value == edges[j].value {
+			return edges[i].kind < edges[j].kind
+		}
+		return edges[i].value < edges[j].value
+	})
+
+	best, count := 0, 0
+	var result OffsetInterval
+	for i, e := range edges {
+		count -= e.kind
+		if count > best && i+1 < len(edges) {
+			best = count
+			result = OffsetInterval{Lower: e.value, Upper: edges[i+1].value}
+		}
+	}
+
+	var agreeing []string
+	for _, interval := range intervals {
+		if interval.Lower <= result.Lower && interval.Upper >= result.Upper {
+			agreeing = append(agreeing, interval.Source)
+		}
+	}
+
+	return result, agreeing
+}
+
+func (oe *OffsetEstimator) MeasurePeer(ctx context.Context, peerID string) (*ClockSample, error) {
+	lightDelay := oe.lightDelayTo(peerID)
+
+	var best *ClockSample
+	var lastErr error
+	for i := 0; i < oe.exchanges; i++ {
+		sample, err := oe.exchange(ctx, peerID, lightDelay)
+		if err != nil {
+			lastErr = err
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+
+		if best == nil || sample.RoundTrip < best.RoundTrip {
+			best = sample
+		}
+	}
+
+	if best == nil {
+		return nil, fmt.Errorf("no valid clock samples from peer %s: %w", peerID, lastErr)
+	}
+
+	oe.mu.Lock()
+	oe.samples[peerID] = best
+	oe.mu.Unlock()
+
+	oe.logger.Debug("Peer clock measured",
+		zap.String("peer_id", peerID),
+		zap.Duration("offset", best.Offset),
+		zap.Duration("round_trip", best.RoundTrip),
+		zap.Duration("uncertainty", best.Uncertainty),
+	)
+
+	return best, nil
+}
+
+func (oe *OffsetEstimator) MeasureAllPeers(ctx context.Context) map[string]*ClockSample {
+	results := make(map[string]*ClockSample)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, conn := range oe.peering.GetActiveConnections() {
+		wg.Add(1)
+		go func(peerID string) {
+			defer wg.Done()
+
+			sample, err := oe.MeasurePeer(ctx, peerID)
+			if err != nil {
+				oe.logger.Debug("Failed to measure peer clock",
+					zap.String("peer_id", peerID),
+					zap.Error(err),
+				)
+				return
+			}
+
+			mu.Lock()
+			results[peerID] = sample
+			mu.Unlock()
+		}(conn.PeerID)
+	}
+
+	wg.Wait()
+	return results
+}
+
+func (oe *OffsetEstimator) exchange(ctx context.Context, peerID string, lightDelay time.Duration) (*ClockSample, error) {
+	oe.mu.Lock()
+	oe.requestSeq++
+	requestID := fmt.Sprintf("%s-%d", peerID, oe.requestSeq)
+	// Responses are matched on the peer as well as the ID, so a peer cannot
+	// answer a probe sent to another.
+	pendingKey := peerID + "/" + requestID
+	responseCh := make(chan *timeSyncResult, 1)
+	oe.pending[pendingKey] = responseCh
+	oe.mu.Unlock()
+
+	defer func() {
+		oe.mu.Lock()
+		delete(oe.pending, pendingKey)
+		oe.mu.Unlock()
+	}()
+
+	request := &TimeSyncPayload{
+		RequestID:  requestID,
+		OriginTime: time.Now().UTC().Add(oe.localCorrection()),
+	}
+	data, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal time request: %w", err)
+	}
+
+	if err := oe.peering.SendPeerMessage(peerID, network.MessageTypeTimeRequest, data); err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(oe.timeout)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-timer.C:
+		return nil, fmt.Errorf("time request %s timed out", requestID)
+	case result := <-responseCh:
+		sample, err := ComputeClockSample(request.OriginTime, result.receiveTime, result.transmitTime, result.arrivalTime, lightDelay)
+		if err != nil {
+			return nil, err
+		}
+		sample.PeerID = peerID
+		sample.PeerCorrection = result.correction
+		return sample, nil
+	}
+}
+
+func (oe *OffsetEstimator) handleTimeRequest(conn *network.PeerConnection, message *network.PeerMessage) {
+	request := &TimeSyncPayload{}
+	if err := json.Unmarshal(message.Payload, request); err != nil {
+		oe.logger.Warn("Invalid time request",
+			zap.String("peer_id", conn.PeerID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	correction := oe.localCorrection()
+	request.ReceiveTime = message.ReceivedAt.Add(correction)
+	request.TransmitTime = time.Now().UTC().Add(correction)
+	request.Correction = correction
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		return
+	}
+
+	if err := oe.peering.SendPeerMessage(conn.PeerID, network.MessageTypeTimeResponse, data); err != nil {
+		oe.logger.Debug("Failed to answer time request",
+			zap.String("peer_id", conn.PeerID),
+			zap.Error(err),
+		)
+	}
+}
+
+func (oe *OffsetEstimator) handleTimeResponse(conn *network.PeerConnection, message *network.PeerMessage) {
+	response := &TimeSyncPayload{}
+	if err := json.Unmarshal(message.Payload, response); err != nil {
+		oe.logger.Warn("Invalid time response",
+			zap.String("peer_id", conn.PeerID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	oe.mu.RLock()
+	responseCh, exists := oe.pending[conn.PeerID+"/"+response.RequestID]
+	oe.mu.RUnlock()
+	if !exists {
+		return
+	}
+
+	result := &timeSyncResult{
+		receiveTime:  response.ReceiveTime,
+		transmitTime: response.TransmitTime,
+		arrivalTime:  message.ReceivedAt.Add(oe.localCorrection()),
+		correction:   response.Correction,
+	}
+
+	select {
+	case responseCh <- result:
+	default:
+	}
+}
+
+func (oe *OffsetEstimator) GetSample(peerID string) *ClockSample {
+	oe.mu.RLock()
+	defer oe.mu.RUnlock()
+
+	sample, exists := oe.samples[peerID]
+	if !exists || time.Since(sample.MeasuredAt) > oe.sampleTTL {
+		return nil
+	}
+	return sample
+}
+
+// ClockOffset returns the peer's offset from its latest unexpired sample,
+// for splitting probe round trips into one-way delays.
+func (oe *OffsetEstimator) ClockOffset(peerID string) (time.Duration, bool) {
+	sample := oe.GetSample(peerID)
+	if sample == nil {
+		return 0, false
+	}
+	return sample.Offset, true
+}
+
+// EstimateOffset returns the skew of nodeID's clock relative to the time the
+// reference nodes agree on. Each reference contributes the interval its
+// sample allows; Marzullo's algorithm discards the falsetickers.
+func (oe *OffsetEstimator) EstimateOffset(nodeID string, referenceNodes []string) (*OffsetEstimate, error) {
+	localID := oe.peering.LocalPeerID()
+
+	intervals := make([]OffsetInterval, 0, len(referenceNodes))
+	for _, refID := range referenceNodes {
+		if refID == nodeID || refID == localID {
+			continue
+		}
+		sample := oe.GetSample(refID)
+		if sample == nil {
+			continue
+		}
+		intervals = append(intervals, OffsetInterval{
+			Source: refID,
+			Lower:  sample.Offset - sample.Uncertainty,
+			Upper:  sample.Offset + sample.Uncertainty,
+		})
+	}
+
+	if len(intervals) == 0 {
+		return nil, fmt.Errorf("no clock samples for reference nodes of %s", nodeID)
+	}
+
+	consensus, agreeing := MarzulloIntersection(intervals)
+	referenceOffset := (consensus.Lower + consensus.Upper) / 2
+	referenceUncertainty := (consensus.Upper - consensus.Lower) / 2
+
+	estimate := &OffsetEstimate{
+		NodeID:      nodeID,
+		References:  len(intervals),
+		Agreeing:    agreeing,
+		EstimatedAt: time.Now().UTC(),
+	}
+
+	agreed := make(map[string]bool, len(agreeing))
+	for _, id := range agreeing {
+		agreed[id] = true
+	}
+	for _, interval := range intervals {
+		if !agreed[interval.Source] {
+			estimate.Falsetickers = append(estimate.Falsetickers, interval.Source)
+		}
+	}
+
+	if nodeID == localID {
+		estimate.Skew = -referenceOffset
+		estimate.Uncertainty = referenceUncertainty
+	} else {
+		sample := oe.GetSample(nodeID)
+		if sample == nil {
+			return nil, fmt.Errorf("no clock sample for node %s", nodeID)
+		}
+		estimate.Skew = sample.Offset - referenceOffset
+		estimate.Uncertainty = sample.Uncertainty + referenceUncertainty
+	}
+
+	agreement := float64(len(agreeing)) / float64(len(intervals))
+	estimate.Confidence = agreement / (1 + float64(estimate.Uncertainty)/float64(100*time.Millisecond))
+
+	return estimate, nil
+}
+
+func (oe *OffsetEstimator) lightDelayTo(peerID string) time.Duration {
+	topology := oe.timingManager.topologyManager
+	if topology == nil {
+		return 0
+	}
+
+	local, err := topology.GetNode(oe.peering.LocalPeerID())
+	if err != nil {
+		return 0
+	}
+	remote, err := topology.GetNode(peerID)
+	if err != nil {
+		return 0
+	}
+
+	distance, err := oe.timingManager.calculateDistance(local.Position, remote.Position)
+	if err != nil {
+		return 0
+	}
+
+	return time.Duration(distance / types.SpeedOfLight * float64(time.Second))
+}
@@ -0,0 +1,329 @@
+package consensus
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
+)
+
+type DriftConfig struct {
+	MaxDriftPPM   float64       `json:"max_drift_ppm"`
+	HistorySize   int           `json:"history_size"`
+	MinSamples    int           `json:"min_samples"`
+	MaxHistoryAge time.Duration `json:"max_history_age"`
+}
+
+func DefaultDriftConfig() *DriftConfig {
+	return &DriftConfig{
+		MaxDriftPPM:   100,
+		HistorySize:   288,
+		MinSamples:    3,
+		MaxHistoryAge: 24 * time.Hour,
+	}
+}
+
+type OffsetPoint struct {
+	Timestamp   time.Time     `json:"timestamp"`
+	Offset      time.Duration `json:"offset"`
+	Uncertainty time.Duration `json:"uncertainty"`
+}
+
+// DriftModel is a least-squares line through a node's offset history.
+// DriftPPM is the slope of the offset in parts per million; predictions are
+// anchored at ReferenceTime, the mean sample time, where the fit is tightest.
+type DriftModel struct {
+	NodeID              string        `json:"node_id"`
+	DriftPPM            float64       `json:"drift_ppm"`
+	DriftUncertaintyPPM float64       `json:"drift_uncertainty_ppm"`
+	ReferenceTime       time.Time     `json:"reference_time"`
+	ReferenceOffset     time.Duration `json:"reference_offset"`
+	ResidualStdDev      time.Duration `json:"residual_std_dev"`
+	MeanUncertainty     time.Duration `json:"mean_uncertainty"`
+	Samples             int           `json:"samples"`
+	FittedAt            time.Time     `json:"fitted_at"`
+
+	sumSquares float64
+}
+
+type DriftAlert struct {
+	ID        string              `json:"id"`
+	NodeID    string              `json:"node_id"`
+	Type      types.AlertType     `json:"type"`
+	Severity  types.AlertSeverity `json:"severity"`
+	DriftPPM  float64             `json:"drift_ppm"`
+	BudgetPPM float64             `json:"budget_ppm"`
+	Message   string              `json:"message"`
+	Timestamp time.Time           `json:"timestamp"`
+	Resolved  bool                `json:"resolved"`
+}
+
+type DriftAlertHandler func(alert *DriftAlert)
+
+type DriftTracker struct {
+	config   *DriftConfig
+	logger   *zap.Logger
+	mu       sync.RWMutex
+	history  map[string][]OffsetPoint
+	models   map[string]*DriftModel
+	alerts   map[string]*DriftAlert
+	handlers []DriftAlertHandler
+}
+
+func NewDriftTracker(config *DriftConfig, logger *zap.Logger) *DriftTracker {
+	if config == nil {
+		config = DefaultDriftConfig()
+	}
+
+	return &DriftTracker{
+		config:  config,
+		logger:  logger,
+		history: make(map[string][]OffsetPoint),
+		models:  make(map[string]*DriftModel),
+		alerts:  make(map[string]*DriftAlert),
+	}
+}
+
+func FitDrift(points []OffsetPoint) (*DriftModel, error) {
+	n := len(points)
+	if n < 2 {
+		return nil, fmt.Errorf("at least 2 points are required to fit drift, got %d", n)
+	}
+
+	reference := points[0].Timestamp
+	var meanX, meanY, meanU float64
+	for _, p := range points {
+		meanX += float64(p.Timestamp.Sub(reference))
+		meanY += float64(p.Offset)
+		meanU += float64(p.Uncertainty)
+	}
+	meanX /= float64(n)
+	meanY /= float64(n)
+	meanU /= float64(n)
+
+	var sxx, sxy float64
+	for _, p := range points {
+		dx := float64(p.Timestamp.Sub(reference)) - meanX
+		sxx += dx * dx
+		sxy += dx * (float64(p.Offset) - meanY)
+	}
+
+	if sxx == 0 {
+		return nil, fmt.Errorf("offset history spans no time")
+	}
+
+	slope := sxy / sxx
+
+	var residuals float64
+	for _, p := range points {
+		x := float64(p.Timestamp.Sub(reference)) - meanX
+		r := float64(p.Offset) - (meanY + slope*x)
+		residuals += r * r
+	}
+
+	var residualStdDev, slopeStdErr float64
+	if n > 2 {
+		residualStdDev = math.Sqrt(residuals / float64(n-2))
+		slopeStdErr = residualStdDev / math.Sqrt(sxx)
+	}
+
+	return &DriftModel{
+		DriftPPM:            slope * 1e6,
+		DriftUncertaintyPPM: slopeStdErr * 1e6,
+		ReferenceTime:       reference.Add(time.Duration(meanX)),
+		ReferenceOffset:     time.Duration(meanY),
+		ResidualStdDev:      time.Duration(residualStdDev),
+		MeanUncertainty:     time.Duration(meanU),
+		Samples:             n,
+		sumSquares:          sxx,
+		FittedAt:            time.Now().UTC(),
+	}, nil
+}
+
+// Predict extrapolates the offset to the given instant. The returned
+// uncertainty widens with distance from the reference time, following the
+// standard error of a regression prediction.
+func (m *DriftModel) Predict(at time.Time) (time.Duration, time.Duration) {
+	dx := float64(at.Sub(m.ReferenceTime))
+	offset := float64(m.ReferenceOffset) + m.DriftPPM/1e6*dx
+
+	spread := 1.0 / float64(m.Samples)
+	if m.sumSquares > 0 {
+		spread += dx * dx / m.sumSquares
+	}
+	uncertainty := float64(m.ResidualStdDev)*math.Sqrt(spread) + float64(m.MeanUncertainty)
+
+	return time.Duration(offset), time.Duration(uncertainty)
+}
+
+func (dt *DriftTracker) Record(nodeID string, point OffsetPoint) *DriftModel {
+	dt.mu.Lock()
+
+	points := append(dt.history[nodeID], point)
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].Timestamp.Before(points[j].Timestamp)
+	})
+
+	cutoff := time.Now().Add(-dt.config.MaxHistoryAge)
+	start := 0
+	for start < len(points) && points[start].Timestamp.Before(cutoff) {
+		start++
+	}
+	if len(points)-start > dt.config.HistorySize {
+		start = len(points) - dt.config.HistorySize
+	}
+	points = points[start:]
+	dt.history[nodeID] = points
+
+	if len(points) < dt.config.MinSamples {
+		dt.mu.Unlock()
+		return nil
+	}
+
+	model, err := FitDrift(points)
+	if err != nil {
+		dt.mu.Unlock()
+		dt.logger.Debug("Failed to fit drift model",
+			zap.String("node_id", nodeID),
+			zap.Error(err),
+		)
+		return nil
+	}
+	model.NodeID = nodeID
+	dt.models[nodeID] = model
+
+	alert := dt.evaluateBudget(model)
+	handlers := dt.handlers
+	dt.mu.Unlock()
+
+	if alert != nil {
+		for _, handler := range handlers {
+			handler(alert)
+		}
+	}
+
+	return model
+}
+
+func (dt *DriftTracker) evaluateBudget(model *DriftModel) *DriftAlert {
+	existing, alerting := dt.alerts[model.NodeID]
+	exceeded := math.Abs(model.DriftPPM) > dt.config.MaxDriftPPM
+
+	switch {
+	case exceeded && !alerting:
+		severity := types.AlertSeverityWarning
+		if math.Abs(model.DriftPPM) > 2*dt.config.MaxDriftPPM {
+			severity = types.AlertSeverityCritical
+		}
+
+		alert := &DriftAlert{
+			ID:        fmt.Sprintf("clock_drift_%s_%d", model.NodeID, time.Now().Unix()),
+			NodeID:    model.NodeID,
+			Type:      types.AlertTypeClockDrift,
+			Severity:  severity,
+			DriftPPM:  model.DriftPPM,
+			BudgetPPM: dt.config.MaxDriftPPM,
+			Message:   fmt.Sprintf("Clock drift of node %s is %.2f ppm, budget is %.2f ppm", model.NodeID, model.DriftPPM, dt.config.MaxDriftPPM),
+			Timestamp: time.Now().UTC(),
+		}
+		dt.alerts[model.NodeID] = alert
+
+		dt.logger.Warn("Clock drift budget exceeded",
+			zap.String("node_id", model.NodeID),
+			zap.Float64("drift_ppm", model.DriftPPM),
+			zap.Float64("budget_ppm", dt.config.MaxDriftPPM),
+		)
+		return alert
+
+	case !exceeded && alerting:
+		delete(dt.alerts, model.NodeID)
+
+		resolved := *existing
+		resolved.Resolved = true
+		resolved.DriftPPM = model.DriftPPM
+		resolved.Timestamp = time.Now().UTC()
+
+		dt.logger.Info("Clock drift back within budget",
+			zap.String("node_id", model.NodeID),
+			zap.Float64("drift_ppm", model.DriftPPM),
+		)
+		return &resolved
+	}
+
+	return nil
+}
+
+func (dt *DriftTracker) GetModel(nodeID string) (*DriftModel, error) {
+	dt.mu.RLock()
+	defer dt.mu.RUnlock()
+
+	model, exists := dt.models[nodeID]
+	if !exists {
+		return nil, fmt.Errorf("no drift model for node %s", nodeID)
+	}
+	return model, nil
+}
+
+func (dt *DriftTracker) GetHistory(nodeID string) []OffsetPoint {
+	dt.mu.RLock()
+	defer dt.mu.RUnlock()
+
+	history := make([]OffsetPoint, len(dt.history[nodeID]))
+	copy(history, dt.history[nodeID])
+	return history
+}
+
+func (dt *DriftTracker) PredictOffset(nodeID string, at time.Time) (time.Duration, time.Duration, error) {
+	model, err := dt.GetModel(nodeID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	offset, uncertainty := model.Predict(at)
+	return offset, uncertainty, nil
+}
+
+func (dt *DriftTracker) OnAlert(handler DriftAlertHandler) {
+	dt.mu.Lock()
+	defer dt.mu.Unlock()
+	dt.handlers = append(dt.handlers, handler)
+}
+
+func (dt *DriftTracker) GetAlerts() []*DriftAlert {
+	dt.mu.RLock()
+	defer dt.mu.RUnlock()
+
+	alerts := make([]*DriftAlert, 0, len(dt.alerts))
+	for _, alert := range dt.alerts {
+		alerts = append(alerts, alert)
+	}
+	return alerts
+}
+
+func (dt *DriftTracker) IsAlerting(nodeID string) bool {
+	dt.mu.RLock()
+	defer dt.mu.RUnlock()
+
+	_, alerting := dt.alerts[nodeID]
+	return alerting
+}
+
+func (dt *DriftTracker) SetBudget(maxDriftPPM float64) {
+	dt.mu.Lock()
+	defer dt.mu.Unlock()
+	dt.config.MaxDriftPPM = maxDriftPPM
+}
+
+func (dt *DriftTracker) Remove(nodeID string) {
+	dt.mu.Lock()
+	defer dt.mu.Unlock()
+
+	delete(dt.history, nodeID)
+	delete(dt.models, nodeID)
+	delete(dt.alerts, nodeID)
+}
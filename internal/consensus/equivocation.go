@@ -0,0 +1,486 @@
+package consensus
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/security"
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
+)
+
+type EvidenceType string
+
+const (
+	EvidenceDoubleProposal EvidenceType = "double_proposal"
+	EvidenceDoubleVote     EvidenceType = "double_vote"
+)
+
+type EvidenceStatus string
+
+const (
+	// EvidenceConfirmed means both conflicting messages were signed within
+	// one propagation window and arrived on time, so the offender had to
+	// sign both while the round was live.
+	EvidenceConfirmed EvidenceStatus = "confirmed"
+	// EvidenceSuspected means at least one message arrived later than light
+	// delay allows for its timestamp, so it may be a stale message from an
+	// earlier view rather than deliberate equivocation.
+	EvidenceSuspected EvidenceStatus = "suspected"
+)
+
+// EquivocationConfig bounds what the detector keeps. Messages are tracked
+// for heights from RetainHeights below the committed height to MaxLead above
+// it, and at most MaxMessagesPerSlot distinct messages per signer and slot;
+// two already prove the offence.
+type EquivocationConfig struct {
+	MinWindow          time.Duration `json:"min_window"`
+	RetainHeights      uint64        `json:"retain_heights"`
+	MaxLead            uint64        `json:"max_lead"`
+	MaxMessagesPerSlot int           `json:"max_messages_per_slot"`
+	MaxEvidence        int           `json:"max_evidence"`
+	SafetyFactor       float64       `json:"safety_factor"`
+}
+
+func DefaultEquivocationConfig() *EquivocationConfig {
+	return &EquivocationConfig{
+		MinWindow:          100 * time.Millisecond,
+		RetainHeights:      1000,
+		MaxLead:            100,
+		MaxMessagesPerSlot: 4,
+		MaxEvidence:        10000,
+		SafetyFactor:       types.ConsensusSafetyFactor,
+	}
+}
+
+type ObservedMessage struct {
+	Hash       string          `json:"hash"`
+	Timestamp  time.Time       `json:"timestamp"`
+	ReceivedAt time.Time       `json:"received_at"`
+	Lateness   time.Duration   `json:"lateness"`
+	Message    json.RawMessage `json:"message"`
+}
+
+type Evidence struct {
+	ID           string          `json:"id"`
+	Type         EvidenceType    `json:"type"`
+	Status       EvidenceStatus  `json:"status"`
+	Offender     string          `json:"offender"`
+	Height       uint64          `json:"height"`
+	Round        uint32          `json:"round"`
+	First        ObservedMessage `json:"first"`
+	Second       ObservedMessage `json:"second"`
+	TimestampGap time.Duration   `json:"timestamp_gap"`
+	Window       time.Duration   `json:"window"`
+	DetectedBy   string          `json:"detected_by"`
+	DetectedAt   time.Time       `json:"detected_at"`
+	KeyType      string          `json:"key_type,omitempty"`
+	PublicKey    string          `json:"public_key,omitempty"`
+	Signature    string          `json:"signature,omitempty"`
+}
+
+// Digest is the hash covered by the evidence signature. It excludes the
+// signature itself and the detector's key.
+func (e *Evidence) Digest() ([]byte, error) {
+	unsigned := *e
+	unsigned.ID = ""
+	unsigned.KeyType = ""
+	unsigned.PublicKey = ""
+	unsigned.Signature = ""
+
+	data, err := json.Marshal(&unsigned)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode evidence: %w", err)
+	}
+	digest := sha256.Sum256(data)
+	return digest[:], nil
+}
+
+// VerifyEvidence checks that the evidence is signed by the key it carries.
+// ConsensusManager.VerifyEvidence also ties that key to DetectedBy.
+func VerifyEvidence(e *Evidence) error {
+	if e.Signature == "" {
+		return fmt.Errorf("evidence is unsigned")
+	}
+	publicKey, err := hex.DecodeString(e.PublicKey)
+	if err != nil {
+		return fmt.Errorf("invalid evidence public key: %w", err)
+	}
+	signature, err := hex.DecodeString(e.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid evidence signature encoding: %w", err)
+	}
+
+	digest, err := e.Digest()
+	if err != nil {
+		return err
+	}
+	key := &security.PublicKey{Type: security.KeyType(e.KeyType), Key: publicKey}
+	if err := security.VerifySignature(key, digest, signature); err != nil {
+		return fmt.Errorf("evidence signature verification failed: %w", err)
+	}
+	if e.ID != hex.EncodeToString(digest) {
+		return fmt.Errorf("evidence id does not match its content")
+	}
+	return nil
+}
+
+type EvidenceFilter struct {
+	Offender   string         `json:"offender,omitempty"`
+	Type       EvidenceType   `json:"type,omitempty"`
+	Status     EvidenceStatus `json:"status,omitempty"`
+	FromHeight uint64         `json:"from_height,omitempty"`
+	ToHeight   uint64         `json:"to_height,omitempty"`
+	Limit      int            `json:"limit,omitempty"`
+}
+
+func (f *EvidenceFilter) Matches(e *Evidence) bool {
+	if f.Offender != "" && e.Offender != f.Offender {
+		return false
+	}
+	if f.Type != "" && e.Type != f.Type {
+		return false
+	}
+	if f.Status != "" && e.Status != f.Status {
+		return false
+	}
+	if f.FromHeight > 0 && e.Height < f.FromHeight {
+		return false
+	}
+	if f.ToHeight > 0 && e.Height > f.ToHeight {
+		return false
+	}
+	return true
+}
+
+type slotKey struct {
+	height uint64
+	round  uint32
+}
+
+type EquivocationDetector struct {
+	timingManager *TimingManager
+	config        *EquivocationConfig
+	logger        *zap.Logger
+	mu            sync.RWMutex
+	localNodeID   string
+	signingKey    *security.SigningKey
+	proposals     map[slotKey]map[string][]ObservedMessage
+	votes         map[slotKey]map[string][]ObservedMessage
+	evidence      map[string]*Evidence
+	evidenceOrder []string
+	committed     uint64
+}
+
+func NewEquivocationDetector(timingManager *TimingManager, config *EquivocationConfig, logger *zap.Logger) *EquivocationDetector {
+	if config == nil {
+		config = DefaultEquivocationConfig()
+	}
+
+	return &EquivocationDetector{
+		timingManager: timingManager,
+		config:        config,
+		logger:        logger,
+		proposals:     make(map[slotKey]map[string][]ObservedMessage),
+		votes:         make(map[slotKey]map[string][]ObservedMessage),
+		evidence:      make(map[string]*Evidence),
+	}
+}
+
+// SetSigner sets the identity that evidence is attributed to and the key it
+// is signed with, normally the validator key registered under that ID so
+// peers can check the signature against it. A nil key keeps the current one.
+// Without a key, evidence is still recorded but left unsigned.
+func (ed *EquivocationDetector) SetSigner(localNodeID string, key *security.SigningKey) {
+	ed.mu.Lock()
+	defer ed.mu.Unlock()
+	ed.localNodeID = localNodeID
+	if key != nil {
+		ed.signingKey = key
+	}
+}
+
+// SetCommittedHeight moves the retention window as blocks are committed.
+// Only committed heights move it, so a validator signing messages at far
+// future heights cannot push everyone else's history out. It never moves
+// back.
+func (ed *EquivocationDetector) SetCommittedHeight(height uint64) {
+	ed.mu.Lock()
+	defer ed.mu.Unlock()
+
+	if height <= ed.committed {
+		return
+	}
+	ed.committed = height
+	ed.pruneLocked()
+}
+
+func (ed *EquivocationDetector) IngestProposal(proposal *types.Proposal, receivedAt time.Time) (*Evidence, error) {
+	if proposal == nil || proposal.Block == nil {
+		return nil, fmt.Errorf("proposal and block cannot be nil")
+	}
+	if proposal.ProposerID == "" {
+		return nil, fmt.Errorf("proposal has no proposer")
+	}
+
+	raw, err := json.Marshal(proposal)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode proposal: %w", err)
+	}
+
+	observed := ObservedMessage{
+		Hash:       proposal.Block.Hash,
+		Timestamp:  proposal.Timestamp,
+		ReceivedAt: receivedAt,
+		Message:    raw,
+	}
+
+	return ed.ingest(ed.proposals, EvidenceDoubleProposal, proposal.ProposerID, slotKey{proposal.Height, proposal.Round}, observed), nil
+}
+
+func (ed *EquivocationDetector) IngestVote(vote *types.Vote, receivedAt time.Time) (*Evidence, error) {
+	if vote == nil {
+		return nil, fmt.Errorf("vote cannot be nil")
+	}
+	if vote.VoterID == "" {
+		return nil, fmt.Errorf("vote has no voter")
+	}
+
+	raw, err := json.Marshal(vote)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode vote: %w", err)
+	}
+
+	observed := ObservedMessage{
+		Hash:       vote.BlockHash,
+		Timestamp:  vote.Timestamp,
+		ReceivedAt: receivedAt,
+		Message:    raw,
+	}
+
+	return ed.ingest(ed.votes, EvidenceDoubleVote, vote.VoterID, slotKey{vote.Height, vote.Round}, observed), nil
+}
+
+func (ed *EquivocationDetector) ingest(store map[slotKey]map[string][]ObservedMessage, evidenceType EvidenceType, signer string, slot slotKey, observed ObservedMessage) *Evidence {
+	expected, window := ed.propagationWindow(signer)
+	observed.Lateness = observed.ReceivedAt.Sub(observed.Timestamp) - expected
+
+	ed.mu.Lock()
+	defer ed.mu.Unlock()
+
+	if !ed.inWindowLocked(slot.height) {
+		return nil
+	}
+
+	bySigner, exists := store[slot]
+	if !exists {
+		bySigner = make(map[string][]ObservedMessage)
+		store[slot] = bySigner
+	}
+
+	seen := bySigner[signer]
+	for _, previous := range seen {
+		if previous.Hash == observed.Hash {
+			return nil
+		}
+	}
+	if len(seen) >= ed.config.MaxMessagesPerSlot {
+		return nil
+	}
+	bySigner[signer] = append(seen, observed)
+
+	if len(seen) == 0 {
+		return nil
+	}
+
+	// Evidence pairs the first message seen with each conflicting one; a
+	// single pair is enough to prove the offence.
+	first := seen[0]
+	evidence := &Evidence{
+		Type:         evidenceType,
+		Status:       EvidenceConfirmed,
+		Offender:     signer,
+		Height:       slot.height,
+		Round:        slot.round,
+		First:        first,
+		Second:       observed,
+		TimestampGap: absDuration(observed.Timestamp.Sub(first.Timestamp)),
+		Window:       window,
+		DetectedBy:   ed.localNodeID,
+		DetectedAt:   time.Now().UTC(),
+	}
+	if first.Lateness > window || observed.Lateness > window || evidence.TimestampGap > window {
+		evidence.Status = EvidenceSuspected
+	}
+
+	if err := ed.signLocked(evidence); err != nil {
+		ed.logger.Error("Failed to sign equivocation evidence",
+			zap.String("offender", signer),
+			zap.Error(err),
+		)
+		if digest, err := evidence.Digest(); err == nil {
+			evidence.ID = hex.EncodeToString(digest)
+		} else {
+			return nil
+		}
+	}
+
+	ed.evidence[evidence.ID] = evidence
+	ed.evidenceOrder = append(ed.evidenceOrder, evidence.ID)
+	if len(ed.evidenceOrder) > ed.config.MaxEvidence {
+		delete(ed.evidence, ed.evidenceOrder[0])
+		ed.evidenceOrder = ed.evidenceOrder[1:]
+	}
+
+	ed.logger.Warn("Equivocation detected",
+		zap.String("type", string(evidenceType)),
+		zap.String("status", string(evidence.Status)),
+		zap.String("offender", signer),
+		zap.Uint64("height", slot.height),
+		zap.Uint32("round", slot.round),
+		zap.String("first_hash", first.Hash),
+		zap.String("second_hash", observed.Hash),
+	)
+
+	return evidence
+}
+
+// propagationWindow returns the expected network delay from the signer to
+// this node and the tolerance around it. Unknown positions fall back to the
+// configured minimum window.
+func (ed *EquivocationDetector) propagationWindow(signer string) (time.Duration, time.Duration) {
+	ed.mu.RLock()
+	localNodeID := ed.localNodeID
+	ed.mu.RUnlock()
+
+	window := ed.config.MinWindow
+	if localNodeID == "" || signer == localNodeID {
+		return 0, window
+	}
+
+	expected, err := ed.timingManager.ExpectedNetworkDelay(signer, localNodeID)
+	if err != nil {
+		return 0, window
+	}
+
+	if scaled := time.Duration(float64(expected) * ed.config.SafetyFactor); scaled > window {
+		window = scaled
+	}
+	return expected, window
+}
+
+func (ed *EquivocationDetector) signLocked(evidence *Evidence) error {
+	if ed.signingKey == nil {
+		return fmt.Errorf("no evidence signing key configured")
+	}
+
+	digest, err := evidence.Digest()
+	if err != nil {
+		return err
+	}
+	publicKey := ed.signingKey.Public()
+	evidence.ID = hex.EncodeToString(digest)
+	evidence.KeyType = string(publicKey.Type)
+	evidence.PublicKey = publicKey.String()
+	evidence.Signature = hex.EncodeToString(ed.signingKey.Sign(digest))
+	return nil
+}
+
+// inWindowLocked compares by difference so that no height, however large,
+// overflows.
+func (ed *EquivocationDetector) inWindowLocked(height uint64) bool {
+	if height < ed.committed {
+		return ed.committed-height <= ed.config.RetainHeights
+	}
+	return height-ed.committed <= ed.config.MaxLead
+}
+
+// pruneLocked drops slots that fell behind the window. Slots ahead of it
+// were never stored.
+func (ed *EquivocationDetector) pruneLocked() {
+	for slot := range ed.proposals {
+		if !ed.inWindowLocked(slot.height) {
+			delete(ed.proposals, slot)
+		}
+	}
+	for slot := range ed.votes {
+		if !ed.inWindowLocked(slot.height) {
+			delete(ed.votes, slot)
+		}
+	}
+}
+
+func (ed *EquivocationDetector) GetEvidence(id string) (*Evidence, error) {
+	ed.mu.RLock()
+	defer ed.mu.RUnlock()
+
+	evidence, exists := ed.evidence[id]
+	if !exists {
+		return nil, fmt.Errorf("evidence %s not found", id)
+	}
+	return evidence, nil
+}
+
+func (ed *EquivocationDetector) QueryEvidence(filter *EvidenceFilter) []*Evidence {
+	ed.mu.RLock()
+	defer ed.mu.RUnlock()
+
+	var results []*Evidence
+	for _, id := range ed.evidenceOrder {
+		evidence := ed.evidence[id]
+		if filter == nil || filter.Matches(evidence) {
+			results = append(results, evidence)
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Height > results[j].Height
+	})
+
+	if filter != nil && filter.Limit > 0 && len(results) > filter.Limit {
+		results = results[:filter.Limit]
+	}
+	return results
+}
+
+// ExportEvidence serialises matching evidence for submission elsewhere.
+// Each record carries its own signature and can be checked with
+// VerifyEvidence.
+func (ed *EquivocationDetector) ExportEvidence(filter *EvidenceFilter) ([]byte, error) {
+	records := ed.QueryEvidence(filter)
+	if records == nil {
+		records = []*Evidence{}
+	}
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to export evidence: %w", err)
+	}
+	return data, nil
+}
+
+func (ed *EquivocationDetector) GetStats() map[string]interface{} {
+	ed.mu.RLock()
+	defer ed.mu.RUnlock()
+
+	byType := make(map[EvidenceType]int)
+	byStatus := make(map[EvidenceStatus]int)
+	for _, evidence := range ed.evidence {
+		byType[evidence.Type]++
+		byStatus[evidence.Status]++
+	}
+
+	return map[string]interface{}{
+		"total_evidence":   len(ed.evidence),
+		"by_type":          byType,
+		"by_status":        byStatus,
+		"tracked_heights":  len(ed.proposals) + len(ed.votes),
+		"committed_height": ed.committed,
+	}
+}
@@ -0,0 +1,319 @@
+package consensus
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/network"
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
+)
+
+// z-score of the 99th percentile of a normal distribution.
+const p99ZScore = 2.326
+
+type FinalityParameters struct {
+	RoundsToCommit    int     `json:"rounds_to_commit"`
+	PhasesPerRound    int     `json:"phases_per_round"`
+	QuorumFraction    float64 `json:"quorum_fraction"`
+	Confirmations     int     `json:"confirmations"`
+	DefaultJitterRate float64 `json:"default_jitter_rate"`
+}
+
+func DefaultFinalityParameters() *FinalityParameters {
+	return &FinalityParameters{
+		RoundsToCommit:    1,
+		PhasesPerRound:    3,
+		QuorumFraction:    2.0 / 3.0,
+		Confirmations:     0,
+		DefaultJitterRate: 0.1,
+	}
+}
+
+type FinalityRequest struct {
+	OriginNode string    `json:"origin_node"`
+	Validators []string  `json:"validators"`
+	Height     uint64    `json:"height"`
+	ProposedAt time.Time `json:"proposed_at"`
+}
+
+type FinalityEstimate struct {
+	OriginNode      string              `json:"origin_node"`
+	Height          uint64              `json:"height,omitempty"`
+	FinalHeight     uint64              `json:"final_height,omitempty"`
+	ValidatorCount  int                 `json:"validator_count"`
+	Quorum          int                 `json:"quorum"`
+	BlockTime       time.Duration       `json:"block_time"`
+	OriginDelay     time.Duration       `json:"origin_delay"`
+	InclusionDelay  time.Duration       `json:"inclusion_delay"`
+	PhaseLatency    time.Duration       `json:"phase_latency"`
+	PhaseLatencyP99 time.Duration       `json:"phase_latency_p99"`
+	Expected        time.Duration       `json:"expected"`
+	P99             time.Duration       `json:"p99"`
+	StartAt         time.Time           `json:"start_at"`
+	ExpectedFinalAt time.Time           `json:"expected_final_at"`
+	P99FinalAt      time.Time           `json:"p99_final_at"`
+	MeasuredLinks   int                 `json:"measured_links"`
+	Parameters      *FinalityParameters `json:"parameters"`
+	EstimatedAt     time.Time           `json:"estimated_at"`
+}
+
+// DelayMatrix holds one-way delays and their jitter between every ordered
+// pair of nodes. Delay[i][j] is the time for a message from Nodes[i] to
+// reach Nodes[j].
+type DelayMatrix struct {
+	Nodes  []string
+	Delay  [][]time.Duration
+	Jitter [][]time.Duration
+}
+
+func NewDelayMatrix(nodes []string) *DelayMatrix {
+	m := &DelayMatrix{
+		Nodes:  nodes,
+		Delay:  make([][]time.Duration, len(nodes)),
+		Jitter: make([][]time.Duration, len(nodes)),
+	}
+	for i := range nodes {
+		m.Delay[i] = make([]time.Duration, len(nodes))
+		m.Jitter[i] = make([]time.Duration, len(nodes))
+	}
+	return m
+}
+
+// QuorumLatency is the time for a quorum of nodes to each hear from a quorum
+// of nodes in one all-to-all phase. Each link is pessimised by z standard
+// deviations of its jitter, so z=0 gives the expected latency.
+func (m *DelayMatrix) QuorumLatency(quorum int, z float64) time.Duration {
+	n := len(m.Nodes)
+	if n == 0 || quorum <= 0 {
+		return 0
+	}
+	if quorum > n {
+		quorum = n
+	}
+
+	completion := make([]time.Duration, n)
+	incoming := make([]time.Duration, n)
+	for to := 0; to < n; to++ {
+		for from := 0; from < n; from++ {
+			incoming[from] = m.Delay[from][to] + time.Duration(z*float64(m.Jitter[from][to]))
+		}
+		sort.Slice(incoming, func(a, b int) bool { return incoming[a] < incoming[b] })
+		completion[to] = incoming[quorum-1]
+	}
+
+	sort.Slice(completion, func(a, b int) bool { return completion[a] < completion[b] })
+	return completion[quorum-1]
+}
+
+// BroadcastLatency is the time for a message from the given node to reach a
+// quorum of the other nodes.
+func (m *DelayMatrix) BroadcastLatency(from, quorum int, z float64) time.Duration {
+	n := len(m.Nodes)
+	if from < 0 || from >= n || quorum <= 0 {
+		return 0
+	}
+	if quorum > n {
+		quorum = n
+	}
+
+	arrivals := make([]time.Duration, n)
+	for to := 0; to < n; to++ {
+		arrivals[to] = m.Delay[from][to] + time.Duration(z*float64(m.Jitter[from][to]))
+	}
+	sort.Slice(arrivals, func(a, b int) bool { return arrivals[a] < arrivals[b] })
+	return arrivals[quorum-1]
+}
+
+func QuorumSize(validators int, fraction float64) int {
+	quorum := int(math.Floor(float64(validators)*fraction)) + 1
+	if quorum > validators {
+		quorum = validators
+	}
+	return quorum
+}
+
+type FinalityEstimator struct {
+	timingManager  *TimingManager
+	latencyMonitor *network.LatencyMonitor
+	params         *FinalityParameters
+	logger         *zap.Logger
+	mu             sync.RWMutex
+}
+
+func NewFinalityEstimator(timingManager *TimingManager, logger *zap.Logger) *FinalityEstimator {
+	return &FinalityEstimator{
+		timingManager: timingManager,
+		params:        DefaultFinalityParameters(),
+		logger:        logger,
+	}
+}
+
+func (fe *FinalityEstimator) SetLatencyMonitor(monitor *network.LatencyMonitor) {
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+	fe.latencyMonitor = monitor
+}
+
+func (fe *FinalityEstimator) SetParameters(params *FinalityParameters) {
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+	fe.params = params
+}
+
+func (fe *FinalityEstimator) GetParameters() *FinalityParameters {
+	fe.mu.RLock()
+	defer fe.mu.RUnlock()
+	params := *fe.params
+	return &params
+}
+
+// Estimate returns the expected and p99 time to finality. Without a height
+// the origin is assumed to hold a fresh transaction that still has to reach
+// a proposer and wait for the next block; with a height the block already
+// exists and only the commit rounds and confirmations remain.
+func (fe *FinalityEstimator) Estimate(request *FinalityRequest) (*FinalityEstimate, error) {
+	if request.OriginNode == "" {
+		return nil, fmt.Errorf("origin node is required")
+	}
+	topology := fe.timingManager.topologyManager
+	if topology == nil {
+		return nil, fmt.Errorf("no topology available")
+	}
+
+	validators := request.Validators
+	if len(validators) == 0 {
+		for _, node := range topology.GetActiveNodes() {
+			validators = append(validators, node.ID)
+		}
+	}
+	if len(validators) == 0 {
+		return nil, fmt.Errorf("no validators available")
+	}
+
+	params := fe.GetParameters()
+
+	timing, err := fe.timingManager.CalculateConsensusTiming(validators)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate consensus timing: %w", err)
+	}
+
+	nodes := append([]string{request.OriginNode}, validators...)
+	matrix, measured, err := fe.buildDelayMatrix(nodes, params.DefaultJitterRate)
+	if err != nil {
+		return nil, err
+	}
+
+	// Row and column 0 belong to the origin; the validator sub-matrix starts at 1.
+	validatorMatrix := &DelayMatrix{Nodes: validators}
+	for i := 1; i < len(nodes); i++ {
+		validatorMatrix.Delay = append(validatorMatrix.Delay, matrix.Delay[i][1:])
+		validatorMatrix.Jitter = append(validatorMatrix.Jitter, matrix.Jitter[i][1:])
+	}
+
+	quorum := QuorumSize(len(validators), params.QuorumFraction)
+	phase := validatorMatrix.QuorumLatency(quorum, 0)
+	phaseP99 := validatorMatrix.QuorumLatency(quorum, p99ZScore)
+	commitPhases := time.Duration(params.RoundsToCommit * params.PhasesPerRound)
+
+	// The origin's own entry is counted, so reaching a validator quorum
+	// takes one more arrival.
+	originQuorum := quorum + 1
+	originDelay := matrix.BroadcastLatency(0, originQuorum, 0)
+	originDelayP99 := matrix.BroadcastLatency(0, originQuorum, p99ZScore)
+
+	confirmations := time.Duration(params.Confirmations) * timing.BlockTime
+
+	estimate := &FinalityEstimate{
+		OriginNode:      request.OriginNode,
+		Height:          request.Height,
+		ValidatorCount:  len(validators),
+		Quorum:          quorum,
+		BlockTime:       timing.BlockTime,
+		OriginDelay:     originDelay,
+		PhaseLatency:    phase,
+		PhaseLatencyP99: phaseP99,
+		Expected:        originDelay + commitPhases*phase + confirmations,
+		P99:             originDelayP99 + commitPhases*phaseP99 + confirmations,
+		StartAt:         request.ProposedAt,
+		MeasuredLinks:   measured,
+		Parameters:      params,
+		EstimatedAt:     time.Now().UTC(),
+	}
+
+	if request.Height == 0 {
+		estimate.InclusionDelay = timing.BlockTime / 2
+		estimate.Expected += timing.BlockTime / 2
+		estimate.P99 += timing.BlockTime
+	} else {
+		estimate.FinalHeight = request.Height + uint64(params.Confirmations)
+	}
+
+	if estimate.StartAt.IsZero() {
+		estimate.StartAt = estimate.EstimatedAt
+	}
+	estimate.ExpectedFinalAt = estimate.StartAt.Add(estimate.Expected)
+	estimate.P99FinalAt = estimate.StartAt.Add(estimate.P99)
+
+	fe.logger.Debug("Finality estimated",
+		zap.String("origin_node", request.OriginNode),
+		zap.Uint64("height", request.Height),
+		zap.Int("validators", len(validators)),
+		zap.Duration("expected", estimate.Expected),
+		zap.Duration("p99", estimate.P99),
+		zap.Int("measured_links", measured),
+	)
+
+	return estimate, nil
+}
+
+// buildDelayMatrix prefers measured latencies and falls back to the
+// geographic network delay. Measurements are round trips, so they are
+// halved to one-way delays.
+func (fe *FinalityEstimator) buildDelayMatrix(nodeIDs []string, defaultJitterRate float64) (*DelayMatrix, int, error) {
+	fe.mu.RLock()
+	monitor := fe.latencyMonitor
+	fe.mu.RUnlock()
+
+	nodes := make([]*types.Node, len(nodeIDs))
+	for i, nodeID := range nodeIDs {
+		node, err := fe.timingManager.topologyManager.GetNode(nodeID)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to get node %s: %w", nodeID, err)
+		}
+		nodes[i] = node
+	}
+
+	matrix := NewDelayMatrix(nodeIDs)
+	measured := 0
+	for i := range nodes {
+		for j := range nodes {
+			if i == j {
+				continue
+			}
+
+			if monitor != nil {
+				if m := monitor.GetMeasurement(nodeIDs[i], nodeIDs[j]); m != nil && m.Average > 0 {
+					matrix.Delay[i][j] = m.Average / 2
+					matrix.Jitter[i][j] = m.Jitter / 2
+					measured++
+					continue
+				}
+			}
+
+			distance, err := fe.timingManager.calculateDistance(nodes[i].Position, nodes[j].Position)
+			if err != nil {
+				return nil, 0, err
+			}
+			delay := time.Duration(distance / types.SpeedOfLight * types.NetworkFactor * float64(time.Second))
+			matrix.Delay[i][j] = delay
+			matrix.Jitter[i][j] = time.Duration(float64(delay) * defaultJitterRate)
+		}
+	}
+
+	return matrix, measured, nil
+}
@@ -0,0 +1,117 @@
+package consensus
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/network"
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/security"
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
+)
+
+// AttachGossip subscribes to the block and vote topics. Proposals and votes
+// from peers are ingested and only forwarded if they verify. Only malformed
+// payloads and bad signatures count against the relaying peer; anything
+// else, such as an unknown validator key or a height outside the window,
+// may be this node's view lagging and is ignored.
+func (cm *ConsensusManager) AttachGossip(router *network.GossipRouter) {
+	cm.mu.Lock()
+	cm.gossip = router
+	cm.mu.Unlock()
+
+	router.Subscribe(network.TopicBlocks, cm.handleGossipProposal)
+	router.Subscribe(network.TopicVotes, cm.handleGossipVote)
+}
+
+// PublishProposal ingests a locally produced proposal and gossips it.
+func (cm *ConsensusManager) PublishProposal(proposal *types.Proposal) (*Evidence, error) {
+	evidence, err := cm.IngestProposal(proposal)
+	if err != nil {
+		return nil, err
+	}
+	return evidence, cm.publish(network.TopicBlocks, proposal)
+}
+
+// PublishVote ingests a locally cast vote and gossips it.
+func (cm *ConsensusManager) PublishVote(vote *types.Vote) (*Evidence, error) {
+	evidence, err := cm.IngestVote(vote)
+	if err != nil {
+		return nil, err
+	}
+	return evidence, cm.publish(network.TopicVotes, vote)
+}
+
+func (cm *ConsensusManager) publish(topic string, value interface{}) error {
+	cm.mu.RLock()
+	router := cm.gossip
+	cm.mu.RUnlock()
+
+	if router == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s message: %w", topic, err)
+	}
+	if _, err := router.Publish(topic, data); err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", topic, err)
+	}
+	return nil
+}
+
+func (cm *ConsensusManager) handleGossipProposal(message *network.GossipMessage) network.GossipValidation {
+	proposal := &types.Proposal{}
+	if err := json.Unmarshal(message.Data, proposal); err != nil {
+		return network.GossipReject
+	}
+
+	evidence, err := cm.IngestProposal(proposal)
+	if err != nil {
+		cm.logger.Debug("Dropped gossiped proposal",
+			zap.String("from", message.ReceivedFrom),
+			zap.Error(err),
+		)
+		return gossipVerdict(err)
+	}
+	if evidence != nil {
+		cm.logger.Warn("Equivocating proposal received over gossip",
+			zap.String("proposer", proposal.ProposerID),
+			zap.Uint64("height", proposal.Height),
+		)
+	}
+	return network.GossipAccept
+}
+
+func (cm *ConsensusManager) handleGossipVote(message *network.GossipMessage) network.GossipValidation {
+	vote := &types.Vote{}
+	if err := json.Unmarshal(message.Data, vote); err != nil {
+		return network.GossipReject
+	}
+
+	evidence, err := cm.IngestVote(vote)
+	if err != nil {
+		cm.logger.Debug("Dropped gossiped vote",
+			zap.String("from", message.ReceivedFrom),
+			zap.Error(err),
+		)
+		return gossipVerdict(err)
+	}
+	if evidence != nil {
+		cm.logger.Warn("Equivocating vote received over gossip",
+			zap.String("voter", vote.VoterID),
+			zap.Uint64("height", vote.Height),
+		)
+	}
+	return network.GossipAccept
+}
+
+func gossipVerdict(err error) network.GossipValidation {
+	if errors.Is(err, security.ErrInvalidSignature) {
+		return network.GossipReject
+	}
+	return network.GossipIgnore
+}
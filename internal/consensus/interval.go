@@ -0,0 +1,350 @@
+package consensus
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/network"
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
+)
+
+type IntervalControllerConfig struct {
+	InitialInterval  time.Duration `json:"initial_interval"`
+	MinInterval      time.Duration `json:"min_interval"`
+	MaxInterval      time.Duration `json:"max_interval"`
+	EvaluationPeriod time.Duration `json:"evaluation_period"`
+	ObservationAge   time.Duration `json:"observation_age"`
+	Cooldown         time.Duration `json:"cooldown"`
+	TargetRatio      float64       `json:"target_ratio"`
+	Hysteresis       float64       `json:"hysteresis"`
+	MaxStepDown      float64       `json:"max_step_down"`
+	BackoffFactor    float64       `json:"backoff_factor"`
+	MaxMissRate      float64       `json:"max_miss_rate"`
+	MaxOrphanRate    float64       `json:"max_orphan_rate"`
+	MaxHistory       int           `json:"max_history"`
+}
+
+func DefaultIntervalControllerConfig() *IntervalControllerConfig {
+	return &IntervalControllerConfig{
+		InitialInterval:  5 * time.Second,
+		MinInterval:      2 * time.Second,
+		MaxInterval:      60 * time.Second,
+		EvaluationPeriod: 30 * time.Second,
+		ObservationAge:   5 * time.Minute,
+		Cooldown:         time.Minute,
+		TargetRatio:      0.5,
+		Hysteresis:       0.1,
+		MaxStepDown:      0.9,
+		BackoffFactor:    1.5,
+		MaxMissRate:      0.05,
+		MaxOrphanRate:    0.02,
+		MaxHistory:       1000,
+	}
+}
+
+type IntervalInputs struct {
+	PropagationP95     time.Duration `json:"propagation_p95"`
+	PropagationSamples int           `json:"propagation_samples"`
+	Rounds             int           `json:"rounds"`
+	MissedRounds       int           `json:"missed_rounds"`
+	MissRate           float64       `json:"miss_rate"`
+	Blocks             int           `json:"blocks"`
+	OrphanedBlocks     int           `json:"orphaned_blocks"`
+	OrphanRate         float64       `json:"orphan_rate"`
+	Desired            time.Duration `json:"desired"`
+}
+
+type IntervalAdjustment struct {
+	ID        string         `json:"id"`
+	Previous  time.Duration  `json:"previous"`
+	Interval  time.Duration  `json:"interval"`
+	Reason    string         `json:"reason"`
+	Inputs    IntervalInputs `json:"inputs"`
+	Timestamp time.Time      `json:"timestamp"`
+}
+
+type timedObservation struct {
+	at       time.Time
+	delay    time.Duration
+	negative bool
+}
+
+// BlockIntervalController recommends a block interval from live network
+// conditions. Propagation sets the floor the interval should keep above;
+// missed rounds and orphaned blocks push it up regardless. Changes smaller
+// than the hysteresis band, or inside the cooldown, are held back so the
+// interval does not oscillate.
+type BlockIntervalController struct {
+	config         *IntervalControllerConfig
+	latencyMonitor *network.LatencyMonitor
+	eventManager   *network.EventManager
+	logger         *zap.Logger
+	mu             sync.RWMutex
+	interval       time.Duration
+	lastChange     time.Time
+	propagation    []timedObservation
+	rounds         []timedObservation
+	blocks         []timedObservation
+	history        []*IntervalAdjustment
+	lastInputs     *IntervalInputs
+	stopChan       chan struct{}
+}
+
+func NewBlockIntervalController(config *IntervalControllerConfig, logger *zap.Logger) *BlockIntervalController {
+	if config == nil {
+		config = DefaultIntervalControllerConfig()
+	}
+
+	return &BlockIntervalController{
+		config:   config,
+		logger:   logger,
+		interval: clampDuration(config.InitialInterval, config.MinInterval, config.MaxInterval),
+		stopChan: make(chan struct{}),
+	}
+}
+
+func (bc *BlockIntervalController) SetLatencyMonitor(monitor *network.LatencyMonitor) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	bc.latencyMonitor = monitor
+}
+
+func (bc *BlockIntervalController) SetEventManager(eventManager *network.EventManager) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	bc.eventManager = eventManager
+}
+
+func (bc *BlockIntervalController) SetBounds(minInterval, maxInterval time.Duration) error {
+	if minInterval <= 0 || maxInterval < minInterval {
+		return fmt.Errorf("invalid block interval bounds [%v, %v]", minInterval, maxInterval)
+	}
+
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	bc.config.MinInterval = minInterval
+	bc.config.MaxInterval = maxInterval
+	bc.interval = clampDuration(bc.interval, minInterval, maxInterval)
+	return nil
+}
+
+func (bc *BlockIntervalController) Start(ctx context.Context) {
+	ticker := time.NewTicker(bc.config.EvaluationPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-bc.stopChan:
+			return
+		case <-ticker.C:
+			bc.Evaluate()
+		}
+	}
+}
+
+func (bc *BlockIntervalController) Stop() {
+	close(bc.stopChan)
+}
+
+func (bc *BlockIntervalController) RecordPropagation(delay time.Duration) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	bc.propagation = append(bc.propagation, timedObservation{at: time.Now(), delay: delay})
+}
+
+func (bc *BlockIntervalController) RecordRound(missed bool) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	bc.rounds = append(bc.rounds, timedObservation{at: time.Now(), negative: missed})
+}
+
+func (bc *BlockIntervalController) RecordBlock(orphaned bool) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	bc.blocks = append(bc.blocks, timedObservation{at: time.Now(), negative: orphaned})
+}
+
+// Evaluate folds the current observations into a new recommendation. It
+// returns the adjustment when the interval changed and nil when it held.
+// Round and block outcomes were observed under the previous interval, so a
+// change discards them; otherwise one burst of missed rounds would back the
+// interval off again after every cooldown until it aged out.
+func (bc *BlockIntervalController) Evaluate() *IntervalAdjustment {
+	bc.mu.Lock()
+
+	now := time.Now()
+	cutoff := now.Add(-bc.config.ObservationAge)
+	bc.propagation = pruneObservations(bc.propagation, cutoff)
+	bc.rounds = pruneObservations(bc.rounds, cutoff)
+	bc.blocks = pruneObservations(bc.blocks, cutoff)
+
+	inputs := bc.collectInputs(cutoff)
+	previous := bc.interval
+
+	var target time.Duration
+	var reason string
+	switch {
+	case inputs.MissRate > bc.config.MaxMissRate:
+		target = time.Duration(float64(previous) * bc.config.BackoffFactor)
+		reason = fmt.Sprintf("missed round rate %.1f%% above %.1f%%", inputs.MissRate*100, bc.config.MaxMissRate*100)
+	case inputs.OrphanRate > bc.config.MaxOrphanRate:
+		target = time.Duration(float64(previous) * bc.config.BackoffFactor)
+		reason = fmt.Sprintf("orphan rate %.1f%% above %.1f%%", inputs.OrphanRate*100, bc.config.MaxOrphanRate*100)
+	case inputs.PropagationSamples > 0:
+		target = inputs.Desired
+		reason = fmt.Sprintf("p95 propagation %v targets interval %v", inputs.PropagationP95, inputs.Desired)
+		if floor := time.Duration(float64(previous) * bc.config.MaxStepDown); target < floor {
+			target = floor
+		}
+	default:
+		bc.lastInputs = &inputs
+		bc.mu.Unlock()
+		return nil
+	}
+
+	target = clampDuration(target, bc.config.MinInterval, bc.config.MaxInterval)
+	bc.lastInputs = &inputs
+
+	change := math.Abs(float64(target-previous)) / float64(previous)
+	if change < bc.config.Hysteresis || (!bc.lastChange.IsZero() && now.Sub(bc.lastChange) < bc.config.Cooldown) {
+		bc.mu.Unlock()
+		return nil
+	}
+
+	adjustment := &IntervalAdjustment{
+		ID:        fmt.Sprintf("interval_%d", now.UnixNano()),
+		Previous:  previous,
+		Interval:  target,
+		Reason:    reason,
+		Inputs:    inputs,
+		Timestamp: now.UTC(),
+	}
+
+	bc.interval = target
+	bc.lastChange = now
+	bc.rounds = nil
+	bc.blocks = nil
+	bc.history = append(bc.history, adjustment)
+	if len(bc.history) > bc.config.MaxHistory {
+		bc.history = bc.history[len(bc.history)-bc.config.MaxHistory:]
+	}
+	eventManager := bc.eventManager
+	bc.mu.Unlock()
+
+	bc.logger.Info("Block interval adjusted",
+		zap.Duration("previous", previous),
+		zap.Duration("interval", target),
+		zap.String("reason", reason),
+	)
+
+	if eventManager != nil {
+		eventManager.EmitEvent(types.EventTypeConsensusChange, "block_interval_controller", map[string]interface{}{
+			"adjustment_id":   adjustment.ID,
+			"previous":        previous.String(),
+			"interval":        target.String(),
+			"reason":          reason,
+			"propagation_p95": inputs.PropagationP95.String(),
+			"miss_rate":       inputs.MissRate,
+			"orphan_rate":     inputs.OrphanRate,
+		}, types.AlertSeverityInfo)
+	}
+
+	return adjustment
+}
+
+func (bc *BlockIntervalController) collectInputs(cutoff time.Time) IntervalInputs {
+	var inputs IntervalInputs
+
+	delays := make([]time.Duration, 0, len(bc.propagation))
+	for _, o := range bc.propagation {
+		delays = append(delays, o.delay)
+	}
+	if bc.latencyMonitor != nil {
+		for _, m := range bc.latencyMonitor.GetAllMeasurements() {
+			if m.LastMeasured.After(cutoff) && m.Average > 0 {
+				delays = append(delays, m.Average/2)
+			}
+		}
+	}
+
+	inputs.PropagationSamples = len(delays)
+	if len(delays) > 0 {
+		sort.Slice(delays, func(i, j int) bool { return delays[i] < delays[j] })
+		inputs.PropagationP95 = delays[int(math.Ceil(0.95*float64(len(delays))))-1]
+		inputs.Desired = time.Duration(float64(inputs.PropagationP95) / bc.config.TargetRatio)
+	}
+
+	inputs.Rounds = len(bc.rounds)
+	for _, o := range bc.rounds {
+		if o.negative {
+			inputs.MissedRounds++
+		}
+	}
+	if inputs.Rounds > 0 {
+		inputs.MissRate = float64(inputs.MissedRounds) / float64(inputs.Rounds)
+	}
+
+	inputs.Blocks = len(bc.blocks)
+	for _, o := range bc.blocks {
+		if o.negative {
+			inputs.OrphanedBlocks++
+		}
+	}
+	if inputs.Blocks > 0 {
+		inputs.OrphanRate = float64(inputs.OrphanedBlocks) / float64(inputs.Blocks)
+	}
+
+	return inputs
+}
+
+func (bc *BlockIntervalController) GetInterval() time.Duration {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.interval
+}
+
+func (bc *BlockIntervalController) GetLastInputs() *IntervalInputs {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.lastInputs
+}
+
+func (bc *BlockIntervalController) GetHistory(limit int) []*IntervalAdjustment {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	start := 0
+	if limit > 0 && len(bc.history) > limit {
+		start = len(bc.history) - limit
+	}
+
+	history := make([]*IntervalAdjustment, len(bc.history)-start)
+	copy(history, bc.history[start:])
+	return history
+}
+
+func pruneObservations(observations []timedObservation, cutoff time.Time) []timedObservation {
+	start := 0
+	for start < len(observations) && observations[start].at.Before(cutoff) {
+		start++
+	}
+	return observations[start:]
+}
+
+func clampDuration(d, lower, upper time.Duration) time.Duration {
+	if d < lower {
+		return lower
+	}
+	if d > upper {
+		return upper
+	}
+	return d
+}
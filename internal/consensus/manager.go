@@ -9,19 +9,37 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/network"
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/security"
 	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
 )
 
+// maxProposedHeights bounds the uncommitted heights whose proposals are
+// kept for orphan counting.
+const maxProposedHeights = 1024
+
 type ConsensusManager struct {
 	timingManager   *TimingManager
 	offsetManager   *OffsetManager
 	calculator      *ConsensusCalculator
 	validator       *ConsensusValidator
 	synchronizer    *Synchronizer
+	estimator       *OffsetEstimator
+	latencyMonitor  *network.LatencyMonitor
+	finality        *FinalityEstimator
+	interval        *BlockIntervalController
+	equivocation    *EquivocationDetector
+	registry        *ValidatorRegistry
+	rounds          *RoundRecorder
+	chainTime       *ChainTimeValidator
+	keyManager      *security.KeyManager
+	gossip          *network.GossipRouter
 	topologyManager *network.TopologyManager
 	logger          *zap.Logger
 	mu              sync.RWMutex
-	stopChan        chan struct{}
+	// proposed holds the block hashes proposed at heights not yet
+	// committed, so the losers can be counted as orphans.
+	proposed map[uint64]map[string]bool
+	stopChan chan struct{}
 }
 
 func NewConsensusManager(topology *network.TopologyManager, logger *zap.Logger) *ConsensusManager {
@@ -30,24 +48,102 @@ func NewConsensusManager(topology *network.TopologyManager, logger *zap.Logger)
 	calculator := NewConsensusCalculator(timingManager, offsetManager, logger)
 	validator := NewConsensusValidator(timingManager, offsetManager, logger)
 	synchronizer := NewSynchronizer(offsetManager, logger)
+	interval := NewBlockIntervalController(DefaultIntervalControllerConfig(), logger)
+
+	var store RecordStore
+	if topology != nil {
+		store = topology
+	}
+	registry := NewValidatorRegistry(timingManager, store, DefaultRegistryConfig(), logger)
 
-	return &ConsensusManager{
+	cm := &ConsensusManager{
 		timingManager:   timingManager,
 		offsetManager:   offsetManager,
 		calculator:      calculator,
 		validator:       validator,
 		synchronizer:    synchronizer,
+		finality:        NewFinalityEstimator(timingManager, logger),
+		interval:        interval,
+		equivocation:    NewEquivocationDetector(timingManager, DefaultEquivocationConfig(), logger),
+		registry:        registry,
+		rounds:          NewRoundRecorder(timingManager, DefaultRoundRecorderConfig(), logger),
+		chainTime:       NewChainTimeValidator(DefaultChainTimeConfig(), logger),
 		topologyManager: topology,
 		logger:          logger,
+		proposed:        make(map[uint64]map[string]bool),
 		stopChan:        make(chan struct{}),
 	}
+	registry.OnEpochChange(cm.syncValidatorKeys)
+	cm.chainTime.SetClock(offsetManager.Now)
+
+	return cm
+}
+
+func (cm *ConsensusManager) AttachPeering(peering *network.PeeringManager) {
+	estimator := NewOffsetEstimator(peering, cm.timingManager, cm.logger)
+
+	cm.mu.Lock()
+	cm.estimator = estimator
+	monitor := cm.latencyMonitor
+	cm.mu.Unlock()
+
+	if monitor != nil {
+		monitor.SetClockOffsetSource(estimator)
+	}
+
+	cm.offsetManager.SetEstimator(estimator)
+	cm.synchronizer.SetPeering(peering)
+	cm.synchronizer.SetAuthorizer(cm.isCurrentValidator)
+	cm.equivocation.SetSigner(peering.LocalPeerID(), nil)
+	cm.rounds.SetLocalNode(peering.LocalPeerID())
+}
+
+func (cm *ConsensusManager) AttachLatencyMonitor(monitor *network.LatencyMonitor) {
+	cm.mu.Lock()
+	cm.latencyMonitor = monitor
+	estimator := cm.estimator
+	cm.mu.Unlock()
+
+	cm.finality.SetLatencyMonitor(monitor)
+	cm.interval.SetLatencyMonitor(monitor)
+	if estimator != nil {
+		monitor.SetClockOffsetSource(estimator)
+	}
+}
+
+func (cm *ConsensusManager) AttachKeyManager(keyManager *security.KeyManager) {
+	cm.mu.Lock()
+	cm.keyManager = keyManager
+	cm.mu.Unlock()
+
+	cm.validator.SetKeyManager(keyManager)
+	if localID := keyManager.LocalID(); localID != "" {
+		cm.rounds.SetLocalNode(localID)
+	}
+	if key := keyManager.LocalKey(); key != nil {
+		cm.equivocation.SetSigner(keyManager.LocalID(), key)
+	}
+	if current, err := cm.registry.GetCurrentSet(); err == nil {
+		cm.syncValidatorKeys(current)
+	}
+}
+
+func (cm *ConsensusManager) AttachEventManager(eventManager *network.EventManager) {
+	cm.interval.SetEventManager(eventManager)
 }
 
 func (cm *ConsensusManager) Start(ctx context.Context) error {
 	cm.logger.Info("Starting Consensus Manager")
 
+	if err := cm.registry.Load(); err != nil {
+		cm.logger.Warn("Failed to load validator registry", zap.Error(err))
+	} else if current, err := cm.registry.GetCurrentSet(); err == nil {
+		cm.syncValidatorKeys(current)
+	}
+
 	go cm.backgroundOffsetCalculation(ctx)
 	go cm.backgroundSynchronization(ctx)
+	go cm.interval.Start(ctx)
 
 	cm.logger.Info("Consensus Manager started successfully")
 	return nil
@@ -58,6 +154,7 @@ func (cm *ConsensusManager) Stop() error {
 
 	close(cm.stopChan)
 	cm.synchronizer.Stop()
+	cm.interval.Stop()
 
 	cm.logger.Info("Consensus Manager stopped successfully")
 	return nil
@@ -74,6 +171,7 @@ func (cm *ConsensusManager) backgroundOffsetCalculation(ctx context.Context) {
 		case <-cm.stopChan:
 			return
 		case <-ticker.C:
+			cm.measurePeerClocks(ctx)
 			cm.calculateAllOffsets()
 		}
 	}
@@ -95,6 +193,19 @@ func (cm *ConsensusManager) backgroundSynchronization(ctx context.Context) {
 	}
 }
 
+func (cm *ConsensusManager) measurePeerClocks(ctx context.Context) {
+	cm.mu.RLock()
+	estimator := cm.estimator
+	cm.mu.RUnlock()
+
+	if estimator == nil {
+		return
+	}
+
+	samples := estimator.MeasureAllPeers(ctx)
+	cm.logger.Debug("Peer clock measurements completed", zap.Int("peers", len(samples)))
+}
+
 func (cm *ConsensusManager) calculateAllOffsets() {
 	nodes := cm.topologyManager.GetAllNodes()
 	nodeIDs := make([]string, len(nodes))
@@ -166,6 +277,332 @@ func (cm *ConsensusManager) AdjustTimestamp(timestamp time.Time, nodeID string)
 	return cm.offsetManager.AdjustTimestamp(timestamp, nodeID)
 }
 
+func (cm *ConsensusManager) GetDriftModel(nodeID string) (*DriftModel, error) {
+	return cm.offsetManager.GetDriftTracker().GetModel(nodeID)
+}
+
+func (cm *ConsensusManager) GetDriftAlerts() []*DriftAlert {
+	return cm.offsetManager.GetDriftTracker().GetAlerts()
+}
+
+func (cm *ConsensusManager) SetDriftBudget(maxDriftPPM float64) {
+	cm.offsetManager.GetDriftTracker().SetBudget(maxDriftPPM)
+}
+
+func (cm *ConsensusManager) OnDriftAlert(handler DriftAlertHandler) {
+	cm.offsetManager.GetDriftTracker().OnAlert(handler)
+}
+
+func (cm *ConsensusManager) GetAllOffsets() map[string]*NodeOffset {
+	return cm.offsetManager.GetAllOffsets()
+}
+
+func (cm *ConsensusManager) GetAggregationReport() *AggregationReport {
+	report := cm.offsetManager.GetAggregationReport()
+	if report == nil {
+		report = cm.offsetManager.CalculateGlobalOffsetReport()
+	}
+	return report
+}
+
+func (cm *ConsensusManager) SetAggregationMethod(method AggregationMethod) {
+	cm.offsetManager.SetAggregationMethod(method)
+}
+
+func (cm *ConsensusManager) FlagNode(nodeID, reason string) {
+	cm.offsetManager.FlagNode(nodeID, reason)
+}
+
+func (cm *ConsensusManager) UnflagNode(nodeID string) {
+	cm.offsetManager.UnflagNode(nodeID)
+}
+
+func (cm *ConsensusManager) UnflagNodeIf(nodeID, reason string) bool {
+	return cm.offsetManager.UnflagNodeIf(nodeID, reason)
+}
+
+func (cm *ConsensusManager) GetFlaggedNodes() map[string]string {
+	return cm.offsetManager.GetFlaggedNodes()
+}
+
+// EstimateFinality defaults to the registered validator set for the
+// request's height when no validators are given.
+func (cm *ConsensusManager) EstimateFinality(request *FinalityRequest) (*FinalityEstimate, error) {
+	if len(request.Validators) == 0 {
+		if set, err := cm.validatorSetFor(request.Height); err == nil {
+			request.Validators = set.ValidatorIDs()
+		}
+	}
+	return cm.finality.Estimate(request)
+}
+
+func (cm *ConsensusManager) SetFinalityParameters(params *FinalityParameters) {
+	cm.finality.SetParameters(params)
+}
+
+func (cm *ConsensusManager) GetBlockIntervalController() *BlockIntervalController {
+	return cm.interval
+}
+
+func (cm *ConsensusManager) GetRecommendedBlockInterval() time.Duration {
+	return cm.interval.GetInterval()
+}
+
+func (cm *ConsensusManager) SetBlockIntervalBounds(minInterval, maxInterval time.Duration) error {
+	return cm.interval.SetBounds(minInterval, maxInterval)
+}
+
+// IngestProposal feeds a proposal to equivocation detection. When a key
+// manager is attached, unsigned or forged proposals are rejected first so
+// nobody can fabricate evidence against another validator.
+func (cm *ConsensusManager) IngestProposal(proposal *types.Proposal) (*Evidence, error) {
+	receivedAt := time.Now().UTC()
+
+	if keyManager := cm.getKeyManager(); keyManager != nil {
+		if err := keyManager.VerifyProposal(proposal); err != nil {
+			return nil, err
+		}
+	}
+
+	evidence, err := cm.equivocation.IngestProposal(proposal, receivedAt)
+	if err != nil {
+		return nil, err
+	}
+	if delay := cm.rounds.RecordProposal(proposal, receivedAt); delay > 0 {
+		cm.interval.RecordPropagation(delay)
+	}
+	if proposal.Block != nil && proposal.Block.Hash != "" {
+		cm.mu.Lock()
+		if cm.proposed[proposal.Height] == nil {
+			cm.proposed[proposal.Height] = make(map[string]bool)
+		}
+		cm.proposed[proposal.Height][proposal.Block.Hash] = true
+		if len(cm.proposed) > maxProposedHeights {
+			lowest := proposal.Height
+			for height := range cm.proposed {
+				if height < lowest {
+					lowest = height
+				}
+			}
+			delete(cm.proposed, lowest)
+		}
+		cm.mu.Unlock()
+	}
+	return evidence, nil
+}
+
+func (cm *ConsensusManager) IngestVote(vote *types.Vote) (*Evidence, error) {
+	receivedAt := time.Now().UTC()
+
+	if keyManager := cm.getKeyManager(); keyManager != nil {
+		if err := keyManager.VerifyVote(vote); err != nil {
+			return nil, err
+		}
+	}
+
+	evidence, err := cm.equivocation.IngestVote(vote, receivedAt)
+	if err != nil {
+		return nil, err
+	}
+	cm.rounds.RecordVote(vote, receivedAt)
+	return evidence, nil
+}
+
+// VerifyEvidence checks evidence reported by another node: the signature
+// must verify and the signing key must be the validator key registered for
+// DetectedBy.
+func (cm *ConsensusManager) VerifyEvidence(evidence *Evidence) error {
+	if err := VerifyEvidence(evidence); err != nil {
+		return err
+	}
+
+	keyManager := cm.getKeyManager()
+	if keyManager == nil {
+		return fmt.Errorf("no key manager attached")
+	}
+	registered, err := keyManager.GetValidatorKey(evidence.DetectedBy)
+	if err != nil {
+		return err
+	}
+	if string(registered.Type) != evidence.KeyType || registered.String() != evidence.PublicKey {
+		return fmt.Errorf("evidence is not signed with the registered key of %s", evidence.DetectedBy)
+	}
+	return nil
+}
+
+// BuildQuorumCertificate aggregates signed votes into a certificate that
+// needs more than two thirds of the validators.
+func (cm *ConsensusManager) BuildQuorumCertificate(votes []*types.Vote, validators []string) (*types.QuorumCertificate, error) {
+	keyManager := cm.getKeyManager()
+	if keyManager == nil {
+		return nil, fmt.Errorf("no key manager attached")
+	}
+	qc, err := keyManager.AggregateVotes(votes, validators, QuorumSize(len(validators), 2.0/3.0))
+	if err != nil {
+		return nil, err
+	}
+	cm.recordCommit(qc.Height, qc.Round, qc.BlockHash, qc.CreatedAt)
+	return qc, nil
+}
+
+func (cm *ConsensusManager) VerifyQuorumCertificate(qc *types.QuorumCertificate, validators []string) error {
+	keyManager := cm.getKeyManager()
+	if keyManager == nil {
+		return fmt.Errorf("no key manager attached")
+	}
+	return keyManager.VerifyQuorumCertificate(qc, validators, QuorumSize(len(validators), 2.0/3.0))
+}
+
+func (cm *ConsensusManager) getKeyManager() *security.KeyManager {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return cm.keyManager
+}
+
+// GetTimingManager returns the timing manager the calculator and validator
+// use, so other components share its validator-set-keyed cache.
+func (cm *ConsensusManager) GetTimingManager() *TimingManager {
+	return cm.timingManager
+}
+
+func (cm *ConsensusManager) GetEquivocationDetector() *EquivocationDetector {
+	return cm.equivocation
+}
+
+func (cm *ConsensusManager) GetRoundRecorder() *RoundRecorder {
+	return cm.rounds
+}
+
+func (cm *ConsensusManager) RecordCommit(height uint64, round uint32, blockHash string) {
+	cm.recordCommit(height, round, blockHash, time.Now().UTC())
+}
+
+// recordCommit closes the round and feeds the block interval controller:
+// a commit in round r means the r rounds before it were missed, and blocks
+// proposed at the height that lost to the committed one are orphaned.
+func (cm *ConsensusManager) recordCommit(height uint64, round uint32, blockHash string, committedAt time.Time) {
+	if !cm.rounds.RecordCommit(height, round, blockHash, committedAt) {
+		return
+	}
+	cm.equivocation.SetCommittedHeight(height)
+
+	for missed := uint32(0); missed < round; missed++ {
+		cm.interval.RecordRound(true)
+	}
+	cm.interval.RecordRound(false)
+
+	cm.mu.Lock()
+	proposed := cm.proposed[height]
+	for pending := range cm.proposed {
+		if pending <= height {
+			delete(cm.proposed, pending)
+		}
+	}
+	cm.mu.Unlock()
+
+	cm.interval.RecordBlock(false)
+	for hash := range proposed {
+		if hash != blockHash {
+			cm.interval.RecordBlock(true)
+		}
+	}
+}
+
+func (cm *ConsensusManager) GetChainTimeValidator() *ChainTimeValidator {
+	return cm.chainTime
+}
+
+// ValidateChainTimestamp checks a header against the median time past of
+// the chain it extends. With accept set, a valid header is added.
+func (cm *ConsensusManager) ValidateChainTimestamp(header *types.BlockHeader, accept bool) (*ChainTimeResult, error) {
+	if accept {
+		return cm.chainTime.Accept(header)
+	}
+	return cm.chainTime.Validate(header)
+}
+
+func (cm *ConsensusManager) GetValidatorRegistry() *ValidatorRegistry {
+	return cm.registry
+}
+
+func (cm *ConsensusManager) BootstrapValidators(validators []ValidatorInfo) (*ValidatorEpoch, error) {
+	return cm.registry.Bootstrap(validators)
+}
+
+func (cm *ConsensusManager) RequestValidatorJoin(validator ValidatorInfo, height uint64) (*MembershipRequest, error) {
+	return cm.registry.RequestJoin(validator, height)
+}
+
+func (cm *ConsensusManager) RequestValidatorLeave(validatorID string, height uint64) (*MembershipRequest, error) {
+	return cm.registry.RequestLeave(validatorID, height)
+}
+
+// AdvanceHeight is called as blocks are committed so the registry can open
+// new epochs.
+func (cm *ConsensusManager) AdvanceHeight(height uint64) ([]*ValidatorEpoch, error) {
+	cm.equivocation.SetCommittedHeight(height)
+	return cm.registry.AdvanceHeight(height)
+}
+
+func (cm *ConsensusManager) GetValidatorSet(epoch uint64) (*ValidatorEpoch, error) {
+	return cm.registry.GetValidatorSet(epoch)
+}
+
+// isCurrentValidator authorises clock corrections: only members of the
+// current validator set may correct this node.
+func (cm *ConsensusManager) isCurrentValidator(nodeID string) bool {
+	current, err := cm.registry.GetCurrentSet()
+	return err == nil && current.Contains(nodeID)
+}
+
+func (cm *ConsensusManager) validatorSetFor(height uint64) (*ValidatorEpoch, error) {
+	if height == 0 {
+		return cm.registry.GetCurrentSet()
+	}
+	return cm.registry.GetValidatorSetAtHeight(height)
+}
+
+// syncValidatorKeys keeps the key manager in step with the validator set:
+// genesis keys are registered when the registry is bootstrapped and keys are
+// dropped when a validator leaves. A key supplied with a join request is
+// only a claim; the joining validator's key must be registered separately,
+// and a mismatch is logged.
+func (cm *ConsensusManager) syncValidatorKeys(epoch *ValidatorEpoch) {
+	keyManager := cm.getKeyManager()
+	if keyManager == nil {
+		return
+	}
+
+	for _, validator := range epoch.Validators {
+		if validator.PublicKey == "" {
+			continue
+		}
+		if validator.JoinedEpoch > 0 {
+			registered, err := keyManager.GetValidatorKey(validator.ID)
+			if err != nil || registered.String() != validator.PublicKey {
+				cm.logger.Warn("Joined validator has no matching registered key",
+					zap.String("validator_id", validator.ID),
+					zap.Uint64("epoch", epoch.Epoch),
+				)
+			}
+			continue
+		}
+		if err := keyManager.RegisterValidatorKey(validator.ID, security.KeyType(validator.KeyType), validator.PublicKey); err != nil {
+			cm.logger.Warn("Failed to register validator key",
+				zap.String("validator_id", validator.ID),
+				zap.Uint64("epoch", epoch.Epoch),
+				zap.Error(err),
+			)
+		}
+	}
+
+	for _, validatorID := range epoch.Left {
+		if validatorID != keyManager.LocalID() {
+			keyManager.RemoveValidatorKey(validatorID)
+		}
+	}
+}
+
 func (cm *ConsensusManager) GetGlobalOffset() time.Duration {
 	return cm.offsetManager.GetGlobalOffset()
 }
@@ -174,6 +611,14 @@ func (cm *ConsensusManager) SyncNodeTime(nodeID string) error {
 	return cm.synchronizer.SyncNode(nodeID)
 }
 
+func (cm *ConsensusManager) GetSyncStats() *SyncStats {
+	return cm.synchronizer.GetSyncStats()
+}
+
+func (cm *ConsensusManager) GetSyncStatus() map[string]*SyncStatus {
+	return cm.synchronizer.GetAllSyncStatus()
+}
+
 func (cm *ConsensusManager) GetConsensusStats() *ConsensusStats {
 	offsetStats := cm.offsetManager.GetOffsetStats()
 	timingCacheStats := cm.timingManager.GetCacheStats()
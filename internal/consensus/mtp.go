@@ -0,0 +1,255 @@
+package consensus
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/relativistic"
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
+)
+
+// ChainTimeConfig sets the median window, the future limit and how many
+// heights below the highest accepted header are remembered, which must
+// cover the window on every branch still worth extending.
+type ChainTimeConfig struct {
+	Window         int           `json:"window"`
+	MaxFutureDrift time.Duration `json:"max_future_drift"`
+	RetainHeights  uint64        `json:"retain_heights"`
+}
+
+func DefaultChainTimeConfig() *ChainTimeConfig {
+	return &ChainTimeConfig{
+		Window:         11,
+		MaxFutureDrift: 30 * time.Second,
+		RetainHeights:  1024,
+	}
+}
+
+type ChainTimeResult struct {
+	BlockHash      string    `json:"block_hash"`
+	Height         uint64    `json:"height"`
+	ParentHash     string    `json:"parent_hash"`
+	Proposer       string    `json:"proposer"`
+	Timestamp      time.Time `json:"timestamp"`
+	MedianTimePast time.Time `json:"median_time_past"`
+	Ancestors      int       `json:"ancestors"`
+	MaxAllowed     time.Time `json:"max_allowed"`
+	Valid          bool      `json:"valid"`
+	Reason         string    `json:"reason,omitempty"`
+}
+
+type chainTimeEntry struct {
+	parent    string
+	height    uint64
+	timestamp time.Time
+	position  types.Position
+}
+
+// ChainTimeValidator applies a median-time-past rule to block headers. The
+// window of a header is the last Window ancestors reached by walking back
+// from its ParentHash, so headers on competing branches are judged against
+// their own history. Each ancestor's timestamp is first moved forward by
+// the light delay from its proposer's position to the new proposer's, both
+// taken from the headers, to the earliest moment the new proposer could
+// have seen it. Every node therefore computes the same median for a header
+// whatever its own view of the network; only the future limit, local time
+// plus MaxFutureDrift, depends on the node.
+type ChainTimeValidator struct {
+	calculator *relativistic.RelativisticCalculator
+	config     *ChainTimeConfig
+	logger     *zap.Logger
+	mu         sync.RWMutex
+	now        func() time.Time
+	headers    map[string]*chainTimeEntry
+	tip        string
+	tipHeight  uint64
+}
+
+func NewChainTimeValidator(config *ChainTimeConfig, logger *zap.Logger) *ChainTimeValidator {
+	if config == nil {
+		config = DefaultChainTimeConfig()
+	}
+	if config.Window <= 0 {
+		config.Window = DefaultChainTimeConfig().Window
+	}
+	if config.RetainHeights < uint64(config.Window) {
+		config.RetainHeights = uint64(config.Window)
+	}
+
+	return &ChainTimeValidator{
+		calculator: relativistic.NewCalculator(),
+		config:     config,
+		logger:     logger,
+		now:        time.Now,
+		headers:    make(map[string]*chainTimeEntry),
+	}
+}
+
+// SetClock replaces the local time source, e.g. with one that applies the
+// consensus clock offset.
+func (cv *ChainTimeValidator) SetClock(now func() time.Time) {
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+	cv.now = now
+}
+
+// Validate checks a header against the chain it extends without adding it.
+// A header other than genesis must extend a known parent at the previous
+// height; seed the validator with Append when starting from stored headers.
+func (cv *ChainTimeValidator) Validate(header *types.BlockHeader) (*ChainTimeResult, error) {
+	if header == nil {
+		return nil, fmt.Errorf("header cannot be nil")
+	}
+
+	result := &ChainTimeResult{
+		BlockHash:  header.Hash(),
+		Height:     header.Height,
+		ParentHash: header.ParentHash,
+		Proposer:   header.ProposerID,
+		Timestamp:  header.Timestamp,
+	}
+
+	cv.mu.RLock()
+	_, duplicate := cv.headers[result.BlockHash]
+	parent, parentKnown := cv.headers[header.ParentHash]
+	result.MedianTimePast, result.Ancestors = cv.medianLocked(header.ParentHash, &header.ProposerPosition)
+	result.MaxAllowed = cv.now().Add(cv.config.MaxFutureDrift)
+	cv.mu.RUnlock()
+
+	switch {
+	case duplicate:
+		result.Reason = "header already accepted"
+	case header.ParentHash == "" && header.Height != 0:
+		result.Reason = fmt.Sprintf("header at height %d has no parent", header.Height)
+	case header.ParentHash != "" && !parentKnown:
+		result.Reason = fmt.Sprintf("parent %s is not known", header.ParentHash)
+	case parentKnown && header.Height != parent.height+1:
+		result.Reason = fmt.Sprintf("height %d does not follow parent height %d", header.Height, parent.height)
+	case !result.MedianTimePast.IsZero() && !header.Timestamp.After(result.MedianTimePast):
+		result.Reason = fmt.Sprintf("timestamp %s is not after median time past %s",
+			header.Timestamp.Format(time.RFC3339Nano), result.MedianTimePast.Format(time.RFC3339Nano))
+	case header.Timestamp.After(result.MaxAllowed):
+		result.Reason = fmt.Sprintf("timestamp is %v beyond the future limit",
+			header.Timestamp.Sub(result.MaxAllowed))
+	default:
+		result.Valid = true
+	}
+
+	return result, nil
+}
+
+// Accept validates a header and, if it passes, adds it to the chain. A
+// header already accepted is rejected, so replays cannot be counted twice.
+func (cv *ChainTimeValidator) Accept(header *types.BlockHeader) (*ChainTimeResult, error) {
+	result, err := cv.Validate(header)
+	if err != nil {
+		return nil, err
+	}
+
+	if !result.Valid {
+		cv.logger.Debug("Header rejected by median time past",
+			zap.String("block_hash", result.BlockHash),
+			zap.Uint64("height", header.Height),
+			zap.String("proposer", header.ProposerID),
+			zap.String("reason", result.Reason),
+		)
+		return result, nil
+	}
+
+	cv.append(result.BlockHash, header)
+	return result, nil
+}
+
+// Append adds an already accepted header without checking it, for seeding
+// the chain from stored headers.
+func (cv *ChainTimeValidator) Append(header *types.BlockHeader) {
+	cv.append(header.Hash(), header)
+}
+
+func (cv *ChainTimeValidator) append(hash string, header *types.BlockHeader) {
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+
+	if _, exists := cv.headers[hash]; exists {
+		return
+	}
+	cv.headers[hash] = &chainTimeEntry{
+		parent:    header.ParentHash,
+		height:    header.Height,
+		timestamp: header.Timestamp,
+		position:  header.ProposerPosition,
+	}
+
+	if cv.tip != "" && header.Height <= cv.tipHeight {
+		return
+	}
+	cv.tip = hash
+	cv.tipHeight = header.Height
+	if cv.tipHeight <= cv.config.RetainHeights {
+		return
+	}
+	floor := cv.tipHeight - cv.config.RetainHeights
+	for stored, entry := range cv.headers {
+		if entry.height < floor {
+			delete(cv.headers, stored)
+		}
+	}
+}
+
+// medianLocked takes the median over up to Window headers ending at hash.
+// With a proposer position, each timestamp is moved forward by the light
+// delay from that header's proposer to it. Even windows take the upper
+// median, following Bitcoin.
+func (cv *ChainTimeValidator) medianLocked(hash string, proposer *types.Position) (time.Time, int) {
+	times := make([]time.Time, 0, cv.config.Window)
+	for len(times) < cv.config.Window {
+		entry, exists := cv.headers[hash]
+		if !exists {
+			break
+		}
+		corrected := entry.timestamp
+		if proposer != nil {
+			corrected = corrected.Add(cv.calculator.CalculateLightDelay(cv.calculator.CalculateDistance(entry.position, *proposer)))
+		}
+		times = append(times, corrected)
+		hash = entry.parent
+	}
+	if len(times) == 0 {
+		return time.Time{}, 0
+	}
+
+	sort.Slice(times, func(i, j int) bool {
+		return times[i].Before(times[j])
+	})
+	return times[len(times)/2], len(times)
+}
+
+// MedianTimePast is the uncorrected median of the window ending at the
+// highest accepted header.
+func (cv *ChainTimeValidator) MedianTimePast() time.Time {
+	cv.mu.RLock()
+	defer cv.mu.RUnlock()
+	median, _ := cv.medianLocked(cv.tip, nil)
+	return median
+}
+
+// Len is the number of headers in the window ending at the highest
+// accepted header.
+func (cv *ChainTimeValidator) Len() int {
+	cv.mu.RLock()
+	defer cv.mu.RUnlock()
+	_, count := cv.medianLocked(cv.tip, nil)
+	return count
+}
+
+func (cv *ChainTimeValidator) Reset() {
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+	cv.headers = make(map[string]*chainTimeEntry)
+	cv.tip = ""
+	cv.tipHeight = 0
+}
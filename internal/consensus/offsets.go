@@ -13,21 +13,42 @@ import (
 
 type OffsetManager struct {
 	timingManager *TimingManager
+	estimator     *OffsetEstimator
+	driftTracker  *DriftTracker
+	aggregation   *AggregationConfig
 	logger        *zap.Logger
 	mu            sync.RWMutex
 	nodeOffsets   map[string]*NodeOffset
+	flaggedNodes  map[string]string
 	globalOffset  time.Duration
+	lastReport    *AggregationReport
+	localOffset   time.Duration
 }
 
+// NodeOffset is a node's offset from the network. For measured offsets,
+// Offset is the residual still to correct on top of AppliedCorrection, the
+// correction the node reported having applied when it was measured.
 type NodeOffset struct {
-	NodeID         string        `json:"node_id"`
-	Offset         time.Duration `json:"offset"`
-	Confidence     float64       `json:"confidence"`
-	LastCalculated time.Time     `json:"last_calculated"`
-	Measurements   int           `json:"measurements"`
-	Region         string        `json:"region"`
+	NodeID            string        `json:"node_id"`
+	Offset            time.Duration `json:"offset"`
+	AppliedCorrection time.Duration `json:"applied_correction"`
+	Skew              time.Duration `json:"skew"`
+	Uncertainty       time.Duration `json:"uncertainty"`
+	DriftRate         float64       `json:"drift_rate_ppm"`
+	Source            OffsetSource  `json:"source"`
+	Confidence        float64       `json:"confidence"`
+	LastCalculated    time.Time     `json:"last_calculated"`
+	Measurements      int           `json:"measurements"`
+	Region            string        `json:"region"`
 }
 
+type OffsetSource string
+
+const (
+	OffsetSourceMeasured   OffsetSource = "measured"
+	OffsetSourceGeographic OffsetSource = "geographic"
+)
+
 type OffsetCalculation struct {
 	SourceNode       string        `json:"source_node"`
 	TargetNode       string        `json:"target_node"`
@@ -40,17 +61,43 @@ type OffsetCalculation struct {
 func NewOffsetManager(timingManager *TimingManager, logger *zap.Logger) *OffsetManager {
 	return &OffsetManager{
 		timingManager: timingManager,
+		driftTracker:  NewDriftTracker(DefaultDriftConfig(), logger),
+		aggregation:   DefaultAggregationConfig(),
 		logger:        logger,
 		nodeOffsets:   make(map[string]*NodeOffset),
+		flaggedNodes:  make(map[string]string),
 		globalOffset:  0,
 	}
 }
 
+func (om *OffsetManager) SetEstimator(estimator *OffsetEstimator) {
+	estimator.SetClockCorrection(om.LocalCorrection)
+
+	om.mu.Lock()
+	defer om.mu.Unlock()
+	om.estimator = estimator
+}
+
 func (om *OffsetManager) CalculateNodeOffset(nodeID string, referenceNodes []string) (*NodeOffset, error) {
 	if len(referenceNodes) == 0 {
 		return nil, fmt.Errorf("reference nodes list cannot be empty")
 	}
 
+	om.mu.RLock()
+	estimator := om.estimator
+	om.mu.RUnlock()
+
+	if estimator != nil {
+		offset, err := om.calculateMeasuredOffset(estimator, nodeID, referenceNodes)
+		if err == nil {
+			return offset, nil
+		}
+		om.logger.Debug("Measured offset unavailable, falling back to geographic estimate",
+			zap.String("node_id", nodeID),
+			zap.Error(err),
+		)
+	}
+
 	node, err := om.getNode(nodeID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get node %s: %w", nodeID, err)
@@ -99,15 +146,14 @@ func (om *OffsetManager) CalculateNodeOffset(nodeID string, referenceNodes []str
 	offset := &NodeOffset{
 		NodeID:         nodeID,
 		Offset:         averageOffset,
+		Source:         OffsetSourceGeographic,
 		Confidence:     overallConfidence,
 		LastCalculated: time.Now().UTC(),
 		Measurements:   measurements,
 		Region:         node.Metadata.Region,
 	}
 
-	om.mu.Lock()
-	om.nodeOffsets[nodeID] = offset
-	om.mu.Unlock()
+	om.recordOffset(offset)
 
 	om.logger.Info("Node offset calculated",
 		zap.String("node_id", nodeID),
@@ -119,6 +165,78 @@ func (om *OffsetManager) CalculateNodeOffset(nodeID string, referenceNodes []str
 	return offset, nil
 }
 
+func (om *OffsetManager) calculateMeasuredOffset(estimator *OffsetEstimator, nodeID string, referenceNodes []string) (*NodeOffset, error) {
+	estimate, err := estimator.EstimateOffset(nodeID, referenceNodes)
+	if err != nil {
+		return nil, err
+	}
+
+	offset := &NodeOffset{
+		NodeID:         nodeID,
+		Offset:         -estimate.Skew,
+		Skew:           estimate.Skew,
+		Uncertainty:    estimate.Uncertainty,
+		Source:         OffsetSourceMeasured,
+		Confidence:     estimate.Confidence,
+		LastCalculated: estimate.EstimatedAt,
+		Measurements:   len(estimate.Agreeing),
+	}
+
+	if sample := estimator.GetSample(nodeID); sample != nil {
+		offset.AppliedCorrection = sample.PeerCorrection
+	}
+	if node, err := om.getNode(nodeID); err == nil {
+		offset.Region = node.Metadata.Region
+	}
+
+	om.recordOffset(offset)
+
+	if len(estimate.Falsetickers) > 0 {
+		om.logger.Warn("Reference clocks disagree with the majority",
+			zap.String("node_id", nodeID),
+			zap.Strings("falsetickers", estimate.Falsetickers),
+		)
+	}
+
+	om.logger.Info("Node clock offset measured",
+		zap.String("node_id", nodeID),
+		zap.Duration("skew", offset.Skew),
+		zap.Duration("uncertainty", offset.Uncertainty),
+		zap.Float64("drift_ppm", offset.DriftRate),
+		zap.Float64("confidence", offset.Confidence),
+	)
+
+	return offset, nil
+}
+
+// recordOffset stores the snapshot and, for measured offsets, feeds the
+// drift tracker. The tracker fits the correction applied to timestamps, so
+// the clock's own drift rate is the negated slope. Geographic snapshots are
+// propagation delays, not clock readings, so they only carry the drift
+// already fitted from measurements.
+func (om *OffsetManager) recordOffset(offset *NodeOffset) {
+	if offset.Source == OffsetSourceMeasured {
+		model := om.driftTracker.Record(offset.NodeID, OffsetPoint{
+			Timestamp:   offset.LastCalculated,
+			Offset:      offset.Offset,
+			Uncertainty: offset.Uncertainty,
+		})
+		if model != nil {
+			offset.DriftRate = -model.DriftPPM
+		}
+	} else if model, err := om.driftTracker.GetModel(offset.NodeID); err == nil {
+		offset.DriftRate = -model.DriftPPM
+	}
+
+	om.mu.Lock()
+	om.nodeOffsets[offset.NodeID] = offset
+	om.mu.Unlock()
+}
+
+func (om *OffsetManager) GetDriftTracker() *DriftTracker {
+	return om.driftTracker
+}
+
 func (om *OffsetManager) calculateOffsetBetweenNodes(nodeA, nodeB *types.Node) (time.Duration, float64, error) {
 	distance, err := om.calculateDistance(nodeA.Position, nodeB.Position)
 	if err != nil {
@@ -194,36 +312,161 @@ func (om *OffsetManager) GetNodeOffset(nodeID string) (*NodeOffset, error) {
 }
 
 func (om *OffsetManager) CalculateGlobalOffset() time.Duration {
+	return om.CalculateGlobalOffsetReport().GlobalOffset
+}
+
+// CalculateGlobalOffsetReport aggregates the current node offsets with the
+// configured method. Flagged nodes and nodes over their drift budget are
+// excluded before aggregation; the report records every exclusion.
+func (om *OffsetManager) CalculateGlobalOffsetReport() *AggregationReport {
+	om.mu.RLock()
+	config := *om.aggregation
+	flagged := make(map[string]string, len(om.flaggedNodes))
+	for nodeID, reason := range om.flaggedNodes {
+		flagged[nodeID] = reason
+	}
+	offsets := make([]*NodeOffset, 0, len(om.nodeOffsets))
+	for _, offset := range om.nodeOffsets {
+		offsets = append(offsets, offset)
+	}
+	om.mu.RUnlock()
+
+	var candidates []*NodeOffset
+	var excluded []ExcludedNode
+	for _, offset := range offsets {
+		if reason, isFlagged := flagged[offset.NodeID]; isFlagged {
+			excluded = append(excluded, ExcludedNode{
+				NodeID: offset.NodeID,
+				Reason: ExclusionFlagged,
+				Detail: reason,
+				Offset: offset.Offset,
+			})
+			continue
+		}
+		if config.ExcludeDriftAlert && om.driftTracker.IsAlerting(offset.NodeID) {
+			excluded = append(excluded, ExcludedNode{
+				NodeID: offset.NodeID,
+				Reason: ExclusionDriftAlert,
+				Detail: fmt.Sprintf("drift %.2f ppm", offset.DriftRate),
+				Offset: offset.Offset,
+			})
+			continue
+		}
+		candidates = append(candidates, offset)
+	}
+
+	report := AggregateOffsets(candidates, &config)
+	report.Excluded = append(excluded, report.Excluded...)
+
+	om.mu.Lock()
+	if len(report.Included) > 0 {
+		om.globalOffset = report.GlobalOffset
+	}
+	om.lastReport = report
+	om.mu.Unlock()
+
+	om.logger.Info("Global offset calculated",
+		zap.String("method", string(report.Method)),
+		zap.Duration("offset", report.GlobalOffset),
+		zap.Int("nodes_considered", len(offsets)),
+		zap.Int("nodes_included", len(report.Included)),
+		zap.Int("nodes_excluded", len(report.Excluded)),
+	)
+
+	return report
+}
+
+func (om *OffsetManager) GetAggregationReport() *AggregationReport {
 	om.mu.RLock()
 	defer om.mu.RUnlock()
+	return om.lastReport
+}
 
-	if len(om.nodeOffsets) == 0 {
-		return 0
+func (om *OffsetManager) SetAggregationConfig(config *AggregationConfig) {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+	om.aggregation = config
+}
+
+func (om *OffsetManager) SetAggregationMethod(method AggregationMethod) {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+	om.aggregation.Method = method
+}
+
+// FlagNode excludes a node from global offset aggregation until it is
+// unflagged, e.g. when anomaly detection marks it as misbehaving.
+func (om *OffsetManager) FlagNode(nodeID, reason string) {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+	om.flaggedNodes[nodeID] = reason
+
+	om.logger.Warn("Node flagged for offset aggregation",
+		zap.String("node_id", nodeID),
+		zap.String("reason", reason),
+	)
+}
+
+func (om *OffsetManager) UnflagNode(nodeID string) {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+	delete(om.flaggedNodes, nodeID)
+}
+
+// UnflagNodeIf unflags a node only while it is still flagged for reason,
+// so a flag someone else has set since is kept. It reports whether the
+// node was unflagged.
+func (om *OffsetManager) UnflagNodeIf(nodeID, reason string) bool {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+
+	current, flagged := om.flaggedNodes[nodeID]
+	if !flagged || current != reason {
+		return false
 	}
+	delete(om.flaggedNodes, nodeID)
+	return true
+}
 
-	var totalWeightedOffset time.Duration
-	var totalWeight float64
+func (om *OffsetManager) GetFlaggedNodes() map[string]string {
+	om.mu.RLock()
+	defer om.mu.RUnlock()
 
-	for _, offset := range om.nodeOffsets {
-		if offset.Confidence > 0.5 {
-			totalWeightedOffset += time.Duration(float64(offset.Offset) * offset.Confidence)
-			totalWeight += offset.Confidence
-		}
+	flagged := make(map[string]string, len(om.flaggedNodes))
+	for nodeID, reason := range om.flaggedNodes {
+		flagged[nodeID] = reason
 	}
+	return flagged
+}
+
+func (om *OffsetManager) GetAllOffsets() map[string]*NodeOffset {
+	om.mu.RLock()
+	defer om.mu.RUnlock()
 
-	if totalWeight == 0 {
-		return 0
+	offsets := make(map[string]*NodeOffset, len(om.nodeOffsets))
+	for nodeID, offset := range om.nodeOffsets {
+		offsets[nodeID] = offset
 	}
+	return offsets
+}
 
-	globalOffset := time.Duration(float64(totalWeightedOffset) / totalWeight)
-	om.globalOffset = globalOffset
+// SetLocalCorrection records a correction to this node's own clock, as
+// accepted from a peer by the synchronizer. It replaces any earlier one.
+func (om *OffsetManager) SetLocalCorrection(correction time.Duration) {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+	om.localOffset = correction
+}
 
-	om.logger.Info("Global offset calculated",
-		zap.Duration("offset", globalOffset),
-		zap.Int("nodes_considered", len(om.nodeOffsets)),
-	)
+func (om *OffsetManager) LocalCorrection() time.Duration {
+	om.mu.RLock()
+	defer om.mu.RUnlock()
+	return om.localOffset
+}
 
-	return globalOffset
+// Now is the local clock with the accepted correction applied.
+func (om *OffsetManager) Now() time.Time {
+	return time.Now().Add(om.LocalCorrection())
 }
 
 func (om *OffsetManager) GetGlobalOffset() time.Duration {
@@ -238,8 +481,12 @@ func (om *OffsetManager) AdjustTimestamp(timestamp time.Time, nodeID string) (ti
 		return timestamp, fmt.Errorf("failed to get offset for node %s: %w", nodeID, err)
 	}
 
-	adjusted := timestamp.Add(offset.Offset)
-	return adjusted, nil
+	predicted, _, err := om.driftTracker.PredictOffset(nodeID, timestamp)
+	if err != nil {
+		return timestamp.Add(offset.Offset), nil
+	}
+
+	return timestamp.Add(predicted), nil
 }
 
 func (om *OffsetManager) BatchCalculateOffsets(nodeIDs []string) map[string]*NodeOffset {
@@ -0,0 +1,566 @@
+package consensus
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
+)
+
+type MembershipAction string
+
+const (
+	MembershipJoin  MembershipAction = "join"
+	MembershipLeave MembershipAction = "leave"
+)
+
+// RecordStore persists registry state. The topology manager implements it on
+// top of Redis.
+type RecordStore interface {
+	SaveRecord(key string, value interface{}) error
+	LoadRecord(key string, value interface{}) error
+	ListRecordKeys(prefix string) ([]string, error)
+}
+
+const (
+	registryEpochPrefix = "validators:epoch:"
+	registryStateKey    = "validators:state"
+)
+
+// RegistryConfig holds chain parameters and must be identical on every
+// node: membership changes are scheduled from EpochLength,
+// MinActivationBlocks, SafetyFactor, ScheduledPropagation and
+// DefaultBlockInterval alone, never from local measurements.
+type RegistryConfig struct {
+	EpochLength          uint64        `json:"epoch_length"`
+	MinActivationBlocks  uint64        `json:"min_activation_blocks"`
+	SafetyFactor         float64       `json:"safety_factor"`
+	DefaultBlockInterval time.Duration `json:"default_block_interval"`
+	ScheduledPropagation time.Duration `json:"scheduled_propagation"`
+	FallbackPropagation  time.Duration `json:"fallback_propagation"`
+	MinValidators        int           `json:"min_validators"`
+}
+
+func DefaultRegistryConfig() *RegistryConfig {
+	return &RegistryConfig{
+		EpochLength:          100,
+		MinActivationBlocks:  2,
+		SafetyFactor:         types.ConsensusSafetyFactor,
+		DefaultBlockInterval: 5 * time.Second,
+		ScheduledPropagation: 500 * time.Millisecond,
+		FallbackPropagation:  500 * time.Millisecond,
+		MinValidators:        1,
+	}
+}
+
+type ValidatorInfo struct {
+	ID          string `json:"id"`
+	KeyType     string `json:"key_type,omitempty"`
+	PublicKey   string `json:"public_key,omitempty"`
+	JoinedEpoch uint64 `json:"joined_epoch"`
+}
+
+type MembershipRequest struct {
+	ID               string           `json:"id"`
+	Action           MembershipAction `json:"action"`
+	Validator        ValidatorInfo    `json:"validator"`
+	RequestHeight    uint64           `json:"request_height"`
+	ActivationDelay  time.Duration    `json:"activation_delay"`
+	ActivationHeight uint64           `json:"activation_height"`
+	EffectiveEpoch   uint64           `json:"effective_epoch"`
+	SubmittedAt      time.Time        `json:"submitted_at"`
+}
+
+// ValidatorEpoch is an immutable snapshot of the validator set. Epoch N
+// covers heights [N*EpochLength, (N+1)*EpochLength).
+type ValidatorEpoch struct {
+	Epoch       uint64          `json:"epoch"`
+	SetID       string          `json:"set_id"`
+	StartHeight uint64          `json:"start_height"`
+	Validators  []ValidatorInfo `json:"validators"`
+	Joined      []string        `json:"joined,omitempty"`
+	Left        []string        `json:"left,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+func (ve *ValidatorEpoch) ValidatorIDs() []string {
+	ids := make([]string, len(ve.Validators))
+	for i, v := range ve.Validators {
+		ids[i] = v.ID
+	}
+	return ids
+}
+
+func (ve *ValidatorEpoch) Contains(validatorID string) bool {
+	for _, v := range ve.Validators {
+		if v.ID == validatorID {
+			return true
+		}
+	}
+	return false
+}
+
+type EpochChangeHandler func(epoch *ValidatorEpoch)
+
+type registryState struct {
+	CurrentEpoch uint64               `json:"current_epoch"`
+	Height       uint64               `json:"height"`
+	Pending      []*MembershipRequest `json:"pending"`
+}
+
+// ValidatorRegistry tracks the validator set across epochs. Membership
+// changes never take effect mid-epoch: a request included at height h is
+// scheduled for the first epoch that starts after the scheduled worst-case
+// propagation delay has elapsed. The schedule depends only on h and the
+// chain parameters, so every node switches sets at the same height.
+type ValidatorRegistry struct {
+	timingManager *TimingManager
+	store         RecordStore
+	config        *RegistryConfig
+	logger        *zap.Logger
+	mu            sync.RWMutex
+	epochs        map[uint64]*ValidatorEpoch
+	current       uint64
+	height        uint64
+	pending       []*MembershipRequest
+	handlers      []EpochChangeHandler
+}
+
+func NewValidatorRegistry(timingManager *TimingManager, store RecordStore, config *RegistryConfig, logger *zap.Logger) *ValidatorRegistry {
+	if config == nil {
+		config = DefaultRegistryConfig()
+	}
+
+	return &ValidatorRegistry{
+		timingManager: timingManager,
+		store:         store,
+		config:        config,
+		logger:        logger,
+		epochs:        make(map[uint64]*ValidatorEpoch),
+	}
+}
+
+func (vr *ValidatorRegistry) OnEpochChange(handler EpochChangeHandler) {
+	vr.mu.Lock()
+	defer vr.mu.Unlock()
+	vr.handlers = append(vr.handlers, handler)
+}
+
+// Bootstrap installs the genesis validator set as epoch 0.
+func (vr *ValidatorRegistry) Bootstrap(validators []ValidatorInfo) (*ValidatorEpoch, error) {
+	if len(validators) < vr.config.MinValidators {
+		return nil, fmt.Errorf("at least %d validators are required", vr.config.MinValidators)
+	}
+
+	vr.mu.Lock()
+	if len(vr.epochs) > 0 {
+		vr.mu.Unlock()
+		return nil, fmt.Errorf("validator registry already initialized")
+	}
+
+	seen := make(map[string]bool, len(validators))
+	genesis := &ValidatorEpoch{CreatedAt: time.Now().UTC()}
+	for _, v := range validators {
+		if v.ID == "" {
+			vr.mu.Unlock()
+			return nil, fmt.Errorf("validator ID cannot be empty")
+		}
+		if seen[v.ID] {
+			vr.mu.Unlock()
+			return nil, fmt.Errorf("duplicate validator %s", v.ID)
+		}
+		seen[v.ID] = true
+		v.JoinedEpoch = 0
+		genesis.Validators = append(genesis.Validators, v)
+		genesis.Joined = append(genesis.Joined, v.ID)
+	}
+	sortValidators(genesis.Validators)
+	sort.Strings(genesis.Joined)
+	genesis.SetID = ValidatorSetID(genesis.ValidatorIDs())
+
+	vr.epochs[0] = genesis
+	vr.persistLocked(genesis)
+	vr.persistStateLocked()
+	handlers := append([]EpochChangeHandler(nil), vr.handlers...)
+	vr.mu.Unlock()
+
+	vr.logger.Info("Validator registry bootstrapped", zap.Int("validators", len(genesis.Validators)))
+
+	for _, handler := range handlers {
+		handler(genesis)
+	}
+	return genesis, nil
+}
+
+// Load restores epochs and pending requests from the store. An empty store
+// is not an error.
+func (vr *ValidatorRegistry) Load() error {
+	if vr.store == nil {
+		return nil
+	}
+
+	keys, err := vr.store.ListRecordKeys(registryEpochPrefix)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	epochs := make(map[uint64]*ValidatorEpoch, len(keys))
+	for _, key := range keys {
+		var epoch ValidatorEpoch
+		if err := vr.store.LoadRecord(key, &epoch); err != nil {
+			return err
+		}
+		epochs[epoch.Epoch] = &epoch
+	}
+
+	var state registryState
+	if err := vr.store.LoadRecord(registryStateKey, &state); err != nil {
+		return err
+	}
+
+	vr.mu.Lock()
+	vr.epochs = epochs
+	vr.current = state.CurrentEpoch
+	vr.height = state.Height
+	vr.pending = state.Pending
+	vr.mu.Unlock()
+
+	vr.logger.Info("Validator registry loaded",
+		zap.Int("epochs", len(epochs)),
+		zap.Uint64("current_epoch", state.CurrentEpoch),
+		zap.Int("pending", len(state.Pending)),
+	)
+	return nil
+}
+
+func (vr *ValidatorRegistry) EpochForHeight(height uint64) uint64 {
+	return height / vr.config.EpochLength
+}
+
+// ScheduledActivationDelay is the activation delay membership changes are
+// scheduled with: ScheduledPropagation inflated by the safety factor.
+func (vr *ValidatorRegistry) ScheduledActivationDelay() time.Duration {
+	return time.Duration(float64(vr.config.ScheduledPropagation) * (1 + vr.config.SafetyFactor))
+}
+
+// ActivationDelay is this node's estimate of the worst-case time for a
+// membership change to reach every validator: the maximum pairwise
+// propagation delay over the current set plus the joining node, inflated by
+// the safety factor. It depends on local topology, so it only checks the
+// scheduled delay and never sets it.
+func (vr *ValidatorRegistry) ActivationDelay(validators []string) time.Duration {
+	propagation := vr.config.FallbackPropagation
+	if vr.timingManager != nil && vr.timingManager.topologyManager != nil && len(validators) >= 2 {
+		timing, err := vr.timingManager.CalculateConsensusTiming(validators)
+		if err == nil {
+			propagation = timing.MaxPropagation
+		} else {
+			vr.logger.Debug("Falling back to default propagation for activation delay", zap.Error(err))
+		}
+	}
+	return time.Duration(float64(propagation) * (1 + vr.config.SafetyFactor))
+}
+
+// ActivationBlocks converts an activation delay into whole blocks at the
+// given block interval, never fewer than MinActivationBlocks.
+func ActivationBlocks(delay, blockInterval time.Duration, minBlocks uint64) uint64 {
+	if blockInterval <= 0 {
+		return minBlocks
+	}
+	blocks := uint64(math.Ceil(float64(delay) / float64(blockInterval)))
+	if blocks < minBlocks {
+		return minBlocks
+	}
+	return blocks
+}
+
+func (vr *ValidatorRegistry) RequestJoin(validator ValidatorInfo, height uint64) (*MembershipRequest, error) {
+	if validator.ID == "" {
+		return nil, fmt.Errorf("validator ID cannot be empty")
+	}
+	return vr.submit(MembershipJoin, validator, height)
+}
+
+func (vr *ValidatorRegistry) RequestLeave(validatorID string, height uint64) (*MembershipRequest, error) {
+	if validatorID == "" {
+		return nil, fmt.Errorf("validator ID cannot be empty")
+	}
+	return vr.submit(MembershipLeave, ValidatorInfo{ID: validatorID}, height)
+}
+
+func (vr *ValidatorRegistry) submit(action MembershipAction, validator ValidatorInfo, height uint64) (*MembershipRequest, error) {
+	vr.mu.RLock()
+	current, exists := vr.epochs[vr.current]
+	vr.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("validator registry not bootstrapped")
+	}
+
+	delay := vr.ScheduledActivationDelay()
+	blocks := ActivationBlocks(delay, vr.config.DefaultBlockInterval, vr.config.MinActivationBlocks)
+
+	nodes := current.ValidatorIDs()
+	if action == MembershipJoin {
+		nodes = append(nodes, validator.ID)
+	}
+	if measured := vr.ActivationDelay(nodes); measured > delay {
+		vr.logger.Warn("Measured activation delay exceeds the scheduled one; scheduled_propagation is too low for this network",
+			zap.Duration("measured", measured),
+			zap.Duration("scheduled", delay),
+		)
+	}
+
+	vr.mu.Lock()
+	defer vr.mu.Unlock()
+
+	if height < vr.height {
+		height = vr.height
+	}
+	if err := vr.checkRequestLocked(action, validator.ID); err != nil {
+		return nil, err
+	}
+
+	activationHeight := height + blocks
+	request := &MembershipRequest{
+		ID:               fmt.Sprintf("%s_%s_%d", action, validator.ID, time.Now().UnixNano()),
+		Action:           action,
+		Validator:        validator,
+		RequestHeight:    height,
+		ActivationDelay:  delay,
+		ActivationHeight: activationHeight,
+		EffectiveEpoch:   vr.EpochForHeight(activationHeight) + 1,
+		SubmittedAt:      time.Now().UTC(),
+	}
+	vr.pending = append(vr.pending, request)
+	vr.persistStateLocked()
+
+	vr.logger.Info("Validator membership change scheduled",
+		zap.String("action", string(action)),
+		zap.String("validator_id", validator.ID),
+		zap.Uint64("request_height", height),
+		zap.Duration("activation_delay", delay),
+		zap.Uint64("effective_epoch", request.EffectiveEpoch),
+	)
+
+	return request, nil
+}
+
+func (vr *ValidatorRegistry) checkRequestLocked(action MembershipAction, validatorID string) error {
+	for _, request := range vr.pending {
+		if request.Validator.ID == validatorID {
+			return fmt.Errorf("validator %s already has a pending %s request", validatorID, request.Action)
+		}
+	}
+
+	current := vr.epochs[vr.current]
+	switch action {
+	case MembershipJoin:
+		if current.Contains(validatorID) {
+			return fmt.Errorf("validator %s is already in the set", validatorID)
+		}
+	case MembershipLeave:
+		if !current.Contains(validatorID) {
+			return fmt.Errorf("validator %s is not in the set", validatorID)
+		}
+		remaining := len(current.Validators) - 1
+		for _, request := range vr.pending {
+			switch request.Action {
+			case MembershipLeave:
+				remaining--
+			case MembershipJoin:
+				remaining++
+			}
+		}
+		if remaining < vr.config.MinValidators {
+			return fmt.Errorf("leave would drop the validator set below %d", vr.config.MinValidators)
+		}
+	}
+	return nil
+}
+
+// AdvanceHeight records the latest committed height and opens every epoch
+// boundary crossed since the last call, applying the changes scheduled for
+// each. It returns the newly created epochs.
+func (vr *ValidatorRegistry) AdvanceHeight(height uint64) ([]*ValidatorEpoch, error) {
+	vr.mu.Lock()
+
+	if _, exists := vr.epochs[vr.current]; !exists {
+		vr.mu.Unlock()
+		return nil, fmt.Errorf("validator registry not bootstrapped")
+	}
+	if height <= vr.height {
+		vr.mu.Unlock()
+		return nil, nil
+	}
+	vr.height = height
+
+	var created []*ValidatorEpoch
+	target := vr.EpochForHeight(height)
+	for vr.current < target {
+		epoch := vr.nextEpochLocked()
+		vr.epochs[epoch.Epoch] = epoch
+		vr.current = epoch.Epoch
+		vr.persistLocked(epoch)
+		created = append(created, epoch)
+	}
+	vr.persistStateLocked()
+	handlers := append([]EpochChangeHandler(nil), vr.handlers...)
+	vr.mu.Unlock()
+
+	for _, epoch := range created {
+		vr.logger.Info("Validator epoch started",
+			zap.Uint64("epoch", epoch.Epoch),
+			zap.Uint64("start_height", epoch.StartHeight),
+			zap.Int("validators", len(epoch.Validators)),
+			zap.Strings("joined", epoch.Joined),
+			zap.Strings("left", epoch.Left),
+		)
+		for _, handler := range handlers {
+			handler(epoch)
+		}
+	}
+
+	return created, nil
+}
+
+func (vr *ValidatorRegistry) nextEpochLocked() *ValidatorEpoch {
+	previous := vr.epochs[vr.current]
+	number := vr.current + 1
+
+	members := make(map[string]ValidatorInfo, len(previous.Validators))
+	for _, v := range previous.Validators {
+		members[v.ID] = v
+	}
+
+	epoch := &ValidatorEpoch{
+		Epoch:       number,
+		StartHeight: number * vr.config.EpochLength,
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	remaining := vr.pending[:0]
+	for _, request := range vr.pending {
+		if request.EffectiveEpoch > number {
+			remaining = append(remaining, request)
+			continue
+		}
+
+		switch request.Action {
+		case MembershipJoin:
+			validator := request.Validator
+			validator.JoinedEpoch = number
+			members[validator.ID] = validator
+			epoch.Joined = append(epoch.Joined, validator.ID)
+		case MembershipLeave:
+			delete(members, request.Validator.ID)
+			epoch.Left = append(epoch.Left, request.Validator.ID)
+		}
+	}
+	vr.pending = remaining
+
+	for _, v := range members {
+		epoch.Validators = append(epoch.Validators, v)
+	}
+	sortValidators(epoch.Validators)
+	sort.Strings(epoch.Joined)
+	sort.Strings(epoch.Left)
+	epoch.SetID = ValidatorSetID(epoch.ValidatorIDs())
+
+	return epoch
+}
+
+func (vr *ValidatorRegistry) persistLocked(epoch *ValidatorEpoch) {
+	if vr.store == nil {
+		return
+	}
+	if err := vr.store.SaveRecord(fmt.Sprintf("%s%d", registryEpochPrefix, epoch.Epoch), epoch); err != nil {
+		vr.logger.Warn("Failed to persist validator epoch",
+			zap.Uint64("epoch", epoch.Epoch),
+			zap.Error(err),
+		)
+	}
+}
+
+func (vr *ValidatorRegistry) persistStateLocked() {
+	if vr.store == nil {
+		return
+	}
+	state := registryState{
+		CurrentEpoch: vr.current,
+		Height:       vr.height,
+		Pending:      vr.pending,
+	}
+	if err := vr.store.SaveRecord(registryStateKey, state); err != nil {
+		vr.logger.Warn("Failed to persist validator registry state", zap.Error(err))
+	}
+}
+
+func (vr *ValidatorRegistry) GetValidatorSet(epoch uint64) (*ValidatorEpoch, error) {
+	vr.mu.RLock()
+	defer vr.mu.RUnlock()
+
+	snapshot, exists := vr.epochs[epoch]
+	if !exists {
+		return nil, fmt.Errorf("validator set for epoch %d not found", epoch)
+	}
+	return snapshot, nil
+}
+
+func (vr *ValidatorRegistry) GetValidatorSetAtHeight(height uint64) (*ValidatorEpoch, error) {
+	return vr.GetValidatorSet(vr.EpochForHeight(height))
+}
+
+func (vr *ValidatorRegistry) GetCurrentSet() (*ValidatorEpoch, error) {
+	vr.mu.RLock()
+	current := vr.current
+	vr.mu.RUnlock()
+	return vr.GetValidatorSet(current)
+}
+
+func (vr *ValidatorRegistry) CurrentEpoch() uint64 {
+	vr.mu.RLock()
+	defer vr.mu.RUnlock()
+	return vr.current
+}
+
+func (vr *ValidatorRegistry) CurrentHeight() uint64 {
+	vr.mu.RLock()
+	defer vr.mu.RUnlock()
+	return vr.height
+}
+
+func (vr *ValidatorRegistry) ListEpochs() []uint64 {
+	vr.mu.RLock()
+	defer vr.mu.RUnlock()
+
+	epochs := make([]uint64, 0, len(vr.epochs))
+	for epoch := range vr.epochs {
+		epochs = append(epochs, epoch)
+	}
+	sort.Slice(epochs, func(i, j int) bool { return epochs[i] < epochs[j] })
+	return epochs
+}
+
+func (vr *ValidatorRegistry) GetPendingRequests() []*MembershipRequest {
+	vr.mu.RLock()
+	defer vr.mu.RUnlock()
+
+	pending := make([]*MembershipRequest, len(vr.pending))
+	copy(pending, vr.pending)
+	return pending
+}
+
+func sortValidators(validators []ValidatorInfo) {
+	sort.Slice(validators, func(i, j int) bool {
+		return validators[i].ID < validators[j].ID
+	})
+}
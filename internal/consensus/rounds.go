@@ -0,0 +1,357 @@
+package consensus
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
+)
+
+type RoundEventType string
+
+const (
+	RoundEventProposalSent     RoundEventType = "proposal_sent"
+	RoundEventProposalReceived RoundEventType = "proposal_received"
+	RoundEventVoteReceived     RoundEventType = "vote_received"
+	RoundEventCommit           RoundEventType = "commit"
+)
+
+type RoundRecorderConfig struct {
+	MaxRounds    int           `json:"max_rounds"`
+	LagThreshold time.Duration `json:"lag_threshold"`
+}
+
+func DefaultRoundRecorderConfig() *RoundRecorderConfig {
+	return &RoundRecorderConfig{
+		MaxRounds:    500,
+		LagThreshold: 100 * time.Millisecond,
+	}
+}
+
+// RoundEvent is one step of a round as seen by this node. For received
+// messages Delay is the observed transit time (receipt minus the sender's
+// timestamp) and Theoretical the geographic delay from the sender.
+type RoundEvent struct {
+	Type        RoundEventType `json:"type"`
+	NodeID      string         `json:"node_id"`
+	BlockHash   string         `json:"block_hash,omitempty"`
+	SentAt      time.Time      `json:"sent_at,omitempty"`
+	At          time.Time      `json:"at"`
+	Delay       time.Duration  `json:"delay,omitempty"`
+	Theoretical time.Duration  `json:"theoretical,omitempty"`
+	Lag         time.Duration  `json:"lag,omitempty"`
+	Lagging     bool           `json:"lagging,omitempty"`
+}
+
+type RoundTimeline struct {
+	ID          string        `json:"id"`
+	Height      uint64        `json:"height"`
+	Round       uint32        `json:"round"`
+	Proposer    string        `json:"proposer,omitempty"`
+	BlockHash   string        `json:"block_hash,omitempty"`
+	StartedAt   time.Time     `json:"started_at"`
+	CommittedAt *time.Time    `json:"committed_at,omitempty"`
+	Duration    time.Duration `json:"duration,omitempty"`
+	Events      []RoundEvent  `json:"events"`
+	Laggards    []string      `json:"laggards,omitempty"`
+}
+
+func RoundID(height uint64, round uint32) string {
+	return fmt.Sprintf("%d-%d", height, round)
+}
+
+// RoundRecorder keeps per-round timelines of proposals, votes and commits
+// so slow rounds can be inspected after the fact.
+type RoundRecorder struct {
+	timingManager *TimingManager
+	config        *RoundRecorderConfig
+	logger        *zap.Logger
+	mu            sync.RWMutex
+	localNodeID   string
+	rounds        map[string]*RoundTimeline
+	order         []string
+}
+
+func NewRoundRecorder(timingManager *TimingManager, config *RoundRecorderConfig, logger *zap.Logger) *RoundRecorder {
+	if config == nil {
+		config = DefaultRoundRecorderConfig()
+	}
+
+	return &RoundRecorder{
+		timingManager: timingManager,
+		config:        config,
+		logger:        logger,
+		rounds:        make(map[string]*RoundTimeline),
+	}
+}
+
+func (rr *RoundRecorder) SetLocalNode(nodeID string) {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	rr.localNodeID = nodeID
+}
+
+// RecordProposal adds a proposal to its round and returns its observed
+// transit delay, which is zero for the local node's own proposals.
+func (rr *RoundRecorder) RecordProposal(proposal *types.Proposal, receivedAt time.Time) time.Duration {
+	var blockHash string
+	if proposal.Block != nil {
+		blockHash = proposal.Block.Hash
+	}
+
+	sent := RoundEvent{
+		Type:      RoundEventProposalSent,
+		NodeID:    proposal.ProposerID,
+		BlockHash: blockHash,
+		At:        proposal.Timestamp,
+	}
+	received := rr.receivedEvent(RoundEventProposalReceived, proposal.ProposerID, blockHash, proposal.Timestamp, receivedAt)
+
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	timeline := rr.timelineLocked(proposal.Height, proposal.Round, proposal.Timestamp)
+	if timeline.Proposer == "" {
+		timeline.Proposer = proposal.ProposerID
+		timeline.BlockHash = blockHash
+	}
+	timeline.Events = append(timeline.Events, sent)
+	if proposal.ProposerID == rr.localNodeID {
+		return 0
+	}
+	timeline.Events = append(timeline.Events, received)
+	return received.Delay
+}
+
+func (rr *RoundRecorder) RecordVote(vote *types.Vote, receivedAt time.Time) {
+	event := rr.receivedEvent(RoundEventVoteReceived, vote.VoterID, vote.BlockHash, vote.Timestamp, receivedAt)
+
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	timeline := rr.timelineLocked(vote.Height, vote.Round, vote.Timestamp)
+	timeline.Events = append(timeline.Events, event)
+	if event.Lagging {
+		timeline.Laggards = appendUnique(timeline.Laggards, vote.VoterID)
+	}
+}
+
+// RecordCommit closes a round and reports whether it was not already
+// committed.
+func (rr *RoundRecorder) RecordCommit(height uint64, round uint32, blockHash string, committedAt time.Time) bool {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	timeline := rr.timelineLocked(height, round, committedAt)
+	if timeline.CommittedAt != nil {
+		return false
+	}
+
+	at := committedAt
+	timeline.CommittedAt = &at
+	timeline.BlockHash = blockHash
+	timeline.Duration = committedAt.Sub(timeline.StartedAt)
+	timeline.Events = append(timeline.Events, RoundEvent{
+		Type:      RoundEventCommit,
+		NodeID:    rr.localNodeID,
+		BlockHash: blockHash,
+		At:        committedAt,
+	})
+
+	rr.logger.Debug("Consensus round committed",
+		zap.String("round_id", timeline.ID),
+		zap.Duration("duration", timeline.Duration),
+		zap.Strings("laggards", timeline.Laggards),
+	)
+	return true
+}
+
+func (rr *RoundRecorder) receivedEvent(eventType RoundEventType, sender, blockHash string, sentAt, receivedAt time.Time) RoundEvent {
+	event := RoundEvent{
+		Type:      eventType,
+		NodeID:    sender,
+		BlockHash: blockHash,
+		SentAt:    sentAt,
+		At:        receivedAt,
+		Delay:     receivedAt.Sub(sentAt),
+	}
+
+	rr.mu.RLock()
+	localNodeID := rr.localNodeID
+	rr.mu.RUnlock()
+
+	if localNodeID != "" && sender != localNodeID {
+		if theoretical, err := rr.timingManager.ExpectedNetworkDelay(sender, localNodeID); err == nil {
+			event.Theoretical = theoretical
+		}
+	}
+	event.Lag = event.Delay - event.Theoretical
+	event.Lagging = event.Lag > rr.config.LagThreshold
+	return event
+}
+
+func (rr *RoundRecorder) timelineLocked(height uint64, round uint32, at time.Time) *RoundTimeline {
+	id := RoundID(height, round)
+	timeline, exists := rr.rounds[id]
+	if !exists {
+		timeline = &RoundTimeline{
+			ID:        id,
+			Height:    height,
+			Round:     round,
+			StartedAt: at,
+		}
+		rr.rounds[id] = timeline
+		rr.order = append(rr.order, id)
+
+		if len(rr.order) > rr.config.MaxRounds {
+			delete(rr.rounds, rr.order[0])
+			rr.order = rr.order[1:]
+		}
+	}
+
+	if !at.IsZero() && at.Before(timeline.StartedAt) {
+		timeline.StartedAt = at
+	}
+	return timeline
+}
+
+func (rr *RoundRecorder) GetTimeline(id string) (*RoundTimeline, error) {
+	rr.mu.RLock()
+	defer rr.mu.RUnlock()
+
+	timeline, exists := rr.rounds[id]
+	if !exists {
+		return nil, fmt.Errorf("round %s not found", id)
+	}
+
+	snapshot := *timeline
+	snapshot.Events = append([]RoundEvent(nil), timeline.Events...)
+	snapshot.Laggards = append([]string(nil), timeline.Laggards...)
+	sort.SliceStable(snapshot.Events, func(i, j int) bool {
+		return snapshot.Events[i].At.Before(snapshot.Events[j].At)
+	})
+	return &snapshot, nil
+}
+
+// ListRounds returns the most recent timelines, newest first.
+func (rr *RoundRecorder) ListRounds(limit int) []*RoundTimeline {
+	rr.mu.RLock()
+	ids := make([]string, len(rr.order))
+	copy(ids, rr.order)
+	rr.mu.RUnlock()
+
+	var timelines []*RoundTimeline
+	for i := len(ids) - 1; i >= 0; i-- {
+		if limit > 0 && len(timelines) >= limit {
+			break
+		}
+		if timeline, err := rr.GetTimeline(ids[i]); err == nil {
+			timelines = append(timelines, timeline)
+		}
+	}
+	return timelines
+}
+
+// TraceEvent is an entry of the Chrome trace-event format, loadable in
+// chrome://tracing or Perfetto. Timestamps and durations are microseconds.
+type TraceEvent struct {
+	Name      string                 `json:"name"`
+	Category  string                 `json:"cat,omitempty"`
+	Phase     string                 `json:"ph"`
+	Timestamp int64                  `json:"ts"`
+	Duration  int64                  `json:"dur,omitempty"`
+	PID       int                    `json:"pid"`
+	TID       int                    `json:"tid"`
+	Scope     string                 `json:"s,omitempty"`
+	Args      map[string]interface{} `json:"args,omitempty"`
+}
+
+type Trace struct {
+	TraceEvents     []TraceEvent `json:"traceEvents"`
+	DisplayTimeUnit string       `json:"displayTimeUnit"`
+}
+
+// ChromeTrace renders a round with one track per validator. Received
+// messages are spans from the sender's timestamp to receipt, so lagging
+// validators show up as long bars.
+func (rr *RoundRecorder) ChromeTrace(id string) (*Trace, error) {
+	timeline, err := rr.GetTimeline(id)
+	if err != nil {
+		return nil, err
+	}
+
+	trace := &Trace{DisplayTimeUnit: "ms"}
+	trace.TraceEvents = append(trace.TraceEvents, TraceEvent{
+		Name:  "process_name",
+		Phase: "M",
+		PID:   1,
+		Args:  map[string]interface{}{"name": fmt.Sprintf("round %s", timeline.ID)},
+	})
+
+	threads := make(map[string]int)
+	thread := func(nodeID string) int {
+		if tid, exists := threads[nodeID]; exists {
+			return tid
+		}
+		tid := len(threads) + 1
+		threads[nodeID] = tid
+		trace.TraceEvents = append(trace.TraceEvents, TraceEvent{
+			Name:  "thread_name",
+			Phase: "M",
+			PID:   1,
+			TID:   tid,
+			Args:  map[string]interface{}{"name": nodeID},
+		})
+		return tid
+	}
+	micros := func(t time.Time) int64 {
+		return t.Sub(timeline.StartedAt).Microseconds()
+	}
+
+	for _, event := range timeline.Events {
+		entry := TraceEvent{
+			Name:      string(event.Type),
+			Category:  "consensus",
+			PID:       1,
+			TID:       thread(event.NodeID),
+			Timestamp: micros(event.At),
+			Args: map[string]interface{}{
+				"node_id":    event.NodeID,
+				"block_hash": event.BlockHash,
+			},
+		}
+
+		switch event.Type {
+		case RoundEventProposalReceived, RoundEventVoteReceived:
+			entry.Phase = "X"
+			entry.Timestamp = micros(event.SentAt)
+			entry.Duration = event.Delay.Microseconds()
+			entry.Args["delay_ms"] = float64(event.Delay) / float64(time.Millisecond)
+			entry.Args["theoretical_ms"] = float64(event.Theoretical) / float64(time.Millisecond)
+			entry.Args["lag_ms"] = float64(event.Lag) / float64(time.Millisecond)
+			entry.Args["lagging"] = event.Lagging
+		case RoundEventCommit:
+			entry.Phase = "i"
+			entry.Scope = "g"
+		default:
+			entry.Phase = "i"
+			entry.Scope = "t"
+		}
+		trace.TraceEvents = append(trace.TraceEvents, entry)
+	}
+
+	return trace, nil
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
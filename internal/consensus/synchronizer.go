@@ -2,18 +2,63 @@ package consensus
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"sync"
 	"time"
 
 	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/network"
 )
 
+type SyncConfig struct {
+	AckTimeout           time.Duration `json:"ack_timeout"`
+	MaxCorrection        time.Duration `json:"max_correction"`
+	ConvergenceThreshold time.Duration `json:"convergence_threshold"`
+}
+
+func DefaultSyncConfig() *SyncConfig {
+	return &SyncConfig{
+		AckTimeout:           30 * time.Second,
+		MaxCorrection:        5 * time.Second,
+		ConvergenceThreshold: 10 * time.Millisecond,
+	}
+}
+
+// SyncCorrection is pushed to a node to tell it how far its clock is off
+// from the network. Offset is the total correction the node should apply,
+// replacing any earlier one.
+type SyncCorrection struct {
+	CorrectionID string        `json:"correction_id"`
+	NodeID       string        `json:"node_id"`
+	Offset       time.Duration `json:"offset"`
+	GlobalOffset time.Duration `json:"global_offset"`
+	Confidence   float64       `json:"confidence"`
+	IssuedAt     time.Time     `json:"issued_at"`
+}
+
+type SyncAck struct {
+	CorrectionID string        `json:"correction_id"`
+	NodeID       string        `json:"node_id"`
+	Applied      bool          `json:"applied"`
+	Offset       time.Duration `json:"offset"`
+	Reason       string        `json:"reason,omitempty"`
+	AckedAt      time.Time     `json:"acked_at"`
+}
+
+// CorrectionAuthorizer reports whether a peer may correct this node's clock.
+type CorrectionAuthorizer func(peerID string) bool
+
 type Synchronizer struct {
 	offsetManager *OffsetManager
+	peering       *network.PeeringManager
+	authorize     CorrectionAuthorizer
+	config        *SyncConfig
 	logger        *zap.Logger
 	mu            sync.RWMutex
 	syncStatus    map[string]*SyncStatus
+	correctionSeq uint64
 	stopChan      chan struct{}
 }
 
@@ -25,17 +70,48 @@ type SyncStatus struct {
 	AverageOffset time.Duration `json:"average_offset"`
 	Status        string        `json:"status"`
 	LastError     string        `json:"last_error,omitempty"`
+	CorrectionID  string        `json:"correction_id,omitempty"`
+	SentAt        time.Time     `json:"sent_at,omitempty"`
+	AckedAt       time.Time     `json:"acked_at,omitempty"`
+	Residual      time.Duration `json:"residual"`
+	Converged     bool          `json:"converged"`
 }
 
 func NewSynchronizer(offsetManager *OffsetManager, logger *zap.Logger) *Synchronizer {
 	return &Synchronizer{
 		offsetManager: offsetManager,
+		config:        DefaultSyncConfig(),
 		logger:        logger,
 		syncStatus:    make(map[string]*SyncStatus),
 		stopChan:      make(chan struct{}),
 	}
 }
 
+// SetPeering enables pushing corrections to peers. Without it offsets are
+// only computed locally and nodes are reported as "synced".
+func (s *Synchronizer) SetPeering(peering *network.PeeringManager) {
+	s.mu.Lock()
+	s.peering = peering
+	s.mu.Unlock()
+
+	peering.RegisterHandler(network.MessageTypeSyncCorrection, s.handleCorrection)
+	peering.RegisterHandler(network.MessageTypeSyncAck, s.handleAck)
+}
+
+// SetAuthorizer sets which peers' corrections are accepted. Without one,
+// every correction is rejected.
+func (s *Synchronizer) SetAuthorizer(authorize CorrectionAuthorizer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.authorize = authorize
+}
+
+func (s *Synchronizer) SetConfig(config *SyncConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config = config
+}
+
 func (s *Synchronizer) Start(ctx context.Context) error {
 	s.logger.Info("Starting Synchronizer")
 
@@ -68,6 +144,7 @@ func (s *Synchronizer) backgroundSync(ctx context.Context) {
 
 func (s *Synchronizer) SyncAllNodes() {
 	s.logger.Debug("Starting synchronization of all nodes")
+	s.expirePending(time.Now().UTC())
 
 	nodes := s.getAllNodeIDs()
 	var wg sync.WaitGroup
@@ -96,10 +173,24 @@ func (s *Synchronizer) SyncAllNodes() {
 		}
 	}
 
+	report := s.offsetManager.CalculateGlobalOffsetReport()
+	for _, excluded := range report.Excluded {
+		if results[excluded.NodeID] != nil {
+			continue
+		}
+		detail := string(excluded.Reason)
+		if excluded.Detail != "" {
+			detail = fmt.Sprintf("%s: %s", excluded.Reason, excluded.Detail)
+		}
+		s.updateSyncStatus(excluded.NodeID, "excluded", 0, detail)
+	}
+
 	s.logger.Info("Batch synchronization completed",
 		zap.Int("total_nodes", len(nodes)),
 		zap.Int("successful", successCount),
 		zap.Int("failed", len(nodes)-successCount),
+		zap.Int("excluded", len(report.Excluded)),
+		zap.Duration("global_offset", report.GlobalOffset),
 	)
 }
 
@@ -124,6 +215,16 @@ func (s *Synchronizer) SyncNode(nodeID string) error {
 
 	s.updateSyncStatus(nodeID, "synced", offset.Offset, "")
 
+	s.mu.RLock()
+	peering := s.peering
+	s.mu.RUnlock()
+	if peering != nil && nodeID != peering.LocalPeerID() && offset.Source == OffsetSourceMeasured {
+		if err := s.PushCorrection(offset); err != nil {
+			s.updateSyncStatus(nodeID, "unreachable", 0, err.Error())
+			return fmt.Errorf("failed to push correction to node %s: %w", nodeID, err)
+		}
+	}
+
 	s.logger.Debug("Node synchronization completed",
 		zap.String("node_id", nodeID),
 		zap.Duration("offset", offset.Offset),
@@ -133,6 +234,170 @@ func (s *Synchronizer) SyncNode(nodeID string) error {
 	return nil
 }
 
+// PushCorrection sends a node its clock correction. Only measured offsets
+// are clock corrections; geographic offsets estimate propagation delay and
+// are never pushed. The measured offset is a residual on the node's
+// corrected clock, so it is added to the correction the node already runs
+// with; pushing the same measurement twice is harmless.
+func (s *Synchronizer) PushCorrection(offset *NodeOffset) error {
+	if offset.Source != OffsetSourceMeasured {
+		return fmt.Errorf("offset for node %s is %s, not measured", offset.NodeID, offset.Source)
+	}
+
+	s.mu.Lock()
+	peering := s.peering
+	if peering == nil {
+		s.mu.Unlock()
+		return fmt.Errorf("peering not attached")
+	}
+	s.correctionSeq++
+	correction := &SyncCorrection{
+		CorrectionID: fmt.Sprintf("correction_%s_%d", offset.NodeID, s.correctionSeq),
+		NodeID:       offset.NodeID,
+		Offset:       offset.AppliedCorrection + offset.Offset,
+		GlobalOffset: s.offsetManager.GetGlobalOffset(),
+		Confidence:   offset.Confidence,
+		IssuedAt:     time.Now().UTC(),
+	}
+	s.mu.Unlock()
+
+	data, err := json.Marshal(correction)
+	if err != nil {
+		return fmt.Errorf("failed to marshal sync correction: %w", err)
+	}
+	if err := peering.SendPeerMessage(offset.NodeID, network.MessageTypeSyncCorrection, data); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	status, exists := s.syncStatus[offset.NodeID]
+	if !exists {
+		status = &SyncStatus{NodeID: offset.NodeID}
+		s.syncStatus[offset.NodeID] = status
+	}
+	status.Status = "pending"
+	status.CorrectionID = correction.CorrectionID
+	status.SentAt = correction.IssuedAt
+	status.Converged = s.convergedLocked(status)
+	s.mu.Unlock()
+	return nil
+}
+
+// handleCorrection runs on the node being corrected. Corrections addressed
+// to another node, from an unauthenticated or unauthorised sender, larger
+// than MaxCorrection or older than the ack timeout are rejected rather than
+// applied, and either way the sender gets an acknowledgement. An applied
+// correction becomes the offset manager's local correction.
+func (s *Synchronizer) handleCorrection(conn *network.PeerConnection, message *network.PeerMessage) {
+	correction := &SyncCorrection{}
+	if err := json.Unmarshal(message.Payload, correction); err != nil {
+		s.logger.Warn("Invalid sync correction",
+			zap.String("peer_id", conn.PeerID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	s.mu.Lock()
+	ack := &SyncAck{
+		CorrectionID: correction.CorrectionID,
+		NodeID:       correction.NodeID,
+		Offset:       correction.Offset,
+		AckedAt:      time.Now().UTC(),
+	}
+	peering := s.peering
+	switch {
+	case peering == nil || correction.NodeID != peering.LocalPeerID():
+		ack.Reason = fmt.Sprintf("correction addressed to %s", correction.NodeID)
+	case !conn.Authenticated || s.authorize == nil || !s.authorize(conn.PeerID):
+		ack.Reason = "sender not authorised to correct this node"
+	case correction.Offset > s.config.MaxCorrection || correction.Offset < -s.config.MaxCorrection:
+		ack.Reason = fmt.Sprintf("correction %v exceeds maximum %v", correction.Offset, s.config.MaxCorrection)
+	case ack.AckedAt.Sub(correction.IssuedAt) > s.config.AckTimeout:
+		ack.Reason = "correction expired"
+	default:
+		ack.Applied = true
+	}
+	s.mu.Unlock()
+
+	if ack.Applied {
+		s.offsetManager.SetLocalCorrection(correction.Offset)
+	}
+
+	s.logger.Info("Sync correction received",
+		zap.String("from", conn.PeerID),
+		zap.Duration("offset", correction.Offset),
+		zap.Bool("applied", ack.Applied),
+		zap.String("reason", ack.Reason),
+	)
+
+	data, err := json.Marshal(ack)
+	if err != nil || peering == nil {
+		return
+	}
+	if err := peering.SendPeerMessage(conn.PeerID, network.MessageTypeSyncAck, data); err != nil {
+		s.logger.Debug("Failed to acknowledge sync correction",
+			zap.String("peer_id", conn.PeerID),
+			zap.Error(err),
+		)
+	}
+}
+
+func (s *Synchronizer) handleAck(conn *network.PeerConnection, message *network.PeerMessage) {
+	ack := &SyncAck{}
+	if err := json.Unmarshal(message.Payload, ack); err != nil {
+		s.logger.Warn("Invalid sync ack",
+			zap.String("peer_id", conn.PeerID),
+			zap.Error(err),
+		)
+		return
+	}
+	s.RecordAck(conn.PeerID, ack)
+}
+
+// RecordAck applies an acknowledgement to the sender's sync state. Acks for
+// anything but the outstanding correction are ignored.
+func (s *Synchronizer) RecordAck(nodeID string, ack *SyncAck) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status, exists := s.syncStatus[nodeID]
+	if !exists || status.CorrectionID != ack.CorrectionID || status.Status != "pending" {
+		return
+	}
+
+	status.AckedAt = ack.AckedAt
+	if ack.Applied {
+		status.Status = "applied"
+		status.LastError = ""
+	} else {
+		status.Status = "rejected"
+		status.LastError = ack.Reason
+	}
+	status.Converged = s.convergedLocked(status)
+}
+
+// expirePending marks nodes that never acknowledged their correction as
+// unreachable.
+func (s *Synchronizer) expirePending(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, status := range s.syncStatus {
+		if status.Status == "pending" && !status.SentAt.IsZero() && now.Sub(status.SentAt) > s.config.AckTimeout {
+			status.Status = "unreachable"
+			status.LastError = "no acknowledgement before timeout"
+			status.Converged = false
+		}
+	}
+}
+
+// GetLocalCorrection returns the last correction a peer pushed to this node
+// and it accepted.
+func (s *Synchronizer) GetLocalCorrection() time.Duration {
+	return s.offsetManager.LocalCorrection()
+}
+
 func (s *Synchronizer) updateSyncStatus(nodeID string, status string, offset time.Duration, errorMsg string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -148,6 +413,10 @@ func (s *Synchronizer) updateSyncStatus(nodeID string, status string, offset tim
 	syncStatus.LastSync = time.Now().UTC()
 	syncStatus.Status = status
 	syncStatus.LastError = errorMsg
+	if status == "synced" {
+		syncStatus.Residual = offset
+	}
+	syncStatus.Converged = s.convergedLocked(syncStatus)
 
 	if offset != 0 {
 		syncStatus.LastOffset = offset
@@ -163,15 +432,33 @@ func (s *Synchronizer) updateSyncStatus(nodeID string, status string, offset tim
 	}
 }
 
+// convergedLocked derives convergence from a node's current state: it is
+// converged while synced, or after applying its correction, as long as the
+// residual last measured for it is within ConvergenceThreshold. A pending,
+// rejected or unreachable node is not.
+func (s *Synchronizer) convergedLocked(status *SyncStatus) bool {
+	switch status.Status {
+	case "synced", "applied":
+		return status.Residual.Abs() <= s.config.ConvergenceThreshold
+	default:
+		return false
+	}
+}
+
 func (s *Synchronizer) getAllNodeIDs() []string {
-	return []string{"node1", "node2", "node3"}
+	return s.offsetManager.getAllNodeIDs()
 }
 
 func (s *Synchronizer) GetSyncStatus(nodeID string) *SyncStatus {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	return s.syncStatus[nodeID]
+	status, exists := s.syncStatus[nodeID]
+	if !exists {
+		return nil
+	}
+	snapshot := *status
+	return &snapshot
 }
 
 func (s *Synchronizer) GetAllSyncStatus() map[string]*SyncStatus {
@@ -180,12 +467,15 @@ func (s *Synchronizer) GetAllSyncStatus() map[string]*SyncStatus {
 
 	status := make(map[string]*SyncStatus)
 	for k, v := range s.syncStatus {
-		status[k] = v
+		snapshot := *v
+		status[k] = &snapshot
 	}
 	return status
 }
 
 func (s *Synchronizer) GetSyncStats() *SyncStats {
+	s.expirePending(time.Now().UTC())
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -201,10 +491,19 @@ func (s *Synchronizer) GetSyncStats() *SyncStats {
 	for _, status := range s.syncStatus {
 		stats.StatusCounts[status.Status]++
 
-		if status.Status == "synced" {
+		switch status.Status {
+		case "synced", "pending", "applied", "rejected":
 			totalOffset += status.AverageOffset
 			nodeCount++
 		}
+		if status.Converged {
+			stats.ConvergedNodes++
+		}
+		if status.Status != "unreachable" && status.Status != "failed" && status.Status != "excluded" {
+			if residual := status.LastOffset.Abs(); residual > stats.MaxResidual {
+				stats.MaxResidual = residual
+			}
+		}
 	}
 
 	if nodeCount > 0 {
@@ -214,18 +513,35 @@ func (s *Synchronizer) GetSyncStats() *SyncStats {
 	stats.SyncedNodes = stats.StatusCounts["synced"]
 	stats.FailedNodes = stats.StatusCounts["failed"]
 	stats.PendingNodes = stats.StatusCounts["pending"]
+	stats.ExcludedNodes = stats.StatusCounts["excluded"]
+	stats.AppliedNodes = stats.StatusCounts["applied"]
+	stats.RejectedNodes = stats.StatusCounts["rejected"]
+	stats.UnreachableNodes = stats.StatusCounts["unreachable"]
+	if stats.TotalNodes > 0 {
+		stats.Convergence = float64(stats.ConvergedNodes) / float64(stats.TotalNodes)
+	}
 
 	return stats
 }
 
+// SyncStats summarises sync state across nodes. A node has converged once
+// the residual measured on its corrected clock is within the convergence
+// threshold, i.e. earlier corrections have taken effect.
 type SyncStats struct {
-	TotalNodes    int            `json:"total_nodes"`
-	SyncedNodes   int            `json:"synced_nodes"`
-	FailedNodes   int            `json:"failed_nodes"`
-	PendingNodes  int            `json:"pending_nodes"`
-	AverageOffset time.Duration  `json:"average_offset"`
-	StatusCounts  map[string]int `json:"status_counts"`
-	Timestamp     time.Time      `json:"timestamp"`
+	TotalNodes       int            `json:"total_nodes"`
+	SyncedNodes      int            `json:"synced_nodes"`
+	FailedNodes      int            `json:"failed_nodes"`
+	PendingNodes     int            `json:"pending_nodes"`
+	ExcludedNodes    int            `json:"excluded_nodes"`
+	AppliedNodes     int            `json:"applied_nodes"`
+	RejectedNodes    int            `json:"rejected_nodes"`
+	UnreachableNodes int            `json:"unreachable_nodes"`
+	ConvergedNodes   int            `json:"converged_nodes"`
+	Convergence      float64        `json:"convergence"`
+	MaxResidual      time.Duration  `json:"max_residual"`
+	AverageOffset    time.Duration  `json:"average_offset"`
+	StatusCounts     map[string]int `json:"status_counts"`
+	Timestamp        time.Time      `json:"timestamp"`
 }
 
 func (s *Synchronizer) CleanupStaleStatus() int {
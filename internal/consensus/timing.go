@@ -12,9 +12,18 @@ type TimingManager struct {
         topologyManager *network.TopologyManager
         logger          *zap.Logger
         mu              sync.RWMutex
-        timingCache     map[string]*ConsensusTiming
+        timingCache     map[string]*timingCacheEntry
+}
+const (
+        timingCacheTTL        = 5 * time.Minute
+        timingCacheMaxEntries = 256
+)
+type timingCacheEntry struct {
+        timing      *ConsensusTiming
+        fingerprint string
 }
 type ConsensusTiming struct {
+        ValidatorSetID string        `json:"validator_set_id"`
         BlockTime      time.Duration `json:"block_time"`
         MaxPropagation time.Duration `json:"max_propagation_delay"`
         SafetyMargin   time.Duration `json:"safety_margin"`
@@ -36,7 +45,7 @@ func NewTimingManager(topology *network.TopologyManager, logger *zap.Logger) *Ti
 	return &TimingManager{
 		topologyManager: topology,
 		logger:          logger,
-		timingCache:     make(map[string]*ConsensusTiming),
+		timingCache:     make(map[string]*timingCacheEntry),
 }
 }
 func (tm *TimingManager) GetNodeOffset(nodeID string) (time.Duration, error) {
@@ -63,28 +72,55 @@ func (t *TimingValidationResult) Error() string {
 	return t.Reason
 }
 
+// CalculateConsensusTiming returns the timing for a validator set. Results
+// are cached by ValidatorSetID, so the calculator and validator share them,
+// and are recomputed once any member's position changes.
 func (tm *TimingManager) CalculateConsensusTiming(validatorNodes []string) (*ConsensusTiming, error) {
-        if len(validatorNodes) == 0 {
+        validators := canonicalValidators(validatorNodes)
+        if len(validators) == 0 {
                 return nil, fmt.Errorf("validator nodes list cannot be empty")
         }
-        cacheKey := tm.generateCacheKey(validatorNodes)
+        setID := validatorSetID(validators)
+        fingerprint := tm.positionFingerprint(validators)
         tm.mu.RLock()
-        if cached, exists := tm.timingCache[cacheKey]; exists {
-                if time.Since(cached.CalculatedAt) < 5*time.Minute {
+        if cached, exists := tm.timingCache[setID]; exists {
+                if time.Since(cached.timing.CalculatedAt) < timingCacheTTL && cached.fingerprint == fingerprint {
                         tm.mu.RUnlock()
-                        return cached, nil
+                        return cached.timing, nil
                 }
         }
         tm.mu.RUnlock()
-        timing, err := tm.calculateTiming(validatorNodes)
+        timing, err := tm.calculateTiming(validators)
         if err != nil {
                 return nil, err
         }
+        timing.ValidatorSetID = setID
         tm.mu.Lock()
-        tm.timingCache[cacheKey] = timing
+        tm.timingCache[setID] = &timingCacheEntry{timing: timing, fingerprint: fingerprint}
+        tm.evictLocked()
         tm.mu.Unlock()
         return timing, nil
 }
+// evictLocked drops expired entries and, if the cache is still over its
+// limit, the oldest ones.
+func (tm *TimingManager) evictLocked() {
+        for setID, entry := range tm.timingCache {
+                if time.Since(entry.timing.CalculatedAt) >= timingCacheTTL {
+                        delete(tm.timingCache, setID)
+                }
+        }
+        for len(tm.timingCache) > timingCacheMaxEntries {
+                var oldestID string
+                var oldest time.Time
+                for setID, entry := range tm.timingCache {
+                        if oldestID == "" || entry.timing.CalculatedAt.Before(oldest) {
+                                oldestID = setID
+                                oldest = entry.timing.CalculatedAt
+                        }
+                }
+                delete(tm.timingCache, oldestID)
+        }
+}
 func (tm *TimingManager) calculateTiming(validatorNodes []string) (*ConsensusTiming, error) {
         nodes := make([]*types.Node, 0, len(validatorNodes))
         for _, nodeID := range validatorNodes {
@@ -155,6 +191,26 @@ func (tm *TimingManager) calculateDistance(pos1, pos2 types.Position) (float64,
         totalDistance := math.Sqrt(math.Pow(distance, 2) + math.Pow(altDiff, 2))
         return totalDistance, nil
 }
+// ExpectedNetworkDelay is the geographic one-way delay between two nodes:
+// the light delay over their distance scaled by the network factor.
+func (tm *TimingManager) ExpectedNetworkDelay(fromID, toID string) (time.Duration, error) {
+        if tm.topologyManager == nil {
+                return 0, fmt.Errorf("no topology available")
+        }
+        from, err := tm.topologyManager.GetNode(fromID)
+        if err != nil {
+                return 0, err
+        }
+        to, err := tm.topologyManager.GetNode(toID)
+        if err != nil {
+                return 0, err
+        }
+        distance, err := tm.calculateDistance(from.Position, to.Position)
+        if err != nil {
+                return 0, err
+        }
+        return time.Duration(distance / types.SpeedOfLight * types.NetworkFactor * float64(time.Second)), nil
+}
 func (tm *TimingManager) calculateOptimalBlockTime(maxPropagation, safetyMargin time.Duration) time.Duration {
         blockTime := maxPropagation + safetyMargin
         minBlockTime := 2 * time.Second
@@ -170,9 +226,6 @@ func (tm *TimingManager) calculateOptimalBlockTime(maxPropagation, safetyMargin
 func (tm *TimingManager) calculateOptimalOffset(maxPropagation time.Duration) time.Duration {
         return maxPropagation / 2
 }
-func (tm *TimingManager) generateCacheKey(validatorNodes []string) string {
-        return fmt.Sprintf("timing:%v", validatorNodes)
-}
 func (tm *TimingManager) GetTimingForValidators(validatorNodes []string) (*ConsensusTiming, error) {
         return tm.CalculateConsensusTiming(validatorNodes)
 }
@@ -211,7 +264,7 @@ func (tm *TimingManager) ValidateBlockTiming(blockTimestamp time.Time, proposedB
 func (tm *TimingManager) ClearCache() {
         tm.mu.Lock()
         defer tm.mu.Unlock()
-        tm.timingCache = make(map[string]*ConsensusTiming)
+        tm.timingCache = make(map[string]*timingCacheEntry)
         tm.logger.Info("Timing cache cleared")
 }
 func (tm *TimingManager) GetCacheStats() map[string]interface{} {
@@ -221,12 +274,12 @@ func (tm *TimingManager) GetCacheStats() map[string]interface{} {
         stats["cache_size"] = len(tm.timingCache)
         oldest := time.Now()
         newest := time.Time{}
-        for _, timing := range tm.timingCache {
-                if timing.CalculatedAt.Before(oldest) {
-                        oldest = timing.CalculatedAt
+        for _, entry := range tm.timingCache {
+                if entry.timing.CalculatedAt.Before(oldest) {
+                        oldest = entry.timing.CalculatedAt
                 }
-                if timing.CalculatedAt.After(newest) {
-                        newest = timing.CalculatedAt
+                if entry.timing.CalculatedAt.After(newest) {
+                        newest = entry.timing.CalculatedAt
                 }
         }
         stats["oldest_entry"] = oldest
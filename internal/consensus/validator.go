@@ -6,12 +6,14 @@ import (
 
 	"go.uber.org/zap"
 
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/security"
 	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
 )
 
 type ConsensusValidator struct {
 	timingManager *TimingManager
 	offsetManager *OffsetManager
+	keyManager    *security.KeyManager
 	logger        *zap.Logger
 }
 
@@ -32,6 +34,12 @@ func NewConsensusValidator(timingManager *TimingManager, offsetManager *OffsetMa
 	}
 }
 
+// SetKeyManager enables signature checks on votes and proposals. Without a
+// key manager only timing is validated.
+func (cv *ConsensusValidator) SetKeyManager(keyManager *security.KeyManager) {
+	cv.keyManager = keyManager
+}
+
 func (cv *ConsensusValidator) ValidateBlockTiming(block *types.Block, validators []string) (*TimingValidationResult, error) {
     isValid, err := cv.timingManager.ValidateBlockTiming(block.Timestamp, block.ProposedBy, validators)
     if err != nil {
@@ -63,7 +71,7 @@ func (cv *ConsensusValidator) ValidateBlockOffsets(block *types.Block, validator
 	now := time.Now().UTC()
 	timeDiff := now.Sub(adjustedTimestamp)
 
-	maxAcceptable := expectedOffset.Offset * 2
+	maxAcceptable := absDuration(expectedOffset.Offset)*2 + expectedOffset.Uncertainty
 	if timeDiff > maxAcceptable {
 		result.Valid = false
 		result.Reason = fmt.Sprintf("Adjusted timestamp difference too large: %v > %v", timeDiff, maxAcceptable)
@@ -82,7 +90,20 @@ func (cv *ConsensusValidator) ValidateBlockOffsets(block *types.Block, validator
 	return result
 }
 
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
 func (cv *ConsensusValidator) ValidateVoteTiming(vote *types.Vote, validators []string) (bool, string) {
+	if cv.keyManager != nil {
+		if err := cv.keyManager.VerifyVote(vote); err != nil {
+			return false, fmt.Sprintf("Vote signature invalid: %v", err)
+		}
+	}
+
 	timing, err := cv.timingManager.CalculateConsensusTiming(validators)
 	if err != nil {
 		return false, fmt.Sprintf("Failed to calculate timing: %v", err)
@@ -101,6 +122,12 @@ func (cv *ConsensusValidator) ValidateVoteTiming(vote *types.Vote, validators []
 }
 
 func (cv *ConsensusValidator) ValidateProposalTiming(proposal *types.Proposal, validators []string) (bool, string) {
+	if cv.keyManager != nil {
+		if err := cv.keyManager.VerifyProposal(proposal); err != nil {
+			return false, fmt.Sprintf("Proposal signature invalid: %v", err)
+		}
+	}
+
 	timing, err := cv.timingManager.CalculateConsensusTiming(validators)
 	if err != nil {
 		return false, fmt.Sprintf("Failed to calculate timing: %v", err)
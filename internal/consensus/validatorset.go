@@ -0,0 +1,67 @@
+package consensus
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"sort"
+)
+
+// ValidatorSetID is the canonical identifier of a validator set: the hex
+// SHA-256 of its sorted, de-duplicated member IDs. The order members are
+// listed in does not change it.
+func ValidatorSetID(validators []string) string {
+	return validatorSetID(canonicalValidators(validators))
+}
+
+func validatorSetID(canonical []string) string {
+	h := sha256.New()
+	var length [4]byte
+	for _, id := range canonical {
+		binary.BigEndian.PutUint32(length[:], uint32(len(id)))
+		h.Write(length[:])
+		h.Write([]byte(id))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func canonicalValidators(validators []string) []string {
+	canonical := make([]string, 0, len(validators))
+	seen := make(map[string]bool, len(validators))
+	for _, id := range validators {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		canonical = append(canonical, id)
+	}
+	sort.Strings(canonical)
+	return canonical
+}
+
+// positionFingerprint hashes the current position of every member, so a
+// cached timing can be recognised as stale once any of them moves, joins
+// the topology or leaves it.
+func (tm *TimingManager) positionFingerprint(canonical []string) string {
+	if tm.topologyManager == nil {
+		return ""
+	}
+
+	h := sha256.New()
+	var buf [8]byte
+	for _, id := range canonical {
+		h.Write([]byte(id))
+		node, err := tm.topologyManager.GetNode(id)
+		if err != nil {
+			h.Write([]byte{0})
+			continue
+		}
+		h.Write([]byte{1})
+		for _, v := range []float64{node.Position.Latitude, node.Position.Longitude, node.Position.Altitude} {
+			binary.BigEndian.PutUint64(buf[:], math.Float64bits(v))
+			h.Write(buf[:])
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
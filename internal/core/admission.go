@@ -0,0 +1,362 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/relativistic"
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
+)
+
+// TransactionValidator is satisfied by ValidationEngine and Engine.
+type TransactionValidator interface {
+	ValidateTransactionTimestamp(ctx context.Context, tx *types.Transaction, originNode string) (*types.ValidationResult, error)
+}
+
+type AdmissionDecision string
+
+const (
+	AdmissionAccepted AdmissionDecision = "accepted"
+	AdmissionParked   AdmissionDecision = "parked"
+	AdmissionRejected AdmissionDecision = "rejected"
+)
+
+const (
+	RejectDuplicate  = "duplicate"
+	RejectFarFuture  = "far_future"
+	RejectStale      = "stale"
+	RejectInvalid    = "invalid"
+	RejectQueueFull  = "queue_full"
+	RejectValidation = "validation_error"
+)
+
+type AdmissionConfig struct {
+	MaxReady        int           `json:"max_ready"`
+	MaxParked       int           `json:"max_parked"`
+	MaxFutureLead   time.Duration `json:"max_future_lead"`
+	MaxParkDuration time.Duration `json:"max_park_duration"`
+	MaxTxAge        time.Duration `json:"max_tx_age"`
+	RecheckInterval time.Duration `json:"recheck_interval"`
+}
+
+func DefaultAdmissionConfig() *AdmissionConfig {
+	return &AdmissionConfig{
+		MaxReady:        10000,
+		MaxParked:       1000,
+		MaxFutureLead:   30 * time.Second,
+		MaxParkDuration: 2 * time.Minute,
+		MaxTxAge:        10 * time.Minute,
+		RecheckInterval: time.Second,
+	}
+}
+
+type AdmissionResult struct {
+	TxHash     string                  `json:"tx_hash"`
+	Decision   AdmissionDecision       `json:"decision"`
+	Reason     string                  `json:"reason,omitempty"`
+	Validation *types.ValidationResult `json:"validation,omitempty"`
+	DecidedAt  time.Time               `json:"decided_at"`
+}
+
+type AdmissionMetrics struct {
+	ReadySize      int            `json:"ready_size"`
+	ParkedSize     int            `json:"parked_size"`
+	Admitted       int64          `json:"admitted"`
+	Parked         int64          `json:"parked"`
+	Promoted       int64          `json:"promoted"`
+	Expired        int64          `json:"expired"`
+	Rejected       map[string]int `json:"rejected"`
+	OldestReadyAge time.Duration  `json:"oldest_ready_age"`
+	Timestamp      time.Time      `json:"timestamp"`
+}
+
+type admissionEntry struct {
+	tx         *types.Transaction
+	originNode string
+	queuedAt   time.Time
+}
+
+// AdmissionController sits in front of block building. Transactions whose
+// timestamps fit the origin's relativistic window are queued as ready;
+// ones slightly ahead of it are parked and re-checked until they fit;
+// ones too far ahead, or already behind it, are rejected.
+type AdmissionController struct {
+	validator TransactionValidator
+	config    *AdmissionConfig
+	logger    *zap.Logger
+	mu        sync.RWMutex
+	ready     map[string]*admissionEntry
+	parked    map[string]*admissionEntry
+	metrics   AdmissionMetrics
+	stopChan  chan struct{}
+}
+
+func NewAdmissionController(validator TransactionValidator, config *AdmissionConfig, logger *zap.Logger) *AdmissionController {
+	if config == nil {
+		config = DefaultAdmissionConfig()
+	}
+
+	return &AdmissionController{
+		validator: validator,
+		config:    config,
+		logger:    logger,
+		ready:     make(map[string]*admissionEntry),
+		parked:    make(map[string]*admissionEntry),
+		metrics:   AdmissionMetrics{Rejected: make(map[string]int)},
+		stopChan:  make(chan struct{}),
+	}
+}
+
+func (ac *AdmissionController) Start(ctx context.Context) {
+	ticker := time.NewTicker(ac.config.RecheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ac.stopChan:
+			return
+		case <-ticker.C:
+			ac.ProcessParked(ctx)
+			ac.ExpireStale()
+		}
+	}
+}
+
+func (ac *AdmissionController) Stop() {
+	close(ac.stopChan)
+}
+
+func (ac *AdmissionController) Admit(ctx context.Context, tx *types.Transaction, originNode string) (*AdmissionResult, error) {
+	if tx == nil {
+		return nil, fmt.Errorf("transaction cannot be nil")
+	}
+	if tx.Hash == "" {
+		return nil, fmt.Errorf("transaction hash is required")
+	}
+
+	result := &AdmissionResult{TxHash: tx.Hash}
+
+	if ac.contains(tx.Hash) {
+		return ac.reject(result, RejectDuplicate), nil
+	}
+
+	validation, err := ac.validator.ValidateTransactionTimestamp(ctx, tx, originNode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate transaction timestamp: %w", err)
+	}
+	result.Validation = validation
+
+	decision, reason := ac.classify(validation)
+	if decision == AdmissionRejected {
+		return ac.reject(result, reason), nil
+	}
+
+	ac.mu.Lock()
+	if _, exists := ac.ready[tx.Hash]; exists {
+		ac.mu.Unlock()
+		return ac.reject(result, RejectDuplicate), nil
+	}
+	if _, exists := ac.parked[tx.Hash]; exists {
+		ac.mu.Unlock()
+		return ac.reject(result, RejectDuplicate), nil
+	}
+
+	entry := &admissionEntry{tx: tx, originNode: originNode, queuedAt: time.Now().UTC()}
+	switch decision {
+	case AdmissionAccepted:
+		if len(ac.ready) >= ac.config.MaxReady {
+			ac.mu.Unlock()
+			return ac.reject(result, RejectQueueFull), nil
+		}
+		ac.ready[tx.Hash] = entry
+		ac.metrics.Admitted++
+	case AdmissionParked:
+		if len(ac.parked) >= ac.config.MaxParked {
+			ac.mu.Unlock()
+			return ac.reject(result, RejectQueueFull), nil
+		}
+		ac.parked[tx.Hash] = entry
+		ac.metrics.Parked++
+	}
+	ac.mu.Unlock()
+
+	result.Decision = decision
+	result.Reason = reason
+	result.DecidedAt = entry.queuedAt
+
+	ac.logger.Debug("Transaction admission",
+		zap.String("tx_hash", tx.Hash),
+		zap.String("origin_node", originNode),
+		zap.String("decision", string(decision)),
+		zap.Duration("time_diff", validation.ActualDiff),
+	)
+
+	return result, nil
+}
+
+// classify maps a timestamp validation onto an admission decision. A
+// negative ActualDiff means the timestamp is ahead of local time; it may be
+// parked if it is no further ahead than the origin's expected delay plus
+// MaxFutureLead.
+func (ac *AdmissionController) classify(validation *types.ValidationResult) (AdmissionDecision, string) {
+	if validation.Valid {
+		return AdmissionAccepted, ""
+	}
+	if validation.ErrorCode != "" {
+		return AdmissionRejected, RejectValidation
+	}
+
+	if lead := -validation.ActualDiff; lead > 0 {
+		if lead > validation.ExpectedDelay+ac.config.MaxFutureLead {
+			return AdmissionRejected, RejectFarFuture
+		}
+		return AdmissionParked, fmt.Sprintf("timestamp %v ahead", lead)
+	}
+	if validation.ActualDiff > ac.config.MaxTxAge {
+		return AdmissionRejected, RejectStale
+	}
+	return AdmissionRejected, RejectInvalid
+}
+
+func (ac *AdmissionController) reject(result *AdmissionResult, reason string) *AdmissionResult {
+	ac.mu.Lock()
+	ac.metrics.Rejected[reason]++
+	ac.mu.Unlock()
+
+	result.Decision = AdmissionRejected
+	result.Reason = reason
+	result.DecidedAt = time.Now().UTC()
+	return result
+}
+
+func (ac *AdmissionController) contains(hash string) bool {
+	ac.mu.RLock()
+	defer ac.mu.RUnlock()
+
+	_, ready := ac.ready[hash]
+	_, parked := ac.parked[hash]
+	return ready || parked
+}
+
+// ProcessParked re-validates parked transactions, promoting those that now
+// fit the window and expiring those parked for longer than MaxParkDuration.
+func (ac *AdmissionController) ProcessParked(ctx context.Context) (promoted, expired int) {
+	ac.mu.RLock()
+	entries := make([]*admissionEntry, 0, len(ac.parked))
+	for _, entry := range ac.parked {
+		entries = append(entries, entry)
+	}
+	ac.mu.RUnlock()
+
+	now := time.Now().UTC()
+	for _, entry := range entries {
+		validation, err := ac.validator.ValidateTransactionTimestamp(ctx, entry.tx, entry.originNode)
+		decision := AdmissionParked
+		if err == nil {
+			decision, _ = ac.classify(validation)
+		}
+
+		ac.mu.Lock()
+		if _, exists := ac.parked[entry.tx.Hash]; !exists {
+			ac.mu.Unlock()
+			continue
+		}
+		switch {
+		case decision == AdmissionAccepted && len(ac.ready) < ac.config.MaxReady:
+			delete(ac.parked, entry.tx.Hash)
+			entry.queuedAt = now
+			ac.ready[entry.tx.Hash] = entry
+			ac.metrics.Promoted++
+			promoted++
+		case decision == AdmissionRejected || now.Sub(entry.queuedAt) > ac.config.MaxParkDuration:
+			delete(ac.parked, entry.tx.Hash)
+			ac.metrics.Expired++
+			expired++
+		}
+		ac.mu.Unlock()
+	}
+
+	if promoted > 0 || expired > 0 {
+		ac.logger.Debug("Parked transactions processed",
+			zap.Int("promoted", promoted),
+			zap.Int("expired", expired),
+		)
+	}
+	return promoted, expired
+}
+
+// ExpireStale drops ready transactions whose timestamps have aged past
+// MaxTxAge without being included in a block.
+func (ac *AdmissionController) ExpireStale() int {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	cutoff := time.Now().Add(-ac.config.MaxTxAge)
+	expired := 0
+	for hash, entry := range ac.ready {
+		if entry.tx.Timestamp.Before(cutoff) {
+			delete(ac.ready, hash)
+			expired++
+		}
+	}
+	ac.metrics.Expired += int64(expired)
+	return expired
+}
+
+// NextBatch removes and returns up to max ready transactions in HLC order,
+// for the block builder. A max of zero returns everything that is ready.
+func (ac *AdmissionController) NextBatch(max int) []*types.Transaction {
+	ac.ExpireStale()
+
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	txs := make([]*types.Transaction, 0, len(ac.ready))
+	for _, entry := range ac.ready {
+		txs = append(txs, entry.tx)
+	}
+	relativistic.SortTransactionsByHLC(txs)
+
+	if max > 0 && len(txs) > max {
+		txs = txs[:max]
+	}
+	for _, tx := range txs {
+		delete(ac.ready, tx.Hash)
+	}
+	return txs
+}
+
+func (ac *AdmissionController) Remove(hash string) {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	delete(ac.ready, hash)
+	delete(ac.parked, hash)
+}
+
+func (ac *AdmissionController) GetMetrics() *AdmissionMetrics {
+	ac.mu.RLock()
+	defer ac.mu.RUnlock()
+
+	metrics := ac.metrics
+	metrics.ReadySize = len(ac.ready)
+	metrics.ParkedSize = len(ac.parked)
+	metrics.Rejected = make(map[string]int, len(ac.metrics.Rejected))
+	for reason, count := range ac.metrics.Rejected {
+		metrics.Rejected[reason] = count
+	}
+
+	now := time.Now()
+	for _, entry := range ac.ready {
+		if age := now.Sub(entry.queuedAt); age > metrics.OldestReadyAge {
+			metrics.OldestReadyAge = age
+		}
+	}
+	metrics.Timestamp = now.UTC()
+	return &metrics
+}
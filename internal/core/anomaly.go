@@ -0,0 +1,146 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// AnomalySource reports validation anomalies seen since a point in time.
+// Engine implements it.
+type AnomalySource interface {
+	DetectValidationAnomalies(since time.Time) []*ValidationAnomaly
+}
+
+// NodeFlagger excludes nodes from offset aggregation. The consensus
+// manager implements it. UnflagNodeIf removes a flag only while its reason
+// still matches, so a flag set by someone else in the meantime survives.
+type NodeFlagger interface {
+	FlagNode(nodeID, reason string)
+	UnflagNodeIf(nodeID, reason string) bool
+	GetFlaggedNodes() map[string]string
+}
+
+type AnomalyFlaggerConfig struct {
+	// Every Interval the anomalies of the last Window are counted per
+	// origin node, and nodes with at least MinAnomalies are flagged.
+	Interval     time.Duration `json:"interval"`
+	Window       time.Duration `json:"window"`
+	MinAnomalies int           `json:"min_anomalies"`
+}
+
+func DefaultAnomalyFlaggerConfig() *AnomalyFlaggerConfig {
+	return &AnomalyFlaggerConfig{
+		Interval:     time.Minute,
+		Window:       10 * time.Minute,
+		MinAnomalies: 3,
+	}
+}
+
+// AnomalyFlagger flags nodes whose timestamps keep failing validation, so
+// robust aggregation leaves their offsets out, and unflags them once their
+// anomalies age out of the window. Nodes flagged by other means, before or
+// after the flagger flagged them, are left alone.
+type AnomalyFlagger struct {
+	source  AnomalySource
+	flagger NodeFlagger
+	config  *AnomalyFlaggerConfig
+	logger  *zap.Logger
+
+	mu       sync.Mutex
+	flagged  map[string]string
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+func NewAnomalyFlagger(source AnomalySource, flagger NodeFlagger, config *AnomalyFlaggerConfig, logger *zap.Logger) *AnomalyFlagger {
+	if config == nil {
+		config = DefaultAnomalyFlaggerConfig()
+	}
+
+	return &AnomalyFlagger{
+		source:   source,
+		flagger:  flagger,
+		config:   config,
+		logger:   logger,
+		flagged:  make(map[string]string),
+		stopChan: make(chan struct{}),
+	}
+}
+
+func (af *AnomalyFlagger) Start(ctx context.Context) {
+	ticker := time.NewTicker(af.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-af.stopChan:
+			return
+		case <-ticker.C:
+			af.Evaluate(time.Now())
+		}
+	}
+}
+
+func (af *AnomalyFlagger) Stop() {
+	af.stopOnce.Do(func() { close(af.stopChan) })
+}
+
+// Evaluate flags and unflags nodes from the anomalies of the window ending
+// at now, and returns the nodes it currently has flagged.
+func (af *AnomalyFlagger) Evaluate(now time.Time) []string {
+	counts := make(map[string]int)
+	latest := make(map[string]*ValidationAnomaly)
+	for _, anomaly := range af.source.DetectValidationAnomalies(now.Add(-af.config.Window)) {
+		if anomaly.OriginNode == "" {
+			continue
+		}
+		counts[anomaly.OriginNode]++
+		if previous := latest[anomaly.OriginNode]; previous == nil || anomaly.Timestamp.After(previous.Timestamp) {
+			latest[anomaly.OriginNode] = anomaly
+		}
+	}
+
+	af.mu.Lock()
+	defer af.mu.Unlock()
+
+	existing := af.flagger.GetFlaggedNodes()
+	for nodeID, count := range counts {
+		if count < af.config.MinAnomalies {
+			continue
+		}
+		if _, ours := af.flagged[nodeID]; ours {
+			continue
+		}
+		if _, taken := existing[nodeID]; taken {
+			continue
+		}
+		reason := fmt.Sprintf("%d validation anomalies, latest: %s", count, latest[nodeID].Description)
+		af.flagged[nodeID] = reason
+		af.flagger.FlagNode(nodeID, reason)
+		existing[nodeID] = reason
+	}
+
+	flagged := make([]string, 0, len(af.flagged))
+	for nodeID, reason := range af.flagged {
+		if existing[nodeID] != reason {
+			// Unflagged or reflagged by someone else since; theirs now.
+			delete(af.flagged, nodeID)
+			continue
+		}
+		if counts[nodeID] >= af.config.MinAnomalies {
+			flagged = append(flagged, nodeID)
+			continue
+		}
+		delete(af.flagged, nodeID)
+		if af.flagger.UnflagNodeIf(nodeID, reason) {
+			af.logger.Info("Node unflagged, validation anomalies cleared", zap.String("node_id", nodeID))
+		}
+	}
+	return flagged
+}
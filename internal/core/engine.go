@@ -11,6 +11,9 @@ type Engine struct {
         relativisticEngine *RelativisticEngine
         propagationManager *PropagationManager
         validationEngine   *ValidationEngine
+        admission          *AdmissionController
+        verifier           *network.PositionVerifier
+        gossip             *network.GossipRouter
         topologyManager    *network.TopologyManager
         logger             *zap.Logger
         mu                 sync.RWMutex
@@ -19,10 +22,12 @@ func NewEngine(topology *network.TopologyManager, latency *network.LatencyMonito
         relativisticEngine := NewRelativisticEngine(topology, latency, logger)
         propagationManager := NewPropagationManager(relativisticEngine, logger)
         validationEngine := NewValidationEngine(relativisticEngine, logger)
+        admission := NewAdmissionController(validationEngine, DefaultAdmissionConfig(), logger)
         return &Engine{
                 relativisticEngine: relativisticEngine,
                 propagationManager: propagationManager,
                 validationEngine:   validationEngine,
+                admission:          admission,
                 topologyManager:    topology,
                 logger:             logger,
         }
@@ -67,6 +72,27 @@ func (e *Engine) BatchValidateTimestamps(ctx context.Context, blocks []*types.Bl
 func (e *Engine) ValidateTransactionTimestamp(ctx context.Context, tx *types.Transaction, originNode string) (*types.ValidationResult, error) {
         return e.validationEngine.ValidateTransactionTimestamp(ctx, tx, originNode)
 }
+func (e *Engine) GetAdmissionController() *AdmissionController {
+        return e.admission
+}
+func (e *Engine) AttachPositionVerifier(verifier *network.PositionVerifier) {
+        e.mu.Lock()
+        e.verifier = verifier
+        e.mu.Unlock()
+        e.relativisticEngine.SetPositionVerifier(verifier)
+}
+func (e *Engine) GetPositionVerifier() *network.PositionVerifier {
+        e.mu.RLock()
+        defer e.mu.RUnlock()
+        return e.verifier
+}
+func (e *Engine) AdmitTransaction(ctx context.Context, tx *types.Transaction, originNode string) (*AdmissionResult, error) {
+        result, err := e.admission.Admit(ctx, tx, originNode)
+        if err == nil && result.Decision != AdmissionRejected {
+                e.publishTransaction(tx, originNode)
+        }
+        return result, err
+}
 func (e *Engine) CalculateInterplanetaryDelay(planetA, planetB string) (time.Duration, error) {        return e.relativisticEngine.CalculateInterplanetaryDelay(planetA, planetB)
 }
 func (e *Engine) GetNetworkMetrics() *types.NetworkMetrics {
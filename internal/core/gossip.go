@@ -0,0 +1,62 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+
+	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/network"
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
+)
+
+// gossipTransaction is the payload carried on the transactions topic.
+type gossipTransaction struct {
+	Transaction *types.Transaction `json:"transaction"`
+	OriginNode  string             `json:"origin_node"`
+}
+
+// AttachGossip subscribes to the transactions topic. Transactions queued by
+// local admission are published; gossiped ones go through admission and are
+// only forwarded if queued here. A timestamp outside this node's window says
+// nothing about the relaying peer, so those are ignored rather than
+// rejected.
+func (e *Engine) AttachGossip(router *network.GossipRouter) {
+	e.mu.Lock()
+	e.gossip = router
+	e.mu.Unlock()
+
+	router.Subscribe(network.TopicTransactions, e.handleGossipTransaction)
+}
+
+func (e *Engine) publishTransaction(tx *types.Transaction, originNode string) {
+	e.mu.RLock()
+	router := e.gossip
+	e.mu.RUnlock()
+
+	if router == nil {
+		return
+	}
+
+	data, err := json.Marshal(&gossipTransaction{Transaction: tx, OriginNode: originNode})
+	if err != nil {
+		e.logger.Warn("Failed to encode transaction for gossip", zap.String("tx_hash", tx.Hash), zap.Error(err))
+		return
+	}
+	if _, err := router.Publish(network.TopicTransactions, data); err != nil {
+		e.logger.Debug("Failed to publish transaction", zap.String("tx_hash", tx.Hash), zap.Error(err))
+	}
+}
+
+func (e *Engine) handleGossipTransaction(message *network.GossipMessage) network.GossipValidation {
+	payload := &gossipTransaction{}
+	if err := json.Unmarshal(message.Data, payload); err != nil || payload.Transaction == nil || payload.Transaction.Hash == "" {
+		return network.GossipReject
+	}
+
+	result, err := e.admission.Admit(context.Background(), payload.Transaction, payload.OriginNode)
+	if err != nil || result.Decision == AdmissionRejected {
+		return network.GossipIgnore
+	}
+	return network.GossipAccept
+}
@@ -21,6 +21,7 @@ type RelativisticEngine struct {
 	cache           *sync.Map
 	mu              sync.RWMutex
 	metrics         *types.EngineMetrics
+	verifier        *network.PositionVerifier
 }
 
 type EngineConfig struct {
@@ -52,6 +53,14 @@ func NewRelativisticEngine(topology *network.TopologyManager, latency *network.L
 	}
 }
 
+// SetPositionVerifier makes timestamp validation scale its confidence by
+// the origin node's location-trust score.
+func (e *RelativisticEngine) SetPositionVerifier(verifier *network.PositionVerifier) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.verifier = verifier
+}
+
 func (e *RelativisticEngine) CalculatePropagationDelay(nodeA, nodeB *types.Node) (time.Duration, error) {
 	startTime := time.Now()
 	e.metrics.Mu.Lock()
@@ -186,6 +195,17 @@ func (e *RelativisticEngine) ValidateTimestamp(ctx context.Context, blockTimesta
 		confidence = 0.0
 	}
 
+	// A timestamp is only as trustworthy as the position its expected
+	// delay was computed from.
+	e.mu.RLock()
+	verifier := e.verifier
+	e.mu.RUnlock()
+	locationTrust := 1.0
+	if verifier != nil {
+		locationTrust = verifier.TrustScore(originNode)
+		confidence *= locationTrust
+	}
+
 	if confidence < e.config.ValidationThreshold {
 		valid = false
 	}
@@ -204,6 +224,7 @@ func (e *RelativisticEngine) ValidateTimestamp(ctx context.Context, blockTimesta
 		zap.Duration("max_acceptable", maxAcceptable),
 		zap.Bool("valid", valid),
 		zap.Float64("confidence", confidence),
+		zap.Float64("location_trust", locationTrust),
 	)
 
 	return valid, &types.ValidationResult{
@@ -241,6 +241,7 @@ func (ve *ValidationEngine) DetectAnomalies(since time.Time) []*ValidationAnomal
 			anomalies = append(anomalies, &ValidationAnomaly{
 				Type:        "LowConfidence",
 				Hash:        hash,
+				OriginNode:  record.OriginNode,
 				Confidence:  record.Confidence,
 				Timestamp:   record.ValidatedAt,
 				Description: fmt.Sprintf("Validation confidence too low: %.2f", record.Confidence),
@@ -251,6 +252,7 @@ func (ve *ValidationEngine) DetectAnomalies(since time.Time) []*ValidationAnomal
 			anomalies = append(anomalies, &ValidationAnomaly{
 				Type:        "LargeTimeDifference",
 				Hash:        hash,
+				OriginNode:  record.OriginNode,
 				TimeDiff:    record.ActualDiff,
 				Timestamp:   record.ValidatedAt,
 				Description: fmt.Sprintf("Unusually large time difference: %v", record.ActualDiff),
@@ -269,6 +271,7 @@ func (ve *ValidationEngine) DetectAnomalies(since time.Time) []*ValidationAnomal
 type ValidationAnomaly struct {
 	Type        string
 	Hash        string
+	OriginNode  string
 	Confidence  float64
 	TimeDiff    time.Duration
 	Timestamp   time.Time
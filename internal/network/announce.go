@@ -0,0 +1,140 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
+)
+
+// minAnnounceInterval spaces out the announcements a run of verified
+// handshakes would otherwise trigger. A changed local record is announced
+// straight away.
+const minAnnounceInterval = 30 * time.Second
+
+// AttachGossip subscribes to the topology topic. Nodes announce their own
+// record there when it changes and after a verified handshake, signed with
+// their node identity, so only the node a record describes can publish it.
+func (pm *PeeringManager) AttachGossip(router *GossipRouter) {
+	pm.mu.Lock()
+	pm.gossip = router
+	pm.mu.Unlock()
+
+	router.Subscribe(TopicTopology, pm.handleNodeAnnouncement)
+}
+
+// SetLocalNode replaces the local node record sent in handshakes and
+// announces it on the topology topic.
+func (pm *PeeringManager) SetLocalNode(node *types.Node) {
+	updated := *node
+	updated.ID = pm.localPeerID
+
+	pm.mu.Lock()
+	pm.config.Node = &updated
+	pm.lastAnnounce = time.Time{}
+	pm.mu.Unlock()
+
+	pm.announceLocalNode()
+}
+
+// announceLocalNode publishes the local record. Without a node identity
+// receivers cannot tell who published it, so nothing is sent.
+func (pm *PeeringManager) announceLocalNode() {
+	pm.mu.Lock()
+	router := pm.gossip
+	if router == nil || pm.config.Identity == nil || pm.config.Node == nil || time.Since(pm.lastAnnounce) < minAnnounceInterval {
+		pm.mu.Unlock()
+		return
+	}
+	pm.lastAnnounce = time.Now()
+	pm.mu.Unlock()
+
+	data, err := json.Marshal(pm.localNode())
+	if err != nil {
+		pm.logger.Warn("Failed to encode node announcement", zap.Error(err))
+		return
+	}
+	if _, err := router.Publish(TopicTopology, data); err != nil {
+		pm.logger.Debug("Failed to announce local node", zap.Error(err))
+	}
+}
+
+// handleNodeAnnouncement checks a gossiped node record before it reaches
+// the topology. The message must be signed by the node it describes, must
+// be newer than the last record applied for that node, must not describe a
+// node whose position verification flagged it, and, if this node has a
+// session with it, must claim a position the session RTT allows.
+func (pm *PeeringManager) handleNodeAnnouncement(message *GossipMessage) GossipValidation {
+	if !message.Authenticated {
+		return GossipIgnore
+	}
+
+	node := &types.Node{}
+	if err := json.Unmarshal(message.Data, node); err != nil {
+		return GossipReject
+	}
+	if node.ID != message.Origin {
+		return GossipReject
+	}
+	if node.ID == pm.localPeerID {
+		return GossipIgnore
+	}
+	if err := pm.checkAnnouncedPosition(node); err != nil {
+		pm.logger.Warn("Rejected node announcement",
+			zap.String("node_id", node.ID),
+			zap.Error(err),
+		)
+		return GossipReject
+	}
+
+	pm.mu.Lock()
+	topology := pm.topologyManager
+	verifier := pm.verifier
+	if last, exists := pm.announced[node.ID]; exists && !message.PublishedAt.After(last) {
+		pm.mu.Unlock()
+		return GossipIgnore
+	}
+	pm.announced[node.ID] = message.PublishedAt
+	pm.mu.Unlock()
+
+	if verifier != nil {
+		for _, flagged := range verifier.FlaggedNodes() {
+			if flagged == node.ID {
+				return GossipIgnore
+			}
+		}
+	}
+
+	if topology != nil {
+		if err := topology.UpsertNode(node); err != nil {
+			pm.logger.Debug("Failed to apply node announcement",
+				zap.String("node_id", node.ID),
+				zap.Error(err),
+			)
+			return GossipIgnore
+		}
+	}
+	return GossipAccept
+}
+
+func (pm *PeeringManager) checkAnnouncedPosition(node *types.Node) error {
+	pm.mu.RLock()
+	conn, connected := pm.connections[node.ID]
+	local := pm.config.Node
+	var rtt time.Duration
+	if connected {
+		rtt = conn.RTT
+	}
+	pm.mu.RUnlock()
+
+	if local == nil || rtt <= 0 {
+		return nil
+	}
+	if err := CheckPositionPlausibility(local.Position, node.Position, rtt, pm.config.RTTTolerance); err != nil {
+		return fmt.Errorf("announced position contradicts session RTT: %w", err)
+	}
+	return nil
+}
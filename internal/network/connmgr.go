@@ -0,0 +1,447 @@
+package network
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type ConnectionManagerConfig struct {
+	// TargetOutbound sessions are kept dialled. Inbound sessions beyond
+	// MaxInbound are closed, worst scoring first.
+	TargetOutbound int `json:"target_outbound"`
+	MaxInbound     int `json:"max_inbound"`
+	// MinOutsideRegion sessions are kept with peers outside the local
+	// region, so losing a region's links cannot isolate the node.
+	MinOutsideRegion int `json:"min_outside_region"`
+
+	Interval time.Duration `json:"interval"`
+	// Every RotationInterval the worst outbound peer is dropped to make
+	// room for an untried candidate; dropped peers are not redialled for
+	// one interval.
+	RotationInterval time.Duration `json:"rotation_interval"`
+
+	// A peer's score is LatencyWeight times the ratio of theoretical to
+	// measured RTT, plus UptimeWeight times its session age relative to
+	// UptimeHorizon, minus MisbehaviorWeight times its misbehavior points.
+	LatencyWeight     float64       `json:"latency_weight"`
+	UptimeWeight      float64       `json:"uptime_weight"`
+	MisbehaviorWeight float64       `json:"misbehavior_weight"`
+	UptimeHorizon     time.Duration `json:"uptime_horizon"`
+
+	// Misbehavior points decay by MisbehaviorDecay every interval. Peers
+	// reaching BanThreshold are disconnected and refused for BanDuration.
+	MisbehaviorDecay float64       `json:"misbehavior_decay"`
+	BanThreshold     float64       `json:"ban_threshold"`
+	BanDuration      time.Duration `json:"ban_duration"`
+}
+
+func DefaultConnectionManagerConfig() *ConnectionManagerConfig {
+	return &ConnectionManagerConfig{
+		TargetOutbound:    8,
+		MaxInbound:        32,
+		MinOutsideRegion:  2,
+		Interval:          30 * time.Second,
+		RotationInterval:  10 * time.Minute,
+		LatencyWeight:     1,
+		UptimeWeight:      0.5,
+		MisbehaviorWeight: 1,
+		UptimeHorizon:     time.Hour,
+		MisbehaviorDecay:  0.9,
+		BanThreshold:      10,
+		BanDuration:       30 * time.Minute,
+	}
+}
+
+// PeerScore is a connected peer's standing with the connection manager.
+// Region and TheoreticalRTT are only set for peers whose position was
+// verified, so a claimed region cannot satisfy the diversity minimum.
+type PeerScore struct {
+	PeerID         string              `json:"peer_id"`
+	Direction      ConnectionDirection `json:"direction"`
+	Region         string              `json:"region,omitempty"`
+	RTT            time.Duration       `json:"rtt"`
+	TheoreticalRTT time.Duration       `json:"theoretical_rtt"`
+	Uptime         time.Duration       `json:"uptime"`
+	Misbehavior    float64             `json:"misbehavior"`
+	Protected      bool                `json:"protected"`
+	Score          float64             `json:"score"`
+}
+
+// ConnectionManager decides which discovered peers to keep sessions with.
+// It holds the outbound degree at a target, caps inbound sessions, keeps a
+// minimum of peers outside the local region, rotates out the worst peer
+// periodically and bans misbehaving ones. Peers added with Connect are
+// protected and never dropped.
+type ConnectionManager struct {
+	peering   *PeeringManager
+	discovery *DiscoveryService
+	config    *ConnectionManagerConfig
+	logger    *zap.Logger
+
+	mu           sync.Mutex
+	gossip       *GossipRouter
+	misbehavior  map[string]float64
+	banned       map[string]time.Time
+	dropped      map[string]time.Time
+	lastRotation time.Time
+	stopChan     chan struct{}
+	stopOnce     sync.Once
+}
+
+func NewConnectionManager(peering *PeeringManager, discovery *DiscoveryService, config *ConnectionManagerConfig, logger *zap.Logger) *ConnectionManager {
+	if config == nil {
+		config = DefaultConnectionManagerConfig()
+	}
+
+	cm := &ConnectionManager{
+		peering:      peering,
+		discovery:    discovery,
+		config:       config,
+		logger:       logger,
+		misbehavior:  make(map[string]float64),
+		banned:       make(map[string]time.Time),
+		dropped:      make(map[string]time.Time),
+		lastRotation: time.Now(),
+		stopChan:     make(chan struct{}),
+	}
+
+	peering.mu.Lock()
+	peering.connManager = cm
+	peering.mu.Unlock()
+	return cm
+}
+
+// AttachGossip counts messages a peer relayed that failed validation as
+// misbehavior, one point per invalid message.
+func (cm *ConnectionManager) AttachGossip(router *GossipRouter) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.gossip = router
+}
+
+func (cm *ConnectionManager) Start(ctx context.Context) {
+	ticker := time.NewTicker(cm.config.Interval)
+	defer ticker.Stop()
+
+	cm.maintain()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-cm.stopChan:
+			return
+		case <-ticker.C:
+			cm.maintain()
+		}
+	}
+}
+
+func (cm *ConnectionManager) Stop() {
+	cm.stopOnce.Do(func() { close(cm.stopChan) })
+}
+
+// Points the peering layer reports on its own: a frame that does not decode,
+// a claimed position the handshake RTT rules out, and a probe reply whose
+// timestamps make the round trip faster than light.
+const (
+	malformedMessagePenalty    = 1.0
+	implausiblePositionPenalty = 5.0
+	implausibleLatencyPenalty  = 5.0
+)
+
+// ReportMisbehavior adds points against a peer. The peer is banned at the
+// next maintenance pass once its points reach BanThreshold.
+func (cm *ConnectionManager) ReportMisbehavior(peerID string, points float64, reason string) {
+	cm.mu.Lock()
+	cm.misbehavior[peerID] += points
+	total := cm.misbehavior[peerID]
+	cm.mu.Unlock()
+
+	cm.logger.Warn("Peer misbehavior reported",
+		zap.String("peer_id", peerID),
+		zap.String("reason", reason),
+		zap.Float64("points", total),
+	)
+}
+
+func (cm *ConnectionManager) IsBanned(peerID string) bool {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	until, banned := cm.banned[peerID]
+	return banned && time.Now().Before(until)
+}
+
+// GetPeerScores returns the connected peers, best scoring first.
+func (cm *ConnectionManager) GetPeerScores() []*PeerScore {
+	local := cm.peering.localNode()
+	var scores []*PeerScore
+
+	cm.peering.mu.RLock()
+	for _, conn := range cm.peering.connections {
+		if conn.Status != Connected || conn.Transient {
+			continue
+		}
+		_, protected := cm.peering.staticPeers[conn.PeerID]
+		score := &PeerScore{
+			PeerID:    conn.PeerID,
+			Direction: conn.Direction,
+			RTT:       conn.RTT,
+			Uptime:    time.Since(conn.Established),
+			Protected: protected,
+		}
+		// Probe latency, once measured, replaces the handshake's one sample.
+		if conn.Metrics != nil && conn.Metrics.Latency > 0 {
+			score.RTT = conn.Metrics.Latency
+		}
+		if conn.RemoteNode != nil && conn.PositionVerified {
+			score.Region = conn.RemoteNode.Metadata.Region
+			if cm.peering.config.Node != nil {
+				score.TheoreticalRTT = MinimumRTT(local.Position, conn.RemoteNode.Position)
+			}
+		}
+		scores = append(scores, score)
+	}
+	cm.peering.mu.RUnlock()
+
+	for _, score := range scores {
+		score.Misbehavior = cm.misbehaviorPoints(score.PeerID)
+		score.Score = cm.score(score)
+	}
+
+	sort.Slice(scores, func(i, j int) bool {
+		return scores[i].Score > scores[j].Score
+	})
+	return scores
+}
+
+func (cm *ConnectionManager) misbehaviorPoints(peerID string) float64 {
+	cm.mu.Lock()
+	points := cm.misbehavior[peerID]
+	router := cm.gossip
+	cm.mu.Unlock()
+
+	if router != nil && router.config.InvalidPenalty > 0 {
+		if gossipScore := router.PeerScore(peerID); gossipScore < 0 {
+			points += -gossipScore / router.config.InvalidPenalty
+		}
+	}
+	return points
+}
+
+// score rates a peer. Without a measured RTT and a verified position the
+// latency term is neutral, so unknown peers rank between good and bad ones.
+func (cm *ConnectionManager) score(peer *PeerScore) float64 {
+	latency := 0.5
+	if peer.RTT > 0 && peer.TheoreticalRTT > 0 {
+		latency = math.Min(1, float64(peer.TheoreticalRTT)/float64(peer.RTT))
+	}
+
+	uptime := 1.0
+	if cm.config.UptimeHorizon > 0 {
+		uptime = math.Min(1, float64(peer.Uptime)/float64(cm.config.UptimeHorizon))
+	}
+
+	return cm.config.LatencyWeight*latency +
+		cm.config.UptimeWeight*uptime -
+		cm.config.MisbehaviorWeight*peer.Misbehavior
+}
+
+func (cm *ConnectionManager) maintain() {
+	now := time.Now()
+	cm.expire(now)
+
+	localRegion := cm.peering.localNode().Metadata.Region
+	outside := func(region string) bool {
+		return localRegion != "" && region != "" && region != localRegion
+	}
+
+	var inbound, outbound []*PeerScore
+	outsideCount := 0
+	connected := make(map[string]bool)
+	for _, peer := range cm.GetPeerScores() {
+		connected[peer.PeerID] = true
+		if !peer.Protected && peer.Misbehavior >= cm.config.BanThreshold {
+			cm.ban(peer, now)
+			continue
+		}
+		if outside(peer.Region) {
+			outsideCount++
+		}
+		if peer.Direction == Inbound {
+			inbound = append(inbound, peer)
+		} else {
+			outbound = append(outbound, peer)
+		}
+	}
+
+	// drop closes the worst scoring peer of the list that can go without
+	// breaking the diversity minimum.
+	drop := func(peers []*PeerScore, reason string) []*PeerScore {
+		for i := len(peers) - 1; i >= 0; i-- {
+			peer := peers[i]
+			if peer.Protected || (outside(peer.Region) && outsideCount <= cm.config.MinOutsideRegion) {
+				continue
+			}
+			if outside(peer.Region) {
+				outsideCount--
+			}
+			cm.drop(peer, reason, now)
+			return append(peers[:i], peers[i+1:]...)
+		}
+		return peers
+	}
+
+	for len(inbound) > cm.config.MaxInbound {
+		before := len(inbound)
+		if inbound = drop(inbound, "inbound limit"); len(inbound) == before {
+			break
+		}
+	}
+	for len(outbound) > cm.config.TargetOutbound {
+		before := len(outbound)
+		if outbound = drop(outbound, "outbound limit"); len(outbound) == before {
+			break
+		}
+	}
+
+	candidates := cm.candidates(connected, now)
+	if cm.config.RotationInterval > 0 && now.Sub(cm.lastRotation) >= cm.config.RotationInterval {
+		cm.lastRotation = now
+		if len(outbound) >= cm.config.TargetOutbound && len(candidates) > 0 {
+			outbound = drop(outbound, "rotation")
+		}
+	}
+
+	dials := cm.config.TargetOutbound - len(outbound)
+	if missing := cm.config.MinOutsideRegion - outsideCount; localRegion != "" && missing > dials {
+		dials = missing
+	}
+	if dials <= 0 {
+		return
+	}
+
+	// Verified candidates outside the region go first while the minimum
+	// is unmet. An unverified region is only a claim, so it earns no
+	// priority.
+	if outsideCount < cm.config.MinOutsideRegion {
+		verifiedOutside := func(peer *Peer) bool {
+			return peer.Verified && outside(peer.Node.Metadata.Region)
+		}
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return verifiedOutside(candidates[i]) && !verifiedOutside(candidates[j])
+		})
+	}
+	for _, candidate := range candidates {
+		if dials == 0 {
+			break
+		}
+		cm.peering.dial(candidate.Node.ID, candidate.Node.Address)
+		dials--
+	}
+}
+
+// candidates returns discovered peers worth dialling: verified peers
+// nearest first, then unverified ones in random order, since ranking them
+// by a claimed position would favour whoever claims to be close.
+func (cm *ConnectionManager) candidates(connected map[string]bool, now time.Time) []*Peer {
+	if cm.discovery == nil {
+		return nil
+	}
+
+	cm.mu.Lock()
+	excluded := make(map[string]bool, len(cm.banned)+len(cm.dropped))
+	for peerID := range cm.banned {
+		excluded[peerID] = true
+	}
+	for peerID := range cm.dropped {
+		excluded[peerID] = true
+	}
+	cm.mu.Unlock()
+
+	var candidates []*Peer
+	for _, peer := range cm.discovery.GetAllPeers() {
+		if peer.Node == nil || peer.Node.Address == "" || peer.Status == PeerDisconnected {
+			continue
+		}
+		if peer.Node.ID == cm.peering.localPeerID || connected[peer.Node.ID] || excluded[peer.Node.ID] {
+			continue
+		}
+		candidates = append(candidates, peer)
+	}
+
+	rand.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+
+	local := cm.peering.localNode()
+	positioned := cm.peering.configuredNode() != nil
+	distance := func(peer *Peer) time.Duration {
+		if !peer.Verified || !positioned {
+			return math.MaxInt64
+		}
+		return MinimumRTT(local.Position, peer.Node.Position)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return distance(candidates[i]) < distance(candidates[j])
+	})
+	return candidates
+}
+
+func (cm *ConnectionManager) ban(peer *PeerScore, now time.Time) {
+	cm.mu.Lock()
+	cm.banned[peer.PeerID] = now.Add(cm.config.BanDuration)
+	cm.mu.Unlock()
+
+	cm.logger.Warn("Banning misbehaving peer",
+		zap.String("peer_id", peer.PeerID),
+		zap.Float64("misbehavior", peer.Misbehavior),
+		zap.Duration("duration", cm.config.BanDuration),
+	)
+	cm.peering.Disconnect(peer.PeerID)
+}
+
+func (cm *ConnectionManager) drop(peer *PeerScore, reason string, now time.Time) {
+	cm.mu.Lock()
+	cm.dropped[peer.PeerID] = now.Add(cm.config.RotationInterval)
+	cm.mu.Unlock()
+
+	cm.logger.Info("Dropping peer",
+		zap.String("peer_id", peer.PeerID),
+		zap.String("reason", reason),
+		zap.Float64("score", peer.Score),
+	)
+	cm.peering.Disconnect(peer.PeerID)
+}
+
+// expire decays misbehavior and lifts bans and drop holds that have run
+// out.
+func (cm *ConnectionManager) expire(now time.Time) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	for peerID, points := range cm.misbehavior {
+		points *= cm.config.MisbehaviorDecay
+		if points < 0.01 {
+			delete(cm.misbehavior, peerID)
+			continue
+		}
+		cm.misbehavior[peerID] = points
+	}
+	for peerID, until := range cm.banned {
+		if !now.Before(until) {
+			delete(cm.banned, peerID)
+		}
+	}
+	for peerID, until := range cm.dropped {
+		if !now.Before(until) {
+			delete(cm.dropped, peerID)
+		}
+	}
+}
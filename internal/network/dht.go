@@ -0,0 +1,719 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/bits"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
+)
+
+const (
+	MessageTypeDHTPing     MessageType = "dht_ping"
+	MessageTypeDHTPong     MessageType = "dht_pong"
+	MessageTypeDHTFindNode MessageType = "dht_find_node"
+	MessageTypeDHTNodes    MessageType = "dht_nodes"
+)
+
+const dhtKeyBits = 256
+
+// NodeKey is a node's place in the DHT keyspace, the SHA-256 of its ID.
+type NodeKey [32]byte
+
+func KeyForNode(nodeID string) NodeKey {
+	return sha256.Sum256([]byte(nodeID))
+}
+
+func XORDistance(a, b NodeKey) NodeKey {
+	var distance NodeKey
+	for i := range a {
+		distance[i] = a[i] ^ b[i]
+	}
+	return distance
+}
+
+// BucketIndex is the length of the prefix a and b share, which is the
+// k-bucket b falls in from a's point of view. It is -1 when a equals b.
+func BucketIndex(a, b NodeKey) int {
+	distance := XORDistance(a, b)
+	for i, x := range distance {
+		if x != 0 {
+			return i*8 + bits.LeadingZeros8(x)
+		}
+	}
+	return -1
+}
+
+func closerTo(target, a, b NodeKey) bool {
+	da := XORDistance(target, a)
+	db := XORDistance(target, b)
+	return bytes.Compare(da[:], db[:]) < 0
+}
+
+// DHTContact is a routing table entry. Position and Region are what the
+// node advertised about itself and are unverified; RTT is measured locally
+// and never sent.
+type DHTContact struct {
+	ID       string         `json:"id"`
+	Address  string         `json:"address"`
+	Position types.Position `json:"position"`
+	Region   string         `json:"region,omitempty"`
+	RTT      time.Duration  `json:"-"`
+	LastSeen time.Time      `json:"-"`
+}
+
+type dhtRequest struct {
+	RequestID string     `json:"request_id"`
+	Sender    DHTContact `json:"sender"`
+	Target    string     `json:"target,omitempty"`
+}
+
+type dhtResponse struct {
+	RequestID string       `json:"request_id"`
+	Contacts  []DHTContact `json:"contacts,omitempty"`
+}
+
+type DHTConfig struct {
+	// K is the bucket size and the number of contacts a lookup returns;
+	// Alpha lookup requests are in flight at once.
+	K              int           `json:"k"`
+	Alpha          int           `json:"alpha"`
+	RequestTimeout time.Duration `json:"request_timeout"`
+	// RefreshInterval is how often the table is refreshed by looking up
+	// the local ID and a random key.
+	RefreshInterval time.Duration `json:"refresh_interval"`
+	// PreferLowLatency lets a newcomer with a lower measured RTT replace
+	// the slowest contact of a full bucket, instead of only replacing
+	// contacts that stop responding.
+	PreferLowLatency bool `json:"prefer_low_latency"`
+	// AdvertiseAddress is the peering address sent to other nodes. The
+	// listener's address is used when empty.
+	AdvertiseAddress string `json:"advertise_address"`
+}
+
+func DefaultDHTConfig() *DHTConfig {
+	return &DHTConfig{
+		K:               20,
+		Alpha:           3,
+		RequestTimeout:  5 * time.Second,
+		RefreshInterval: 10 * time.Minute,
+	}
+}
+
+// DHT is a Kademlia routing table over node-ID hashes. FIND_NODE and PING
+// travel as peer messages, so every contact reached is authenticated by
+// the peer session that carried the reply.
+type DHT struct {
+	peering  *PeeringManager
+	config   *DHTConfig
+	logger   *zap.Logger
+	localID  string
+	localKey NodeKey
+
+	mu          sync.Mutex
+	buckets     [dhtKeyBits][]*DHTContact
+	requestSeq  uint64
+	pending     map[string]chan *dhtResponse
+	challenging map[string]bool
+	stopChan    chan struct{}
+	stopOnce    sync.Once
+}
+
+func NewDHT(peering *PeeringManager, config *DHTConfig, logger *zap.Logger) *DHT {
+	if config == nil {
+		config = DefaultDHTConfig()
+	}
+
+	d := &DHT{
+		peering:     peering,
+		config:      config,
+		logger:      logger,
+		localID:     peering.LocalPeerID(),
+		localKey:    KeyForNode(peering.LocalPeerID()),
+		pending:     make(map[string]chan *dhtResponse),
+		challenging: make(map[string]bool),
+		stopChan:    make(chan struct{}),
+	}
+
+	peering.RegisterHandler(MessageTypeDHTPing, d.handlePing)
+	peering.RegisterHandler(MessageTypeDHTFindNode, d.handleFindNode)
+	peering.RegisterHandler(MessageTypeDHTPong, d.handleResponse)
+	peering.RegisterHandler(MessageTypeDHTNodes, d.handleResponse)
+	return d
+}
+
+func (d *DHT) Start(ctx context.Context) {
+	ticker := time.NewTicker(d.config.RefreshInterval)
+	defer ticker.Stop()
+
+	d.seedFromConnections()
+	d.refresh(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.stopChan:
+			return
+		case <-ticker.C:
+			d.seedFromConnections()
+			d.refresh(ctx)
+		}
+	}
+}
+
+func (d *DHT) Stop() {
+	d.stopOnce.Do(func() { close(d.stopChan) })
+}
+
+// Bootstrap adds the seeds that answer a ping and then looks up the local
+// ID, which fills the buckets nearest to it and announces this node to the
+// nodes that will be asked about it.
+func (d *DHT) Bootstrap(ctx context.Context, seeds []DHTContact) error {
+	reached := 0
+	for _, seed := range seeds {
+		if _, err := d.Ping(ctx, seed); err != nil {
+			d.logger.Debug("DHT seed unreachable",
+				zap.String("peer_id", seed.ID),
+				zap.Error(err),
+			)
+			continue
+		}
+		reached++
+	}
+	if reached == 0 && d.Size() == 0 {
+		return fmt.Errorf("no DHT seed reachable")
+	}
+
+	d.lookup(ctx, d.localKey)
+	return nil
+}
+
+// Ping measures the round trip to a contact and adds it to the table.
+func (d *DHT) Ping(ctx context.Context, contact DHTContact) (time.Duration, error) {
+	_, rtt, err := d.request(ctx, contact, MessageTypeDHTPing, &dhtRequest{})
+	return rtt, err
+}
+
+// FindNode runs an iterative lookup and returns the K contacts closest to
+// nodeID.
+func (d *DHT) FindNode(ctx context.Context, nodeID string) ([]DHTContact, error) {
+	if d.Size() == 0 {
+		return nil, fmt.Errorf("routing table is empty")
+	}
+	return d.lookup(ctx, KeyForNode(nodeID)), nil
+}
+
+// Lookup finds the contact for nodeID, from the table if it is known and
+// otherwise through the network.
+func (d *DHT) Lookup(ctx context.Context, nodeID string) (*DHTContact, error) {
+	if contact := d.contact(nodeID); contact != nil {
+		return contact, nil
+	}
+
+	contacts, err := d.FindNode(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	for _, contact := range contacts {
+		if contact.ID == nodeID {
+			return &contact, nil
+		}
+	}
+	return nil, fmt.Errorf("node %s not found", nodeID)
+}
+
+// Name and Discover make the DHT a DiscoverySource: each round refreshes
+// the table and reports its contacts.
+func (d *DHT) Name() string {
+	return "dht"
+}
+
+func (d *DHT) Discover(ctx context.Context) ([]*Peer, error) {
+	d.seedFromConnections()
+	if d.Size() == 0 {
+		return nil, fmt.Errorf("routing table is empty")
+	}
+	d.refresh(ctx)
+
+	contacts := d.Contacts()
+	peers := make([]*Peer, 0, len(contacts))
+	for _, contact := range contacts {
+		node := &types.Node{
+			ID:       contact.ID,
+			Address:  contact.Address,
+			Position: contact.Position,
+			Metadata: types.Metadata{Region: contact.Region},
+		}
+		peers = append(peers, newDiscoveredPeer(node, d.Name()))
+	}
+	return peers, nil
+}
+
+// Contacts returns every contact in the table.
+func (d *DHT) Contacts() []DHTContact {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var contacts []DHTContact
+	for _, bucket := range d.buckets {
+		for _, contact := range bucket {
+			contacts = append(contacts, *contact)
+		}
+	}
+	return contacts
+}
+
+func (d *DHT) Size() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	size := 0
+	for _, bucket := range d.buckets {
+		size += len(bucket)
+	}
+	return size
+}
+
+// BucketSizes maps bucket index to the number of contacts in it, for the
+// non-empty buckets.
+func (d *DHT) BucketSizes() map[int]int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	sizes := make(map[int]int)
+	for i, bucket := range d.buckets {
+		if len(bucket) > 0 {
+			sizes[i] = len(bucket)
+		}
+	}
+	return sizes
+}
+
+func (d *DHT) refresh(ctx context.Context) {
+	var random NodeKey
+	rand.Read(random[:])
+
+	d.lookup(ctx, d.localKey)
+	d.lookup(ctx, random)
+}
+
+// seedFromConnections adds the peers we dialled, whose addresses are known
+// to be reachable, so the table starts from the peering mesh.
+func (d *DHT) seedFromConnections() {
+	var contacts []DHTContact
+
+	d.peering.mu.RLock()
+	for _, conn := range d.peering.connections {
+		if conn.Status != Connected || conn.Direction != Outbound {
+			continue
+		}
+		contact := DHTContact{ID: conn.PeerID, Address: conn.RemoteAddr, RTT: conn.RTT}
+		if conn.RemoteNode != nil {
+			contact.Position = conn.RemoteNode.Position
+			contact.Region = conn.RemoteNode.Metadata.Region
+		}
+		contacts = append(contacts, contact)
+	}
+	d.peering.mu.RUnlock()
+
+	for _, contact := range contacts {
+		d.update(contact)
+	}
+}
+
+func (d *DHT) self() DHTContact {
+	node := d.peering.localNode()
+	address := d.config.AdvertiseAddress
+	if address == "" {
+		if addr := d.peering.ListenAddr(); addr != nil {
+			address = addr.String()
+		}
+	}
+	return DHTContact{
+		ID:       d.localID,
+		Address:  address,
+		Position: node.Position,
+		Region:   node.Metadata.Region,
+	}
+}
+
+func (d *DHT) contact(nodeID string) *DHTContact {
+	index := BucketIndex(d.localKey, KeyForNode(nodeID))
+	if index < 0 {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, contact := range d.buckets[index] {
+		if contact.ID == nodeID {
+			found := *contact
+			return &found
+		}
+	}
+	return nil
+}
+
+// update records that a contact was seen. A known contact moves to the
+// tail of its bucket. A full bucket keeps its oldest contact if that still
+// answers a ping, unless PreferLowLatency lets a faster newcomer replace
+// the slowest entry.
+func (d *DHT) update(contact DHTContact) {
+	if contact.ID == "" || contact.ID == d.localID || contact.Address == "" {
+		return
+	}
+	index := BucketIndex(d.localKey, KeyForNode(contact.ID))
+	contact.LastSeen = time.Now().UTC()
+
+	d.mu.Lock()
+	bucket := d.buckets[index]
+	for i, existing := range bucket {
+		if existing.ID == contact.ID {
+			if contact.RTT == 0 {
+				contact.RTT = existing.RTT
+			}
+			bucket = append(bucket[:i], bucket[i+1:]...)
+			d.buckets[index] = append(bucket, &contact)
+			d.mu.Unlock()
+			return
+		}
+	}
+
+	if len(bucket) < d.config.K {
+		d.buckets[index] = append(bucket, &contact)
+		d.mu.Unlock()
+		return
+	}
+
+	if d.config.PreferLowLatency && contact.RTT > 0 {
+		slowest := 0
+		for i, existing := range bucket {
+			if slowerThan(existing.RTT, bucket[slowest].RTT) {
+				slowest = i
+			}
+		}
+		if slowerThan(bucket[slowest].RTT, contact.RTT) {
+			bucket = append(bucket[:slowest], bucket[slowest+1:]...)
+			d.buckets[index] = append(bucket, &contact)
+			d.mu.Unlock()
+			return
+		}
+	}
+
+	oldest := *bucket[0]
+	if d.challenging[oldest.ID] {
+		d.mu.Unlock()
+		return
+	}
+	d.challenging[oldest.ID] = true
+	d.mu.Unlock()
+
+	go d.challenge(oldest, contact)
+}
+
+// slowerThan orders RTTs with unmeasured ones last.
+func slowerThan(a, b time.Duration) bool {
+	if a == 0 {
+		return b != 0
+	}
+	return b != 0 && a > b
+}
+
+// challenge pings the oldest contact of a full bucket and gives its place
+// to the newcomer if it does not answer.
+func (d *DHT) challenge(oldest, newcomer DHTContact) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.config.RequestTimeout)
+	defer cancel()
+
+	_, err := d.Ping(ctx, oldest)
+
+	d.mu.Lock()
+	delete(d.challenging, oldest.ID)
+	d.mu.Unlock()
+
+	if err != nil {
+		d.update(newcomer)
+	}
+}
+
+func (d *DHT) remove(nodeID string) {
+	index := BucketIndex(d.localKey, KeyForNode(nodeID))
+	if index < 0 {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	bucket := d.buckets[index]
+	for i, contact := range bucket {
+		if contact.ID == nodeID {
+			d.buckets[index] = append(bucket[:i], bucket[i+1:]...)
+			return
+		}
+	}
+}
+
+// closest returns up to n contacts ordered by distance to target.
+func (d *DHT) closest(target NodeKey, n int, exclude string) []DHTContact {
+	contacts := d.Contacts()
+	filtered := contacts[:0]
+	for _, contact := range contacts {
+		if contact.ID != exclude {
+			filtered = append(filtered, contact)
+		}
+	}
+	sortByDistance(target, filtered)
+	if len(filtered) > n {
+		filtered = filtered[:n]
+	}
+	return filtered
+}
+
+func sortByDistance(target NodeKey, contacts []DHTContact) {
+	sort.Slice(contacts, func(i, j int) bool {
+		return closerTo(target, KeyForNode(contacts[i].ID), KeyForNode(contacts[j].ID))
+	})
+}
+
+// lookup is the iterative Kademlia node lookup: it asks the Alpha closest
+// unqueried contacts for their closest nodes to target, merges the answers
+// and repeats until the K closest contacts have all been queried.
+func (d *DHT) lookup(ctx context.Context, target NodeKey) []DHTContact {
+	shortlist := d.closest(target, d.config.K, "")
+	seen := map[string]bool{d.localID: true}
+	for _, contact := range shortlist {
+		seen[contact.ID] = true
+	}
+	queried := make(map[string]bool)
+
+	for ctx.Err() == nil {
+		var batch []DHTContact
+		for _, contact := range shortlist {
+			if !queried[contact.ID] {
+				batch = append(batch, contact)
+				queried[contact.ID] = true
+				if len(batch) == d.config.Alpha {
+					break
+				}
+			}
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		var found []DHTContact
+		failed := make(map[string]bool)
+		for _, contact := range batch {
+			wg.Add(1)
+			go func(contact DHTContact) {
+				defer wg.Done()
+				contacts, err := d.findNode(ctx, contact, target)
+
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					failed[contact.ID] = true
+					return
+				}
+				found = append(found, contacts...)
+			}(contact)
+		}
+		wg.Wait()
+
+		merged := shortlist[:0]
+		for _, contact := range shortlist {
+			if !failed[contact.ID] {
+				merged = append(merged, contact)
+			}
+		}
+		for _, contact := range found {
+			if contact.ID == "" || contact.Address == "" || seen[contact.ID] {
+				continue
+			}
+			seen[contact.ID] = true
+			merged = append(merged, contact)
+		}
+		sortByDistance(target, merged)
+		if len(merged) > d.config.K {
+			merged = merged[:d.config.K]
+		}
+		shortlist = merged
+	}
+
+	return shortlist
+}
+
+func (d *DHT) findNode(ctx context.Context, contact DHTContact, target NodeKey) ([]DHTContact, error) {
+	response, _, err := d.request(ctx, contact, MessageTypeDHTFindNode, &dhtRequest{
+		Target: hex.EncodeToString(target[:]),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response.Contacts, nil
+}
+
+// request sends a DHT message over a session with the contact, opening a
+// request session if there is none, and waits for the matching reply.
+// Contacts that answer are added to the table with the measured RTT; those
+// that do not are dropped. Hitting the local session limit says nothing
+// about the contact, so it is kept.
+func (d *DHT) request(ctx context.Context, contact DHTContact, messageType MessageType, request *dhtRequest) (*dhtResponse, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.config.RequestTimeout)
+	defer cancel()
+
+	if err := d.peering.awaitSession(ctx, contact.ID, contact.Address); err != nil {
+		if !errors.Is(err, ErrPeerLimit) {
+			d.remove(contact.ID)
+		}
+		return nil, 0, err
+	}
+
+	d.mu.Lock()
+	d.requestSeq++
+	requestID := strconv.FormatUint(d.requestSeq, 10)
+	// Replies are matched on the peer as well as the ID, so a peer cannot
+	// answer a request sent to another.
+	pendingKey := contact.ID + "/" + requestID
+	responseCh := make(chan *dhtResponse, 1)
+	d.pending[pendingKey] = responseCh
+	d.mu.Unlock()
+
+	defer func() {
+		d.mu.Lock()
+		delete(d.pending, pendingKey)
+		d.mu.Unlock()
+	}()
+
+	request.RequestID = requestID
+	request.Sender = d.self()
+	data, err := json.Marshal(request)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to encode %s: %w", messageType, err)
+	}
+
+	sentAt := time.Now()
+	if err := d.peering.SendPeerMessage(contact.ID, messageType, data); err != nil {
+		d.remove(contact.ID)
+		return nil, 0, err
+	}
+
+	select {
+	case <-ctx.Done():
+		d.remove(contact.ID)
+		return nil, 0, fmt.Errorf("%s to %s timed out: %w", messageType, contact.ID, ctx.Err())
+	case response := <-responseCh:
+		contact.RTT = time.Since(sentAt)
+		d.update(contact)
+		return response, contact.RTT, nil
+	}
+}
+
+// observeSender adds the node behind a request to the table. Its ID is the
+// session's peer ID; for sessions we dialled the address is the one we
+// reached it on, otherwise the one it advertised.
+func (d *DHT) observeSender(conn *PeerConnection, sender DHTContact) {
+	sender.ID = conn.PeerID
+	sender.RTT = 0
+
+	d.peering.mu.RLock()
+	if conn.Direction == Outbound {
+		sender.Address = conn.RemoteAddr
+	}
+	d.peering.mu.RUnlock()
+
+	d.update(sender)
+}
+
+func (d *DHT) parseRequest(conn *PeerConnection, message *PeerMessage) *dhtRequest {
+	request := &dhtRequest{}
+	if err := json.Unmarshal(message.Payload, request); err != nil {
+		d.logger.Debug("Invalid DHT request",
+			zap.String("peer_id", conn.PeerID),
+			zap.Error(err),
+		)
+		return nil
+	}
+	d.observeSender(conn, request.Sender)
+	return request
+}
+
+func (d *DHT) respond(peerID string, messageType MessageType, response *dhtResponse) {
+	data, err := json.Marshal(response)
+	if err != nil {
+		return
+	}
+	if err := d.peering.SendPeerMessage(peerID, messageType, data); err != nil {
+		d.logger.Debug("Failed to answer DHT request",
+			zap.String("peer_id", peerID),
+			zap.Error(err),
+		)
+	}
+}
+
+func (d *DHT) handlePing(conn *PeerConnection, message *PeerMessage) {
+	request := d.parseRequest(conn, message)
+	if request == nil {
+		return
+	}
+	d.respond(conn.PeerID, MessageTypeDHTPong, &dhtResponse{RequestID: request.RequestID})
+}
+
+func (d *DHT) handleFindNode(conn *PeerConnection, message *PeerMessage) {
+	request := d.parseRequest(conn, message)
+	if request == nil {
+		return
+	}
+
+	raw, err := hex.DecodeString(request.Target)
+	if err != nil || len(raw) != len(NodeKey{}) {
+		d.logger.Debug("Invalid DHT lookup target", zap.String("peer_id", conn.PeerID))
+		return
+	}
+	var target NodeKey
+	copy(target[:], raw)
+
+	d.respond(conn.PeerID, MessageTypeDHTNodes, &dhtResponse{
+		RequestID: request.RequestID,
+		Contacts:  d.closest(target, d.config.K, conn.PeerID),
+	})
+}
+
+func (d *DHT) handleResponse(conn *PeerConnection, message *PeerMessage) {
+	response := &dhtResponse{}
+	if err := json.Unmarshal(message.Payload, response); err != nil {
+		d.logger.Debug("Invalid DHT response",
+			zap.String("peer_id", conn.PeerID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	d.mu.Lock()
+	responseCh, exists := d.pending[conn.PeerID+"/"+response.RequestID]
+	d.mu.Unlock()
+	if !exists {
+		return
+	}
+
+	select {
+	case responseCh <- response:
+	default:
+	}
+}
@@ -19,6 +19,8 @@ type DiscoveryService struct {
 	logger          *zap.Logger
 	mu              sync.RWMutex
 	peers           map[string]*Peer
+	sources         []DiscoverySource
+	dht             *DHT
 	stopChan        chan struct{}
 }
 
@@ -28,6 +30,11 @@ type Peer struct {
 	Status       PeerStatus
 	Capabilities []string
 	Version      string
+	// Source names the discovery source that found the peer. Verified is
+	// set once a handshake has supplied the peer's own metadata; until
+	// then Node holds whatever the source claimed.
+	Source   string
+	Verified bool
 }
 
 type PeerStatus string
@@ -47,6 +54,23 @@ func NewDiscoveryService(topology *TopologyManager, logger *zap.Logger) *Discove
 	}
 }
 
+// AddSource registers a source queried on every discovery round.
+func (ds *DiscoveryService) AddSource(source DiscoverySource) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ds.sources = append(ds.sources, source)
+}
+
+// AttachDHT adds the DHT as a discovery source. It replaces polling each
+// active peer's HTTP peer list, which does not scale past small networks.
+func (ds *DiscoveryService) AttachDHT(dht *DHT) {
+	ds.mu.Lock()
+	ds.dht = dht
+	ds.mu.Unlock()
+
+	ds.AddSource(dht)
+}
+
 func (ds *DiscoveryService) StartDiscovery(ctx context.Context) error {
 	ds.logger.Info("Starting network discovery service")
 
@@ -61,6 +85,7 @@ func (ds *DiscoveryService) peerDiscovery(ctx context.Context) {
 	ticker := time.NewTicker(2 * time.Minute)
 	defer ticker.Stop()
 
+	ds.discoverNewPeers(ctx)
 	for {
 		select {
 		case <-ctx.Done():
@@ -68,63 +93,48 @@ func (ds *DiscoveryService) peerDiscovery(ctx context.Context) {
 		case <-ds.stopChan:
 			return
 		case <-ticker.C:
-			ds.discoverNewPeers()
+			ds.discoverNewPeers(ctx)
 		}
 	}
 }
 
-func (ds *DiscoveryService) discoverNewPeers() {
+func (ds *DiscoveryService) discoverNewPeers(ctx context.Context) {
 	ds.logger.Debug("Discovering new peers")
 
-	ds.discoverFromDNSeeds()
-	ds.discoverFromKnownPeers()
-	ds.discoverFromBootstrapNodes()
-}
+	ds.discoverFromSources(ctx)
 
-func (ds *DiscoveryService) discoverFromDNSeeds() {
-	dnsSeeds := []string{
-		"seed1.relativistic-sdk.com",
-		"seed2.relativistic-sdk.com",
-		"seed3.relativistic-sdk.com",
+	ds.mu.RLock()
+	hasDHT := ds.dht != nil
+	ds.mu.RUnlock()
+	if !hasDHT {
+		ds.discoverFromKnownPeers()
 	}
+}
 
-	for _, seed := range dnsSeeds {
-		addrs, err := net.LookupHost(seed)
+func (ds *DiscoveryService) discoverFromSources(ctx context.Context) {
+	ds.mu.RLock()
+	sources := append([]DiscoverySource(nil), ds.sources...)
+	ds.mu.RUnlock()
+
+	for _, source := range sources {
+		sourceCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		peers, err := source.Discover(sourceCtx)
+		cancel()
 		if err != nil {
-			ds.logger.Debug("DNS seed lookup failed",
-				zap.String("seed", seed),
+			ds.logger.Debug("Discovery source failed",
+				zap.String("source", source.Name()),
 				zap.Error(err),
 			)
 			continue
 		}
 
-		for _, addr := range addrs {
-			peer := &Peer{
-				Node: &types.Node{
-					ID: fmt.Sprintf("dns-peer-%s", addr),
-					Position: types.Position{
-						Latitude:  0,
-						Longitude: 0,
-						Altitude:  0,
-					},
-					Address: fmt.Sprintf("%s:8080", addr),
-					Metadata: types.Metadata{
-						Region:       "unknown",
-						Provider:     "dns-seed",
-						Version:      "1.0.0",
-						Capabilities: []string{"blockchain", "consensus"},
-					},
-					IsActive: true,
-					LastSeen: time.Now().UTC(),
-				},
-				LastSeen:     time.Now().UTC(),
-				Status:       PeerPending,
-				Capabilities: []string{"blockchain", "consensus"},
-				Version:      "1.0.0",
-			}
-
+		for _, peer := range peers {
 			ds.AddPeer(peer)
 		}
+		ds.logger.Debug("Discovery source returned peers",
+			zap.String("source", source.Name()),
+			zap.Int("count", len(peers)),
+		)
 	}
 }
 
@@ -142,6 +152,13 @@ func (ds *DiscoveryService) discoverFromKnownPeers() {
 		}
 
 		for _, newPeer := range peerList {
+			if newPeer == nil || newPeer.Node == nil {
+				continue
+			}
+			// Another node's view of a peer is a claim like any other.
+			newPeer.Verified = false
+			newPeer.Status = PeerPending
+			newPeer.Source = "peer:" + peer.Node.ID
 			ds.AddPeer(newPeer)
 		}
 	}
@@ -172,42 +189,6 @@ func (ds *DiscoveryService) queryPeerForPeers(peer *Peer) ([]*Peer, error) {
 	return peerResponse.Peers, nil
 }
 
-func (ds *DiscoveryService) discoverFromBootstrapNodes() {
-	bootstrapNodes := []string{
-		"bootstrap1.relativistic-sdk.com:8080",
-		"bootstrap2.relativistic-sdk.com:8080",
-		"bootstrap3.relativistic-sdk.com:8080",
-	}
-
-	for i, addr := range bootstrapNodes {
-		peer := &Peer{
-			Node: &types.Node{
-				ID: fmt.Sprintf("bootstrap-%d", i+1),
-				Position: types.Position{
-					Latitude:  0,
-					Longitude: 0,
-					Altitude:  0,
-				},
-				Address: addr,
-				Metadata: types.Metadata{
-					Region:       "global",
-					Provider:     "bootstrap",
-					Version:      "1.0.0",
-					Capabilities: []string{"blockchain", "consensus", "bootstrap"},
-				},
-				IsActive: true,
-				LastSeen: time.Now().UTC(),
-			},
-			LastSeen:     time.Now().UTC(),
-			Status:       PeerConnected,
-			Capabilities: []string{"blockchain", "consensus", "bootstrap"},
-			Version:      "1.0.0",
-		}
-
-		ds.AddPeer(peer)
-	}
-}
-
 func (ds *DiscoveryService) healthCheck(ctx context.Context) {
 	ticker := time.NewTicker(30 * time.Second)
 	defer ticker.Stop()
@@ -244,17 +225,14 @@ func (ds *DiscoveryService) checkPeerHealth() {
 	}
 }
 
+// pingPeer reports whether the peer's peering address accepts connections.
 func (ds *DiscoveryService) pingPeer(peer *Peer) bool {
-	client := &http.Client{Timeout: 5 * time.Second}
-
-	url := fmt.Sprintf("http://%s/health", peer.Node.Address)
-	resp, err := client.Get(url)
+	conn, err := net.DialTimeout("tcp", peer.Node.Address, 5*time.Second)
 	if err != nil {
 		return false
 	}
-	defer resp.Body.Close()
-
-	return resp.StatusCode == http.StatusOK
+	conn.Close()
+	return true
 }
 
 func (ds *DiscoveryService) cleanupStalePeers(ctx context.Context) {
@@ -292,21 +270,34 @@ func (ds *DiscoveryService) removeStalePeers() {
 	}
 }
 
+// AddPeer records a peer. Only verified peers are written to the topology,
+// so positions a discovery source merely claims never reach timing
+// calculations; a rediscovered verified peer keeps its handshake metadata.
 func (ds *DiscoveryService) AddPeer(peer *Peer) {
 	ds.mu.Lock()
 	defer ds.mu.Unlock()
 
-	existingNode, err := ds.topologyManager.GetNode(peer.Node.ID)
-	if err != nil {
-		if err := ds.topologyManager.AddNode(peer.Node); err != nil {
-			ds.logger.Warn("Failed to add peer to topology",
-				zap.String("peer_id", peer.Node.ID),
-				zap.Error(err),
-			)
+	if existing, exists := ds.peers[peer.Node.ID]; exists && existing.Verified && !peer.Verified {
+		existing.LastSeen = time.Now().UTC()
+		if existing.Status == PeerDisconnected {
+			existing.Status = PeerPending
+		}
+		return
+	}
+
+	if peer.Verified && ds.topologyManager != nil {
+		existingNode, err := ds.topologyManager.GetNode(peer.Node.ID)
+		if err != nil {
+			if err := ds.topologyManager.AddNode(peer.Node); err != nil {
+				ds.logger.Warn("Failed to add peer to topology",
+					zap.String("peer_id", peer.Node.ID),
+					zap.Error(err),
+				)
+			}
+		} else {
+			existingNode.LastSeen = time.Now().UTC()
+			existingNode.IsActive = true
 		}
-	} else {
-		existingNode.LastSeen = time.Now().UTC()
-		existingNode.IsActive = true
 	}
 
 	ds.peers[peer.Node.ID] = peer
@@ -314,17 +305,42 @@ func (ds *DiscoveryService) AddPeer(peer *Peer) {
 	ds.logger.Debug("Peer added to discovery service",
 		zap.String("peer_id", peer.Node.ID),
 		zap.String("status", string(peer.Status)),
+		zap.String("source", peer.Source),
+		zap.Bool("verified", peer.Verified),
 	)
 }
 
+// MarkVerified replaces a peer's discovered metadata with the metadata it
+// sent in an authenticated handshake.
+func (ds *DiscoveryService) MarkVerified(node *types.Node) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	now := time.Now().UTC()
+	verified := *node
+	peer, exists := ds.peers[node.ID]
+	if !exists {
+		peer = &Peer{Source: "handshake"}
+		ds.peers[node.ID] = peer
+	}
+	peer.Node = &verified
+	peer.LastSeen = now
+	peer.Status = PeerConnected
+	peer.Capabilities = node.Metadata.Capabilities
+	peer.Version = node.Metadata.Version
+	peer.Verified = true
+}
+
 func (ds *DiscoveryService) RemovePeer(peerID string) {
 	ds.mu.Lock()
 	defer ds.mu.Unlock()
 
 	delete(ds.peers, peerID)
 
-	if node, err := ds.topologyManager.GetNode(peerID); err == nil {
-		node.IsActive = false
+	if ds.topologyManager != nil {
+		if node, err := ds.topologyManager.GetNode(peerID); err == nil {
+			node.IsActive = false
+		}
 	}
 
 	ds.logger.Debug("Peer removed from discovery service", zap.String("peer_id", peerID))
@@ -392,6 +408,9 @@ func (ds *DiscoveryService) GetDiscoveryStats() *DiscoveryStats {
 	for _, peer := range peers {
 		stats.StatusBreakdown[peer.Status]++
 		stats.RegionBreakdown[peer.Node.Metadata.Region]++
+		if !peer.Verified {
+			stats.UnverifiedPeers++
+		}
 	}
 
 	return stats
@@ -399,6 +418,7 @@ func (ds *DiscoveryService) GetDiscoveryStats() *DiscoveryStats {
 
 type DiscoveryStats struct {
 	TotalPeers      int
+	UnverifiedPeers int
 	StatusBreakdown map[PeerStatus]int
 	RegionBreakdown map[string]int
 	Timestamp       time.Time
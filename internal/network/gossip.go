@@ -0,0 +1,747 @@
+package network
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	MessageTypeGossip        MessageType = "gossip"
+	MessageTypeGossipControl MessageType = "gossip_control"
+)
+
+// Topics carried by the gossip layer.
+const (
+	TopicBlocks       = "blocks"
+	TopicVotes        = "votes"
+	TopicTransactions = "transactions"
+	TopicTopology     = "topology"
+)
+
+// GossipValidation is a topic handler's verdict on a received message.
+// Accepted messages are forwarded, ignored ones are dropped quietly and
+// rejected ones count against the peer that sent them.
+type GossipValidation int
+
+const (
+	GossipAccept GossipValidation = iota
+	GossipIgnore
+	GossipReject
+)
+
+// GossipHandler receives messages for a subscribed topic and decides
+// whether they are forwarded.
+type GossipHandler func(message *GossipMessage) GossipValidation
+
+// GossipMessage is signed by its origin's node identity, as in GossipSub:
+// Key is the origin's public key, which the origin ID must derive from, and
+// Signature covers the ID and publish time. Authenticated is set on
+// receipt once both check out. On networks without node identities
+// messages are unsigned and Origin is only a claim, so handlers that act on
+// who published a message must check Authenticated or authenticate the
+// payload themselves, as votes and proposals are by validator signatures.
+type GossipMessage struct {
+	ID          string    `json:"id"`
+	Topic       string    `json:"topic"`
+	Origin      string    `json:"origin"`
+	Seqno       uint64    `json:"seqno"`
+	Hops        int       `json:"hops"`
+	PublishedAt time.Time `json:"published_at"`
+	Data        []byte    `json:"data"`
+	Key         []byte    `json:"key,omitempty"`
+	Signature   []byte    `json:"signature,omitempty"`
+
+	ReceivedFrom  string `json:"-"`
+	Authenticated bool   `json:"-"`
+}
+
+// signedBytes is what the origin signs. Hops changes in flight and is left
+// out.
+func (m *GossipMessage) signedBytes() []byte {
+	data := make([]byte, 0, len(m.ID)+8)
+	data = append(data, m.ID...)
+	return binary.BigEndian.AppendUint64(data, uint64(m.PublishedAt.UnixNano()))
+}
+
+// verifySignature reports whether the message is signed by the identity
+// its origin ID derives from.
+func (m *GossipMessage) verifySignature() bool {
+	if len(m.Key) != ed25519.PublicKeySize || NodeIDFromPublicKey(m.Key) != m.Origin {
+		return false
+	}
+	return ed25519.Verify(m.Key, m.signedBytes(), m.Signature)
+}
+
+// gossipControl carries subscription changes, mesh membership changes and
+// lazy IHAVE/IWANT gossip, piggybacked into one frame.
+type gossipControl struct {
+	Subscribe   []string            `json:"subscribe,omitempty"`
+	Unsubscribe []string            `json:"unsubscribe,omitempty"`
+	Graft       []string            `json:"graft,omitempty"`
+	Prune       []string            `json:"prune,omitempty"`
+	IHave       map[string][]string `json:"ihave,omitempty"`
+	IWant       []string            `json:"iwant,omitempty"`
+}
+
+func (c *gossipControl) empty() bool {
+	return len(c.Subscribe) == 0 && len(c.Unsubscribe) == 0 && len(c.Graft) == 0 &&
+		len(c.Prune) == 0 && len(c.IHave) == 0 && len(c.IWant) == 0
+}
+
+type GossipConfig struct {
+	// D is the target mesh degree per topic; the mesh is rebuilt when it
+	// leaves [DLow, DHigh]. DLazy peers outside the mesh get IHAVE gossip.
+	D     int `json:"d"`
+	DLow  int `json:"d_low"`
+	DHigh int `json:"d_high"`
+	DLazy int `json:"d_lazy"`
+
+	HeartbeatInterval time.Duration `json:"heartbeat_interval"`
+	FanoutTTL         time.Duration `json:"fanout_ttl"`
+	SeenTTL           time.Duration `json:"seen_ttl"`
+	// HistoryLength heartbeats of messages are kept to answer IWANT; the
+	// newest HistoryGossip of them are advertised in IHAVE.
+	HistoryLength int `json:"history_length"`
+	HistoryGossip int `json:"history_gossip"`
+
+	MaxHops    int           `json:"max_hops"`
+	MessageTTL time.Duration `json:"message_ttl"`
+	// StrictSigning rejects unsigned messages. Messages are signed whenever
+	// the peering manager has a node identity; a bad signature is always
+	// rejected.
+	StrictSigning bool `json:"strict_signing"`
+
+	FirstDeliveryReward float64 `json:"first_delivery_reward"`
+	InvalidPenalty      float64 `json:"invalid_penalty"`
+	ScoreDecay          float64 `json:"score_decay"`
+	MaxScore            float64 `json:"max_score"`
+	// Peers below PruneThreshold are removed from meshes; below
+	// GraylistThreshold their messages are dropped unread.
+	PruneThreshold    float64 `json:"prune_threshold"`
+	GraylistThreshold float64 `json:"graylist_threshold"`
+}
+
+func DefaultGossipConfig() *GossipConfig {
+	return &GossipConfig{
+		D:                   6,
+		DLow:                4,
+		DHigh:               12,
+		DLazy:               6,
+		HeartbeatInterval:   time.Second,
+		FanoutTTL:           time.Minute,
+		SeenTTL:             2 * time.Minute,
+		HistoryLength:       5,
+		HistoryGossip:       3,
+		MaxHops:             10,
+		MessageTTL:          2 * time.Minute,
+		FirstDeliveryReward: 1,
+		InvalidPenalty:      10,
+		ScoreDecay:          0.9,
+		MaxScore:            100,
+		PruneThreshold:      -5,
+		GraylistThreshold:   -20,
+	}
+}
+
+type GossipMetrics struct {
+	Published  int64 `json:"published"`
+	Delivered  int64 `json:"delivered"`
+	Forwarded  int64 `json:"forwarded"`
+	Duplicates int64 `json:"duplicates"`
+	Ignored    int64 `json:"ignored"`
+	Rejected   int64 `json:"rejected"`
+	Graylisted int64 `json:"graylisted"`
+}
+
+// GossipRouter is a topic-based pub/sub layer over peering, modelled on
+// GossipSub: messages are pushed eagerly to a bounded mesh of peers per
+// topic, advertised lazily to others with IHAVE, deduplicated by message ID
+// and dropped after MaxHops forwards. Peers are scored on what they deliver
+// and badly scored peers are pruned and eventually ignored.
+type GossipRouter struct {
+	peering  *PeeringManager
+	config   *GossipConfig
+	logger   *zap.Logger
+	localID  string
+	identity *NodeIdentity
+
+	mu          sync.Mutex
+	seqno       uint64
+	handlers    map[string]GossipHandler
+	peerTopics  map[string]map[string]bool
+	announced   map[string]bool
+	mesh        map[string]map[string]bool
+	fanout      map[string]map[string]bool
+	lastPublish map[string]time.Time
+	seen        map[string]time.Time
+	history     []map[string]*GossipMessage
+	scores      map[string]float64
+	metrics     GossipMetrics
+	stopChan    chan struct{}
+	stopOnce    sync.Once
+}
+
+func NewGossipRouter(peering *PeeringManager, config *GossipConfig, logger *zap.Logger) *GossipRouter {
+	if config == nil {
+		config = DefaultGossipConfig()
+	}
+
+	gr := &GossipRouter{
+		peering:     peering,
+		config:      config,
+		logger:      logger,
+		localID:     peering.LocalPeerID(),
+		identity:    peering.config.Identity,
+		handlers:    make(map[string]GossipHandler),
+		peerTopics:  make(map[string]map[string]bool),
+		announced:   make(map[string]bool),
+		mesh:        make(map[string]map[string]bool),
+		fanout:      make(map[string]map[string]bool),
+		lastPublish: make(map[string]time.Time),
+		seen:        make(map[string]time.Time),
+		history:     []map[string]*GossipMessage{make(map[string]*GossipMessage)},
+		scores:      make(map[string]float64),
+		stopChan:    make(chan struct{}),
+	}
+
+	peering.RegisterHandler(MessageTypeGossip, gr.handleGossip)
+	peering.RegisterHandler(MessageTypeGossipControl, gr.handleControl)
+	return gr
+}
+
+func (gr *GossipRouter) Start(ctx context.Context) {
+	ticker := time.NewTicker(gr.config.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-gr.stopChan:
+			return
+		case <-ticker.C:
+			gr.heartbeat()
+		}
+	}
+}
+
+func (gr *GossipRouter) Stop() {
+	gr.stopOnce.Do(func() { close(gr.stopChan) })
+}
+
+// GossipMessageID identifies a message by its origin, sequence number,
+// topic and content, so every node derives the same ID independently.
+func GossipMessageID(origin string, seqno uint64, topic string, data []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(origin))
+	binary.Write(hash, binary.BigEndian, seqno)
+	hash.Write([]byte(topic))
+	hash.Write(data)
+	return hex.EncodeToString(hash.Sum(nil)[:20])
+}
+
+// Subscribe joins a topic. Messages received on it are passed to handler,
+// and the mesh for it is built at the next heartbeat.
+func (gr *GossipRouter) Subscribe(topic string, handler GossipHandler) {
+	gr.mu.Lock()
+	_, existing := gr.handlers[topic]
+	gr.handlers[topic] = handler
+	if _, ok := gr.mesh[topic]; !ok {
+		gr.mesh[topic] = make(map[string]bool)
+		delete(gr.fanout, topic)
+		delete(gr.lastPublish, topic)
+	}
+	peers := setKeys(gr.announced)
+	gr.mu.Unlock()
+
+	if existing {
+		return
+	}
+	for _, peerID := range peers {
+		gr.sendControl(peerID, &gossipControl{Subscribe: []string{topic}})
+	}
+}
+
+func (gr *GossipRouter) Unsubscribe(topic string) {
+	gr.mu.Lock()
+	if _, exists := gr.handlers[topic]; !exists {
+		gr.mu.Unlock()
+		return
+	}
+	delete(gr.handlers, topic)
+	meshPeers := gr.mesh[topic]
+	delete(gr.mesh, topic)
+	peers := setKeys(gr.announced)
+	gr.mu.Unlock()
+
+	for _, peerID := range peers {
+		control := &gossipControl{Unsubscribe: []string{topic}}
+		if meshPeers[peerID] {
+			control.Prune = []string{topic}
+		}
+		gr.sendControl(peerID, control)
+	}
+}
+
+// Publish sends data on a topic and returns the message ID. Without a
+// subscription the message goes to fanout peers subscribed to the topic.
+func (gr *GossipRouter) Publish(topic string, data []byte) (string, error) {
+	gr.mu.Lock()
+	gr.seqno++
+	message := &GossipMessage{
+		Topic:       topic,
+		Origin:      gr.localID,
+		Seqno:       gr.seqno,
+		PublishedAt: time.Now().UTC(),
+		Data:        data,
+	}
+	message.ID = GossipMessageID(message.Origin, message.Seqno, topic, data)
+	if gr.identity != nil {
+		message.Key = gr.identity.PublicKey()
+		message.Signature = gr.identity.Sign(message.signedBytes())
+	}
+
+	gr.seen[message.ID] = time.Now().Add(gr.config.SeenTTL)
+	gr.history[0][message.ID] = message
+	gr.metrics.Published++
+
+	var peers map[string]bool
+	if _, subscribed := gr.handlers[topic]; subscribed {
+		peers = gr.mesh[topic]
+		if len(peers) == 0 {
+			peers = gr.selectPeers(topic, gr.config.D, nil)
+		}
+	} else {
+		if len(gr.fanout[topic]) == 0 {
+			gr.fanout[topic] = gr.selectPeers(topic, gr.config.D, nil)
+		}
+		gr.lastPublish[topic] = time.Now()
+		peers = gr.fanout[topic]
+	}
+	targets := setKeys(peers)
+	gr.mu.Unlock()
+
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode gossip message: %w", err)
+	}
+	if len(targets) == 0 {
+		gr.logger.Debug("No peers for gossip topic",
+			zap.String("topic", topic),
+			zap.String("message_id", message.ID),
+		)
+	}
+	gr.send(targets, payload)
+	return message.ID, nil
+}
+
+func (gr *GossipRouter) handleGossip(conn *PeerConnection, peerMessage *PeerMessage) {
+	from := conn.PeerID
+
+	gr.mu.Lock()
+	if gr.scores[from] < gr.config.GraylistThreshold {
+		gr.metrics.Graylisted++
+		gr.mu.Unlock()
+		return
+	}
+	gr.mu.Unlock()
+
+	message := &GossipMessage{}
+	if err := json.Unmarshal(peerMessage.Payload, message); err != nil {
+		gr.penalize(from, "malformed message")
+		return
+	}
+	if message.ID != GossipMessageID(message.Origin, message.Seqno, message.Topic, message.Data) {
+		gr.penalize(from, "message ID mismatch")
+		return
+	}
+	switch {
+	case len(message.Signature) > 0:
+		if !message.verifySignature() {
+			gr.penalize(from, "bad message signature")
+			return
+		}
+		message.Authenticated = true
+	case gr.config.StrictSigning:
+		gr.penalize(from, "unsigned message")
+		return
+	}
+	message.ReceivedFrom = from
+
+	gr.mu.Lock()
+	if _, seen := gr.seen[message.ID]; seen {
+		gr.metrics.Duplicates++
+		gr.mu.Unlock()
+		return
+	}
+	gr.seen[message.ID] = time.Now().Add(gr.config.SeenTTL)
+	handler, subscribed := gr.handlers[message.Topic]
+	age := time.Since(message.PublishedAt)
+	if !subscribed || message.Hops > gr.config.MaxHops || age > gr.config.MessageTTL || age < -gr.config.MessageTTL {
+		gr.metrics.Ignored++
+		gr.mu.Unlock()
+		return
+	}
+	gr.mu.Unlock()
+
+	switch handler(message) {
+	case GossipReject:
+		gr.penalize(from, "rejected by topic handler")
+		return
+	case GossipIgnore:
+		gr.mu.Lock()
+		gr.metrics.Ignored++
+		gr.mu.Unlock()
+		return
+	}
+
+	gr.mu.Lock()
+	gr.metrics.Delivered++
+	gr.reward(from)
+	gr.history[0][message.ID] = message
+
+	var targets []string
+	if message.Hops < gr.config.MaxHops {
+		for peerID := range gr.mesh[message.Topic] {
+			if peerID != from && peerID != message.Origin {
+				targets = append(targets, peerID)
+			}
+		}
+	}
+	gr.metrics.Forwarded += int64(len(targets))
+	gr.mu.Unlock()
+
+	if len(targets) == 0 {
+		return
+	}
+
+	forward := *message
+	forward.Hops++
+	payload, err := json.Marshal(&forward)
+	if err != nil {
+		return
+	}
+	gr.send(targets, payload)
+}
+
+func (gr *GossipRouter) handleControl(conn *PeerConnection, peerMessage *PeerMessage) {
+	from := conn.PeerID
+
+	control := &gossipControl{}
+	if err := json.Unmarshal(peerMessage.Payload, control); err != nil {
+		gr.penalize(from, "malformed control message")
+		return
+	}
+
+	reply := &gossipControl{}
+	var wanted []*GossipMessage
+
+	gr.mu.Lock()
+	topics := gr.peerTopics[from]
+	if topics == nil {
+		topics = make(map[string]bool)
+		gr.peerTopics[from] = topics
+	}
+	for _, topic := range control.Subscribe {
+		topics[topic] = true
+	}
+	for _, topic := range control.Unsubscribe {
+		delete(topics, topic)
+		delete(gr.mesh[topic], from)
+		delete(gr.fanout[topic], from)
+	}
+
+	for _, topic := range control.Graft {
+		topics[topic] = true
+		mesh, subscribed := gr.mesh[topic]
+		if !subscribed || gr.scores[from] < gr.config.PruneThreshold || len(mesh) >= gr.config.DHigh {
+			reply.Prune = append(reply.Prune, topic)
+			continue
+		}
+		mesh[from] = true
+	}
+	for _, topic := range control.Prune {
+		delete(gr.mesh[topic], from)
+	}
+
+	if gr.scores[from] >= gr.config.PruneThreshold {
+		for topic, ids := range control.IHave {
+			if _, subscribed := gr.handlers[topic]; !subscribed {
+				continue
+			}
+			for _, id := range ids {
+				if _, seen := gr.seen[id]; !seen {
+					reply.IWant = append(reply.IWant, id)
+				}
+			}
+		}
+		for _, id := range control.IWant {
+			if message := gr.cached(id); message != nil {
+				wanted = append(wanted, message)
+			}
+		}
+	}
+	gr.mu.Unlock()
+
+	if !reply.empty() {
+		gr.sendControl(from, reply)
+	}
+	for _, message := range wanted {
+		payload, err := json.Marshal(message)
+		if err != nil {
+			continue
+		}
+		gr.send([]string{from}, payload)
+	}
+}
+
+// heartbeat maintains the meshes and fanout sets, emits IHAVE gossip and
+// ages the caches and scores.
+func (gr *GossipRouter) heartbeat() {
+	connected := make(map[string]bool)
+	for _, conn := range gr.peering.GetActiveConnections() {
+		connected[conn.PeerID] = true
+	}
+
+	controls := make(map[string]*gossipControl)
+	control := func(peerID string) *gossipControl {
+		if controls[peerID] == nil {
+			controls[peerID] = &gossipControl{}
+		}
+		return controls[peerID]
+	}
+
+	gr.mu.Lock()
+	for peerID := range gr.peerTopics {
+		if !connected[peerID] {
+			delete(gr.peerTopics, peerID)
+			delete(gr.announced, peerID)
+		}
+	}
+	for peerID := range connected {
+		if !gr.announced[peerID] {
+			gr.announced[peerID] = true
+			for topic := range gr.handlers {
+				control(peerID).Subscribe = append(control(peerID).Subscribe, topic)
+			}
+		}
+	}
+
+	for topic, mesh := range gr.mesh {
+		for peerID := range mesh {
+			if !connected[peerID] || !gr.peerTopics[peerID][topic] {
+				delete(mesh, peerID)
+			} else if gr.scores[peerID] < gr.config.PruneThreshold {
+				delete(mesh, peerID)
+				control(peerID).Prune = append(control(peerID).Prune, topic)
+			}
+		}
+
+		if len(mesh) < gr.config.DLow {
+			for peerID := range gr.selectPeers(topic, gr.config.D-len(mesh), mesh) {
+				mesh[peerID] = true
+				control(peerID).Graft = append(control(peerID).Graft, topic)
+			}
+		}
+
+		if len(mesh) > gr.config.DHigh {
+			ranked := gr.rank(setKeys(mesh))
+			for _, peerID := range ranked[gr.config.D:] {
+				delete(mesh, peerID)
+				control(peerID).Prune = append(control(peerID).Prune, topic)
+			}
+		}
+	}
+
+	now := time.Now()
+	for topic, peers := range gr.fanout {
+		if now.Sub(gr.lastPublish[topic]) > gr.config.FanoutTTL {
+			delete(gr.fanout, topic)
+			delete(gr.lastPublish, topic)
+			continue
+		}
+		for peerID := range peers {
+			if !connected[peerID] || !gr.peerTopics[peerID][topic] || gr.scores[peerID] < gr.config.PruneThreshold {
+				delete(peers, peerID)
+			}
+		}
+		if len(peers) < gr.config.D {
+			for peerID := range gr.selectPeers(topic, gr.config.D-len(peers), peers) {
+				peers[peerID] = true
+			}
+		}
+	}
+
+	gossip := make(map[string][]string)
+	for i := 0; i < len(gr.history) && i < gr.config.HistoryGossip; i++ {
+		for id, message := range gr.history[i] {
+			gossip[message.Topic] = append(gossip[message.Topic], id)
+		}
+	}
+	for topic, ids := range gossip {
+		exclude := make(map[string]bool)
+		for peerID := range gr.mesh[topic] {
+			exclude[peerID] = true
+		}
+		for peerID := range gr.fanout[topic] {
+			exclude[peerID] = true
+		}
+		for peerID := range gr.selectPeers(topic, gr.config.DLazy, exclude) {
+			c := control(peerID)
+			if c.IHave == nil {
+				c.IHave = make(map[string][]string)
+			}
+			c.IHave[topic] = ids
+		}
+	}
+
+	gr.history = append([]map[string]*GossipMessage{make(map[string]*GossipMessage)}, gr.history...)
+	if len(gr.history) > gr.config.HistoryLength {
+		gr.history = gr.history[:gr.config.HistoryLength]
+	}
+	for id, expiry := range gr.seen {
+		if now.After(expiry) {
+			delete(gr.seen, id)
+		}
+	}
+	for peerID, score := range gr.scores {
+		score *= gr.config.ScoreDecay
+		if score > -0.01 && score < 0.01 && !connected[peerID] {
+			delete(gr.scores, peerID)
+			continue
+		}
+		gr.scores[peerID] = score
+	}
+	gr.mu.Unlock()
+
+	for peerID, c := range controls {
+		gr.sendControl(peerID, c)
+	}
+}
+
+// selectPeers picks up to n connected peers subscribed to topic and not in
+// exclude, preferring higher scores. Callers hold gr.mu.
+func (gr *GossipRouter) selectPeers(topic string, n int, exclude map[string]bool) map[string]bool {
+	var candidates []string
+	for peerID, topics := range gr.peerTopics {
+		if topics[topic] && !exclude[peerID] && gr.scores[peerID] >= gr.config.PruneThreshold {
+			candidates = append(candidates, peerID)
+		}
+	}
+
+	selected := make(map[string]bool)
+	for _, peerID := range gr.rank(candidates) {
+		if len(selected) >= n {
+			break
+		}
+		selected[peerID] = true
+	}
+	return selected
+}
+
+// rank orders peers by descending score, breaking ties randomly so meshes
+// do not all converge on the same peers.
+func (gr *GossipRouter) rank(peers []string) []string {
+	rand.Shuffle(len(peers), func(i, j int) { peers[i], peers[j] = peers[j], peers[i] })
+	sort.SliceStable(peers, func(i, j int) bool {
+		return gr.scores[peers[i]] > gr.scores[peers[j]]
+	})
+	return peers
+}
+
+func (gr *GossipRouter) cached(id string) *GossipMessage {
+	for _, window := range gr.history {
+		if message, exists := window[id]; exists {
+			return message
+		}
+	}
+	return nil
+}
+
+// reward credits a peer for a first delivery. Callers hold gr.mu.
+func (gr *GossipRouter) reward(peerID string) {
+	score := gr.scores[peerID] + gr.config.FirstDeliveryReward
+	if score > gr.config.MaxScore {
+		score = gr.config.MaxScore
+	}
+	gr.scores[peerID] = score
+}
+
+func (gr *GossipRouter) penalize(peerID, reason string) {
+	gr.mu.Lock()
+	gr.scores[peerID] -= gr.config.InvalidPenalty
+	gr.metrics.Rejected++
+	score := gr.scores[peerID]
+	gr.mu.Unlock()
+
+	gr.logger.Debug("Penalized gossip peer",
+		zap.String("peer_id", peerID),
+		zap.String("reason", reason),
+		zap.Float64("score", score),
+	)
+}
+
+func (gr *GossipRouter) send(peers []string, payload []byte) {
+	for _, peerID := range peers {
+		if err := gr.peering.SendPeerMessage(peerID, MessageTypeGossip, payload); err != nil {
+			gr.logger.Debug("Failed to send gossip message",
+				zap.String("peer_id", peerID),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+func (gr *GossipRouter) sendControl(peerID string, control *gossipControl) {
+	payload, err := json.Marshal(control)
+	if err != nil {
+		return
+	}
+	if err := gr.peering.SendPeerMessage(peerID, MessageTypeGossipControl, payload); err != nil {
+		gr.logger.Debug("Failed to send gossip control",
+			zap.String("peer_id", peerID),
+			zap.Error(err),
+		)
+	}
+}
+
+func (gr *GossipRouter) PeerScore(peerID string) float64 {
+	gr.mu.Lock()
+	defer gr.mu.Unlock()
+	return gr.scores[peerID]
+}
+
+// MeshPeers returns the peers in the mesh for a subscribed topic.
+func (gr *GossipRouter) MeshPeers(topic string) []string {
+	gr.mu.Lock()
+	defer gr.mu.Unlock()
+
+	peers := setKeys(gr.mesh[topic])
+	sort.Strings(peers)
+	return peers
+}
+
+func (gr *GossipRouter) GetMetrics() GossipMetrics {
+	gr.mu.Lock()
+	defer gr.mu.Unlock()
+	return gr.metrics
+}
+
+func setKeys(set map[string]bool) []string {
+	result := make([]string, 0, len(set))
+	for key := range set {
+		result = append(result, key)
+	}
+	return result
+}
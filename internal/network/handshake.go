@@ -0,0 +1,254 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/relativistic"
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
+)
+
+// SupportedProtocolVersions lists the peer protocol versions this build
+// speaks, oldest first.
+var SupportedProtocolVersions = []uint32{1}
+
+const DefaultRTTTolerance = 2 * time.Millisecond
+
+// HandshakePayload is the body of handshake messages. The dialler sends
+// the versions it supports; the reply also carries the version chosen.
+// Transient marks a request session, which the accepting side counts
+// against its request limit instead of MaxPeers.
+type HandshakePayload struct {
+	Node             *types.Node `json:"node"`
+	ProtocolVersions []uint32    `json:"protocol_versions"`
+	ProtocolVersion  uint32      `json:"protocol_version,omitempty"`
+	Transient        bool        `json:"transient,omitempty"`
+}
+
+// NegotiateProtocolVersion returns the highest version both sides support.
+func NegotiateProtocolVersion(local, remote []uint32) (uint32, error) {
+	supported := make(map[uint32]bool, len(local))
+	for _, version := range local {
+		supported[version] = true
+	}
+
+	var best uint32
+	for _, version := range remote {
+		if supported[version] && version > best {
+			best = version
+		}
+	}
+	if best == 0 {
+		return 0, fmt.Errorf("no common protocol version: local %v, remote %v", local, remote)
+	}
+	return best, nil
+}
+
+// MinimumRTT is the round trip light needs between two positions. No
+// measured RTT can be shorter, so a peer whose RTT is below it cannot be
+// where it claims.
+func MinimumRTT(a, b types.Position) time.Duration {
+	calculator := relativistic.NewCalculator()
+	return 2 * calculator.CalculateLightDelay(calculator.CalculateDistance(a, b))
+}
+
+// CheckPositionPlausibility reports whether a measured RTT is consistent
+// with the claimed position, allowing tolerance for position error.
+func CheckPositionPlausibility(local, claimed types.Position, rtt, tolerance time.Duration) error {
+	if minimum := MinimumRTT(local, claimed); rtt+tolerance < minimum {
+		return fmt.Errorf("measured RTT %v is below the %v light round trip to the claimed position", rtt, minimum)
+	}
+	return nil
+}
+
+func (pm *PeeringManager) localNode() *types.Node {
+	if configured := pm.configuredNode(); configured != nil {
+		node := *configured
+		node.ID = pm.localPeerID
+		return &node
+	}
+	return &types.Node{ID: pm.localPeerID}
+}
+
+// configuredNode is the local node record, or nil when no position is
+// configured.
+func (pm *PeeringManager) configuredNode() *types.Node {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return pm.config.Node
+}
+
+func (pm *PeeringManager) handshakeMessage(negotiated uint32, transient bool) (*PeerMessage, error) {
+	payload, err := json.Marshal(&HandshakePayload{
+		Node:             pm.localNode(),
+		ProtocolVersions: pm.config.ProtocolVersions,
+		ProtocolVersion:  negotiated,
+		Transient:        transient,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode handshake: %w", err)
+	}
+
+	return &PeerMessage{
+		Type:      MessageTypeHandshake,
+		PeerID:    pm.localPeerID,
+		Timestamp: time.Now().UTC(),
+		Payload:   payload,
+	}, nil
+}
+
+// parseHandshake decodes a handshake and negotiates the protocol version.
+// The node described must be the one that sent the message.
+func (pm *PeeringManager) parseHandshake(message *PeerMessage) (*HandshakePayload, uint32, error) {
+	payload := &HandshakePayload{}
+	if err := json.Unmarshal(message.Payload, payload); err != nil {
+		return nil, 0, fmt.Errorf("invalid handshake payload: %w", err)
+	}
+	if payload.Node == nil || payload.Node.ID != message.PeerID {
+		return nil, 0, fmt.Errorf("handshake node does not match sender %s", message.PeerID)
+	}
+
+	version, err := NegotiateProtocolVersion(pm.config.ProtocolVersions, payload.ProtocolVersions)
+	if err != nil {
+		return nil, 0, err
+	}
+	if payload.ProtocolVersion != 0 && payload.ProtocolVersion != version {
+		return nil, 0, fmt.Errorf("peer chose protocol version %d, expected %d", payload.ProtocolVersion, version)
+	}
+	return payload, version, nil
+}
+
+func (pm *PeeringManager) sendHandshake(conn *PeerConnection, negotiated uint32) error {
+	pm.mu.RLock()
+	transient := conn.Transient
+	pm.mu.RUnlock()
+
+	message, err := pm.handshakeMessage(negotiated, transient)
+	if err != nil {
+		return err
+	}
+
+	pm.mu.Lock()
+	conn.handshakeSentAt = time.Now()
+	pm.mu.Unlock()
+
+	return pm.sendMessageToConnection(conn, message)
+}
+
+// handleHandshakeReply runs on the dialling side when the remote answers
+// our handshake; the time since ours was sent is the RTT.
+func (pm *PeeringManager) handleHandshakeReply(conn *PeerConnection, message *PeerMessage) {
+	payload, version, err := pm.parseHandshake(message)
+	if err != nil {
+		pm.logger.Warn("Refusing peer after handshake",
+			zap.String("peer_id", conn.PeerID),
+			zap.Error(err),
+		)
+		pm.Disconnect(conn.PeerID)
+		return
+	}
+
+	pm.mu.Lock()
+	rtt := message.ReceivedAt.Sub(conn.handshakeSentAt)
+	conn.ProtocolVersion = version
+	conn.RemoteNode = payload.Node
+	pm.mu.Unlock()
+
+	ack := &PeerMessage{
+		Type:      MessageTypeHandshakeAck,
+		PeerID:    pm.localPeerID,
+		Timestamp: time.Now().UTC(),
+	}
+	if err := pm.sendMessageToConnection(conn, ack); err != nil {
+		pm.logger.Warn("Failed to send handshake ack",
+			zap.String("peer_id", conn.PeerID),
+			zap.Error(err),
+		)
+	}
+
+	pm.completeHandshake(conn, rtt)
+}
+
+// handleHandshakeAck runs on the accepting side, which measures the RTT
+// from its handshake reply to the ack.
+func (pm *PeeringManager) handleHandshakeAck(conn *PeerConnection, message *PeerMessage) {
+	pm.mu.Lock()
+	if conn.handshakeSentAt.IsZero() || conn.RTT != 0 {
+		pm.mu.Unlock()
+		return
+	}
+	rtt := message.ReceivedAt.Sub(conn.handshakeSentAt)
+	pm.mu.Unlock()
+
+	pm.completeHandshake(conn, rtt)
+}
+
+// completeHandshake records the RTT and, if the peer's claimed position is
+// consistent with it, marks the peer verified in discovery and writes its
+// node data to the topology. Only authenticated sessions get that far,
+// since without an identity any peer could claim any node ID.
+func (pm *PeeringManager) completeHandshake(conn *PeerConnection, rtt time.Duration) {
+	pm.mu.Lock()
+	conn.RTT = rtt
+	remote := conn.RemoteNode
+	authenticated := conn.Authenticated
+	verifier := pm.verifier
+	local := pm.config.Node
+	pm.mu.Unlock()
+
+	if verifier != nil && verifier.IsAnchor(pm.localPeerID) {
+		if err := verifier.RecordRTT(pm.localPeerID, conn.PeerID, rtt); err != nil {
+			pm.logger.Debug("Failed to record anchor RTT",
+				zap.String("peer_id", conn.PeerID),
+				zap.Error(err),
+			)
+		}
+	}
+
+	if remote == nil {
+		return
+	}
+
+	if local == nil {
+		pm.logger.Debug("No local position configured, peer position left unverified",
+			zap.String("peer_id", conn.PeerID),
+		)
+		return
+	}
+
+	if err := CheckPositionPlausibility(local.Position, remote.Position, rtt, pm.config.RTTTolerance); err != nil {
+		pm.logger.Warn("Peer position is implausible",
+			zap.String("peer_id", conn.PeerID),
+			zap.Float64("claimed_lat", remote.Position.Latitude),
+			zap.Float64("claimed_lon", remote.Position.Longitude),
+			zap.Error(err),
+		)
+		pm.reportMisbehavior(conn.PeerID, implausiblePositionPenalty, "implausible position")
+		return
+	}
+
+	pm.mu.Lock()
+	conn.PositionVerified = true
+	pm.mu.Unlock()
+
+	if !authenticated {
+		return
+	}
+	if pm.discoveryService != nil {
+		pm.discoveryService.MarkVerified(remote)
+	}
+	pm.announceLocalNode()
+	if pm.topologyManager == nil {
+		return
+	}
+	node := *remote
+	if err := pm.topologyManager.UpsertNode(&node); err != nil {
+		pm.logger.Warn("Failed to update topology from handshake",
+			zap.String("peer_id", conn.PeerID),
+			zap.Error(err),
+		)
+	}
+}
@@ -0,0 +1,182 @@
+package network
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// NodeIDLength is the number of public key hash bytes in a node ID.
+const NodeIDLength = 20
+
+// NodeIdentity is a node's long-lived ed25519 keypair. The node ID is
+// derived from the public key, so a peer can only claim an ID by proving
+// it holds the matching private key in the TLS handshake.
+type NodeIdentity struct {
+	ID          string
+	privateKey  ed25519.PrivateKey
+	certificate tls.Certificate
+}
+
+// NodeIDFromPublicKey is the hex encoding of the first NodeIDLength bytes
+// of the SHA-256 of the public key.
+func NodeIDFromPublicKey(publicKey ed25519.PublicKey) string {
+	sum := sha256.Sum256(publicKey)
+	return hex.EncodeToString(sum[:NodeIDLength])
+}
+
+func GenerateIdentity() (*NodeIdentity, error) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate identity key: %w", err)
+	}
+	return newNodeIdentity(privateKey)
+}
+
+// LoadOrCreateIdentity reads the PEM-encoded identity key at path, creating
+// it with a fresh key if it does not exist.
+func LoadOrCreateIdentity(path string) (*NodeIdentity, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		identity, err := GenerateIdentity()
+		if err != nil {
+			return nil, err
+		}
+		if err := identity.Save(path); err != nil {
+			return nil, err
+		}
+		return identity, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read identity file: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("identity file %s does not contain a PEM private key", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse identity key: %w", err)
+	}
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("identity key is %T, expected ed25519", key)
+	}
+	return newNodeIdentity(privateKey)
+}
+
+func (ni *NodeIdentity) Save(path string) error {
+	der, err := x509.MarshalPKCS8PrivateKey(ni.privateKey)
+	if err != nil {
+		return fmt.Errorf("failed to encode identity key: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create identity directory: %w", err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write identity file: %w", err)
+	}
+	return nil
+}
+
+func (ni *NodeIdentity) PublicKey() ed25519.PublicKey {
+	return ni.privateKey.Public().(ed25519.PublicKey)
+}
+
+// Sign signs data with the identity key.
+func (ni *NodeIdentity) Sign(data []byte) []byte {
+	return ed25519.Sign(ni.privateKey, data)
+}
+
+func newNodeIdentity(privateKey ed25519.PrivateKey) (*NodeIdentity, error) {
+	publicKey := privateKey.Public().(ed25519.PublicKey)
+	id := NodeIDFromPublicKey(publicKey)
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate certificate serial: %w", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: id},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(10 * 365 * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, publicKey, privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create identity certificate: %w", err)
+	}
+
+	return &NodeIdentity{
+		ID:         id,
+		privateKey: privateKey,
+		certificate: tls.Certificate{
+			Certificate: [][]byte{der},
+			PrivateKey:  privateKey,
+		},
+	}, nil
+}
+
+// TLSConfig returns a mutual TLS configuration using the self-signed
+// identity certificate. Certificates are not checked against a CA; instead
+// the peer's node ID is derived from its certificate key and, when
+// expectedPeerID is set, must match it.
+func (ni *NodeIdentity) TLSConfig(expectedPeerID string) *tls.Config {
+	return &tls.Config{
+		Certificates:       []tls.Certificate{ni.certificate},
+		MinVersion:         tls.VersionTLS13,
+		ClientAuth:         tls.RequireAnyClientCert,
+		InsecureSkipVerify: true,
+		VerifyConnection: func(state tls.ConnectionState) error {
+			peerID, err := PeerIDFromConnectionState(state)
+			if err != nil {
+				return err
+			}
+			if expectedPeerID != "" && peerID != expectedPeerID {
+				return fmt.Errorf("peer presented identity %s, expected %s", peerID, expectedPeerID)
+			}
+			return nil
+		},
+	}
+}
+
+// PeerIDFromConnectionState verifies the peer's self-signed certificate and
+// derives its node ID from the certificate key.
+func PeerIDFromConnectionState(state tls.ConnectionState) (string, error) {
+	if len(state.PeerCertificates) == 0 {
+		return "", fmt.Errorf("peer presented no certificate")
+	}
+
+	cert := state.PeerCertificates[0]
+	publicKey, ok := cert.PublicKey.(ed25519.PublicKey)
+	if !ok {
+		return "", fmt.Errorf("peer certificate key is %T, expected ed25519", cert.PublicKey)
+	}
+	if err := cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature); err != nil {
+		return "", fmt.Errorf("peer certificate is not self-signed: %w", err)
+	}
+	now := time.Now()
+	if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		return "", fmt.Errorf("peer certificate is not valid at %s", now.Format(time.RFC3339))
+	}
+
+	peerID := NodeIDFromPublicKey(publicKey)
+	if cert.Subject.CommonName != peerID {
+		return "", fmt.Errorf("peer certificate names %s but its key belongs to %s", cert.Subject.CommonName, peerID)
+	}
+	return peerID, nil
+}
@@ -2,9 +2,11 @@ package network
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"math"
-	"net"
+	"sort"
+	"strconv"
 	"sync"
 	"time"
 
@@ -13,14 +15,83 @@ import (
 	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
 )
 
+// ProbeConfig controls latency probing over peer sessions. Each round
+// sends ProbesPerLink timestamped keepalives on up to Budget links; when a
+// node has more sessions than that, the links probed longest ago go first,
+// so every link is covered over successive rounds.
+type ProbeConfig struct {
+	Interval      time.Duration `json:"interval"`
+	Budget        int           `json:"budget"`
+	ProbesPerLink int           `json:"probes_per_link"`
+	ProbeSpacing  time.Duration `json:"probe_spacing"`
+	ProbeTimeout  time.Duration `json:"probe_timeout"`
+	// MaxProcessing caps the processing time a peer may claim between
+	// receiving a probe and replying; anything beyond it is counted as
+	// network delay, so a peer cannot make itself look faster by padding it.
+	MaxProcessing time.Duration `json:"max_processing"`
+	// Window is the number of recent probes per link that percentiles,
+	// histograms and loss are computed over.
+	Window int `json:"window"`
+	// HistogramBounds are the upper bounds of the RTT histogram buckets;
+	// a final bucket catches everything above the last bound.
+	HistogramBounds []time.Duration `json:"histogram_bounds"`
+}
+
+func DefaultProbeConfig() *ProbeConfig {
+	return &ProbeConfig{
+		Interval:      30 * time.Second,
+		Budget:        32,
+		ProbesPerLink: 5,
+		ProbeSpacing:  200 * time.Millisecond,
+		ProbeTimeout:  2 * time.Second,
+		MaxProcessing: 10 * time.Millisecond,
+		Window:        256,
+		HistogramBounds: []time.Duration{
+			time.Millisecond, 2 * time.Millisecond, 5 * time.Millisecond,
+			10 * time.Millisecond, 20 * time.Millisecond, 50 * time.Millisecond,
+			100 * time.Millisecond, 200 * time.Millisecond, 500 * time.Millisecond,
+			time.Second,
+		},
+	}
+}
+
+// ClockOffsetSource supplies a peer's clock offset (peer clock minus local
+// clock), which splits a probe's round trip into one-way delays.
+type ClockOffsetSource interface {
+	ClockOffset(peerID string) (time.Duration, bool)
+}
+
+// ProbePayload rides on keepalive messages. The prober sets Seq and SentAt;
+// the peer echoes them back in a keepalive ack with its own receive and
+// reply times, as in an NTP exchange.
+type ProbePayload struct {
+	Seq        uint64    `json:"seq"`
+	SentAt     time.Time `json:"sent_at"`
+	ReceivedAt time.Time `json:"received_at,omitempty"`
+	RepliedAt  time.Time `json:"replied_at,omitempty"`
+}
+
 type LatencyMonitor struct {
 	topologyManager *TopologyManager
 	logger          *zap.Logger
 	measurements    map[string]*LatencyMeasurement
 	mu              sync.RWMutex
 	stopChan        chan struct{}
+
+	peering    *PeeringManager
+	config     *ProbeConfig
+	offsets    ClockOffsetSource
+	probeSeq   uint64
+	pending    map[string]chan *probeReply
+	links      map[string]*linkStats
+	lastProbed map[string]time.Time
 }
 
+// LatencyMeasurement summarises a link. Actual is the latest RTT; Average,
+// StdDev, the percentiles, Histogram and PacketLoss cover the probe window.
+// Jitter is the smoothed difference between consecutive RTTs (RFC 3550).
+// ForwardDelay and ReverseDelay are only set when OneWayKnown, i.e. when
+// the target's clock offset was known.
 type LatencyMeasurement struct {
 	SourceNode   string
 	TargetNode   string
@@ -32,6 +103,43 @@ type LatencyMeasurement struct {
 	Measurements int
 	Average      time.Duration
 	StdDev       time.Duration
+
+	P50          time.Duration
+	P95          time.Duration
+	P99          time.Duration
+	Histogram    []HistogramBucket
+	ForwardDelay time.Duration
+	ReverseDelay time.Duration
+	OneWayKnown  bool
+}
+
+// HistogramBucket counts RTTs up to UpperBound; the last bucket's bound is
+// math.MaxInt64.
+type HistogramBucket struct {
+	UpperBound time.Duration `json:"le"`
+	Count      int           `json:"count"`
+}
+
+type probeReply struct {
+	payload   *ProbePayload
+	arrivalAt time.Time
+}
+
+type probeSample struct {
+	rtt      time.Duration
+	forward  time.Duration
+	reverse  time.Duration
+	oneWay   bool
+	lost     bool
+	rejected bool
+}
+
+type linkStats struct {
+	samples []probeSample
+	next    int
+	total   int
+	lastRTT time.Duration
+	jitter  time.Duration
 }
 
 func NewLatencyMonitor(topology *TopologyManager, logger *zap.Logger) *LatencyMonitor {
@@ -40,11 +148,41 @@ func NewLatencyMonitor(topology *TopologyManager, logger *zap.Logger) *LatencyMo
 		logger:          logger,
 		measurements:    make(map[string]*LatencyMeasurement),
 		stopChan:        make(chan struct{}),
+		config:          DefaultProbeConfig(),
+		pending:         make(map[string]chan *probeReply),
+		links:           make(map[string]*linkStats),
+		lastProbed:      make(map[string]time.Time),
+	}
+}
+
+// AttachPeering makes the monitor probe this node's peer sessions. Until
+// it is attached no measurements are taken.
+func (lm *LatencyMonitor) AttachPeering(peering *PeeringManager, config *ProbeConfig) {
+	if config == nil {
+		config = DefaultProbeConfig()
 	}
+
+	lm.mu.Lock()
+	lm.peering = peering
+	lm.config = config
+	lm.mu.Unlock()
+
+	peering.RegisterHandler(MessageTypeKeepAliveAck, lm.handleProbeReply)
+}
+
+// SetClockOffsetSource enables one-way delay estimates.
+func (lm *LatencyMonitor) SetClockOffsetSource(source ClockOffsetSource) {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+	lm.offsets = source
 }
 
 func (lm *LatencyMonitor) StartMonitoring(ctx context.Context) {
-	ticker := time.NewTicker(30 * time.Second)
+	lm.mu.RLock()
+	interval := lm.config.Interval
+	lm.mu.RUnlock()
+
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
@@ -54,117 +192,339 @@ func (lm *LatencyMonitor) StartMonitoring(ctx context.Context) {
 		case <-lm.stopChan:
 			return
 		case <-ticker.C:
-			lm.performMeasurements()
+			lm.performMeasurements(ctx)
 		}
 	}
 }
 
-func (lm *LatencyMonitor) performMeasurements() {
-	nodes := lm.topologyManager.GetActiveNodes()
-	if len(nodes) < 2 {
+func (lm *LatencyMonitor) performMeasurements(ctx context.Context) {
+	lm.mu.RLock()
+	peering := lm.peering
+	lm.mu.RUnlock()
+
+	if peering == nil {
 		return
 	}
 
-	for i := 0; i < min(5, len(nodes)); i++ {
-		for j := i + 1; j < min(i+3, len(nodes)); j++ {
-			lm.measureLatency(nodes[i], nodes[j])
+	var wg sync.WaitGroup
+	for _, peerID := range lm.scheduleLinks() {
+		wg.Add(1)
+		go func(peerID string) {
+			defer wg.Done()
+			lm.ProbePeer(ctx, peerID)
+		}(peerID)
+	}
+	wg.Wait()
+}
+
+// scheduleLinks picks this round's links: all of them when they fit the
+// budget, otherwise the ones probed longest ago. Links to peers that are no
+// longer connected are dropped.
+func (lm *LatencyMonitor) scheduleLinks() []string {
+	localID := lm.peering.LocalPeerID()
+
+	var peerIDs []string
+	connected := make(map[string]bool)
+	lm.peering.mu.RLock()
+	for _, conn := range lm.peering.connections {
+		if conn.Status == Connected && conn.RemoteNode != nil && !conn.Transient {
+			peerIDs = append(peerIDs, conn.PeerID)
+			connected[conn.PeerID] = true
+		}
+	}
+	lm.peering.mu.RUnlock()
+
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+
+	for peerID := range lm.lastProbed {
+		if !connected[peerID] {
+			lm.forgetLink(localID, peerID)
+		}
+	}
+	for peerID := range lm.links {
+		if !connected[peerID] {
+			lm.forgetLink(localID, peerID)
+		}
+	}
+
+	if budget := lm.config.Budget; budget > 0 && len(peerIDs) > budget {
+		sort.Slice(peerIDs, func(i, j int) bool {
+			return lm.lastProbed[peerIDs[i]].Before(lm.lastProbed[peerIDs[j]])
+		})
+		peerIDs = peerIDs[:budget]
+	}
+
+	now := time.Now()
+	for _, peerID := range peerIDs {
+		lm.lastProbed[peerID] = now
+	}
+	return peerIDs
+}
+
+// forgetLink drops a link's state. Callers hold lm.mu.
+func (lm *LatencyMonitor) forgetLink(localID, peerID string) {
+	delete(lm.links, peerID)
+	delete(lm.lastProbed, peerID)
+	delete(lm.measurements, fmt.Sprintf("%s-%s", localID, peerID))
+}
+
+// ProbePeer sends a burst of probes to a connected peer and returns the
+// link's updated measurement.
+func (lm *LatencyMonitor) ProbePeer(ctx context.Context, peerID string) (*LatencyMeasurement, error) {
+	lm.mu.RLock()
+	peering := lm.peering
+	config := lm.config
+	lm.mu.RUnlock()
+
+	if peering == nil {
+		return nil, fmt.Errorf("latency monitor is not attached to peering")
+	}
+
+	for i := 0; i < config.ProbesPerLink; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(config.ProbeSpacing):
+			}
+		}
+
+		sample, err := lm.probe(ctx, peerID, config)
+		if err != nil {
+			return nil, err
+		}
+		if sample.rejected {
+			peering.reportMisbehavior(peerID, implausibleLatencyPenalty, "probe faster than light")
+			continue
 		}
+		lm.record(peerID, sample)
 	}
+
+	return lm.GetMeasurement(peering.LocalPeerID(), peerID), nil
 }
 
-func (lm *LatencyMonitor) measureLatency(nodeA, nodeB *types.Node) {
-	key := fmt.Sprintf("%s-%s", nodeA.ID, nodeB.ID)
+// probe sends one keepalive probe. A reply that does not arrive within the
+// timeout counts as lost; failing to send at all is an error. The peer's
+// claimed processing time is capped at MaxProcessing, and a round trip
+// below the theoretical latency to a verified peer is rejected.
+func (lm *LatencyMonitor) probe(ctx context.Context, peerID string, config *ProbeConfig) (probeSample, error) {
+	lm.mu.Lock()
+	lm.probeSeq++
+	seq := lm.probeSeq
+	key := peerID + "/" + strconv.FormatUint(seq, 10)
+	replyCh := make(chan *probeReply, 1)
+	lm.pending[key] = replyCh
+	offsets := lm.offsets
+	lm.mu.Unlock()
+
+	defer func() {
+		lm.mu.Lock()
+		delete(lm.pending, key)
+		lm.mu.Unlock()
+	}()
 
-	latency, jitter, packetLoss, err := lm.pingNode(nodeB.Address)
+	request := &ProbePayload{Seq: seq, SentAt: time.Now().UTC()}
+	data, err := json.Marshal(request)
 	if err != nil {
-		lm.logger.Debug("Latency measurement failed",
-			zap.String("target", nodeB.Address),
+		return probeSample{}, fmt.Errorf("failed to encode probe: %w", err)
+	}
+	if err := lm.peering.SendPeerMessage(peerID, MessageTypeKeepAlive, data); err != nil {
+		return probeSample{}, err
+	}
+
+	timer := time.NewTimer(config.ProbeTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return probeSample{}, ctx.Err()
+	case <-timer.C:
+		return probeSample{lost: true}, nil
+	case reply := <-replyCh:
+		t1, t2, t3, t4 := request.SentAt, reply.payload.ReceivedAt, reply.payload.RepliedAt, reply.arrivalAt
+		processing := t3.Sub(t2)
+		if processing < 0 {
+			processing = 0
+		}
+		if processing > config.MaxProcessing {
+			processing = config.MaxProcessing
+		}
+		sample := probeSample{rtt: t4.Sub(t1) - processing}
+		if sample.rtt < 0 {
+			sample.rtt = t4.Sub(t1)
+		}
+		if theoretical := lm.theoreticalTo(peerID); theoretical > 0 && sample.rtt < theoretical {
+			lm.logger.Debug("Rejected probe below theoretical latency",
+				zap.String("peer_id", peerID),
+				zap.Duration("rtt", sample.rtt),
+				zap.Duration("theoretical", theoretical),
+			)
+			return probeSample{rejected: true}, nil
+		}
+		if offsets != nil {
+			if offset, ok := offsets.ClockOffset(peerID); ok {
+				sample.forward = t2.Sub(t1) - offset
+				sample.reverse = t4.Sub(t3) + offset
+				sample.oneWay = true
+			}
+		}
+		return sample, nil
+	}
+}
+
+func (lm *LatencyMonitor) handleProbeReply(conn *PeerConnection, message *PeerMessage) {
+	payload := &ProbePayload{}
+	if err := json.Unmarshal(message.Payload, payload); err != nil {
+		lm.logger.Debug("Invalid probe reply",
+			zap.String("peer_id", conn.PeerID),
 			zap.Error(err),
 		)
 		return
 	}
 
-	theoretical := lm.calculateTheoreticalLatency(nodeA, nodeB)
+	lm.mu.RLock()
+	replyCh, exists := lm.pending[conn.PeerID+"/"+strconv.FormatUint(payload.Seq, 10)]
+	lm.mu.RUnlock()
+	if !exists {
+		return
+	}
 
-	measurement := &LatencyMeasurement{
-		SourceNode:   nodeA.ID,
-		TargetNode:   nodeB.ID,
-		Theoretical:  theoretical,
-		Actual:       latency,
-		Jitter:       jitter,
-		PacketLoss:   packetLoss,
-		LastMeasured: time.Now().UTC(),
-		Measurements: 1,
-		Average:      latency,
+	select {
+	case replyCh <- &probeReply{payload: payload, arrivalAt: message.ReceivedAt}:
+	default:
 	}
+}
+
+// record adds a probe result to the link's window and republishes its
+// measurement.
+func (lm *LatencyMonitor) record(peerID string, sample probeSample) {
+	localID := lm.peering.LocalPeerID()
+	theoretical := lm.theoreticalTo(peerID)
 
 	lm.mu.Lock()
-	if existing, exists := lm.measurements[key]; exists {
-		existing.Measurements++
-		existing.Actual = latency
-		existing.Jitter = jitter
-		existing.PacketLoss = packetLoss
-		existing.LastMeasured = time.Now().UTC()
-		existing.Average = time.Duration(
-			(float64(existing.Average)*float64(existing.Measurements-1) + float64(latency)) / float64(existing.Measurements),
-		)
+	link, exists := lm.links[peerID]
+	if !exists {
+		link = &linkStats{}
+		lm.links[peerID] = link
+	}
+	if len(link.samples) < lm.config.Window {
+		link.samples = append(link.samples, sample)
 	} else {
-		lm.measurements[key] = measurement
+		link.samples[link.next] = sample
+		link.next = (link.next + 1) % len(link.samples)
 	}
+	link.total++
+	if !sample.lost {
+		if link.lastRTT > 0 {
+			diff := sample.rtt - link.lastRTT
+			if diff < 0 {
+				diff = -diff
+			}
+			link.jitter += (diff - link.jitter) / 16
+		}
+		link.lastRTT = sample.rtt
+	}
+
+	measurement := summarise(link, lm.config.HistogramBounds)
+	measurement.SourceNode = localID
+	measurement.TargetNode = peerID
+	measurement.Theoretical = theoretical
+	lm.measurements[fmt.Sprintf("%s-%s", localID, peerID)] = measurement
 	lm.mu.Unlock()
 
-	lm.logger.Debug("Latency measurement completed",
-		zap.String("source", nodeA.ID),
-		zap.String("target", nodeB.ID),
-		zap.Duration("theoretical", theoretical),
-		zap.Duration("actual", latency),
-		zap.Duration("jitter", jitter),
-		zap.Float64("packet_loss", packetLoss),
-	)
+	if measurement.P50 > 0 {
+		lm.peering.mu.Lock()
+		if conn, ok := lm.peering.connections[peerID]; ok && conn.Metrics != nil {
+			conn.Metrics.Latency = measurement.P50
+		}
+		lm.peering.mu.Unlock()
+	}
 }
 
-func (lm *LatencyMonitor) pingNode(address string) (time.Duration, time.Duration, float64, error) {
-	const attempts = 5
-	var latencies []time.Duration
-	successful := 0
+// theoreticalTo is the theoretical latency to a peer whose position was
+// verified, or zero.
+func (lm *LatencyMonitor) theoreticalTo(peerID string) time.Duration {
+	local := lm.peering.localNode()
 
-	for i := 0; i < attempts; i++ {
-		start := time.Now()
+	lm.peering.mu.RLock()
+	defer lm.peering.mu.RUnlock()
 
-		conn, err := net.DialTimeout("tcp", address, 5*time.Second)
-		if err != nil {
-			continue
-		}
-		conn.Close()
+	conn, exists := lm.peering.connections[peerID]
+	if !exists || lm.peering.config.Node == nil || conn.RemoteNode == nil || !conn.PositionVerified {
+		return 0
+	}
+	return lm.calculateTheoreticalLatency(local, conn.RemoteNode)
+}
 
-		latency := time.Since(start)
-		latencies = append(latencies, latency)
-		successful++
+func summarise(link *linkStats, bounds []time.Duration) *LatencyMeasurement {
+	measurement := &LatencyMeasurement{
+		Actual:       link.lastRTT,
+		Jitter:       link.jitter,
+		LastMeasured: time.Now().UTC(),
+		Measurements: link.total,
+	}
 
-		time.Sleep(100 * time.Millisecond)
+	var rtts []time.Duration
+	var forward, reverse time.Duration
+	oneWay := 0
+	for _, sample := range link.samples {
+		if sample.lost {
+			continue
+		}
+		rtts = append(rtts, sample.rtt)
+		if sample.oneWay {
+			forward += sample.forward
+			reverse += sample.reverse
+			oneWay++
+		}
+	}
+	measurement.PacketLoss = 1 - float64(len(rtts))/float64(len(link.samples))
+	if oneWay > 0 {
+		measurement.ForwardDelay = forward / time.Duration(oneWay)
+		measurement.ReverseDelay = reverse / time.Duration(oneWay)
+		measurement.OneWayKnown = true
 	}
 
-	if successful == 0 {
-		return 0, 0, 1.0, fmt.Errorf("all ping attempts failed")
+	measurement.Histogram = make([]HistogramBucket, len(bounds)+1)
+	for i, bound := range bounds {
+		measurement.Histogram[i].UpperBound = bound
+	}
+	measurement.Histogram[len(bounds)].UpperBound = math.MaxInt64
+	if len(rtts) == 0 {
+		return measurement
 	}
 
-	totalLatency := time.Duration(0)
-	for _, lat := range latencies {
-		totalLatency += lat
+	sort.Slice(rtts, func(i, j int) bool { return rtts[i] < rtts[j] })
+
+	var total time.Duration
+	for _, rtt := range rtts {
+		total += rtt
+		bucket := sort.Search(len(bounds), func(i int) bool { return rtt <= bounds[i] })
+		measurement.Histogram[bucket].Count++
 	}
-	averageLatency := totalLatency / time.Duration(successful)
+	measurement.Average = total / time.Duration(len(rtts))
 
-	var variance time.Duration
-	for _, lat := range latencies {
-		diff := lat - averageLatency
+	var variance float64
+	for _, rtt := range rtts {
+		diff := float64(rtt - measurement.Average)
 		variance += diff * diff
 	}
-	variance /= time.Duration(successful)
-	jitter := time.Duration(math.Sqrt(float64(variance)))
+	measurement.StdDev = time.Duration(math.Sqrt(variance / float64(len(rtts))))
 
-	packetLoss := 1.0 - float64(successful)/float64(attempts)
+	measurement.P50 = percentile(rtts, 0.50)
+	measurement.P95 = percentile(rtts, 0.95)
+	measurement.P99 = percentile(rtts, 0.99)
+	return measurement
+}
 
-	return averageLatency, jitter, packetLoss, nil
+// percentile uses the nearest-rank method on sorted values.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
 }
 
 func (lm *LatencyMonitor) calculateTheoreticalLatency(nodeA, nodeB *types.Node) time.Duration {
@@ -258,10 +618,3 @@ type NetworkHealth struct {
 func (lm *LatencyMonitor) Stop() {
 	close(lm.stopChan)
 }
-
-func min(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
-}
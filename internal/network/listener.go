@@ -0,0 +1,377 @@
+package network
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
+)
+
+// ErrPeerLimit is returned when a session cannot be opened because the
+// local peer or request session limit is reached.
+var ErrPeerLimit = errors.New("peer limit reached")
+
+type ConnectionDirection string
+
+const (
+	Inbound  ConnectionDirection = "inbound"
+	Outbound ConnectionDirection = "outbound"
+)
+
+type PeeringConfig struct {
+	NodeID           string        `json:"node_id"`
+	ListenAddress    string        `json:"listen_address"`
+	MaxPeers         int           `json:"max_peers"`
+	HandshakeTimeout time.Duration `json:"handshake_timeout"`
+
+	// Node is the metadata advertised in the handshake. Its position is
+	// also the reference for checking the positions peers claim.
+	Node             *types.Node   `json:"node,omitempty"`
+	ProtocolVersions []uint32      `json:"protocol_versions"`
+	RTTTolerance     time.Duration `json:"rtt_tolerance"`
+
+	// Identity enables mutual TLS on every session. When set, NodeID is
+	// replaced by the ID derived from the identity key.
+	Identity *NodeIdentity `json:"-"`
+
+	// MaxRequestSessions bounds the short-lived sessions opened for single
+	// requests such as DHT queries. They do not count against MaxPeers and
+	// are closed after RequestSessionIdle without traffic.
+	MaxRequestSessions int           `json:"max_request_sessions"`
+	RequestSessionIdle time.Duration `json:"request_session_idle"`
+}
+
+func DefaultPeeringConfig() *PeeringConfig {
+	return &PeeringConfig{
+		NodeID:           "local-node",
+		ListenAddress:    ":7070",
+		MaxPeers:         50,
+		HandshakeTimeout: 10 * time.Second,
+		ProtocolVersions: SupportedProtocolVersions,
+		RTTTolerance:     DefaultRTTTolerance,
+
+		MaxRequestSessions: 64,
+		RequestSessionIdle: 10 * time.Second,
+	}
+}
+
+// ParsePeerAddress splits a "node-id@host:port" peer address.
+func ParsePeerAddress(address string) (string, string, error) {
+	peerID, hostPort, found := strings.Cut(address, "@")
+	if !found || peerID == "" || hostPort == "" {
+		return "", "", fmt.Errorf("invalid peer address %q, expected node-id@host:port", address)
+	}
+	if _, _, err := net.SplitHostPort(hostPort); err != nil {
+		return "", "", fmt.Errorf("invalid peer address %q: %w", address, err)
+	}
+	return peerID, hostPort, nil
+}
+
+// Connect registers a peer to keep a session with and dials it. The peer
+// is redialled by connection maintenance if the session drops.
+func (pm *PeeringManager) Connect(peerID, address string) error {
+	if peerID == pm.localPeerID {
+		return fmt.Errorf("cannot connect to self")
+	}
+
+	pm.mu.Lock()
+	pm.staticPeers[peerID] = address
+	pm.mu.Unlock()
+
+	pm.dial(peerID, address)
+	return nil
+}
+
+// ListenAddr returns the address the listener is bound to, or nil when
+// not listening.
+func (pm *PeeringManager) ListenAddr() net.Addr {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	if pm.listener == nil {
+		return nil
+	}
+	return pm.listener.Addr()
+}
+
+func (pm *PeeringManager) listen() error {
+	if pm.config.ListenAddress == "" {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", pm.config.ListenAddress)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", pm.config.ListenAddress, err)
+	}
+
+	pm.mu.Lock()
+	pm.listener = listener
+	pm.mu.Unlock()
+
+	pm.logger.Info("Peering listener started",
+		zap.String("node_id", pm.localPeerID),
+		zap.String("address", listener.Addr().String()),
+	)
+
+	go pm.acceptLoop(listener)
+	return nil
+}
+
+func (pm *PeeringManager) acceptLoop(listener net.Listener) {
+	for {
+		netConn, err := listener.Accept()
+		if err != nil {
+			select {
+			case <-pm.stopChan:
+				return
+			default:
+			}
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				continue
+			}
+			pm.logger.Warn("Peering listener stopped", zap.Error(err))
+			return
+		}
+
+		// Which limit applies is only known from the handshake.
+		if pm.atCapacity() && pm.requestsAtCapacity() {
+			pm.logger.Debug("Rejecting inbound connection, peer limit reached",
+				zap.String("remote_addr", netConn.RemoteAddr().String()),
+				zap.Int("max_peers", pm.config.MaxPeers),
+			)
+			netConn.Close()
+			continue
+		}
+
+		go pm.handleInbound(netConn)
+	}
+}
+
+// handleInbound waits for the remote's handshake to learn its peer ID and
+// protocol versions, adopts the session and answers with our own handshake
+// carrying the negotiated version.
+func (pm *PeeringManager) handleInbound(netConn net.Conn) {
+	pm.mu.RLock()
+	config := pm.wireConfig
+	pm.mu.RUnlock()
+
+	netConn.SetDeadline(time.Now().Add(pm.config.HandshakeTimeout))
+
+	var authenticatedID string
+	if identity := pm.config.Identity; identity != nil {
+		tlsConn := tls.Server(netConn, identity.TLSConfig(""))
+		if err := tlsConn.Handshake(); err != nil {
+			pm.logger.Debug("Rejecting inbound connection, TLS handshake failed",
+				zap.String("remote_addr", netConn.RemoteAddr().String()),
+				zap.Error(err),
+			)
+			netConn.Close()
+			return
+		}
+		peerID, err := PeerIDFromConnectionState(tlsConn.ConnectionState())
+		if err != nil {
+			tlsConn.Close()
+			return
+		}
+		authenticatedID = peerID
+		netConn = tlsConn
+	}
+
+	reader := NewFrameReader(netConn, config)
+	message, n, err := reader.ReadMessage()
+	if err != nil || message.Type != MessageTypeHandshake || message.PeerID == "" || message.PeerID == pm.localPeerID {
+		pm.logger.Debug("Rejecting inbound connection without valid handshake",
+			zap.String("remote_addr", netConn.RemoteAddr().String()),
+			zap.Error(err),
+		)
+		netConn.Close()
+		return
+	}
+	if pm.banned(message.PeerID) {
+		pm.logger.Debug("Rejecting banned peer",
+			zap.String("peer_id", message.PeerID),
+			zap.String("remote_addr", netConn.RemoteAddr().String()),
+		)
+		netConn.Close()
+		return
+	}
+	if authenticatedID != "" && message.PeerID != authenticatedID {
+		pm.logger.Warn("Rejecting peer claiming an identity it does not hold",
+			zap.String("claimed_id", message.PeerID),
+			zap.String("authenticated_id", authenticatedID),
+			zap.String("remote_addr", netConn.RemoteAddr().String()),
+		)
+		netConn.Close()
+		return
+	}
+	handshake, version, err := pm.parseHandshake(message)
+	if err != nil {
+		pm.logger.Warn("Refusing incompatible peer",
+			zap.String("peer_id", message.PeerID),
+			zap.String("remote_addr", netConn.RemoteAddr().String()),
+			zap.Error(err),
+		)
+		netConn.Close()
+		return
+	}
+	if (handshake.Transient && pm.requestsAtCapacity()) || (!handshake.Transient && pm.atCapacity()) {
+		pm.logger.Debug("Rejecting inbound connection, peer limit reached",
+			zap.String("peer_id", message.PeerID),
+			zap.Bool("request_session", handshake.Transient),
+		)
+		netConn.Close()
+		return
+	}
+	netConn.SetDeadline(time.Time{})
+
+	writer, err := NewFrameWriter(netConn, config)
+	if err != nil {
+		netConn.Close()
+		return
+	}
+
+	now := time.Now().UTC()
+	conn := &PeerConnection{
+		PeerID:          message.PeerID,
+		RemoteAddr:      netConn.RemoteAddr().String(),
+		LocalAddr:       netConn.LocalAddr().String(),
+		Protocol:        pm.protocol(),
+		Direction:       Inbound,
+		Authenticated:   authenticatedID != "",
+		Transient:       handshake.Transient,
+		ProtocolVersion: version,
+		RemoteNode:      handshake.Node,
+		Established:     now,
+		LastActivity:    now,
+		Status:          Connected,
+		Metrics: &ConnectionMetrics{
+			BytesReceived:    int64(n),
+			MessagesReceived: 1,
+			LastMessageAt:    now,
+		},
+		netConn: netConn,
+		writer:  writer,
+	}
+
+	if !pm.adoptSession(conn) {
+		netConn.Close()
+		return
+	}
+
+	pm.logger.Info("Inbound peer connection established",
+		zap.String("peer_id", conn.PeerID),
+		zap.String("remote_addr", conn.RemoteAddr),
+		zap.Uint32("protocol_version", version),
+	)
+
+	go pm.handleIncomingMessages(conn, netConn, reader)
+
+	if err := pm.sendHandshake(conn, version); err != nil {
+		pm.logger.Warn("Failed to send handshake",
+			zap.String("peer_id", conn.PeerID),
+			zap.Error(err),
+		)
+	}
+}
+
+// adoptSession makes conn the session for its peer. When a connected
+// session already exists a full session wins over a request session;
+// otherwise both nodes keep the one dialled by the node with the lower ID,
+// so simultaneous dials converge on a single session.
+func (pm *PeeringManager) adoptSession(conn *PeerConnection) bool {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	existing, exists := pm.connections[conn.PeerID]
+	if exists && existing != conn && existing.Status == Connected {
+		keepExisting := existing.Direction == pm.preferredDirection(conn.PeerID)
+		if existing.Transient != conn.Transient {
+			keepExisting = conn.Transient
+		}
+		if keepExisting {
+			pm.logger.Debug("Dropping duplicate peer session",
+				zap.String("peer_id", conn.PeerID),
+				zap.String("kept", string(existing.Direction)),
+			)
+			return false
+		}
+
+		existing.Status = Disconnected
+		if existing.netConn != nil {
+			existing.netConn.Close()
+		}
+		pm.logger.Debug("Replacing duplicate peer session",
+			zap.String("peer_id", conn.PeerID),
+			zap.String("kept", string(conn.Direction)),
+		)
+	}
+
+	pm.connections[conn.PeerID] = conn
+	return true
+}
+
+func (pm *PeeringManager) preferredDirection(peerID string) ConnectionDirection {
+	if pm.localPeerID < peerID {
+		return Outbound
+	}
+	return Inbound
+}
+
+func (pm *PeeringManager) protocol() string {
+	if pm.config.Identity != nil {
+		return "tls"
+	}
+	return "tcp"
+}
+
+func (pm *PeeringManager) atCapacity() bool {
+	if pm.config.MaxPeers <= 0 {
+		return false
+	}
+	return len(pm.GetActiveConnections()) >= pm.config.MaxPeers
+}
+
+func (pm *PeeringManager) requestsAtCapacity() bool {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	open := 0
+	for _, conn := range pm.connections {
+		if conn.Transient && (conn.Status == Connected || conn.Status == Connecting) {
+			open++
+		}
+	}
+	return open >= pm.config.MaxRequestSessions
+}
+
+func (pm *PeeringManager) closeListener() {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if pm.listener != nil {
+		pm.listener.Close()
+		pm.listener = nil
+	}
+}
+
+func (pm *PeeringManager) startListener(ctx context.Context) error {
+	if err := pm.listen(); err != nil {
+		return err
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-pm.stopChan:
+		}
+		pm.closeListener()
+	}()
+	return nil
+}
@@ -0,0 +1,269 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
+)
+
+var mdnsGroup = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// mdnsUnicastResponse is the QU bit: the querier asks for the answer to be
+// sent straight back rather than multicast.
+const mdnsUnicastResponse = 1 << 15
+
+// MDNSSource finds peers on the local network with multicast DNS. Nodes
+// are published as <node-id>._relativistic._tcp.local with an SRV record
+// for the peering port and a TXT record in the ParsePeerTXT format.
+type MDNSSource struct {
+	service string
+	local   *types.Node
+	port    int
+	timeout time.Duration
+	logger  *zap.Logger
+}
+
+// NewMDNSSource browses for peers; when local is set, Serve also answers
+// queries for it on port.
+func NewMDNSSource(local *types.Node, port int, logger *zap.Logger) *MDNSSource {
+	return &MDNSSource{
+		service: "_" + DiscoveryServiceName + "._tcp.local.",
+		local:   local,
+		port:    port,
+		timeout: 2 * time.Second,
+		logger:  logger,
+	}
+}
+
+func (s *MDNSSource) Name() string {
+	return "mdns"
+}
+
+// Discover multicasts a PTR query for the service and collects answers
+// until the timeout.
+func (s *MDNSSource) Discover(ctx context.Context) ([]*Peer, error) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mDNS socket: %w", err)
+	}
+	defer conn.Close()
+
+	query, err := s.buildQuery()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.WriteToUDP(query, mdnsGroup); err != nil {
+		return nil, fmt.Errorf("failed to send mDNS query: %w", err)
+	}
+
+	deadline := time.Now().Add(s.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetReadDeadline(deadline)
+
+	found := make(map[string]*Peer)
+	buf := make([]byte, 9000)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				break
+			}
+			return nil, fmt.Errorf("failed to read mDNS response: %w", err)
+		}
+
+		for _, node := range s.parseResponse(buf[:n], from.IP) {
+			if s.local != nil && node.ID == s.local.ID {
+				continue
+			}
+			found[node.ID] = newDiscoveredPeer(node, s.Name())
+		}
+	}
+
+	peers := make([]*Peer, 0, len(found))
+	for _, peer := range found {
+		peers = append(peers, peer)
+	}
+	return peers, nil
+}
+
+// Serve answers mDNS queries for the local node until ctx is done.
+func (s *MDNSSource) Serve(ctx context.Context) error {
+	if s.local == nil {
+		return fmt.Errorf("no local node to advertise")
+	}
+
+	conn, err := net.ListenMulticastUDP("udp4", nil, mdnsGroup)
+	if err != nil {
+		return fmt.Errorf("failed to join mDNS group: %w", err)
+	}
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	response, err := s.buildResponse()
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	go func() {
+		buf := make([]byte, 9000)
+		for {
+			n, from, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				if ctx.Err() == nil {
+					s.logger.Warn("mDNS responder stopped", zap.Error(err))
+				}
+				return
+			}
+			if !s.isServiceQuery(buf[:n]) {
+				continue
+			}
+			if _, err := conn.WriteToUDP(response, from); err != nil {
+				s.logger.Debug("Failed to send mDNS response",
+					zap.String("to", from.String()),
+					zap.Error(err),
+				)
+			}
+		}
+	}()
+	return nil
+}
+
+func (s *MDNSSource) buildQuery() ([]byte, error) {
+	name, err := dnsmessage.NewName(s.service)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mDNS service name: %w", err)
+	}
+
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{})
+	builder.StartQuestions()
+	builder.Question(dnsmessage.Question{
+		Name:  name,
+		Type:  dnsmessage.TypePTR,
+		Class: dnsmessage.ClassINET | mdnsUnicastResponse,
+	})
+	return builder.Finish()
+}
+
+func (s *MDNSSource) buildResponse() ([]byte, error) {
+	service, err := dnsmessage.NewName(s.service)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mDNS service name: %w", err)
+	}
+	instance, err := dnsmessage.NewName(s.local.ID + "." + s.service)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mDNS instance name: %w", err)
+	}
+	target, err := dnsmessage.NewName(s.local.ID + ".local.")
+	if err != nil {
+		return nil, fmt.Errorf("invalid mDNS target name: %w", err)
+	}
+
+	header := func(name dnsmessage.Name, recordType dnsmessage.Type) dnsmessage.ResourceHeader {
+		return dnsmessage.ResourceHeader{Name: name, Type: recordType, Class: dnsmessage.ClassINET, TTL: 120}
+	}
+
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{Response: true, Authoritative: true})
+	builder.StartAnswers()
+	if err := builder.PTRResource(header(service, dnsmessage.TypePTR), dnsmessage.PTRResource{PTR: instance}); err != nil {
+		return nil, err
+	}
+	if err := builder.SRVResource(header(instance, dnsmessage.TypeSRV), dnsmessage.SRVResource{Target: target, Port: uint16(s.port)}); err != nil {
+		return nil, err
+	}
+	if err := builder.TXTResource(header(instance, dnsmessage.TypeTXT), dnsmessage.TXTResource{TXT: peerTXT(s.local)}); err != nil {
+		return nil, err
+	}
+	return builder.Finish()
+}
+
+func (s *MDNSSource) isServiceQuery(msg []byte) bool {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(msg)
+	if err != nil || header.Response {
+		return false
+	}
+	questions, err := parser.AllQuestions()
+	if err != nil {
+		return false
+	}
+	for _, question := range questions {
+		if question.Type == dnsmessage.TypePTR && strings.EqualFold(question.Name.String(), s.service) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseResponse extracts nodes from an mDNS response. The SRV target is
+// not resolved; the response's source address is the node's host.
+func (s *MDNSSource) parseResponse(msg []byte, host net.IP) []*types.Node {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(msg)
+	if err != nil || !header.Response {
+		return nil
+	}
+	if err := parser.SkipAllQuestions(); err != nil {
+		return nil
+	}
+
+	ports := make(map[string]uint16)
+	txts := make(map[string][]string)
+	for {
+		rh, err := parser.AnswerHeader()
+		if err == dnsmessage.ErrSectionDone {
+			break
+		}
+		if err != nil {
+			return nil
+		}
+
+		instance := strings.ToLower(rh.Name.String())
+		switch rh.Type {
+		case dnsmessage.TypeSRV:
+			srv, err := parser.SRVResource()
+			if err != nil {
+				return nil
+			}
+			ports[instance] = srv.Port
+		case dnsmessage.TypeTXT:
+			txt, err := parser.TXTResource()
+			if err != nil {
+				return nil
+			}
+			txts[instance] = txt.TXT
+		default:
+			if err := parser.SkipAnswer(); err != nil {
+				return nil
+			}
+		}
+	}
+
+	var nodes []*types.Node
+	for instance, port := range ports {
+		if !strings.HasSuffix(instance, strings.ToLower(s.service)) {
+			continue
+		}
+		node, err := ParsePeerTXT(txts[instance])
+		if err != nil {
+			continue
+		}
+		node.Address = net.JoinHostPort(host.String(), strconv.Itoa(int(port)))
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
@@ -4,12 +4,15 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"sync"
 	"time"
 
 	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
 )
 
 type PeeringManager struct {
@@ -20,19 +23,43 @@ type PeeringManager struct {
 	connections      map[string]*PeerConnection
 	stopChan         chan struct{}
 	localPeerID      string
+	handlers         map[MessageType]PeerMessageHandler
+	wireConfig       *WireConfig
+	config           *PeeringConfig
+	listener         net.Listener
+	staticPeers      map[string]string
+	verifier         *PositionVerifier
+	connManager      *ConnectionManager
+	gossip           *GossipRouter
+	announced        map[string]time.Time
+	lastAnnounce     time.Time
 }
 
+type PeerMessageHandler func(conn *PeerConnection, message *PeerMessage)
+
 type PeerConnection struct {
-	PeerID       string
-	RemoteAddr   string
-	LocalAddr    string
-	Protocol     string
-	Established  time.Time
-	LastActivity time.Time
-	Status       ConnectionStatus
-	Metrics      *ConnectionMetrics
-	netConn      net.Conn
-	lastError    error
+	PeerID        string
+	RemoteAddr    string
+	LocalAddr     string
+	Protocol      string
+	Direction     ConnectionDirection
+	Authenticated bool
+	// Transient marks a request session: it is opened for one-off
+	// requests, is not handed to other subsystems as a peer and is closed
+	// once idle.
+	Transient        bool
+	ProtocolVersion  uint32
+	RemoteNode       *types.Node
+	RTT              time.Duration
+	PositionVerified bool
+	Established      time.Time
+	LastActivity     time.Time
+	Status           ConnectionStatus
+	Metrics          *ConnectionMetrics
+	netConn          net.Conn
+	writer           *FrameWriter
+	lastError        error
+	handshakeSentAt  time.Time
 }
 
 type ConnectionStatus string
@@ -53,20 +80,89 @@ type ConnectionMetrics struct {
 	Latency          time.Duration
 }
 
-func NewPeeringManager(discovery *DiscoveryService, topology *TopologyManager, logger *zap.Logger) *PeeringManager {
+func NewPeeringManager(discovery *DiscoveryService, topology *TopologyManager, config *PeeringConfig, logger *zap.Logger) *PeeringManager {
+	if config == nil {
+		config = DefaultPeeringConfig()
+	}
+	if config.Identity != nil {
+		config.NodeID = config.Identity.ID
+	}
+	if config.NodeID == "" {
+		config.NodeID = DefaultPeeringConfig().NodeID
+	}
+	if config.HandshakeTimeout <= 0 {
+		config.HandshakeTimeout = DefaultPeeringConfig().HandshakeTimeout
+	}
+	if len(config.ProtocolVersions) == 0 {
+		config.ProtocolVersions = SupportedProtocolVersions
+	}
+	if config.RTTTolerance <= 0 {
+		config.RTTTolerance = DefaultRTTTolerance
+	}
+	if config.MaxRequestSessions <= 0 {
+		config.MaxRequestSessions = DefaultPeeringConfig().MaxRequestSessions
+	}
+	if config.RequestSessionIdle <= 0 {
+		config.RequestSessionIdle = DefaultPeeringConfig().RequestSessionIdle
+	}
+
 	return &PeeringManager{
 		discoveryService: discovery,
 		topologyManager:  topology,
 		logger:           logger,
 		connections:      make(map[string]*PeerConnection),
 		stopChan:         make(chan struct{}),
-		localPeerID:      "local-node",
+		localPeerID:      config.NodeID,
+		handlers:         make(map[MessageType]PeerMessageHandler),
+		wireConfig:       DefaultWireConfig(),
+		config:           config,
+		staticPeers:      make(map[string]string),
+		announced:        make(map[string]time.Time),
 	}
 }
 
+// SetWireConfig changes the codec and size limits used for connections
+// established afterwards.
+func (pm *PeeringManager) SetWireConfig(config *WireConfig) error {
+	if _, err := CodecByID(config.Codec); err != nil {
+		return err
+	}
+	if config.MaxFrameSize <= 0 || config.MaxMessageSize <= 0 {
+		return fmt.Errorf("wire size limits must be positive")
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.wireConfig = config
+	return nil
+}
+
+// AttachPositionVerifier reports handshake RTTs to the verifier when this
+// node is one of its anchors.
+func (pm *PeeringManager) AttachPositionVerifier(verifier *PositionVerifier) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.verifier = verifier
+}
+
+func (pm *PeeringManager) LocalPeerID() string {
+	return pm.localPeerID
+}
+
+func (pm *PeeringManager) RegisterHandler(messageType MessageType, handler PeerMessageHandler) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	pm.handlers[messageType] = handler
+}
+
 func (pm *PeeringManager) Start(ctx context.Context) error {
 	pm.logger.Info("Starting Peering Manager")
 
+	if err := pm.startListener(ctx); err != nil {
+		return err
+	}
+
 	go pm.connectionMaintenance(ctx)
 	go pm.metricsCollection(ctx)
 
@@ -90,38 +186,116 @@ func (pm *PeeringManager) connectionMaintenance(ctx context.Context) {
 }
 
 func (pm *PeeringManager) maintainConnections() {
-	activePeers := pm.discoveryService.GetActivePeers()
-
-	for _, peer := range activePeers {
-		if peer.Status == PeerConnected {
-			pm.ensureConnection(peer)
+	pm.mu.RLock()
+	managed := pm.connManager != nil
+	pm.mu.RUnlock()
+
+	// With a connection manager, it picks which discovered peers to dial.
+	if pm.discoveryService != nil && !managed {
+		for _, peer := range pm.discoveryService.GetAllPeers() {
+			if peer.Status != PeerDisconnected {
+				pm.ensureConnection(peer)
+			}
 		}
 	}
 
+	pm.mu.RLock()
+	staticPeers := make(map[string]string, len(pm.staticPeers))
+	for peerID, address := range pm.staticPeers {
+		staticPeers[peerID] = address
+	}
+	pm.mu.RUnlock()
+
+	for peerID, address := range staticPeers {
+		pm.dial(peerID, address)
+	}
+
 	pm.cleanupStaleConnections()
 }
 
 func (pm *PeeringManager) ensureConnection(peer *Peer) {
+	pm.dial(peer.Node.ID, peer.Node.Address)
+}
+
+// dial opens an outbound session unless one is connected or in progress,
+// or the peer limit has been reached. A request session to the peer is
+// closed in favour of the full one.
+func (pm *PeeringManager) dial(peerID, address string) {
+	pm.openSession(peerID, address, false)
+}
+
+// openSession dials the peer for a full or a request session. A request
+// session is only needed when no session exists, and is limited by
+// MaxRequestSessions rather than MaxPeers.
+func (pm *PeeringManager) openSession(peerID, address string, transient bool) error {
+	if peerID == pm.localPeerID || pm.banned(peerID) {
+		return fmt.Errorf("cannot open a session with peer %s", peerID)
+	}
+
+	busy := func(conn *PeerConnection, exists bool) bool {
+		if !exists || (conn.Status != Connected && conn.Status != Connecting) {
+			return false
+		}
+		return transient || !conn.Transient
+	}
+
+	pm.mu.RLock()
+	conn, exists := pm.connections[peerID]
+	pm.mu.RUnlock()
+
+	if busy(conn, exists) {
+		return nil
+	}
+	if (transient && pm.requestsAtCapacity()) || (!transient && pm.atCapacity()) {
+		return ErrPeerLimit
+	}
+
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
 
-	conn, exists := pm.connections[peer.Node.ID]
-	if !exists {
-		conn = &PeerConnection{
-			PeerID:       peer.Node.ID,
-			RemoteAddr:   peer.Node.Address,
-			Protocol:     "tcp",
-			Established:  time.Now().UTC(),
-			LastActivity: time.Now().UTC(),
-			Status:       Connecting,
-			Metrics:      &ConnectionMetrics{},
-		}
-		pm.connections[peer.Node.ID] = conn
+	conn, exists = pm.connections[peerID]
+	if busy(conn, exists) {
+		return nil
+	}
+	if exists && conn.Transient && conn.netConn != nil {
+		conn.Status = Disconnected
+		conn.netConn.Close()
+	}
+
+	conn = &PeerConnection{
+		PeerID:       peerID,
+		RemoteAddr:   address,
+		Protocol:     pm.protocol(),
+		Direction:    Outbound,
+		Transient:    transient,
+		Established:  time.Now().UTC(),
+		LastActivity: time.Now().UTC(),
+		Status:       Connecting,
+		Metrics:      &ConnectionMetrics{},
+	}
+	pm.connections[peerID] = conn
+
+	go pm.establishConnection(conn)
+	return nil
+}
 
-		go pm.establishConnection(conn)
-	} else if conn.Status == Disconnected || conn.Status == Failed {
-		conn.Status = Connecting
-		go pm.establishConnection(conn)
+func (pm *PeeringManager) banned(peerID string) bool {
+	pm.mu.RLock()
+	connManager := pm.connManager
+	pm.mu.RUnlock()
+
+	return connManager != nil && connManager.IsBanned(peerID)
+}
+
+// reportMisbehavior passes points against a peer to the connection
+// manager, if one is attached.
+func (pm *PeeringManager) reportMisbehavior(peerID string, points float64, reason string) {
+	pm.mu.RLock()
+	connManager := pm.connManager
+	pm.mu.RUnlock()
+
+	if connManager != nil {
+		connManager.ReportMisbehavior(peerID, points, reason)
 	}
 }
 
@@ -142,17 +316,22 @@ func (pm *PeeringManager) establishConnection(conn *PeerConnection) {
 		return
 	}
 
-	if conn.Protocol == "tls" {
-		tlsConfig := &tls.Config{
-			InsecureSkipVerify: true,
-		}
-		tlsConn := tls.Client(netConn, tlsConfig)
+	if identity := pm.config.Identity; identity != nil {
+		// Pinning the expected ID makes the handshake fail unless the
+		// remote holds the key the ID was derived from.
+		tlsConn := tls.Client(netConn, identity.TLSConfig(conn.PeerID))
+		tlsConn.SetDeadline(time.Now().Add(pm.config.HandshakeTimeout))
 
 		if err := tlsConn.Handshake(); err != nil {
 			pm.handleConnectionFailure(conn, err)
 			netConn.Close()
 			return
 		}
+		tlsConn.SetDeadline(time.Time{})
+
+		pm.mu.Lock()
+		conn.Authenticated = true
+		pm.mu.Unlock()
 		netConn = tlsConn
 	}
 
@@ -161,12 +340,30 @@ func (pm *PeeringManager) establishConnection(conn *PeerConnection) {
 
 func (pm *PeeringManager) handleConnectionSuccess(conn *PeerConnection, netConn net.Conn) {
 	pm.mu.Lock()
+	writer, err := NewFrameWriter(netConn, pm.wireConfig)
+	if err != nil {
+		pm.mu.Unlock()
+		netConn.Close()
+		pm.handleConnectionFailure(conn, err)
+		return
+	}
+	reader := NewFrameReader(netConn, pm.wireConfig)
 	conn.Status = Connected
 	conn.LastActivity = time.Now().UTC()
+	conn.LocalAddr = netConn.LocalAddr().String()
 	conn.netConn = netConn
+	conn.writer = writer
 	pm.mu.Unlock()
 
-	go pm.handleIncomingMessages(conn, netConn)
+	if !pm.adoptSession(conn) {
+		pm.mu.Lock()
+		conn.Status = Disconnected
+		pm.mu.Unlock()
+		netConn.Close()
+		return
+	}
+
+	go pm.handleIncomingMessages(conn, netConn, reader)
 
 	pm.logger.Info("Peer connection established",
 		zap.String("peer_id", conn.PeerID),
@@ -174,14 +371,7 @@ func (pm *PeeringManager) handleConnectionSuccess(conn *PeerConnection, netConn
 		zap.String("local_addr", netConn.LocalAddr().String()),
 	)
 
-	handshake := &PeerMessage{
-		Type:      MessageTypeHandshake,
-		PeerID:    pm.localPeerID,
-		Timestamp: time.Now().UTC(),
-		Payload:   []byte("HELLO"),
-	}
-
-	if err := pm.sendMessageToConnection(conn, handshake); err != nil {
+	if err := pm.sendHandshake(conn, 0); err != nil {
 		pm.logger.Warn("Failed to send handshake",
 			zap.String("peer_id", conn.PeerID),
 			zap.Error(err),
@@ -196,6 +386,10 @@ func (pm *PeeringManager) handleConnectionFailure(conn *PeerConnection, err erro
 	conn.lastError = err
 	pm.mu.Unlock()
 
+	if pm.discoveryService != nil {
+		pm.discoveryService.updatePeerStatus(conn.PeerID, PeerDisconnected)
+	}
+
 	pm.logger.Warn("Peer connection failed",
 		zap.String("peer_id", conn.PeerID),
 		zap.String("remote_addr", conn.RemoteAddr),
@@ -203,35 +397,36 @@ func (pm *PeeringManager) handleConnectionFailure(conn *PeerConnection, err erro
 	)
 }
 
-func (pm *PeeringManager) handleIncomingMessages(conn *PeerConnection, netConn net.Conn) {
+func (pm *PeeringManager) handleIncomingMessages(conn *PeerConnection, netConn net.Conn, reader *FrameReader) {
 	defer netConn.Close()
 
-	buffer := make([]byte, 4096)
-
 	for {
 		netConn.SetReadDeadline(time.Now().Add(30 * time.Second))
 
-		n, err := netConn.Read(buffer)
-		if err != nil {
+		message, n, err := reader.ReadMessage()
+		if err != nil && !errors.Is(err, ErrMalformedMessage) {
 			pm.handleConnectionError(conn, err)
 			return
 		}
 
+		receivedAt := time.Now().UTC()
+
 		pm.mu.Lock()
-		conn.LastActivity = time.Now().UTC()
+		conn.LastActivity = receivedAt
 		conn.Metrics.BytesReceived += int64(n)
 		conn.Metrics.MessagesReceived++
 		conn.Metrics.LastMessageAt = time.Now().UTC()
 		pm.mu.Unlock()
 
-		message := &PeerMessage{}
-		if err := json.Unmarshal(buffer[:n], message); err != nil {
+		if err != nil {
 			pm.logger.Warn("Failed to parse peer message",
 				zap.String("peer_id", conn.PeerID),
 				zap.Error(err),
 			)
+			pm.reportMisbehavior(conn.PeerID, malformedMessagePenalty, "malformed message")
 			continue
 		}
+		message.ReceivedAt = receivedAt
 
 		pm.handlePeerMessage(conn, message)
 	}
@@ -253,9 +448,24 @@ func (pm *PeeringManager) handleConnectionError(conn *PeerConnection, err error)
 func (pm *PeeringManager) handlePeerMessage(conn *PeerConnection, message *PeerMessage) {
 	switch message.Type {
 	case MessageTypeHandshake:
+		if message.PeerID != conn.PeerID {
+			pm.logger.Warn("Peer handshake identity mismatch",
+				zap.String("peer_id", conn.PeerID),
+				zap.String("claimed_id", message.PeerID),
+			)
+			pm.Disconnect(conn.PeerID)
+			return
+		}
 		pm.logger.Debug("Received handshake from peer",
 			zap.String("peer_id", conn.PeerID),
 		)
+		if conn.Direction == Outbound {
+			pm.handleHandshakeReply(conn, message)
+		}
+	case MessageTypeHandshakeAck:
+		if conn.Direction == Inbound {
+			pm.handleHandshakeAck(conn, message)
+		}
 	case MessageTypeData:
 		pm.logger.Debug("Received data message from peer",
 			zap.String("peer_id", conn.PeerID),
@@ -265,6 +475,56 @@ func (pm *PeeringManager) handlePeerMessage(conn *PeerConnection, message *PeerM
 		pm.mu.Lock()
 		conn.LastActivity = time.Now().UTC()
 		pm.mu.Unlock()
+
+		if len(message.Payload) > 0 {
+			pm.answerProbe(conn, message)
+		}
+	default:
+		pm.mu.RLock()
+		handler, exists := pm.handlers[message.Type]
+		pm.mu.RUnlock()
+
+		if !exists {
+			pm.logger.Debug("No handler registered for peer message",
+				zap.String("peer_id", conn.PeerID),
+				zap.String("type", string(message.Type)),
+			)
+			return
+		}
+		handler(conn, message)
+	}
+}
+
+// answerProbe echoes a latency probe carried by a keepalive, adding when
+// it arrived and when the reply left.
+func (pm *PeeringManager) answerProbe(conn *PeerConnection, message *PeerMessage) {
+	probe := &ProbePayload{}
+	if err := json.Unmarshal(message.Payload, probe); err != nil {
+		pm.logger.Debug("Invalid keepalive probe",
+			zap.String("peer_id", conn.PeerID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	probe.ReceivedAt = message.ReceivedAt
+	probe.RepliedAt = time.Now().UTC()
+	payload, err := json.Marshal(probe)
+	if err != nil {
+		return
+	}
+
+	reply := &PeerMessage{
+		Type:      MessageTypeKeepAliveAck,
+		PeerID:    pm.localPeerID,
+		Timestamp: probe.RepliedAt,
+		Payload:   payload,
+	}
+	if err := pm.sendMessageToConnection(conn, reply); err != nil {
+		pm.logger.Debug("Failed to answer keepalive probe",
+			zap.String("peer_id", conn.PeerID),
+			zap.Error(err),
+		)
 	}
 }
 
@@ -298,19 +558,33 @@ func (pm *PeeringManager) SendMessage(peerID string, message []byte) error {
 	return nil
 }
 
-func (pm *PeeringManager) sendMessageToConnection(conn *PeerConnection, message *PeerMessage) error {
-	if conn.netConn == nil {
-		return fmt.Errorf("no network connection")
+func (pm *PeeringManager) SendPeerMessage(peerID string, messageType MessageType, payload []byte) error {
+	conn := pm.GetConnection(peerID)
+	if conn == nil || conn.Status != Connected {
+		return fmt.Errorf("no active connection to peer %s", peerID)
 	}
 
-	data, err := json.Marshal(message)
-	if err != nil {
-		return fmt.Errorf("failed to marshal message: %w", err)
+	peerMessage := &PeerMessage{
+		Type:      messageType,
+		PeerID:    pm.localPeerID,
+		Timestamp: time.Now().UTC(),
+		Payload:   payload,
+	}
+
+	if err := pm.sendMessageToConnection(conn, peerMessage); err != nil {
+		return fmt.Errorf("failed to send %s message: %w", messageType, err)
+	}
+	return nil
+}
+
+func (pm *PeeringManager) sendMessageToConnection(conn *PeerConnection, message *PeerMessage) error {
+	if conn.netConn == nil || conn.writer == nil {
+		return fmt.Errorf("no network connection")
 	}
 
 	conn.netConn.SetWriteDeadline(time.Now().Add(10 * time.Second))
 
-	n, err := conn.netConn.Write(data)
+	n, err := conn.writer.WriteMessage(message)
 	if err != nil {
 		return fmt.Errorf("failed to write to connection: %w", err)
 	}
@@ -370,7 +644,6 @@ func (pm *PeeringManager) updateConnectionMetrics() {
 			conn.Metrics.MessagesSent += 1
 			conn.Metrics.MessagesReceived += 1
 			conn.Metrics.LastMessageAt = time.Now().UTC()
-			conn.Metrics.Latency = time.Duration(50+time.Now().Unix()%50) * time.Millisecond
 		}
 	}
 }
@@ -380,15 +653,30 @@ func (pm *PeeringManager) cleanupStaleConnections() {
 	defer pm.mu.Unlock()
 
 	staleThreshold := time.Now().Add(-10 * time.Minute)
+	idleThreshold := time.Now().Add(-pm.config.RequestSessionIdle)
 	removedCount := 0
 
 	for peerID, conn := range pm.connections {
+		if conn.Transient && conn.Status == Connected && conn.LastActivity.Before(idleThreshold) {
+			conn.Status = Disconnected
+			if conn.netConn != nil {
+				conn.netConn.Close()
+			}
+		}
 		if conn.Status == Disconnected && conn.LastActivity.Before(staleThreshold) {
 			delete(pm.connections, peerID)
 			removedCount++
 		}
 	}
 
+	// Announcements this old are past the gossip message TTL and can no
+	// longer be replayed, so their times need not be kept.
+	for nodeID, publishedAt := range pm.announced {
+		if publishedAt.Before(staleThreshold) {
+			delete(pm.announced, nodeID)
+		}
+	}
+
 	if removedCount > 0 {
 		pm.logger.Debug("Cleaned up stale connections", zap.Int("count", removedCount))
 	}
@@ -401,6 +689,56 @@ func (pm *PeeringManager) GetConnection(peerID string) *PeerConnection {
 	return pm.connections[peerID]
 }
 
+// awaitSession opens a request session with the peer if no session exists
+// and waits until it has exchanged handshakes, so messages sent afterwards
+// reach the peer's handlers. It returns ErrPeerLimit when the request
+// session limit is reached.
+func (pm *PeeringManager) awaitSession(ctx context.Context, peerID, address string) error {
+	ready := func() (bool, bool) {
+		pm.mu.RLock()
+		defer pm.mu.RUnlock()
+
+		conn, exists := pm.connections[peerID]
+		if !exists {
+			return false, true
+		}
+		switch conn.Status {
+		case Connected:
+			return conn.RemoteNode != nil, false
+		case Connecting:
+			return false, false
+		default:
+			return false, true
+		}
+	}
+
+	if ok, _ := ready(); ok {
+		return nil
+	}
+	if err := pm.openSession(peerID, address, true); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		ok, failed := ready()
+		if ok {
+			return nil
+		}
+		if failed {
+			return fmt.Errorf("no session with peer %s", peerID)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
 func (pm *PeeringManager) GetAllConnections() []*PeerConnection {
 	pm.mu.RLock()
 	defer pm.mu.RUnlock()
@@ -412,13 +750,15 @@ func (pm *PeeringManager) GetAllConnections() []*PeerConnection {
 	return connections
 }
 
+// GetActiveConnections returns the connected full sessions; request
+// sessions are left out.
 func (pm *PeeringManager) GetActiveConnections() []*PeerConnection {
 	pm.mu.RLock()
 	defer pm.mu.RUnlock()
 
 	var active []*PeerConnection
 	for _, conn := range pm.connections {
-		if conn.Status == Connected {
+		if conn.Status == Connected && !conn.Transient {
 			active = append(active, conn)
 		}
 	}
@@ -493,19 +833,30 @@ type PeerMessage struct {
 	PeerID    string      `json:"peer_id"`
 	Timestamp time.Time   `json:"timestamp"`
 	Payload   []byte      `json:"payload"`
+
+	ReceivedAt time.Time `json:"-"`
 }
 
 type MessageType string
 
 const (
-	MessageTypeHandshake MessageType = "handshake"
-	MessageTypeData      MessageType = "data"
-	MessageTypeKeepAlive MessageType = "keepalive"
-	MessageTypeGoodbye   MessageType = "goodbye"
+	MessageTypeHandshake    MessageType = "handshake"
+	MessageTypeHandshakeAck MessageType = "handshake_ack"
+	MessageTypeData         MessageType = "data"
+	MessageTypeKeepAlive    MessageType = "keepalive"
+	MessageTypeKeepAliveAck MessageType = "keepalive_ack"
+	MessageTypeGoodbye      MessageType = "goodbye"
+
+	MessageTypeTimeRequest  MessageType = "time_request"
+	MessageTypeTimeResponse MessageType = "time_response"
+
+	MessageTypeSyncCorrection MessageType = "sync_correction"
+	MessageTypeSyncAck        MessageType = "sync_ack"
 )
 
 func (pm *PeeringManager) Stop() {
 	close(pm.stopChan)
+	pm.closeListener()
 
 	pm.mu.Lock()
 	for peerID := range pm.connections {
@@ -0,0 +1,216 @@
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
+)
+
+// DiscoverySource finds candidate peers. Peers it returns are unverified:
+// their IDs are only trusted once a handshake authenticates them, and any
+// position they carry is a claim until checked against measured RTTs.
+type DiscoverySource interface {
+	Name() string
+	Discover(ctx context.Context) ([]*Peer, error)
+}
+
+// DiscoveryServiceName is the DNS SRV service name peers are published
+// under, as in _relativistic._tcp.example.com.
+const DiscoveryServiceName = "relativistic"
+
+func newDiscoveredPeer(node *types.Node, source string) *Peer {
+	now := time.Now().UTC()
+	node.LastSeen = now
+	node.Metadata.Provider = source
+	return &Peer{
+		Node:         node,
+		LastSeen:     now,
+		Status:       PeerPending,
+		Capabilities: node.Metadata.Capabilities,
+		Version:      node.Metadata.Version,
+		Source:       source,
+	}
+}
+
+// StaticSource returns a fixed list of "node-id@host:port" addresses.
+type StaticSource struct {
+	addresses []string
+	logger    *zap.Logger
+}
+
+func NewStaticSource(addresses []string, logger *zap.Logger) *StaticSource {
+	return &StaticSource{addresses: addresses, logger: logger}
+}
+
+func (s *StaticSource) Name() string {
+	return "static"
+}
+
+func (s *StaticSource) Discover(ctx context.Context) ([]*Peer, error) {
+	var peers []*Peer
+	for _, address := range s.addresses {
+		peerID, hostPort, err := ParsePeerAddress(address)
+		if err != nil {
+			s.logger.Debug("Skipping static peer without node ID",
+				zap.String("address", address),
+				zap.Error(err),
+			)
+			continue
+		}
+		peers = append(peers, newDiscoveredPeer(&types.Node{ID: peerID, Address: hostPort}, s.Name()))
+	}
+	return peers, nil
+}
+
+// DNSResolver is the subset of net.Resolver used by DNSSource.
+type DNSResolver interface {
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// DNSSource looks up _<service>._tcp.<domain> SRV records. Each SRV target
+// must publish a TXT record with the node's ID and may add its position;
+// see ParsePeerTXT.
+type DNSSource struct {
+	domain   string
+	service  string
+	resolver DNSResolver
+}
+
+func NewDNSSource(domain string, resolver DNSResolver) *DNSSource {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	return &DNSSource{
+		domain:   domain,
+		service:  DiscoveryServiceName,
+		resolver: resolver,
+	}
+}
+
+func (s *DNSSource) Name() string {
+	return "dns:" + s.domain
+}
+
+func (s *DNSSource) Discover(ctx context.Context) ([]*Peer, error) {
+	_, records, err := s.resolver.LookupSRV(ctx, s.service, "tcp", s.domain)
+	if err != nil {
+		return nil, fmt.Errorf("SRV lookup for %s failed: %w", s.domain, err)
+	}
+
+	var peers []*Peer
+	for _, record := range records {
+		target := strings.TrimSuffix(record.Target, ".")
+		txt, err := s.resolver.LookupTXT(ctx, record.Target)
+		if err != nil {
+			continue
+		}
+		node, err := ParsePeerTXT(txt)
+		if err != nil {
+			continue
+		}
+		node.Address = net.JoinHostPort(target, strconv.Itoa(int(record.Port)))
+		peers = append(peers, newDiscoveredPeer(node, s.Name()))
+	}
+	return peers, nil
+}
+
+// ParsePeerTXT reads node metadata from TXT strings of the form
+// "id=<node-id>", "lat=<deg>", "lon=<deg>", "alt=<m>", "region=<name>" and
+// "version=<v>". Only id is required. Unknown keys are ignored.
+func ParsePeerTXT(records []string) (*types.Node, error) {
+	node := &types.Node{}
+	for _, record := range records {
+		for _, field := range strings.Fields(record) {
+			key, value, found := strings.Cut(field, "=")
+			if !found {
+				continue
+			}
+
+			var err error
+			switch key {
+			case "id":
+				node.ID = value
+			case "lat":
+				node.Position.Latitude, err = strconv.ParseFloat(value, 64)
+			case "lon":
+				node.Position.Longitude, err = strconv.ParseFloat(value, 64)
+			case "alt":
+				node.Position.Altitude, err = strconv.ParseFloat(value, 64)
+			case "region":
+				node.Metadata.Region = value
+			case "version":
+				node.Metadata.Version = value
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s in TXT record: %w", key, err)
+			}
+		}
+	}
+
+	if node.ID == "" {
+		return nil, fmt.Errorf("TXT record has no node id")
+	}
+	return node, nil
+}
+
+// peerTXT is the inverse of ParsePeerTXT.
+func peerTXT(node *types.Node) []string {
+	txt := []string{
+		"id=" + node.ID,
+		"lat=" + strconv.FormatFloat(node.Position.Latitude, 'f', -1, 64),
+		"lon=" + strconv.FormatFloat(node.Position.Longitude, 'f', -1, 64),
+		"alt=" + strconv.FormatFloat(node.Position.Altitude, 'f', -1, 64),
+	}
+	if node.Metadata.Region != "" {
+		txt = append(txt, "region="+node.Metadata.Region)
+	}
+	if node.Metadata.Version != "" {
+		txt = append(txt, "version="+node.Metadata.Version)
+	}
+	return txt
+}
+
+// FileSource reads a JSON array of nodes from a file on every discovery
+// round, so operators can edit the peer list without a restart.
+type FileSource struct {
+	path string
+}
+
+func NewFileSource(path string) *FileSource {
+	return &FileSource{path: path}
+}
+
+func (s *FileSource) Name() string {
+	return "file:" + s.path
+}
+
+func (s *FileSource) Discover(ctx context.Context) ([]*Peer, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read peers file: %w", err)
+	}
+
+	var nodes []*types.Node
+	if err := json.Unmarshal(data, &nodes); err != nil {
+		return nil, fmt.Errorf("failed to parse peers file %s: %w", s.path, err)
+	}
+
+	peers := make([]*Peer, 0, len(nodes))
+	for _, node := range nodes {
+		if node == nil || node.ID == "" || node.Address == "" {
+			continue
+		}
+		peers = append(peers, newDiscoveredPeer(node, s.Name()))
+	}
+	return peers, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -167,6 +168,41 @@ func (tm *TopologyManager) UpdateNodePosition(nodeID string, newPos types.Positi
 	return nil
 }
 
+// UpsertNode adds a node or replaces the position, address and metadata
+// of an existing one.
+func (tm *TopologyManager) UpsertNode(node *types.Node) error {
+	if err := tm.validateNode(node); err != nil {
+		return fmt.Errorf("invalid node data: %w", err)
+	}
+
+	tm.mu.Lock()
+	existing, exists := tm.nodes[node.ID]
+	tm.mu.Unlock()
+	if !exists {
+		return tm.AddNode(node)
+	}
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	existing.Position = node.Position
+	existing.Address = node.Address
+	existing.Metadata = node.Metadata
+	existing.IsActive = true
+	existing.LastSeen = time.Now().UTC()
+
+	if err := tm.persistNode(existing); err != nil {
+		return fmt.Errorf("failed to update node in Redis: %w", err)
+	}
+
+	tm.eventCh <- TopologyEvent{
+		Type:      types.EventTypeNodeUpdated,
+		Node:      existing,
+		Timestamp: time.Now().UTC(),
+	}
+	return nil
+}
+
 func (tm *TopologyManager) GetAllNodes() []*types.Node {
 	tm.mu.RLock()
 	defer tm.mu.RUnlock()
@@ -319,6 +355,47 @@ func (tm *TopologyManager) Close() {
 	tm.redis.Close()
 }
 
+// SaveRecord stores an arbitrary JSON-encoded record alongside the topology
+// so other components can persist state without their own Redis client.
+func (tm *TopologyManager) SaveRecord(key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode record %s: %w", key, err)
+	}
+
+	ctx := context.Background()
+	if err := tm.redis.Set(ctx, fmt.Sprintf("record:%s", key), data, 0).Err(); err != nil {
+		return fmt.Errorf("failed to save record %s: %w", key, err)
+	}
+	return nil
+}
+
+func (tm *TopologyManager) LoadRecord(key string, value interface{}) error {
+	ctx := context.Background()
+	data, err := tm.redis.Get(ctx, fmt.Sprintf("record:%s", key)).Bytes()
+	if err != nil {
+		return fmt.Errorf("failed to load record %s: %w", key, err)
+	}
+
+	if err := json.Unmarshal(data, value); err != nil {
+		return fmt.Errorf("failed to decode record %s: %w", key, err)
+	}
+	return nil
+}
+
+func (tm *TopologyManager) ListRecordKeys(prefix string) ([]string, error) {
+	ctx := context.Background()
+	var keys []string
+	iter := tm.redis.Scan(ctx, 0, fmt.Sprintf("record:%s*", prefix), 100).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, strings.TrimPrefix(iter.Val(), "record:"))
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list records: %w", err)
+	}
+	return keys, nil
+}
+
 func (tm *TopologyManager) GetTopologyGraph() (interface{}, error) {
     return nil, nil
 }
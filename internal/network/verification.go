@@ -0,0 +1,343 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/relativistic"
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
+)
+
+// NodeSource looks up registered nodes and their claimed positions. The
+// topology manager implements it.
+type NodeSource interface {
+	GetNode(nodeID string) (*types.Node, error)
+}
+
+// PositionVerifierConfig controls how anchor RTTs constrain claimed
+// positions.
+type PositionVerifierConfig struct {
+	// MinAnchors is the number of anchor measurements needed before a
+	// verification is considered complete.
+	MinAnchors int `json:"min_anchors"`
+	// RTTTolerance widens every anchor's distance bound to cover anchor
+	// position error and timer resolution.
+	RTTTolerance time.Duration `json:"rtt_tolerance"`
+	// MaxSampleAge drops anchor measurements older than this.
+	MaxSampleAge time.Duration `json:"max_sample_age"`
+	// UnverifiedTrust is the score of a node no anchor has measured.
+	UnverifiedTrust float64 `json:"unverified_trust"`
+	// ReverifyInterval is how often measured nodes are re-checked, so
+	// expired samples and changed positions are picked up.
+	ReverifyInterval time.Duration `json:"reverify_interval"`
+}
+
+func DefaultPositionVerifierConfig() *PositionVerifierConfig {
+	return &PositionVerifierConfig{
+		MinAnchors:       3,
+		RTTTolerance:     DefaultRTTTolerance,
+		MaxSampleAge:     10 * time.Minute,
+		UnverifiedTrust:  1.0,
+		ReverifyInterval: time.Minute,
+	}
+}
+
+// AnchorConstraint is the disc one anchor's RTT allows the node to be in:
+// a signal cannot travel further than c × RTT/2 before the reply leaves.
+type AnchorConstraint struct {
+	AnchorID          string        `json:"anchor_id"`
+	RTT               time.Duration `json:"rtt"`
+	MaxDistanceKm     float64       `json:"max_distance_km"`
+	ClaimedDistanceKm float64       `json:"claimed_distance_km"`
+	Satisfied         bool          `json:"satisfied"`
+	MeasuredAt        time.Time     `json:"measured_at"`
+}
+
+// PositionVerification is the outcome of checking a node's claimed
+// position against the feasible region, the intersection of all anchor
+// constraint discs.
+type PositionVerification struct {
+	NodeID      string              `json:"node_id"`
+	Claimed     types.Position      `json:"claimed"`
+	Constraints []*AnchorConstraint `json:"constraints"`
+	Violations  int                 `json:"violations"`
+	Complete    bool                `json:"complete"`
+	Flagged     bool                `json:"flagged"`
+	TrustScore  float64             `json:"trust_score"`
+	VerifiedAt  time.Time           `json:"verified_at"`
+}
+
+type anchorSample struct {
+	rtt        time.Duration
+	measuredAt time.Time
+}
+
+// PositionVerifier checks claimed node positions against RTTs measured from
+// trusted anchor nodes and keeps a location-trust score per node.
+type PositionVerifier struct {
+	nodes      NodeSource
+	config     *PositionVerifierConfig
+	calculator *relativistic.RelativisticCalculator
+	logger     *zap.Logger
+	mu         sync.RWMutex
+	anchors    map[string]bool
+	samples    map[string]map[string]*anchorSample
+	results    map[string]*PositionVerification
+}
+
+func NewPositionVerifier(nodes NodeSource, config *PositionVerifierConfig, logger *zap.Logger) *PositionVerifier {
+	if config == nil {
+		config = DefaultPositionVerifierConfig()
+	}
+	return &PositionVerifier{
+		nodes:      nodes,
+		config:     config,
+		calculator: relativistic.NewCalculator(),
+		logger:     logger,
+		anchors:    make(map[string]bool),
+		samples:    make(map[string]map[string]*anchorSample),
+		results:    make(map[string]*PositionVerification),
+	}
+}
+
+// AddAnchor marks a node as trusted to report RTTs. Its own position must
+// be registered in the topology.
+func (pv *PositionVerifier) AddAnchor(nodeID string) error {
+	if _, err := pv.nodes.GetNode(nodeID); err != nil {
+		return fmt.Errorf("anchor %s is not in the topology: %w", nodeID, err)
+	}
+
+	pv.mu.Lock()
+	defer pv.mu.Unlock()
+	pv.anchors[nodeID] = true
+	return nil
+}
+
+func (pv *PositionVerifier) RemoveAnchor(nodeID string) {
+	pv.mu.Lock()
+	defer pv.mu.Unlock()
+
+	delete(pv.anchors, nodeID)
+	for _, samples := range pv.samples {
+		delete(samples, nodeID)
+	}
+}
+
+func (pv *PositionVerifier) Anchors() []string {
+	pv.mu.RLock()
+	defer pv.mu.RUnlock()
+
+	anchors := make([]string, 0, len(pv.anchors))
+	for id := range pv.anchors {
+		anchors = append(anchors, id)
+	}
+	sort.Strings(anchors)
+	return anchors
+}
+
+func (pv *PositionVerifier) IsAnchor(nodeID string) bool {
+	pv.mu.RLock()
+	defer pv.mu.RUnlock()
+	return pv.anchors[nodeID]
+}
+
+// RecordRTT stores an RTT measured by an anchor and re-verifies the node.
+// Only the smallest recent RTT per anchor is kept, since queueing can only
+// make RTTs longer.
+func (pv *PositionVerifier) RecordRTT(anchorID, nodeID string, rtt time.Duration) error {
+	if rtt <= 0 {
+		return fmt.Errorf("RTT must be positive")
+	}
+	if anchorID == nodeID {
+		return fmt.Errorf("anchor cannot measure itself")
+	}
+
+	pv.mu.Lock()
+	if !pv.anchors[anchorID] {
+		pv.mu.Unlock()
+		return fmt.Errorf("node %s is not an anchor", anchorID)
+	}
+
+	now := time.Now().UTC()
+	samples, exists := pv.samples[nodeID]
+	if !exists {
+		samples = make(map[string]*anchorSample)
+		pv.samples[nodeID] = samples
+	}
+	if existing, ok := samples[anchorID]; ok && existing.rtt <= rtt && now.Sub(existing.measuredAt) <= pv.config.MaxSampleAge {
+		pv.mu.Unlock()
+		return nil
+	}
+	samples[anchorID] = &anchorSample{rtt: rtt, measuredAt: now}
+	pv.mu.Unlock()
+
+	if _, err := pv.Verify(nodeID); err != nil {
+		pv.logger.Debug("Failed to verify measured node",
+			zap.String("node_id", nodeID),
+			zap.Error(err),
+		)
+	}
+	return nil
+}
+
+// Start re-verifies every measured node each ReverifyInterval until ctx is
+// done.
+func (pv *PositionVerifier) Start(ctx context.Context) {
+	ticker := time.NewTicker(pv.config.ReverifyInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			pv.VerifyAll()
+		}
+	}
+}
+
+// VerifyAll re-verifies every node with anchor measurements.
+func (pv *PositionVerifier) VerifyAll() {
+	pv.mu.RLock()
+	nodeIDs := make([]string, 0, len(pv.samples))
+	for nodeID := range pv.samples {
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+	pv.mu.RUnlock()
+
+	for _, nodeID := range nodeIDs {
+		if _, err := pv.Verify(nodeID); err != nil {
+			pv.logger.Debug("Failed to verify measured node",
+				zap.String("node_id", nodeID),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// Verify checks the node's current claimed position against every recent
+// anchor measurement and updates its trust score.
+func (pv *PositionVerifier) Verify(nodeID string) (*PositionVerification, error) {
+	node, err := pv.nodes.GetNode(nodeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get node %s: %w", nodeID, err)
+	}
+
+	pv.mu.Lock()
+	defer pv.mu.Unlock()
+
+	now := time.Now().UTC()
+	result := &PositionVerification{
+		NodeID:     nodeID,
+		Claimed:    node.Position,
+		VerifiedAt: now,
+	}
+
+	toleranceKm := distanceForDelay(pv.config.RTTTolerance/2) / 1000
+	for anchorID, sample := range pv.samples[nodeID] {
+		if now.Sub(sample.measuredAt) > pv.config.MaxSampleAge {
+			delete(pv.samples[nodeID], anchorID)
+			continue
+		}
+		anchor, err := pv.nodes.GetNode(anchorID)
+		if err != nil {
+			continue
+		}
+
+		maxDistanceKm := distanceForDelay(sample.rtt/2) / 1000
+		claimedKm := pv.calculator.CalculateDistance(anchor.Position, node.Position) / 1000
+		constraint := &AnchorConstraint{
+			AnchorID:          anchorID,
+			RTT:               sample.rtt,
+			MaxDistanceKm:     maxDistanceKm,
+			ClaimedDistanceKm: claimedKm,
+			Satisfied:         claimedKm <= maxDistanceKm+toleranceKm,
+			MeasuredAt:        sample.measuredAt,
+		}
+		if !constraint.Satisfied {
+			result.Violations++
+		}
+		result.Constraints = append(result.Constraints, constraint)
+	}
+	sort.Slice(result.Constraints, func(i, j int) bool {
+		return result.Constraints[i].AnchorID < result.Constraints[j].AnchorID
+	})
+
+	result.Complete = len(result.Constraints) >= pv.config.MinAnchors
+	result.Flagged = result.Violations > 0
+	result.TrustScore = pv.trustScore(result)
+	pv.results[nodeID] = result
+
+	if result.Flagged {
+		pv.logger.Warn("Node position outside the feasible region",
+			zap.String("node_id", nodeID),
+			zap.Float64("lat", node.Position.Latitude),
+			zap.Float64("lon", node.Position.Longitude),
+			zap.Int("violations", result.Violations),
+			zap.Int("anchors", len(result.Constraints)),
+			zap.Float64("trust_score", result.TrustScore),
+		)
+	}
+
+	return result, nil
+}
+
+// distanceForDelay is how far light travels in d, in metres.
+func distanceForDelay(d time.Duration) float64 {
+	return d.Seconds() * types.SpeedOfLight
+}
+
+// trustScore is the fraction of anchor constraints the claimed position
+// satisfies. With fewer than MinAnchors measurements the missing anchors
+// count at UnverifiedTrust, so thin evidence moves the score only partly.
+func (pv *PositionVerifier) trustScore(result *PositionVerification) float64 {
+	total := len(result.Constraints)
+	if total == 0 {
+		return pv.config.UnverifiedTrust
+	}
+
+	satisfied := float64(total - result.Violations)
+	if missing := pv.config.MinAnchors - total; missing > 0 {
+		return (satisfied + float64(missing)*pv.config.UnverifiedTrust) / float64(pv.config.MinAnchors)
+	}
+	return satisfied / float64(total)
+}
+
+// TrustScore returns the node's score from its last verification, or
+// UnverifiedTrust when it has not been verified.
+func (pv *PositionVerifier) TrustScore(nodeID string) float64 {
+	pv.mu.RLock()
+	defer pv.mu.RUnlock()
+
+	if result, exists := pv.results[nodeID]; exists {
+		return result.TrustScore
+	}
+	return pv.config.UnverifiedTrust
+}
+
+func (pv *PositionVerifier) GetVerification(nodeID string) *PositionVerification {
+	pv.mu.RLock()
+	defer pv.mu.RUnlock()
+	return pv.results[nodeID]
+}
+
+// FlaggedNodes returns the IDs of nodes whose last verification found the
+// claimed position outside the feasible region.
+func (pv *PositionVerifier) FlaggedNodes() []string {
+	pv.mu.RLock()
+	defer pv.mu.RUnlock()
+
+	var flagged []string
+	for id, result := range pv.results {
+		if result.Flagged {
+			flagged = append(flagged, id)
+		}
+	}
+	sort.Strings(flagged)
+	return flagged
+}
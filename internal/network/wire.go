@@ -0,0 +1,326 @@
+package network
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Every frame starts with a fixed header: the payload length (big-endian
+// uint32, header excluded), the wire version, the codec that encoded the
+// message and a flags byte. Messages larger than MaxFrameSize are streamed
+// as several frames, all but the last carrying FrameFlagMore.
+const (
+	WireVersion     byte = 1
+	FrameHeaderSize      = 7
+
+	FrameFlagMore byte = 1 << 0
+
+	DefaultMaxFrameSize   = 64 * 1024
+	DefaultMaxMessageSize = 16 * 1024 * 1024
+)
+
+var (
+	ErrUnsupportedVersion = errors.New("unsupported wire version")
+	ErrUnknownCodec       = errors.New("unknown codec")
+	ErrFrameTooLarge      = errors.New("frame exceeds maximum size")
+	ErrMessageTooLarge    = errors.New("message exceeds maximum size")
+	ErrMalformedMessage   = errors.New("malformed message")
+)
+
+type CodecID byte
+
+const (
+	CodecJSON   CodecID = 1
+	CodecBinary CodecID = 2
+)
+
+// Codec turns a PeerMessage into the payload of a frame and back.
+type Codec interface {
+	ID() CodecID
+	Name() string
+	Marshal(message *PeerMessage) ([]byte, error)
+	Unmarshal(data []byte, message *PeerMessage) error
+}
+
+func CodecByID(id CodecID) (Codec, error) {
+	switch id {
+	case CodecJSON:
+		return JSONCodec{}, nil
+	case CodecBinary:
+		return BinaryCodec{}, nil
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrUnknownCodec, id)
+	}
+}
+
+type JSONCodec struct{}
+
+func (JSONCodec) ID() CodecID  { return CodecJSON }
+func (JSONCodec) Name() string { return "json" }
+
+func (JSONCodec) Marshal(message *PeerMessage) ([]byte, error) {
+	return json.Marshal(message)
+}
+
+func (JSONCodec) Unmarshal(data []byte, message *PeerMessage) error {
+	if err := json.Unmarshal(data, message); err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformedMessage, err)
+	}
+	return nil
+}
+
+// BinaryCodec encodes messages in the protobuf wire format:
+//
+//	message PeerMessage {
+//	  string  type      = 1;
+//	  string  peer_id   = 2;
+//	  sfixed64 timestamp = 3; // Unix nanoseconds
+//	  bytes   payload   = 4;
+//	}
+//
+// Unknown fields are skipped so newer peers can add fields.
+type BinaryCodec struct{}
+
+const (
+	binaryFieldType      protowire.Number = 1
+	binaryFieldPeerID    protowire.Number = 2
+	binaryFieldTimestamp protowire.Number = 3
+	binaryFieldPayload   protowire.Number = 4
+)
+
+func (BinaryCodec) ID() CodecID  { return CodecBinary }
+func (BinaryCodec) Name() string { return "binary" }
+
+func (BinaryCodec) Marshal(message *PeerMessage) ([]byte, error) {
+	var buf []byte
+	if message.Type != "" {
+		buf = protowire.AppendTag(buf, binaryFieldType, protowire.BytesType)
+		buf = protowire.AppendString(buf, string(message.Type))
+	}
+	if message.PeerID != "" {
+		buf = protowire.AppendTag(buf, binaryFieldPeerID, protowire.BytesType)
+		buf = protowire.AppendString(buf, message.PeerID)
+	}
+	if !message.Timestamp.IsZero() {
+		buf = protowire.AppendTag(buf, binaryFieldTimestamp, protowire.Fixed64Type)
+		buf = protowire.AppendFixed64(buf, uint64(message.Timestamp.UnixNano()))
+	}
+	if len(message.Payload) > 0 {
+		buf = protowire.AppendTag(buf, binaryFieldPayload, protowire.BytesType)
+		buf = protowire.AppendBytes(buf, message.Payload)
+	}
+	return buf, nil
+}
+
+func (BinaryCodec) Unmarshal(data []byte, message *PeerMessage) error {
+	*message = PeerMessage{}
+
+	for len(data) > 0 {
+		number, wireType, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return fmt.Errorf("%w: %v", ErrMalformedMessage, protowire.ParseError(n))
+		}
+		data = data[n:]
+
+		switch {
+		case number == binaryFieldType && wireType == protowire.BytesType:
+			value, n := protowire.ConsumeString(data)
+			if n < 0 {
+				return fmt.Errorf("%w: type: %v", ErrMalformedMessage, protowire.ParseError(n))
+			}
+			message.Type = MessageType(value)
+			data = data[n:]
+		case number == binaryFieldPeerID && wireType == protowire.BytesType:
+			value, n := protowire.ConsumeString(data)
+			if n < 0 {
+				return fmt.Errorf("%w: peer_id: %v", ErrMalformedMessage, protowire.ParseError(n))
+			}
+			message.PeerID = value
+			data = data[n:]
+		case number == binaryFieldTimestamp && wireType == protowire.Fixed64Type:
+			value, n := protowire.ConsumeFixed64(data)
+			if n < 0 {
+				return fmt.Errorf("%w: timestamp: %v", ErrMalformedMessage, protowire.ParseError(n))
+			}
+			message.Timestamp = time.Unix(0, int64(value)).UTC()
+			data = data[n:]
+		case number == binaryFieldPayload && wireType == protowire.BytesType:
+			value, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return fmt.Errorf("%w: payload: %v", ErrMalformedMessage, protowire.ParseError(n))
+			}
+			message.Payload = append([]byte(nil), value...)
+			data = data[n:]
+		default:
+			n := protowire.ConsumeFieldValue(number, wireType, data)
+			if n < 0 {
+				return fmt.Errorf("%w: field %d: %v", ErrMalformedMessage, number, protowire.ParseError(n))
+			}
+			data = data[n:]
+		}
+	}
+	return nil
+}
+
+type WireConfig struct {
+	Codec          CodecID `json:"codec"`
+	MaxFrameSize   int     `json:"max_frame_size"`
+	MaxMessageSize int     `json:"max_message_size"`
+}
+
+func DefaultWireConfig() *WireConfig {
+	return &WireConfig{
+		Codec:          CodecBinary,
+		MaxFrameSize:   DefaultMaxFrameSize,
+		MaxMessageSize: DefaultMaxMessageSize,
+	}
+}
+
+// FrameWriter writes messages as frames. It is safe for concurrent use;
+// the frames of one message are never interleaved with another's.
+type FrameWriter struct {
+	w      io.Writer
+	codec  Codec
+	config *WireConfig
+	mu     sync.Mutex
+}
+
+func NewFrameWriter(w io.Writer, config *WireConfig) (*FrameWriter, error) {
+	if config == nil {
+		config = DefaultWireConfig()
+	}
+	codec, err := CodecByID(config.Codec)
+	if err != nil {
+		return nil, err
+	}
+	return &FrameWriter{w: w, codec: codec, config: config}, nil
+}
+
+// WriteMessage encodes and writes a message, returning the bytes written
+// including frame headers.
+func (fw *FrameWriter) WriteMessage(message *PeerMessage) (int, error) {
+	data, err := fw.codec.Marshal(message)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode message: %w", err)
+	}
+	if len(data) > fw.config.MaxMessageSize {
+		return 0, fmt.Errorf("%w: %d bytes, limit %d", ErrMessageTooLarge, len(data), fw.config.MaxMessageSize)
+	}
+
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	written := 0
+	header := make([]byte, FrameHeaderSize)
+	for {
+		chunk := data
+		flags := byte(0)
+		if len(chunk) > fw.config.MaxFrameSize {
+			chunk = chunk[:fw.config.MaxFrameSize]
+			flags |= FrameFlagMore
+		}
+
+		binary.BigEndian.PutUint32(header[0:4], uint32(len(chunk)))
+		header[4] = WireVersion
+		header[5] = byte(fw.codec.ID())
+		header[6] = flags
+
+		n, err := fw.w.Write(append(header, chunk...))
+		written += n
+		if err != nil {
+			return written, err
+		}
+
+		data = data[len(chunk):]
+		if flags&FrameFlagMore == 0 {
+			return written, nil
+		}
+	}
+}
+
+// FrameReader reassembles messages from a stream of frames. Frames may
+// arrive split across, or packed into, arbitrary reads.
+type FrameReader struct {
+	r      *bufio.Reader
+	config *WireConfig
+	header [FrameHeaderSize]byte
+}
+
+func NewFrameReader(r io.Reader, config *WireConfig) *FrameReader {
+	if config == nil {
+		config = DefaultWireConfig()
+	}
+	return &FrameReader{r: bufio.NewReader(r), config: config}
+}
+
+// ReadMessage reads the next message and the number of bytes it took on
+// the wire. Errors wrapping ErrMalformedMessage leave the stream aligned
+// on the next frame; any other error means the stream is unusable.
+func (fr *FrameReader) ReadMessage() (*PeerMessage, int, error) {
+	var data []byte
+	var codecID CodecID
+	read := 0
+
+	for first := true; ; first = false {
+		if _, err := io.ReadFull(fr.r, fr.header[:]); err != nil {
+			if !first && err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, read, err
+		}
+		read += FrameHeaderSize
+
+		length := int(binary.BigEndian.Uint32(fr.header[0:4]))
+		version := fr.header[4]
+		frameCodec := CodecID(fr.header[5])
+		flags := fr.header[6]
+
+		if version != WireVersion {
+			return nil, read, fmt.Errorf("%w: %d", ErrUnsupportedVersion, version)
+		}
+		if first {
+			codecID = frameCodec
+		} else if frameCodec != codecID {
+			return nil, read, fmt.Errorf("codec changed mid-message from %d to %d", codecID, frameCodec)
+		}
+		if length > fr.config.MaxFrameSize {
+			return nil, read, fmt.Errorf("%w: %d bytes, limit %d", ErrFrameTooLarge, length, fr.config.MaxFrameSize)
+		}
+		if len(data)+length > fr.config.MaxMessageSize {
+			return nil, read, fmt.Errorf("%w: limit %d", ErrMessageTooLarge, fr.config.MaxMessageSize)
+		}
+
+		start := len(data)
+		data = append(data, make([]byte, length)...)
+		if _, err := io.ReadFull(fr.r, data[start:]); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, read, err
+		}
+		read += length
+
+		if flags&FrameFlagMore == 0 {
+			break
+		}
+	}
+
+	codec, err := CodecByID(codecID)
+	if err != nil {
+		return nil, read, fmt.Errorf("%w: %v", ErrMalformedMessage, err)
+	}
+
+	message := &PeerMessage{}
+	if err := codec.Unmarshal(data, message); err != nil {
+		return nil, read, err
+	}
+	return message, read, nil
+}
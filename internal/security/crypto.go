@@ -110,18 +110,13 @@ func (cm *CryptoManager) VerifyPassword(password, salt, expectedHash string) boo
 }
 
 func (cm *CryptoManager) GenerateKeyPair() (privateKey, publicKey string, err error) {
-	privateBytes, err := cm.GenerateRandomBytes(32)
+	key, err := GenerateSigningKey(KeyTypeEd25519)
 	if err != nil {
 		return "", "", err
 	}
 
-	publicBytes, err := cm.GenerateRandomBytes(32)
-	if err != nil {
-		return "", "", err
-	}
-
-	privateKey = base64.URLEncoding.EncodeToString(privateBytes)
-	publicKey = base64.URLEncoding.EncodeToString(publicBytes)
+	privateKey = base64.URLEncoding.EncodeToString(key.Bytes())
+	publicKey = base64.URLEncoding.EncodeToString(key.Public().Key)
 
 	return privateKey, publicKey, nil
 }
@@ -0,0 +1,503 @@
+package security
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
+)
+
+type KeyType string
+
+// ErrInvalidSignature marks a signature that is missing, malformed or does
+// not verify against the signer's registered key, as opposed to a signer
+// whose key is not known here.
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// ErrKeyConflict marks an attempt to register a key for a validator that
+// already has a different one; RotateValidatorKey replaces keys.
+var ErrKeyConflict = errors.New("validator already has a different key registered")
+
+const (
+	KeyTypeEd25519   KeyType = "ed25519"
+	KeyTypeSecp256k1 KeyType = "secp256k1"
+)
+
+// SigningKey is a validator's private key. Ed25519 signs the payload
+// directly; secp256k1 signs its SHA-256 digest and emits DER signatures.
+type SigningKey struct {
+	Type       KeyType
+	ed25519Key ed25519.PrivateKey
+	secpKey    *secp256k1.PrivateKey
+}
+
+type PublicKey struct {
+	Type KeyType `json:"type"`
+	Key  []byte  `json:"key"`
+}
+
+func (pk *PublicKey) String() string {
+	return hex.EncodeToString(pk.Key)
+}
+
+func (pk *PublicKey) Equal(other *PublicKey) bool {
+	return other != nil && pk.Type == other.Type && bytes.Equal(pk.Key, other.Key)
+}
+
+// ParsePublicKey decodes a hex public key and checks it is valid for its
+// type.
+func ParsePublicKey(keyType KeyType, publicKeyHex string) (*PublicKey, error) {
+	key, err := hex.DecodeString(publicKeyHex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key encoding: %w", err)
+	}
+
+	switch keyType {
+	case KeyTypeEd25519:
+		if len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid ed25519 public key length: %d", len(key))
+		}
+	case KeyTypeSecp256k1:
+		if _, err := secp256k1.ParsePubKey(key); err != nil {
+			return nil, fmt.Errorf("invalid secp256k1 public key: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported key type: %s", keyType)
+	}
+	return &PublicKey{Type: keyType, Key: key}, nil
+}
+
+func GenerateSigningKey(keyType KeyType) (*SigningKey, error) {
+	switch keyType {
+	case KeyTypeEd25519:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate ed25519 key: %w", err)
+		}
+		return &SigningKey{Type: keyType, ed25519Key: key}, nil
+	case KeyTypeSecp256k1:
+		key, err := secp256k1.GeneratePrivateKey()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate secp256k1 key: %w", err)
+		}
+		return &SigningKey{Type: keyType, secpKey: key}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type: %s", keyType)
+	}
+}
+
+// ParseSigningKey restores a key from the bytes returned by Bytes.
+func ParseSigningKey(keyType KeyType, data []byte) (*SigningKey, error) {
+	switch keyType {
+	case KeyTypeEd25519:
+		switch len(data) {
+		case ed25519.SeedSize:
+			return &SigningKey{Type: keyType, ed25519Key: ed25519.NewKeyFromSeed(data)}, nil
+		case ed25519.PrivateKeySize:
+			return &SigningKey{Type: keyType, ed25519Key: ed25519.PrivateKey(data)}, nil
+		}
+		return nil, fmt.Errorf("invalid ed25519 private key length: %d", len(data))
+	case KeyTypeSecp256k1:
+		if len(data) != secp256k1.PrivKeyBytesLen {
+			return nil, fmt.Errorf("invalid secp256k1 private key length: %d", len(data))
+		}
+		return &SigningKey{Type: keyType, secpKey: secp256k1.PrivKeyFromBytes(data)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type: %s", keyType)
+	}
+}
+
+// LoadOrCreateSigningKey reads the PEM-encoded validator key at path,
+// creating it with a fresh key of keyType if it does not exist. The key type
+// of an existing file is taken from its Key-Type header.
+func LoadOrCreateSigningKey(path string, keyType KeyType) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		key, err := GenerateSigningKey(keyType)
+		if err != nil {
+			return nil, err
+		}
+		if err := key.Save(path); err != nil {
+			return nil, err
+		}
+		return key, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read validator key file: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "VALIDATOR PRIVATE KEY" {
+		return nil, fmt.Errorf("validator key file %s does not contain a PEM validator key", path)
+	}
+	return ParseSigningKey(KeyType(block.Headers["Key-Type"]), block.Bytes)
+}
+
+func (sk *SigningKey) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create validator key directory: %w", err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{
+		Type:    "VALIDATOR PRIVATE KEY",
+		Headers: map[string]string{"Key-Type": string(sk.Type)},
+		Bytes:   sk.Bytes(),
+	})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write validator key file: %w", err)
+	}
+	return nil
+}
+
+func (sk *SigningKey) Bytes() []byte {
+	if sk.Type == KeyTypeSecp256k1 {
+		return sk.secpKey.Serialize()
+	}
+	return sk.ed25519Key.Seed()
+}
+
+func (sk *SigningKey) Ed25519() ed25519.PrivateKey {
+	return sk.ed25519Key
+}
+
+func (sk *SigningKey) Public() *PublicKey {
+	if sk.Type == KeyTypeSecp256k1 {
+		return &PublicKey{Type: sk.Type, Key: sk.secpKey.PubKey().SerializeCompressed()}
+	}
+	return &PublicKey{Type: sk.Type, Key: sk.ed25519Key.Public().(ed25519.PublicKey)}
+}
+
+func (sk *SigningKey) Sign(payload []byte) []byte {
+	if sk.Type == KeyTypeSecp256k1 {
+		digest := sha256.Sum256(payload)
+		return ecdsa.Sign(sk.secpKey, digest[:]).Serialize()
+	}
+	return ed25519.Sign(sk.ed25519Key, payload)
+}
+
+func VerifySignature(publicKey *PublicKey, payload, signature []byte) error {
+	switch publicKey.Type {
+	case KeyTypeEd25519:
+		if len(publicKey.Key) != ed25519.PublicKeySize {
+			return fmt.Errorf("invalid ed25519 public key length: %d", len(publicKey.Key))
+		}
+		if !ed25519.Verify(publicKey.Key, payload, signature) {
+			return fmt.Errorf("ed25519 signature verification failed")
+		}
+		return nil
+	case KeyTypeSecp256k1:
+		key, err := secp256k1.ParsePubKey(publicKey.Key)
+		if err != nil {
+			return fmt.Errorf("invalid secp256k1 public key: %w", err)
+		}
+		sig, err := ecdsa.ParseDERSignature(signature)
+		if err != nil {
+			return fmt.Errorf("invalid secp256k1 signature: %w", err)
+		}
+		digest := sha256.Sum256(payload)
+		if !sig.Verify(digest[:], key) {
+			return fmt.Errorf("secp256k1 signature verification failed")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported key type: %s", publicKey.Type)
+	}
+}
+
+// KeyManager holds this node's signing key and the registered public keys
+// of every validator. Signatures travel hex-encoded in the Signature fields
+// of votes, proposals and quorum certificates.
+type KeyManager struct {
+	logger        *zap.Logger
+	mu            sync.RWMutex
+	localID       string
+	localKey      *SigningKey
+	validatorKeys map[string]*PublicKey
+}
+
+func NewKeyManager(logger *zap.Logger) *KeyManager {
+	return &KeyManager{
+		logger:        logger,
+		validatorKeys: make(map[string]*PublicKey),
+	}
+}
+
+// SetLocalKey installs this node's signing key and registers its public key
+// under the given validator ID.
+func (km *KeyManager) SetLocalKey(validatorID string, key *SigningKey) {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+
+	km.localID = validatorID
+	km.localKey = key
+	km.validatorKeys[validatorID] = key.Public()
+}
+
+func (km *KeyManager) LocalID() string {
+	km.mu.RLock()
+	defer km.mu.RUnlock()
+	return km.localID
+}
+
+func (km *KeyManager) LocalKey() *SigningKey {
+	km.mu.RLock()
+	defer km.mu.RUnlock()
+	return km.localKey
+}
+
+// RegisterValidatorKey records a validator's public key. Registering the
+// key already on record is a no-op; a different key is refused with
+// ErrKeyConflict, so a registered key can only be replaced by rotating it.
+func (km *KeyManager) RegisterValidatorKey(validatorID string, keyType KeyType, publicKeyHex string) error {
+	publicKey, err := ParsePublicKey(keyType, publicKeyHex)
+	if err != nil {
+		return err
+	}
+
+	km.mu.Lock()
+	if existing, exists := km.validatorKeys[validatorID]; exists {
+		km.mu.Unlock()
+		if existing.Equal(publicKey) {
+			return nil
+		}
+		return fmt.Errorf("%w: %s", ErrKeyConflict, validatorID)
+	}
+	km.validatorKeys[validatorID] = publicKey
+	km.mu.Unlock()
+
+	km.logger.Info("Validator key registered",
+		zap.String("validator_id", validatorID),
+		zap.String("key_type", string(keyType)),
+	)
+	return nil
+}
+
+// RotateValidatorKey replaces a validator's registered key. The signature
+// must be by the key being replaced over KeyRotationPayload, so only its
+// holder can hand the registration over.
+func (km *KeyManager) RotateValidatorKey(validatorID string, keyType KeyType, publicKeyHex, signatureHex string) error {
+	next, err := ParsePublicKey(keyType, publicKeyHex)
+	if err != nil {
+		return err
+	}
+
+	km.mu.Lock()
+	defer km.mu.Unlock()
+
+	current, exists := km.validatorKeys[validatorID]
+	if !exists {
+		return fmt.Errorf("no public key registered for validator %s", validatorID)
+	}
+	signature, err := hex.DecodeString(signatureHex)
+	if err != nil {
+		return fmt.Errorf("%w: bad encoding from %s: %v", ErrInvalidSignature, validatorID, err)
+	}
+	payload := types.KeyRotationPayload(validatorID, current.Key, string(next.Type), next.Key)
+	if err := VerifySignature(current, payload, signature); err != nil {
+		return fmt.Errorf("%w: key rotation for %s: %v", ErrInvalidSignature, validatorID, err)
+	}
+	km.validatorKeys[validatorID] = next
+
+	km.logger.Info("Validator key rotated",
+		zap.String("validator_id", validatorID),
+		zap.String("key_type", string(keyType)),
+	)
+	return nil
+}
+
+// SignKeyRotation signs the hand-over of the local validator's registration
+// to next, for other nodes' RotateValidatorKey.
+func (km *KeyManager) SignKeyRotation(next *PublicKey) (string, error) {
+	km.mu.RLock()
+	localID := km.localID
+	key := km.localKey
+	km.mu.RUnlock()
+
+	if key == nil {
+		return "", fmt.Errorf("no local signing key configured")
+	}
+	payload := types.KeyRotationPayload(localID, key.Public().Key, string(next.Type), next.Key)
+	return hex.EncodeToString(key.Sign(payload)), nil
+}
+
+func (km *KeyManager) RemoveValidatorKey(validatorID string) {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+	delete(km.validatorKeys, validatorID)
+}
+
+func (km *KeyManager) GetValidatorKey(validatorID string) (*PublicKey, error) {
+	km.mu.RLock()
+	defer km.mu.RUnlock()
+
+	key, exists := km.validatorKeys[validatorID]
+	if !exists {
+		return nil, fmt.Errorf("no public key registered for validator %s", validatorID)
+	}
+	return key, nil
+}
+
+func (km *KeyManager) sign(payload []byte) (string, error) {
+	km.mu.RLock()
+	key := km.localKey
+	km.mu.RUnlock()
+
+	if key == nil {
+		return "", fmt.Errorf("no local signing key configured")
+	}
+	return hex.EncodeToString(key.Sign(payload)), nil
+}
+
+func (km *KeyManager) verify(signerID string, payload []byte, signatureHex string) error {
+	if signatureHex == "" {
+		return fmt.Errorf("%w: missing signature from %s", ErrInvalidSignature, signerID)
+	}
+
+	publicKey, err := km.GetValidatorKey(signerID)
+	if err != nil {
+		return err
+	}
+
+	signature, err := hex.DecodeString(signatureHex)
+	if err != nil {
+		return fmt.Errorf("%w: bad encoding from %s: %v", ErrInvalidSignature, signerID, err)
+	}
+
+	if err := VerifySignature(publicKey, payload, signature); err != nil {
+		return fmt.Errorf("%w from %s: %v", ErrInvalidSignature, signerID, err)
+	}
+	return nil
+}
+
+func (km *KeyManager) SignVote(vote *types.Vote) error {
+	signature, err := km.sign(vote.SigningPayload())
+	if err != nil {
+		return err
+	}
+	vote.Signature = signature
+	return nil
+}
+
+func (km *KeyManager) VerifyVote(vote *types.Vote) error {
+	return km.verify(vote.VoterID, vote.SigningPayload(), vote.Signature)
+}
+
+func (km *KeyManager) SignProposal(proposal *types.Proposal) error {
+	signature, err := km.sign(proposal.SigningPayload())
+	if err != nil {
+		return err
+	}
+	proposal.Signature = signature
+	return nil
+}
+
+func (km *KeyManager) VerifyProposal(proposal *types.Proposal) error {
+	if proposal.Block != nil && proposal.Block.ProposedBy != "" && proposal.Block.ProposedBy != proposal.ProposerID {
+		return fmt.Errorf("%w: proposal signer %s does not match block proposer %s", ErrInvalidSignature, proposal.ProposerID, proposal.Block.ProposedBy)
+	}
+	return km.verify(proposal.ProposerID, proposal.SigningPayload(), proposal.Signature)
+}
+
+func (km *KeyManager) SignHeader(header *types.BlockHeader) error {
+	signature, err := km.sign(header.SigningPayload())
+	if err != nil {
+		return err
+	}
+	header.Signature = signature
+	return nil
+}
+
+func (km *KeyManager) VerifyHeader(header *types.BlockHeader) error {
+	return km.verify(header.ProposerID, header.SigningPayload(), header.Signature)
+}
+
+// AggregateVotes builds a quorum certificate from votes for the same block,
+// height and round. Votes with invalid signatures or from validators outside
+// the set are dropped; an error is returned if fewer than quorum remain.
+func (km *KeyManager) AggregateVotes(votes []*types.Vote, validators []string, quorum int) (*types.QuorumCertificate, error) {
+	if len(votes) == 0 {
+		return nil, fmt.Errorf("no votes to aggregate")
+	}
+
+	allowed := make(map[string]bool, len(validators))
+	for _, validatorID := range validators {
+		allowed[validatorID] = true
+	}
+
+	first := votes[0]
+	qc := &types.QuorumCertificate{
+		BlockHash: first.BlockHash,
+		Height:    first.Height,
+		Round:     first.Round,
+	}
+
+	signed := make(map[string]bool)
+	for _, vote := range votes {
+		if vote.BlockHash != qc.BlockHash || vote.Height != qc.Height || vote.Round != qc.Round {
+			return nil, fmt.Errorf("vote from %s is for a different block, height or round", vote.VoterID)
+		}
+		if !allowed[vote.VoterID] || signed[vote.VoterID] {
+			continue
+		}
+		if err := km.VerifyVote(vote); err != nil {
+			km.logger.Debug("Dropping vote with invalid signature",
+				zap.String("voter_id", vote.VoterID),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		signed[vote.VoterID] = true
+		qc.Signatures = append(qc.Signatures, types.ValidatorSignature{
+			ValidatorID: vote.VoterID,
+			Timestamp:   vote.Timestamp,
+			Signature:   vote.Signature,
+		})
+	}
+
+	if len(qc.Signatures) < quorum {
+		return nil, fmt.Errorf("insufficient valid votes: %d of %d required", len(qc.Signatures), quorum)
+	}
+
+	qc.CreatedAt = time.Now().UTC()
+	return qc, nil
+}
+
+func (km *KeyManager) VerifyQuorumCertificate(qc *types.QuorumCertificate, validators []string, quorum int) error {
+	allowed := make(map[string]bool, len(validators))
+	for _, validatorID := range validators {
+		allowed[validatorID] = true
+	}
+
+	signed := make(map[string]bool)
+	for _, signature := range qc.Signatures {
+		if !allowed[signature.ValidatorID] {
+			return fmt.Errorf("signature from %s who is not in the validator set", signature.ValidatorID)
+		}
+		if signed[signature.ValidatorID] {
+			return fmt.Errorf("duplicate signature from %s", signature.ValidatorID)
+		}
+		if err := km.VerifyVote(qc.Vote(signature)); err != nil {
+			return err
+		}
+		signed[signature.ValidatorID] = true
+	}
+
+	if len(signed) < quorum {
+		return fmt.Errorf("quorum certificate has %d signatures, %d required", len(signed), quorum)
+	}
+	return nil
+}
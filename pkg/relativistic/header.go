@@ -0,0 +1,120 @@
+package relativistic
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
+)
+
+const DefaultHeaderClockTolerance = 500 * time.Millisecond
+
+// HeaderValidator checks block headers and their linkage. A child's
+// proposer cannot have seen the parent before the light delay between the
+// two proposers elapsed, so the child's timestamp must be at least that far
+// after the parent's, less ClockTolerance for clock disagreement.
+type HeaderValidator struct {
+	calculator     *RelativisticCalculator
+	ClockTolerance time.Duration
+}
+
+func NewHeaderValidator(clockTolerance time.Duration) *HeaderValidator {
+	if clockTolerance <= 0 {
+		clockTolerance = DefaultHeaderClockTolerance
+	}
+
+	return &HeaderValidator{
+		calculator:     NewCalculator(),
+		ClockTolerance: clockTolerance,
+	}
+}
+
+func (hv *HeaderValidator) ValidateHeader(header *types.BlockHeader) error {
+	if header == nil {
+		return types.NewError(types.ErrInvalidInput, "header cannot be nil")
+	}
+	if header.ProposerID == "" {
+		return types.NewError(types.ErrValidation, "header has no proposer")
+	}
+	if header.Timestamp.IsZero() {
+		return types.NewError(types.ErrValidation, "header has no timestamp")
+	}
+	if header.Height > 0 && header.ParentHash == "" {
+		return types.NewErrorWithDetails(types.ErrValidation,
+			"header has no parent hash",
+			fmt.Sprintf("height %d is not genesis", header.Height),
+		)
+	}
+	if header.Height == 0 && header.ParentHash != "" {
+		return types.NewError(types.ErrValidation, "genesis header cannot have a parent hash")
+	}
+	return nil
+}
+
+// MinChildDelay is the light delay between the parent's and the child's
+// proposers; zero when the same node proposed both.
+func (hv *HeaderValidator) MinChildDelay(parent, child *types.BlockHeader) time.Duration {
+	if parent.ProposerID == child.ProposerID {
+		return 0
+	}
+	distance := hv.calculator.CalculateDistance(parent.ProposerPosition, child.ProposerPosition)
+	return hv.calculator.CalculateLightDelay(distance)
+}
+
+// ValidateChild checks that child directly extends parent: consecutive
+// height, matching parent hash, HLC strictly after the parent's and a
+// timestamp no earlier than the parent's plus the light delay between
+// their proposers, within ClockTolerance.
+func (hv *HeaderValidator) ValidateChild(parent, child *types.BlockHeader) error {
+	if err := hv.ValidateHeader(parent); err != nil {
+		return err
+	}
+	if err := hv.ValidateHeader(child); err != nil {
+		return err
+	}
+
+	if child.Height != parent.Height+1 {
+		return types.NewErrorWithDetails(types.ErrValidation,
+			"header height does not follow parent",
+			fmt.Sprintf("parent height %d, child height %d", parent.Height, child.Height),
+		)
+	}
+	if parentHash := parent.Hash(); child.ParentHash != parentHash {
+		return types.NewErrorWithDetails(types.ErrValidation,
+			"header parent hash mismatch",
+			fmt.Sprintf("expected %s, got %s", parentHash, child.ParentHash),
+		)
+	}
+	if parent.HLC != nil && child.HLC != nil && !parent.HLC.Before(*child.HLC) {
+		return types.NewErrorWithDetails(types.ErrValidation,
+			"header HLC does not advance",
+			fmt.Sprintf("parent %s, child %s", parent.HLC, child.HLC),
+		)
+	}
+
+	earliest := parent.Timestamp.Add(hv.MinChildDelay(parent, child) - hv.ClockTolerance)
+	if child.Timestamp.Before(earliest) {
+		return types.NewErrorWithDetails(types.ErrValidation,
+			"header timestamp precedes parent",
+			fmt.Sprintf("child is %v before the earliest plausible time %s",
+				earliest.Sub(child.Timestamp), earliest.Format(time.RFC3339Nano)),
+		)
+	}
+	return nil
+}
+
+// ValidateChain checks a sequence of headers ordered by height.
+func (hv *HeaderValidator) ValidateChain(headers []*types.BlockHeader) error {
+	if len(headers) == 0 {
+		return nil
+	}
+	if err := hv.ValidateHeader(headers[0]); err != nil {
+		return err
+	}
+	for i := 1; i < len(headers); i++ {
+		if err := hv.ValidateChild(headers[i-1], headers[i]); err != nil {
+			return types.WrapError(err, types.ErrValidation, fmt.Sprintf("invalid header at height %d", headers[i].Height))
+		}
+	}
+	return nil
+}
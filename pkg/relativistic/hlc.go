@@ -0,0 +1,233 @@
+package relativistic
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
+)
+
+const DefaultHLCMaxOffset = 500 * time.Millisecond
+
+// PeerClockBounds describes what is known about a peer's clock. Offset is
+// the correction that maps the peer's clock onto ours, Delay is the minimum
+// one-way propagation delay from the peer (its light delay) and Uncertainty
+// is the error on the offset estimate.
+type PeerClockBounds struct {
+	Offset      time.Duration `json:"offset"`
+	Delay       time.Duration `json:"delay"`
+	Uncertainty time.Duration `json:"uncertainty"`
+}
+
+// HybridLogicalClock combines the local physical clock with a logical
+// counter so that causally related events are always ordered, even when wall
+// clocks disagree. Remote timestamps are only merged if they are physically
+// plausible: a message cannot have been sent later than its light delay
+// before it arrived, once the peer's known offset is applied.
+type HybridLogicalClock struct {
+	nodeID    string
+	maxOffset time.Duration
+	now       func() time.Time
+	mu        sync.Mutex
+	last      types.HLCTimestamp
+	peers     map[string]PeerClockBounds
+}
+
+func NewHybridLogicalClock(nodeID string, maxOffset time.Duration) *HybridLogicalClock {
+	if maxOffset <= 0 {
+		maxOffset = DefaultHLCMaxOffset
+	}
+
+	return &HybridLogicalClock{
+		nodeID:    nodeID,
+		maxOffset: maxOffset,
+		now:       time.Now,
+		peers:     make(map[string]PeerClockBounds),
+	}
+}
+
+// SetPhysicalClock replaces the physical time source, e.g. with one that
+// applies the consensus clock offset.
+func (c *HybridLogicalClock) SetPhysicalClock(now func() time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+func (c *HybridLogicalClock) SetPeerBounds(peerID string, bounds PeerClockBounds) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.peers[peerID] = bounds
+}
+
+func (c *HybridLogicalClock) RemovePeer(peerID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.peers, peerID)
+}
+
+func (c *HybridLogicalClock) NodeID() string {
+	return c.nodeID
+}
+
+// Now returns a timestamp for a local or send event.
+func (c *HybridLogicalClock) Now() types.HLCTimestamp {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	physical := c.now().UnixNano()
+	if physical > c.last.Wall {
+		c.last = types.HLCTimestamp{Wall: physical}
+	} else {
+		c.last.Logical++
+	}
+	return c.last
+}
+
+// Last returns the most recent timestamp issued without advancing the clock.
+func (c *HybridLogicalClock) Last() types.HLCTimestamp {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.last
+}
+
+// MaxAcceptable is the latest wall time a timestamp from the given peer may
+// carry right now. Without bounds for the peer only the configured maximum
+// offset is allowed.
+func (c *HybridLogicalClock) MaxAcceptable(peerID string) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.maxAcceptableLocked(peerID, c.now().UnixNano())
+}
+
+func (c *HybridLogicalClock) maxAcceptableLocked(peerID string, physical int64) int64 {
+	bounds, exists := c.peers[peerID]
+	if !exists {
+		return physical + int64(c.maxOffset)
+	}
+
+	tolerance := bounds.Uncertainty
+	if tolerance < c.maxOffset {
+		tolerance = c.maxOffset
+	}
+	return physical - int64(bounds.Offset) - int64(bounds.Delay) + int64(tolerance)
+}
+
+// Update merges a timestamp received from a peer and returns the timestamp
+// of the receive event. Timestamps beyond the peer's bound are rejected
+// without touching the clock, so one skewed peer cannot drag it forward.
+func (c *HybridLogicalClock) Update(peerID string, remote types.HLCTimestamp) (types.HLCTimestamp, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	physical := c.now().UnixNano()
+	if limit := c.maxAcceptableLocked(peerID, physical); remote.Wall > limit {
+		return c.last, types.NewErrorWithDetails(types.ErrValidation,
+			"HLC timestamp too far ahead",
+			fmt.Sprintf("peer %s sent %s, %v beyond the bound", peerID, remote, time.Duration(remote.Wall-limit)),
+		)
+	}
+
+	previous := c.last
+	wall := physical
+	if previous.Wall > wall {
+		wall = previous.Wall
+	}
+	if remote.Wall > wall {
+		wall = remote.Wall
+	}
+
+	var logical uint32
+	switch {
+	case wall == previous.Wall && wall == remote.Wall:
+		logical = previous.Logical
+		if remote.Logical > logical {
+			logical = remote.Logical
+		}
+		logical++
+	case wall == previous.Wall:
+		logical = previous.Logical + 1
+	case wall == remote.Wall:
+		logical = remote.Logical + 1
+	}
+
+	c.last = types.HLCTimestamp{Wall: wall, Logical: logical}
+	return c.last, nil
+}
+
+func (c *HybridLogicalClock) StampBlock(block *types.Block) types.HLCTimestamp {
+	ts := c.Now()
+	block.HLC = &ts
+	return ts
+}
+
+func (c *HybridLogicalClock) StampTransaction(tx *types.Transaction) types.HLCTimestamp {
+	ts := c.Now()
+	tx.HLC = &ts
+	return ts
+}
+
+// ObserveBlock merges the HLC of a block received from a peer. Blocks
+// without one are ignored.
+func (c *HybridLogicalClock) ObserveBlock(peerID string, block *types.Block) (types.HLCTimestamp, error) {
+	if block.HLC == nil {
+		return c.Last(), nil
+	}
+	return c.Update(peerID, *block.HLC)
+}
+
+func (c *HybridLogicalClock) ObserveTransaction(peerID string, tx *types.Transaction) (types.HLCTimestamp, error) {
+	if tx.HLC == nil {
+		return c.Last(), nil
+	}
+	return c.Update(peerID, *tx.HLC)
+}
+
+func EncodeHLC(ts types.HLCTimestamp) []byte {
+	return ts.Bytes()
+}
+
+func DecodeHLC(data []byte) (types.HLCTimestamp, error) {
+	if len(data) != types.HLCEncodedSize {
+		return types.HLCTimestamp{}, types.NewErrorWithDetails(types.ErrInvalidInput,
+			"invalid HLC encoding",
+			fmt.Sprintf("expected %d bytes, got %d", types.HLCEncodedSize, len(data)),
+		)
+	}
+	return types.HLCTimestamp{
+		Wall:    int64(binary.BigEndian.Uint64(data[:8])),
+		Logical: binary.BigEndian.Uint32(data[8:]),
+	}, nil
+}
+
+// ParseHLC is the inverse of HLCTimestamp.String.
+func ParseHLC(s string) (types.HLCTimestamp, error) {
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		return types.HLCTimestamp{}, types.WrapError(err, types.ErrInvalidInput, "invalid HLC string")
+	}
+	return DecodeHLC(data)
+}
+
+// SortTransactionsByHLC orders transactions by HLC, falling back to wall
+// time for unstamped ones and to hash for ties, so every node derives the
+// same order from the same set.
+func SortTransactionsByHLC(txs []*types.Transaction) {
+	key := func(tx *types.Transaction) types.HLCTimestamp {
+		if tx.HLC != nil {
+			return *tx.HLC
+		}
+		return types.HLCTimestamp{Wall: tx.Timestamp.UnixNano()}
+	}
+
+	sort.SliceStable(txs, func(i, j int) bool {
+		if cmp := key(txs[i]).Compare(key(txs[j])); cmp != 0 {
+			return cmp < 0
+		}
+		return txs[i].Hash < txs[j].Hash
+	})
+}
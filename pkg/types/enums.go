@@ -45,6 +45,7 @@ const (
 	AlertTypeCapacity         AlertType = "capacity"
 	AlertTypeDiscoveryIssue   AlertType = "discovery_issue"
 	AlertTypeNetworkPartition AlertType = "network_partition"
+	AlertTypeClockDrift       AlertType = "clock_drift"
 )
 
 type EventType string
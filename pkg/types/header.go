@@ -0,0 +1,77 @@
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// BlockHeader carries everything needed for chain-level checks: linkage to
+// the parent, height and round, the proposer and where it is, a commitment
+// to the block data and to the validator set that may sign it.
+type BlockHeader struct {
+	Height           uint64        `json:"height"`
+	Round            uint32        `json:"round"`
+	ParentHash       string        `json:"parent_hash"`
+	Timestamp        time.Time     `json:"timestamp"`
+	HLC              *HLCTimestamp `json:"hlc,omitempty"`
+	ProposerID       string        `json:"proposer_id"`
+	ProposerPosition Position      `json:"proposer_position"`
+	DataHash         string        `json:"data_hash"`
+	ValidatorSetHash string        `json:"validator_set_hash"`
+	Signature        string        `json:"signature,omitempty"`
+}
+
+// NewBlockHeader builds the header for a block extending parent, or a
+// genesis header when parent is nil.
+func NewBlockHeader(parent *BlockHeader, block *Block, round uint32, validatorSetHash string) *BlockHeader {
+	dataHash := sha256.Sum256(block.Data)
+	header := &BlockHeader{
+		Round:            round,
+		Timestamp:        block.Timestamp,
+		HLC:              block.HLC,
+		ProposerID:       block.ProposedBy,
+		ProposerPosition: block.NodePosition,
+		DataHash:         hex.EncodeToString(dataHash[:]),
+		ValidatorSetHash: validatorSetHash,
+	}
+	if parent != nil {
+		header.Height = parent.Height + 1
+		header.ParentHash = parent.Hash()
+	}
+	return header
+}
+
+func (h *BlockHeader) IsGenesis() bool {
+	return h.Height == 0 && h.ParentHash == ""
+}
+
+// SigningPayload is the canonical serialization of the header. The
+// signature is excluded so the proposer signs, and the hash covers, the
+// same bytes.
+func (h *BlockHeader) SigningPayload() []byte {
+	w := newPayloadWriter(SigningDomainHeader)
+	w.uint64(h.Height)
+	w.uint32(h.Round)
+	w.string(h.ParentHash)
+	w.time(h.Timestamp)
+	if h.HLC != nil {
+		w.bytes(h.HLC.Bytes())
+	} else {
+		w.bytes(nil)
+	}
+	w.string(h.ProposerID)
+	w.float64(h.ProposerPosition.Latitude)
+	w.float64(h.ProposerPosition.Longitude)
+	w.float64(h.ProposerPosition.Altitude)
+	w.string(h.DataHash)
+	w.string(h.ValidatorSetHash)
+	return w.buf
+}
+
+// Hash is the hex SHA-256 of the canonical serialization; children refer
+// to it as their ParentHash.
+func (h *BlockHeader) Hash() string {
+	sum := sha256.Sum256(h.SigningPayload())
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,53 @@
+package types
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// HLCEncodedSize is the length of the binary form of an HLC timestamp: an
+// 8-byte wall time followed by a 4-byte logical counter, both big-endian.
+const HLCEncodedSize = 12
+
+// HLCTimestamp is a hybrid logical clock reading. Wall is nanoseconds since
+// the Unix epoch and never runs behind any timestamp the clock has seen;
+// Logical orders events that share the same wall time.
+type HLCTimestamp struct {
+	Wall    int64  `json:"wall"`
+	Logical uint32 `json:"logical"`
+}
+
+func (t HLCTimestamp) IsZero() bool {
+	return t.Wall == 0 && t.Logical == 0
+}
+
+// Compare returns -1, 0 or 1 as t is before, equal to or after other.
+func (t HLCTimestamp) Compare(other HLCTimestamp) int {
+	switch {
+	case t.Wall < other.Wall:
+		return -1
+	case t.Wall > other.Wall:
+		return 1
+	case t.Logical < other.Logical:
+		return -1
+	case t.Logical > other.Logical:
+		return 1
+	}
+	return 0
+}
+
+func (t HLCTimestamp) Before(other HLCTimestamp) bool {
+	return t.Compare(other) < 0
+}
+
+func (t HLCTimestamp) Bytes() []byte {
+	buf := make([]byte, 0, HLCEncodedSize)
+	buf = binary.BigEndian.AppendUint64(buf, uint64(t.Wall))
+	return binary.BigEndian.AppendUint32(buf, t.Logical)
+}
+
+// String renders the timestamp as fixed-width hex so that, for wall times
+// after the epoch, lexical order matches clock order.
+func (t HLCTimestamp) String() string {
+	return fmt.Sprintf("%016x%08x", uint64(t.Wall), t.Logical)
+}
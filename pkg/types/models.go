@@ -22,17 +22,19 @@ type Metadata struct {
         Capabilities []string `json:"capabilities"`
 }
 type Block struct {
-        Hash         string    `json:"hash"`
-        Timestamp    time.Time `json:"timestamp"`
-        ProposedBy   string    `json:"proposed_by"`
-        NodePosition Position  `json:"node_position"`
-        Data         []byte    `json:"data"`
+        Hash         string        `json:"hash"`
+        Timestamp    time.Time     `json:"timestamp"`
+        HLC          *HLCTimestamp `json:"hlc,omitempty"`
+        ProposedBy   string        `json:"proposed_by"`
+        NodePosition Position      `json:"node_position"`
+        Data         []byte        `json:"data"`
 }
 type Transaction struct {
-        Hash         string    `json:"hash"`
-        Timestamp    time.Time `json:"timestamp"`
-        NodePosition Position  `json:"node_position"`
-        Data         []byte    `json:"data"`
+        Hash         string        `json:"hash"`
+        Timestamp    time.Time     `json:"timestamp"`
+        HLC          *HLCTimestamp `json:"hlc,omitempty"`
+        NodePosition Position      `json:"node_position"`
+        Data         []byte        `json:"data"`
 }
 type ValidationResult struct {
         BlockHash     string        `json:"block_hash"` 
@@ -94,12 +96,16 @@ type ValidatableItem struct {
 type Vote struct {
         BlockHash string    `json:"block_hash"`
         VoterID   string    `json:"voter_id"`
+        Height    uint64    `json:"height"`
+        Round     uint32    `json:"round"`
         Timestamp time.Time `json:"timestamp"`
         Signature string    `json:"signature"`
 }
 type Proposal struct {
         Block      *Block    `json:"block"`
         ProposerID string    `json:"proposer_id"`
+        Height     uint64    `json:"height"`
+        Round      uint32    `json:"round"`
         Signature  string    `json:"signature"`
         Timestamp  time.Time `json:"timestamp"`
 }
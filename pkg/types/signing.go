@@ -0,0 +1,141 @@
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+// Domain tags keep a signature over one message type from being replayed as
+// another.
+const (
+	SigningDomainBlock    = "relativistic/block/v1"
+	SigningDomainHeader   = "relativistic/header/v1"
+	SigningDomainVote     = "relativistic/vote/v1"
+	SigningDomainProposal = "relativistic/proposal/v1"
+	SigningDomainRotation = "relativistic/key-rotation/v1"
+)
+
+// payloadWriter builds canonical payloads: every variable-length field is
+// length-prefixed and integers are big-endian, so two different messages can
+// never share an encoding.
+type payloadWriter struct {
+	buf []byte
+}
+
+func newPayloadWriter(domain string) *payloadWriter {
+	w := &payloadWriter{}
+	w.bytes([]byte(domain))
+	return w
+}
+
+func (w *payloadWriter) bytes(b []byte) {
+	w.buf = binary.BigEndian.AppendUint32(w.buf, uint32(len(b)))
+	w.buf = append(w.buf, b...)
+}
+
+func (w *payloadWriter) string(s string) {
+	w.bytes([]byte(s))
+}
+
+func (w *payloadWriter) uint64(v uint64) {
+	w.buf = binary.BigEndian.AppendUint64(w.buf, v)
+}
+
+func (w *payloadWriter) uint32(v uint32) {
+	w.buf = binary.BigEndian.AppendUint32(w.buf, v)
+}
+
+func (w *payloadWriter) time(t time.Time) {
+	w.uint64(uint64(t.UnixNano()))
+}
+
+func (w *payloadWriter) float64(f float64) {
+	w.uint64(math.Float64bits(f))
+}
+
+// SigningPayload returns the canonical bytes a block proposer signs. The
+// block data is committed to by its hash rather than included verbatim.
+func (b *Block) SigningPayload() []byte {
+	w := newPayloadWriter(SigningDomainBlock)
+	w.string(b.Hash)
+	w.time(b.Timestamp)
+	w.string(b.ProposedBy)
+	w.float64(b.NodePosition.Latitude)
+	w.float64(b.NodePosition.Longitude)
+	w.float64(b.NodePosition.Altitude)
+	dataHash := sha256.Sum256(b.Data)
+	w.bytes(dataHash[:])
+	if b.HLC != nil {
+		w.bytes(b.HLC.Bytes())
+	} else {
+		w.bytes(nil)
+	}
+	return w.buf
+}
+
+func (v *Vote) SigningPayload() []byte {
+	w := newPayloadWriter(SigningDomainVote)
+	w.string(v.BlockHash)
+	w.string(v.VoterID)
+	w.uint64(v.Height)
+	w.uint32(v.Round)
+	w.time(v.Timestamp)
+	return w.buf
+}
+
+func (p *Proposal) SigningPayload() []byte {
+	w := newPayloadWriter(SigningDomainProposal)
+	w.string(p.ProposerID)
+	w.uint64(p.Height)
+	w.uint32(p.Round)
+	w.time(p.Timestamp)
+	if p.Block != nil {
+		w.bytes(p.Block.SigningPayload())
+	} else {
+		w.bytes(nil)
+	}
+	return w.buf
+}
+
+// QuorumCertificate aggregates individual validator vote signatures for one
+// block at one height and round. Each signature covers the signer's own vote
+// payload, so the certificate can be verified without the original votes.
+type QuorumCertificate struct {
+	BlockHash  string               `json:"block_hash"`
+	Height     uint64               `json:"height"`
+	Round      uint32               `json:"round"`
+	Signatures []ValidatorSignature `json:"signatures"`
+	CreatedAt  time.Time            `json:"created_at"`
+}
+
+type ValidatorSignature struct {
+	ValidatorID string    `json:"validator_id"`
+	Timestamp   time.Time `json:"timestamp"`
+	Signature   string    `json:"signature"`
+}
+
+// Vote reconstructs the vote a signature in the certificate was made over.
+func (qc *QuorumCertificate) Vote(signature ValidatorSignature) *Vote {
+	return &Vote{
+		BlockHash: qc.BlockHash,
+		VoterID:   signature.ValidatorID,
+		Height:    qc.Height,
+		Round:     qc.Round,
+		Timestamp: signature.Timestamp,
+		Signature: signature.Signature,
+	}
+}
+
+// KeyRotationPayload is what a validator's current key signs to hand its
+// registration over to nextKey. Naming the key being replaced ties the
+// signature to that one transition.
+func KeyRotationPayload(validatorID string, previousKey []byte, keyType string, nextKey []byte) []byte {
+	w := newPayloadWriter(SigningDomainRotation)
+	w.string(validatorID)
+	w.bytes(previousKey)
+	w.string(keyType)
+	w.bytes(nextKey)
+	return w.buf
+}
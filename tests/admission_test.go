@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/core"
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
+)
+
+// windowValidator mimics RelativisticEngine.ValidateTimestamp with a fixed
+// expected delay and acceptance window.
+type windowValidator struct {
+	expected time.Duration
+	window   time.Duration
+	shift    time.Duration
+}
+
+func (v *windowValidator) ValidateTransactionTimestamp(ctx context.Context, tx *types.Transaction, originNode string) (*types.ValidationResult, error) {
+	diff := time.Now().Add(v.shift).Sub(tx.Timestamp)
+	abs := diff
+	if abs < 0 {
+		abs = -abs
+	}
+	return &types.ValidationResult{
+		Valid:         abs <= v.expected+v.window,
+		ExpectedDelay: v.expected,
+		ActualDiff:    diff,
+	}, nil
+}
+
+func TestAdmissionController(t *testing.T) {
+	validator := &windowValidator{expected: 50 * time.Millisecond, window: time.Second}
+	config := core.DefaultAdmissionConfig()
+	config.MaxFutureLead = 10 * time.Second
+	ac := core.NewAdmissionController(validator, config, zap.NewNop())
+	ctx := context.Background()
+	now := time.Now()
+
+	result, err := ac.Admit(ctx, &types.Transaction{Hash: "ok", Timestamp: now}, "origin")
+	require.NoError(t, err)
+	assert.Equal(t, core.AdmissionAccepted, result.Decision)
+
+	result, err = ac.Admit(ctx, &types.Transaction{Hash: "ok", Timestamp: now}, "origin")
+	require.NoError(t, err)
+	assert.Equal(t, core.RejectDuplicate, result.Reason)
+
+	result, err = ac.Admit(ctx, &types.Transaction{Hash: "early", Timestamp: now.Add(5 * time.Second)}, "origin")
+	require.NoError(t, err)
+	assert.Equal(t, core.AdmissionParked, result.Decision)
+
+	result, err = ac.Admit(ctx, &types.Transaction{Hash: "far", Timestamp: now.Add(time.Minute)}, "origin")
+	require.NoError(t, err)
+	assert.Equal(t, core.RejectFarFuture, result.Reason)
+
+	result, err = ac.Admit(ctx, &types.Transaction{Hash: "stale", Timestamp: now.Add(-time.Hour)}, "origin")
+	require.NoError(t, err)
+	assert.Equal(t, core.RejectStale, result.Reason)
+
+	// Nothing has changed yet, so the early transaction stays parked.
+	promoted, expired := ac.ProcessParked(ctx)
+	assert.Zero(t, promoted)
+	assert.Zero(t, expired)
+
+	// Once local time catches up it is promoted and batched after "ok".
+	validator.shift = 5 * time.Second
+	promoted, _ = ac.ProcessParked(ctx)
+	assert.Equal(t, 1, promoted)
+
+	batch := ac.NextBatch(0)
+	require.Len(t, batch, 2)
+	assert.Equal(t, "ok", batch[0].Hash)
+	assert.Equal(t, "early", batch[1].Hash)
+
+	metrics := ac.GetMetrics()
+	assert.Equal(t, int64(1), metrics.Admitted)
+	assert.Equal(t, int64(1), metrics.Parked)
+	assert.Equal(t, int64(1), metrics.Promoted)
+	assert.Equal(t, 1, metrics.Rejected[core.RejectFarFuture])
+	assert.Zero(t, metrics.ReadySize)
+	assert.Zero(t, metrics.ParkedSize)
+}
@@ -0,0 +1,48 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/consensus"
+)
+
+func TestOffsetAggregation(t *testing.T) {
+	offsets := []*consensus.NodeOffset{
+		{NodeID: "node1", Offset: 10 * time.Millisecond, Uncertainty: 2 * time.Millisecond, Confidence: 0.9},
+		{NodeID: "node2", Offset: 12 * time.Millisecond, Uncertainty: 2 * time.Millisecond, Confidence: 0.9},
+		{NodeID: "node3", Offset: 11 * time.Millisecond, Uncertainty: 2 * time.Millisecond, Confidence: 0.8},
+		{NodeID: "node4", Offset: 13 * time.Millisecond, Uncertainty: 2 * time.Millisecond, Confidence: 0.8},
+		{NodeID: "byzantine", Offset: 5 * time.Second, Uncertainty: 2 * time.Millisecond, Confidence: 0.9},
+		{NodeID: "unsure", Offset: 11 * time.Millisecond, Confidence: 0.2},
+	}
+
+	methods := []consensus.AggregationMethod{
+		consensus.AggregationMean,
+		consensus.AggregationMedian,
+		consensus.AggregationTrimmedMean,
+		consensus.AggregationMarzullo,
+	}
+
+	for _, method := range methods {
+		t.Run(string(method), func(t *testing.T) {
+			config := consensus.DefaultAggregationConfig()
+			config.Method = method
+
+			report := consensus.AggregateOffsets(offsets, config)
+
+			assert.InDelta(t, float64(11500*time.Microsecond), float64(report.GlobalOffset), float64(2*time.Millisecond))
+			assert.ElementsMatch(t, []string{"node1", "node2", "node3", "node4"}, report.Included)
+
+			excluded, ok := report.IsExcluded("byzantine")
+			assert.True(t, ok)
+			assert.Equal(t, consensus.ExclusionOutlier, excluded.Reason)
+
+			excluded, ok = report.IsExcluded("unsure")
+			assert.True(t, ok)
+			assert.Equal(t, consensus.ExclusionLowConfidence, excluded.Reason)
+		})
+	}
+}
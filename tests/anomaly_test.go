@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/core"
+)
+
+type anomalyList []*core.ValidationAnomaly
+
+func (l *anomalyList) DetectValidationAnomalies(since time.Time) []*core.ValidationAnomaly {
+	var result []*core.ValidationAnomaly
+	for _, anomaly := range *l {
+		if anomaly.Timestamp.After(since) {
+			result = append(result, anomaly)
+		}
+	}
+	return result
+}
+
+type flagRecorder struct {
+	flags map[string]string
+	// afterRead runs once GetFlaggedNodes has taken its snapshot.
+	afterRead func()
+}
+
+func (f *flagRecorder) FlagNode(nodeID, reason string) { f.flags[nodeID] = reason }
+
+func (f *flagRecorder) UnflagNodeIf(nodeID, reason string) bool {
+	if f.flags[nodeID] != reason {
+		return false
+	}
+	delete(f.flags, nodeID)
+	return true
+}
+
+func (f *flagRecorder) GetFlaggedNodes() map[string]string {
+	flagged := make(map[string]string, len(f.flags))
+	for nodeID, reason := range f.flags {
+		flagged[nodeID] = reason
+	}
+	if f.afterRead != nil {
+		f.afterRead()
+	}
+	return flagged
+}
+
+func TestAnomalyFlagger(t *testing.T) {
+	now := time.Now()
+	anomalies := &anomalyList{}
+	for i := 0; i < 3; i++ {
+		*anomalies = append(*anomalies,
+			&core.ValidationAnomaly{Type: "LargeTimeDifference", OriginNode: "liar", Timestamp: now.Add(-time.Duration(i) * time.Minute)},
+			&core.ValidationAnomaly{Type: "LargeTimeDifference", OriginNode: "operator-pick", Timestamp: now.Add(-time.Duration(i) * time.Minute)},
+		)
+	}
+	*anomalies = append(*anomalies,
+		&core.ValidationAnomaly{Type: "LowConfidence", OriginNode: "honest", Timestamp: now},
+		&core.ValidationAnomaly{Type: "LowConfidence", Timestamp: now},
+	)
+
+	recorder := &flagRecorder{flags: map[string]string{}}
+	flags := recorder.flags
+	manual := "flagged by operator"
+	flags["operator-pick"] = manual
+
+	flagger := core.NewAnomalyFlagger(anomalies, recorder, core.DefaultAnomalyFlaggerConfig(), zap.NewNop())
+
+	assert.Equal(t, []string{"liar"}, flagger.Evaluate(now))
+	assert.Contains(t, flags, "liar")
+	assert.NotContains(t, flags, "honest", "a single anomaly should not flag a node")
+	assert.Equal(t, manual, flags["operator-pick"], "an existing flag keeps its reason")
+
+	// Once the anomalies age out of the window, only the node the flagger
+	// flagged itself is unflagged.
+	later := now.Add(time.Hour)
+	assert.Empty(t, flagger.Evaluate(later))
+	assert.NotContains(t, flags, "liar")
+	assert.Equal(t, manual, flags["operator-pick"])
+
+	// A node the operator reflags after the flagger flagged it is the
+	// operator's from then on.
+	assert.Equal(t, []string{"liar"}, flagger.Evaluate(now))
+	flags["liar"] = manual
+	assert.Empty(t, flagger.Evaluate(later))
+	assert.Equal(t, manual, flags["liar"])
+
+	// An operator reflagging the node while the flagger is deciding to
+	// unflag it keeps the flag.
+	delete(flags, "liar")
+	assert.Equal(t, []string{"liar"}, flagger.Evaluate(now))
+	recorder.afterRead = func() { flags["liar"] = manual }
+	assert.Empty(t, flagger.Evaluate(later))
+	assert.Equal(t, manual, flags["liar"])
+}
@@ -0,0 +1,48 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/consensus"
+)
+
+func TestClockOffsetEstimation(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("SymmetricExchange", func(t *testing.T) {
+		// Peer clock is 40ms ahead, each direction takes 10ms, peer holds 1ms.
+		t1 := base
+		t2 := base.Add(50 * time.Millisecond)
+		t3 := t2.Add(time.Millisecond)
+		t4 := base.Add(21 * time.Millisecond)
+
+		sample, err := consensus.ComputeClockSample(t1, t2, t3, t4, 4*time.Millisecond)
+		assert.NoError(t, err)
+		assert.Equal(t, 40*time.Millisecond, sample.Offset)
+		assert.Equal(t, 20*time.Millisecond, sample.RoundTrip)
+		assert.Equal(t, 6*time.Millisecond, sample.Uncertainty)
+	})
+
+	t.Run("FasterThanLightRejected", func(t *testing.T) {
+		t4 := base.Add(5 * time.Millisecond)
+		_, err := consensus.ComputeClockSample(base, base, base, t4, 10*time.Millisecond)
+		assert.Error(t, err)
+	})
+
+	t.Run("MarzulloDiscardsFalseticker", func(t *testing.T) {
+		intervals := []consensus.OffsetInterval{
+			{Source: "a", Lower: 8 * time.Millisecond, Upper: 12 * time.Millisecond},
+			{Source: "b", Lower: 11 * time.Millisecond, Upper: 13 * time.Millisecond},
+			{Source: "c", Lower: 10 * time.Millisecond, Upper: 12 * time.Millisecond},
+			{Source: "d", Lower: 500 * time.Millisecond, Upper: 510 * time.Millisecond},
+		}
+
+		result, agreeing := consensus.MarzulloIntersection(intervals)
+		assert.Equal(t, 11*time.Millisecond, result.Lower)
+		assert.Equal(t, 12*time.Millisecond, result.Upper)
+		assert.ElementsMatch(t, []string{"a", "b", "c"}, agreeing)
+	})
+}
@@ -0,0 +1,150 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/network"
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
+)
+
+func startRegionalPeer(t *testing.T, ctx context.Context, nodeID, region string, discovery *network.DiscoveryService) *network.PeeringManager {
+	pm := network.NewPeeringManager(discovery, nil, &network.PeeringConfig{
+		NodeID:        nodeID,
+		ListenAddress: "127.0.0.1:0",
+		MaxPeers:      20,
+		Node:          &types.Node{Metadata: types.Metadata{Region: region}},
+	}, zap.NewNop())
+	require.NoError(t, pm.Start(ctx))
+	t.Cleanup(pm.Stop)
+	return pm
+}
+
+func sessions(pm *network.PeeringManager, direction network.ConnectionDirection) []*network.PeerConnection {
+	var matching []*network.PeerConnection
+	for _, conn := range pm.GetActiveConnections() {
+		if conn.Direction == direction {
+			matching = append(matching, conn)
+		}
+	}
+	return matching
+}
+
+func TestConnectionManager(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	discovery := network.NewDiscoveryService(nil, zap.NewNop())
+	hub := startRegionalPeer(t, ctx, "hub", "eu-west", discovery)
+
+	regions := map[string]string{
+		"eu-1": "eu-west", "eu-2": "eu-west", "eu-3": "eu-west", "eu-4": "eu-west",
+		"us-1": "us-east", "us-2": "us-east",
+	}
+	// The peers were verified in earlier sessions; only verified regions
+	// give a candidate priority.
+	for nodeID, region := range regions {
+		pm := startRegionalPeer(t, ctx, nodeID, region, nil)
+		discovery.MarkVerified(&types.Node{
+			ID:       nodeID,
+			Address:  pm.ListenAddr().String(),
+			Metadata: types.Metadata{Region: region},
+		})
+	}
+
+	config := network.DefaultConnectionManagerConfig()
+	config.TargetOutbound = 3
+	config.MaxInbound = 1
+	config.MinOutsideRegion = 2
+	config.Interval = 50 * time.Millisecond
+	manager := network.NewConnectionManager(hub, discovery, config, zap.NewNop())
+	go manager.Start(ctx)
+	t.Cleanup(manager.Stop)
+
+	// The target degree is met and both peers outside eu-west are kept.
+	require.Eventually(t, func() bool {
+		outbound := sessions(hub, network.Outbound)
+		if len(outbound) != 3 {
+			return false
+		}
+		outside := 0
+		for _, score := range manager.GetPeerScores() {
+			if score.Region == "us-east" {
+				outside++
+			}
+		}
+		return outside == 2
+	}, 5*time.Second, 20*time.Millisecond)
+
+	// Inbound sessions beyond the limit are closed.
+	for _, nodeID := range []string{"in-1", "in-2"} {
+		pm := startRegionalPeer(t, ctx, nodeID, "eu-west", nil)
+		require.NoError(t, pm.Connect("hub", hub.ListenAddr().String()))
+	}
+	require.Eventually(t, func() bool {
+		return len(sessions(hub, network.Inbound)) == 1
+	}, 5*time.Second, 20*time.Millisecond)
+
+	// A misbehaving peer is banned and not redialled, even though that
+	// leaves the region minimum unmet.
+	manager.ReportMisbehavior("us-1", 20, "invalid blocks")
+	require.Eventually(t, func() bool {
+		return manager.IsBanned("us-1") && activeSession(hub, "us-1") == nil
+	}, 5*time.Second, 20*time.Millisecond)
+	time.Sleep(200 * time.Millisecond)
+	assert.Nil(t, activeSession(hub, "us-1"))
+	assert.Len(t, sessions(hub, network.Outbound), 3)
+
+	for _, score := range manager.GetPeerScores() {
+		assert.NotEqual(t, "us-1", score.PeerID)
+	}
+}
+
+func TestConnectionManagerClaimedRegion(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hub := startRegionalPeer(t, ctx, "hub", "eu-west", nil)
+	config := network.DefaultConnectionManagerConfig()
+	config.Interval = time.Hour
+	manager := network.NewConnectionManager(hub, nil, config, zap.NewNop())
+
+	// Over loopback a peer claiming to be on another continent answers
+	// faster than light allows: its region is not counted and the claim
+	// is reported as misbehavior.
+	liar := network.NewPeeringManager(nil, nil, &network.PeeringConfig{
+		NodeID:        "liar",
+		ListenAddress: "127.0.0.1:0",
+		MaxPeers:      20,
+		Node: &types.Node{
+			Position: types.Position{Latitude: 40.7, Longitude: -74.0},
+			Metadata: types.Metadata{Region: "us-east"},
+		},
+	}, zap.NewNop())
+	require.NoError(t, liar.Start(ctx))
+	t.Cleanup(liar.Stop)
+	honest := startRegionalPeer(t, ctx, "honest", "us-east", nil)
+
+	require.NoError(t, hub.Connect("liar", liar.ListenAddr().String()))
+	require.NoError(t, hub.Connect("honest", honest.ListenAddr().String()))
+	require.Eventually(t, func() bool {
+		scores := make(map[string]*network.PeerScore)
+		for _, score := range manager.GetPeerScores() {
+			scores[score.PeerID] = score
+		}
+		return scores["liar"] != nil && scores["liar"].Misbehavior > 0 &&
+			scores["honest"] != nil && scores["honest"].Region == "us-east"
+	}, 5*time.Second, 20*time.Millisecond)
+
+	for _, score := range manager.GetPeerScores() {
+		if score.PeerID == "liar" {
+			assert.Empty(t, score.Region)
+			assert.Zero(t, score.TheoreticalRTT)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/network"
+)
+
+func TestBucketIndex(t *testing.T) {
+	var a, b network.NodeKey
+	assert.Equal(t, -1, network.BucketIndex(a, b))
+
+	b[0] = 0x80
+	assert.Equal(t, 0, network.BucketIndex(a, b))
+
+	b[0] = 0x01
+	assert.Equal(t, 7, network.BucketIndex(a, b))
+
+	b[0] = 0
+	b[31] = 0x01
+	assert.Equal(t, 255, network.BucketIndex(a, b))
+
+	assert.Equal(t, network.XORDistance(a, b), network.XORDistance(b, a))
+}
+
+func TestDHTLookup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const nodeCount = 24
+	config := network.DefaultDHTConfig()
+	config.K = 4
+	config.RequestTimeout = 2 * time.Second
+
+	peers := make([]*network.PeeringManager, nodeCount)
+	dhts := make([]*network.DHT, nodeCount)
+	for i := range peers {
+		peers[i] = startPeer(t, ctx, fmt.Sprintf("node-%02d", i), 64)
+		dhts[i] = network.NewDHT(peers[i], config, zap.NewNop())
+		t.Cleanup(dhts[i].Stop)
+	}
+
+	// Every node knows only node-00 to begin with.
+	seed := []network.DHTContact{{ID: "node-00", Address: peers[0].ListenAddr().String()}}
+	for i := 1; i < nodeCount; i++ {
+		require.NoError(t, dhts[i].Bootstrap(ctx, seed))
+	}
+	for _, dht := range dhts {
+		for _, size := range dht.BucketSizes() {
+			assert.LessOrEqual(t, size, config.K)
+		}
+	}
+
+	for i := 1; i < nodeCount; i++ {
+		target := (i * 7) % nodeCount
+		if target == i {
+			continue
+		}
+		targetID := fmt.Sprintf("node-%02d", target)
+
+		contact, err := dhts[i].Lookup(ctx, targetID)
+		require.NoError(t, err, "node-%02d looking up %s", i, targetID)
+		assert.Equal(t, peers[target].ListenAddr().String(), contact.Address)
+	}
+
+	_, err := dhts[1].Lookup(ctx, "node-missing")
+	assert.Error(t, err)
+
+	peersFound, err := dhts[1].Discover(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, peersFound)
+	for _, peer := range peersFound {
+		assert.Equal(t, "dht", peer.Source)
+		assert.False(t, peer.Verified)
+	}
+}
+
+func TestDHTBeyondPeerLimit(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Far more nodes than any one may keep sessions with: DHT queries run
+	// over request sessions, which neither count against MaxPeers nor
+	// evict contacts that could not be reached for lack of a slot.
+	const nodeCount, maxPeers = 24, 3
+	config := network.DefaultDHTConfig()
+	config.K = 4
+	config.RequestTimeout = 2 * time.Second
+
+	peers := make([]*network.PeeringManager, nodeCount)
+	dhts := make([]*network.DHT, nodeCount)
+	for i := range peers {
+		peers[i] = startPeer(t, ctx, fmt.Sprintf("node-%02d", i), maxPeers)
+		dhts[i] = network.NewDHT(peers[i], config, zap.NewNop())
+		t.Cleanup(dhts[i].Stop)
+	}
+
+	seed := []network.DHTContact{{ID: "node-00", Address: peers[0].ListenAddr().String()}}
+	for i := 1; i < nodeCount; i++ {
+		require.NoError(t, dhts[i].Bootstrap(ctx, seed))
+	}
+
+	for i := 1; i < nodeCount; i++ {
+		assert.NotZero(t, dhts[i].Size(), "node-%02d emptied its routing table", i)
+
+		target := (i * 5) % nodeCount
+		if target == i {
+			continue
+		}
+		targetID := fmt.Sprintf("node-%02d", target)
+		contact, err := dhts[i].Lookup(ctx, targetID)
+		require.NoError(t, err, "node-%02d looking up %s", i, targetID)
+		assert.Equal(t, peers[target].ListenAddr().String(), contact.Address)
+	}
+
+	for _, pm := range peers {
+		assert.LessOrEqual(t, len(pm.GetActiveConnections()), maxPeers)
+	}
+
+	// A full session still fits alongside the request sessions.
+	require.NoError(t, peers[1].Connect("node-02", peers[2].ListenAddr().String()))
+	require.Eventually(t, func() bool {
+		session := activeSession(peers[1], "node-02")
+		return session != nil && !session.Transient
+	}, 5*time.Second, 20*time.Millisecond)
+}
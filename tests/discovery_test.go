@@ -0,0 +1,147 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/network"
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
+)
+
+type fakeResolver struct {
+	srv map[string][]*net.SRV
+	txt map[string][]string
+}
+
+func (r *fakeResolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	key := "_" + service + "._" + proto + "." + name
+	records, ok := r.srv[key]
+	if !ok {
+		return "", nil, errors.New("no such host")
+	}
+	return key, records, nil
+}
+
+func (r *fakeResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	txt, ok := r.txt[name]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	return txt, nil
+}
+
+func TestParsePeerTXT(t *testing.T) {
+	node, err := network.ParsePeerTXT([]string{"id=node-a lat=52.37 lon=4.89", "alt=12 region=eu-west version=1.0.0 extra=ignored"})
+	require.NoError(t, err)
+	assert.Equal(t, "node-a", node.ID)
+	assert.Equal(t, 52.37, node.Position.Latitude)
+	assert.Equal(t, 4.89, node.Position.Longitude)
+	assert.Equal(t, 12.0, node.Position.Altitude)
+	assert.Equal(t, "eu-west", node.Metadata.Region)
+	assert.Equal(t, "1.0.0", node.Metadata.Version)
+
+	_, err = network.ParsePeerTXT([]string{"lat=1 lon=2"})
+	assert.Error(t, err, "id is required")
+
+	_, err = network.ParsePeerTXT([]string{"id=node-a lat=north"})
+	assert.Error(t, err)
+}
+
+func TestDiscoverySources(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("static skips entries without node ID", func(t *testing.T) {
+		source := network.NewStaticSource([]string{"node-a@10.0.0.1:7070", "10.0.0.2:7070"}, zap.NewNop())
+		peers, err := source.Discover(ctx)
+		require.NoError(t, err)
+		require.Len(t, peers, 1)
+		assert.Equal(t, "node-a", peers[0].Node.ID)
+		assert.Equal(t, "10.0.0.1:7070", peers[0].Node.Address)
+		assert.Equal(t, network.PeerPending, peers[0].Status)
+		assert.False(t, peers[0].Verified)
+	})
+
+	t.Run("dns reads position from TXT records", func(t *testing.T) {
+		resolver := &fakeResolver{
+			srv: map[string][]*net.SRV{
+				"_relativistic._tcp.seed.example": {
+					{Target: "a.seed.example.", Port: 7070},
+					{Target: "b.seed.example.", Port: 7071},
+					{Target: "c.seed.example.", Port: 7072},
+				},
+			},
+			txt: map[string][]string{
+				"a.seed.example.": {"id=node-a lat=35.68 lon=139.69 region=ap-northeast"},
+				"b.seed.example.": {"lat=1 lon=2"},
+			},
+		}
+
+		peers, err := network.NewDNSSource("seed.example", resolver).Discover(ctx)
+		require.NoError(t, err)
+		require.Len(t, peers, 1, "targets without a usable TXT record are skipped")
+		assert.Equal(t, "node-a", peers[0].Node.ID)
+		assert.Equal(t, "a.seed.example:7070", peers[0].Node.Address)
+		assert.Equal(t, 35.68, peers[0].Node.Position.Latitude)
+		assert.Equal(t, "dns:seed.example", peers[0].Source)
+		assert.False(t, peers[0].Verified)
+
+		_, err = network.NewDNSSource("missing.example", resolver).Discover(ctx)
+		assert.Error(t, err)
+	})
+
+	t.Run("file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "peers.json")
+		require.NoError(t, os.WriteFile(path, []byte(`[
+			{"id": "node-a", "address": "10.0.0.1:7070", "position": {"latitude": 40.7, "longitude": -74.0}},
+			{"id": "", "address": "10.0.0.2:7070"}
+		]`), 0o600))
+
+		peers, err := network.NewFileSource(path).Discover(ctx)
+		require.NoError(t, err)
+		require.Len(t, peers, 1)
+		assert.Equal(t, "node-a", peers[0].Node.ID)
+		assert.Equal(t, 40.7, peers[0].Node.Position.Latitude)
+
+		_, err = network.NewFileSource(filepath.Join(t.TempDir(), "missing.json")).Discover(ctx)
+		assert.Error(t, err)
+	})
+}
+
+func TestDiscoveryServiceVerification(t *testing.T) {
+	ds := network.NewDiscoveryService(nil, zap.NewNop())
+
+	claimed := &types.Node{ID: "node-a", Address: "10.0.0.1:7070"}
+	ds.AddPeer(&network.Peer{Node: claimed, Status: network.PeerPending, Source: "static"})
+
+	peer := ds.GetPeer("node-a")
+	require.NotNil(t, peer)
+	assert.False(t, peer.Verified)
+	assert.Equal(t, 1, ds.GetDiscoveryStats().UnverifiedPeers)
+
+	ds.MarkVerified(&types.Node{
+		ID:       "node-a",
+		Address:  "10.0.0.1:7070",
+		Position: types.Position{Latitude: 48.85, Longitude: 2.35},
+		Metadata: types.Metadata{Region: "eu-west", Version: "1.0.0"},
+	})
+
+	peer = ds.GetPeer("node-a")
+	assert.True(t, peer.Verified)
+	assert.Equal(t, network.PeerConnected, peer.Status)
+	assert.Equal(t, 48.85, peer.Node.Position.Latitude)
+	assert.Equal(t, 0, ds.GetDiscoveryStats().UnverifiedPeers)
+
+	// Rediscovery must not overwrite handshake metadata with a claim.
+	ds.AddPeer(&network.Peer{Node: &types.Node{ID: "node-a", Address: "10.0.0.9:7070"}, Status: network.PeerPending, Source: "static"})
+	peer = ds.GetPeer("node-a")
+	assert.True(t, peer.Verified)
+	assert.Equal(t, "10.0.0.1:7070", peer.Node.Address)
+}
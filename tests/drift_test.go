@@ -0,0 +1,62 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/consensus"
+)
+
+func TestClockDriftTracking(t *testing.T) {
+	base := time.Now().UTC().Add(-time.Hour)
+
+	t.Run("FitAndPredict", func(t *testing.T) {
+		// Offset grows by 1ms every 100s, i.e. 10 ppm.
+		var points []consensus.OffsetPoint
+		for i := 0; i < 5; i++ {
+			points = append(points, consensus.OffsetPoint{
+				Timestamp:   base.Add(time.Duration(i) * 100 * time.Second),
+				Offset:      time.Duration(i) * time.Millisecond,
+				Uncertainty: time.Millisecond,
+			})
+		}
+
+		model, err := consensus.FitDrift(points)
+		require.NoError(t, err)
+		assert.InDelta(t, 10.0, model.DriftPPM, 1e-6)
+
+		offset, uncertainty := model.Predict(base.Add(1000 * time.Second))
+		assert.InDelta(t, float64(10*time.Millisecond), float64(offset), float64(time.Microsecond))
+		assert.GreaterOrEqual(t, uncertainty, time.Millisecond)
+	})
+
+	t.Run("BudgetAlert", func(t *testing.T) {
+		tracker := consensus.NewDriftTracker(&consensus.DriftConfig{
+			MaxDriftPPM:   50,
+			HistorySize:   10,
+			MinSamples:    3,
+			MaxHistoryAge: 24 * time.Hour,
+		}, zap.NewNop())
+
+		var alerts []*consensus.DriftAlert
+		tracker.OnAlert(func(alert *consensus.DriftAlert) {
+			alerts = append(alerts, alert)
+		})
+
+		// 10ms per 100s is 100 ppm, twice the budget.
+		for i := 0; i < 3; i++ {
+			tracker.Record("node1", consensus.OffsetPoint{
+				Timestamp: base.Add(time.Duration(i) * 100 * time.Second),
+				Offset:    time.Duration(i) * 10 * time.Millisecond,
+			})
+		}
+
+		assert.True(t, tracker.IsAlerting("node1"))
+		require.Len(t, alerts, 1)
+		assert.Equal(t, "node1", alerts[0].NodeID)
+	})
+}
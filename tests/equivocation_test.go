@@ -0,0 +1,140 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/consensus"
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/security"
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
+)
+
+func TestEquivocationDetection(t *testing.T) {
+	logger := zap.NewNop()
+	detector := consensus.NewEquivocationDetector(consensus.NewTimingManager(nil, logger), nil, logger)
+	key, err := security.GenerateSigningKey(security.KeyTypeSecp256k1)
+	require.NoError(t, err)
+	detector.SetSigner("detector", key)
+	now := time.Now().UTC()
+
+	vote := func(hash string, sent, received time.Time) *consensus.Evidence {
+		evidence, err := detector.IngestVote(&types.Vote{
+			BlockHash: hash,
+			VoterID:   "validator1",
+			Height:    10,
+			Round:     0,
+			Timestamp: sent,
+		}, received)
+		require.NoError(t, err)
+		return evidence
+	}
+
+	assert.Nil(t, vote("block_a", now, now.Add(20*time.Millisecond)))
+	assert.Nil(t, vote("block_a", now, now.Add(30*time.Millisecond)), "a duplicate is not equivocation")
+
+	evidence := vote("block_b", now.Add(10*time.Millisecond), now.Add(40*time.Millisecond))
+	require.NotNil(t, evidence)
+	assert.Equal(t, consensus.EvidenceDoubleVote, evidence.Type)
+	assert.Equal(t, consensus.EvidenceConfirmed, evidence.Status)
+	assert.Equal(t, "validator1", evidence.Offender)
+
+	late := vote("block_c", now.Add(-10*time.Second), now.Add(50*time.Millisecond))
+	require.NotNil(t, late)
+	assert.Equal(t, consensus.EvidenceSuspected, late.Status)
+
+	data, err := detector.ExportEvidence(&consensus.EvidenceFilter{Offender: "validator1"})
+	require.NoError(t, err)
+
+	var exported []*consensus.Evidence
+	require.NoError(t, json.Unmarshal(data, &exported))
+	require.Len(t, exported, 2)
+	for _, record := range exported {
+		assert.NoError(t, consensus.VerifyEvidence(record))
+	}
+
+	exported[0].Offender = "validator2"
+	assert.Error(t, consensus.VerifyEvidence(exported[0]))
+}
+
+func TestEvidenceSigner(t *testing.T) {
+	logger := zap.NewNop()
+	now := time.Now().UTC()
+	doubleVote := func(ingest func(*types.Vote, time.Time) (*consensus.Evidence, error)) *consensus.Evidence {
+		var evidence *consensus.Evidence
+		for _, hash := range []string{"block_a", "block_b"} {
+			var err error
+			evidence, err = ingest(&types.Vote{BlockHash: hash, VoterID: "validator1", Height: 10, Timestamp: now}, now)
+			require.NoError(t, err)
+		}
+		require.NotNil(t, evidence)
+		return evidence
+	}
+
+	// Without a configured key evidence is kept but cannot be verified.
+	unsigned := consensus.NewEquivocationDetector(consensus.NewTimingManager(nil, logger), nil, logger)
+	evidence := doubleVote(unsigned.IngestVote)
+	assert.NotEmpty(t, evidence.ID)
+	assert.Error(t, consensus.VerifyEvidence(evidence))
+
+	// With a validator key, evidence verifies against the key registered
+	// for the detecting validator and no other.
+	key, err := security.GenerateSigningKey(security.KeyTypeEd25519)
+	require.NoError(t, err)
+	keyManager := security.NewKeyManager(logger)
+	keyManager.SetLocalKey("validator9", key)
+	manager := consensus.NewConsensusManager(nil, logger)
+	manager.AttachKeyManager(keyManager)
+
+	evidence = doubleVote(manager.GetEquivocationDetector().IngestVote)
+	assert.Equal(t, "validator9", evidence.DetectedBy)
+	assert.NoError(t, manager.VerifyEvidence(evidence))
+
+	other, err := security.GenerateSigningKey(security.KeyTypeEd25519)
+	require.NoError(t, err)
+	proof, err := keyManager.SignKeyRotation(other.Public())
+	require.NoError(t, err)
+	require.NoError(t, keyManager.RotateValidatorKey("validator9", other.Type, other.Public().String(), proof))
+	assert.Error(t, manager.VerifyEvidence(evidence))
+}
+
+func TestEquivocationRetention(t *testing.T) {
+	logger := zap.NewNop()
+	detector := consensus.NewEquivocationDetector(consensus.NewTimingManager(nil, logger), nil, logger)
+	now := time.Now().UTC()
+
+	vote := func(voter, hash string, height uint64) *consensus.Evidence {
+		evidence, err := detector.IngestVote(&types.Vote{BlockHash: hash, VoterID: voter, Height: height, Timestamp: now}, now)
+		require.NoError(t, err)
+		return evidence
+	}
+
+	detector.SetCommittedHeight(1500)
+
+	// A validly signed message claiming a huge height is ignored rather than
+	// moving the window, so real heights are still checked.
+	assert.Nil(t, vote("validator1", "block_a", 1<<63))
+	assert.Nil(t, vote("validator1", "block_b", 1<<63))
+	assert.Nil(t, vote("validator1", "block_a", ^uint64(0)))
+	assert.Nil(t, vote("validator2", "block_a", 1501))
+	assert.NotNil(t, vote("validator2", "block_b", 1501))
+
+	// Heights behind the window are ignored, and committing past a slot
+	// prunes it.
+	assert.Nil(t, vote("validator3", "block_a", 1))
+	assert.Nil(t, vote("validator3", "block_b", 1))
+	detector.SetCommittedHeight(5000)
+	assert.Nil(t, vote("validator2", "block_c", 1501))
+
+	// Stored messages per signer and slot are capped.
+	config := consensus.DefaultEquivocationConfig()
+	for i := 0; i < config.MaxMessagesPerSlot; i++ {
+		vote("validator4", fmt.Sprintf("block_%d", i), 5000)
+	}
+	assert.Nil(t, vote("validator4", "block_over_cap", 5000))
+}
@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/network"
+)
+
+// startFakeRedis serves just enough of RESP2 for a TopologyManager to keep
+// its nodes in memory: writes are acknowledged and nothing is stored, so a
+// fresh manager always starts with an empty topology.
+func startFakeRedis(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveFakeRedis(conn)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func serveFakeRedis(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	for {
+		args, err := readRESPCommand(reader)
+		if err != nil {
+			return
+		}
+
+		var reply string
+		switch strings.ToUpper(args[0]) {
+		case "PING":
+			reply = "+PONG\r\n"
+		case "HSET", "DEL":
+			reply = ":1\r\n"
+		case "KEYS", "HGETALL":
+			reply = "*0\r\n"
+		case "HELLO":
+			reply = "-ERR unknown command 'HELLO'\r\n"
+		default:
+			reply = "+OK\r\n"
+		}
+		if _, err := conn.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+func readRESPCommand(reader *bufio.Reader) ([]string, error) {
+	header, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(header, "*") {
+		return nil, fmt.Errorf("unexpected request: %q", header)
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(header[1:]))
+	if err != nil || count < 1 {
+		return nil, fmt.Errorf("invalid array header: %q", header)
+	}
+
+	args := make([]string, count)
+	for i := range args {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		length, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, fmt.Errorf("invalid bulk header: %q", line)
+		}
+		data := make([]byte, length+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:length])
+	}
+	return args, nil
+}
+
+// newTestTopology returns a TopologyManager backed by a fake Redis.
+func newTestTopology(t *testing.T) *network.TopologyManager {
+	topology, err := network.NewTopologyManager(startFakeRedis(t), zap.NewNop())
+	require.NoError(t, err)
+	t.Cleanup(topology.Close)
+	return topology
+}
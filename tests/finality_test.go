@@ -0,0 +1,52 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/consensus"
+)
+
+func TestFinalityQuorumLatency(t *testing.T) {
+	// Three close validators and one far away: a 3-of-4 quorum never waits
+	// on the distant node.
+	matrix := consensus.NewDelayMatrix([]string{"a", "b", "c", "far"})
+	near := 10 * time.Millisecond
+	far := 150 * time.Millisecond
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			switch {
+			case i == j:
+			case i == 3 || j == 3:
+				matrix.Delay[i][j] = far
+				matrix.Jitter[i][j] = far / 10
+			default:
+				matrix.Delay[i][j] = near
+				matrix.Jitter[i][j] = near / 10
+			}
+		}
+	}
+
+	quorum := consensus.QuorumSize(4, 2.0/3.0)
+	assert.Equal(t, 3, quorum)
+
+	assert.Equal(t, near, matrix.QuorumLatency(quorum, 0))
+	assert.Greater(t, matrix.QuorumLatency(quorum, 2.326), near)
+	assert.Less(t, matrix.QuorumLatency(quorum, 2.326), far)
+	assert.Equal(t, near, matrix.BroadcastLatency(0, quorum, 0))
+	assert.Equal(t, far, matrix.BroadcastLatency(3, quorum, 0))
+}
+
+func TestFinalityWithoutTopology(t *testing.T) {
+	logger := zap.NewNop()
+	estimator := consensus.NewFinalityEstimator(consensus.NewTimingManager(nil, logger), logger)
+
+	for _, validators := range [][]string{nil, {"a", "b", "c"}} {
+		estimate, err := estimator.Estimate(&consensus.FinalityRequest{OriginNode: "a", Validators: validators})
+		assert.Error(t, err)
+		assert.Nil(t, estimate)
+	}
+}
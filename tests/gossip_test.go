@@ -0,0 +1,229 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/consensus"
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/network"
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/security"
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
+)
+
+type gossipNode struct {
+	peering  *network.PeeringManager
+	router   *network.GossipRouter
+	mu       sync.Mutex
+	received map[string]int
+}
+
+func (n *gossipNode) count(data string) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.received[data]
+}
+
+func startGossipNode(t *testing.T, ctx context.Context, nodeID string) *gossipNode {
+	config := network.DefaultGossipConfig()
+	config.HeartbeatInterval = 50 * time.Millisecond
+
+	node := &gossipNode{
+		peering:  startPeer(t, ctx, nodeID, 10),
+		received: make(map[string]int),
+	}
+	node.router = network.NewGossipRouter(node.peering, config, zap.NewNop())
+	node.router.Subscribe(network.TopicVotes, func(message *network.GossipMessage) network.GossipValidation {
+		node.mu.Lock()
+		defer node.mu.Unlock()
+		node.received[string(message.Data)]++
+		if string(message.Data) == "forged" {
+			return network.GossipReject
+		}
+		return network.GossipAccept
+	})
+	go node.router.Start(ctx)
+	t.Cleanup(node.router.Stop)
+	return node
+}
+
+func TestGossipPropagation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a := startGossipNode(t, ctx, "node-a")
+	b := startGossipNode(t, ctx, "node-b")
+	c := startGossipNode(t, ctx, "node-c")
+	d := startGossipNode(t, ctx, "node-d")
+
+	// a-b-c-d in a line, plus a-c so c hears messages twice.
+	link := func(from, to *gossipNode) {
+		require.NoError(t, from.peering.Connect(to.peering.LocalPeerID(), to.peering.ListenAddr().String()))
+	}
+	link(a, b)
+	link(b, c)
+	link(c, d)
+	link(a, c)
+
+	require.Eventually(t, func() bool {
+		return len(a.router.MeshPeers(network.TopicVotes)) == 2 &&
+			len(b.router.MeshPeers(network.TopicVotes)) == 2 &&
+			len(c.router.MeshPeers(network.TopicVotes)) == 3 &&
+			len(d.router.MeshPeers(network.TopicVotes)) == 1
+	}, 5*time.Second, 20*time.Millisecond)
+
+	id, err := a.router.Publish(network.TopicVotes, []byte("vote-1"))
+	require.NoError(t, err)
+	assert.NotEmpty(t, id)
+
+	require.Eventually(t, func() bool {
+		return b.count("vote-1") == 1 && c.count("vote-1") == 1 && d.count("vote-1") == 1
+	}, 5*time.Second, 20*time.Millisecond)
+
+	// Let the remaining copies arrive, then check none was delivered twice.
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, 0, a.count("vote-1"))
+	for _, node := range []*gossipNode{b, c, d} {
+		assert.Equal(t, 1, node.count("vote-1"))
+	}
+	assert.Positive(t, d.router.PeerScore("node-c"), "first deliveries earn score")
+
+	// A message the handler rejects is not forwarded and costs the sender.
+	_, err = a.router.Publish(network.TopicVotes, []byte("forged"))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return b.router.PeerScore("node-a") < 0 && c.router.PeerScore("node-a") < 0
+	}, 5*time.Second, 20*time.Millisecond)
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, 0, d.count("forged"))
+	assert.Positive(t, b.router.GetMetrics().Rejected)
+}
+
+func TestGossipMessageID(t *testing.T) {
+	id := network.GossipMessageID("node-a", 1, network.TopicBlocks, []byte("block"))
+	assert.Equal(t, id, network.GossipMessageID("node-a", 1, network.TopicBlocks, []byte("block")))
+	assert.NotEqual(t, id, network.GossipMessageID("node-a", 2, network.TopicBlocks, []byte("block")))
+	assert.NotEqual(t, id, network.GossipMessageID("node-b", 1, network.TopicBlocks, []byte("block")))
+	assert.NotEqual(t, id, network.GossipMessageID("node-a", 1, network.TopicVotes, []byte("block")))
+}
+
+func TestConsensusGossipVerdicts(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	config := network.DefaultGossipConfig()
+	config.HeartbeatInterval = 50 * time.Millisecond
+	senderPeering := startPeer(t, ctx, "node-a", 10)
+	receiverPeering := startPeer(t, ctx, "node-b", 10)
+	sender := network.NewGossipRouter(senderPeering, config, zap.NewNop())
+	receiver := network.NewGossipRouter(receiverPeering, config, zap.NewNop())
+	for _, router := range []*network.GossipRouter{sender, receiver} {
+		go router.Start(ctx)
+		t.Cleanup(router.Stop)
+	}
+
+	key, err := security.GenerateSigningKey(security.KeyTypeEd25519)
+	require.NoError(t, err)
+	keyManager := security.NewKeyManager(zap.NewNop())
+	require.NoError(t, keyManager.RegisterValidatorKey("v1", key.Type, key.Public().String()))
+	manager := consensus.NewConsensusManager(nil, zap.NewNop())
+	manager.AttachKeyManager(keyManager)
+	manager.AttachGossip(receiver)
+
+	sender.Subscribe(network.TopicVotes, func(*network.GossipMessage) network.GossipValidation {
+		return network.GossipAccept
+	})
+	require.NoError(t, senderPeering.Connect("node-b", receiverPeering.ListenAddr().String()))
+	require.Eventually(t, func() bool {
+		return len(receiver.MeshPeers(network.TopicVotes)) == 1
+	}, 5*time.Second, 20*time.Millisecond)
+
+	publish := func(vote *types.Vote) {
+		data, err := json.Marshal(vote)
+		require.NoError(t, err)
+		_, err = sender.Publish(network.TopicVotes, data)
+		require.NoError(t, err)
+	}
+
+	// A validator this node has no key for may be one it has not caught up
+	// with yet; the relay is not penalised.
+	unknown := &types.Vote{BlockHash: "block", VoterID: "v9", Height: 1, Timestamp: time.Now().UTC(), Signature: "00"}
+	publish(unknown)
+	require.Eventually(t, func() bool {
+		return receiver.GetMetrics().Ignored == 1
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.GreaterOrEqual(t, receiver.PeerScore("node-a"), 0.0)
+
+	// A bad signature from a known validator is.
+	forged := &types.Vote{BlockHash: "block", VoterID: "v1", Height: 1, Timestamp: time.Now().UTC(), Signature: "00"}
+	publish(forged)
+	require.Eventually(t, func() bool {
+		return receiver.PeerScore("node-a") < 0
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.Equal(t, int64(1), receiver.GetMetrics().Rejected)
+}
+
+func TestGossipNodeAnnouncements(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	here := types.Position{Latitude: -6.2, Longitude: 106.8}
+	start := func(topology *network.TopologyManager) (*network.PeeringManager, *network.GossipRouter) {
+		identity, err := network.GenerateIdentity()
+		require.NoError(t, err)
+		pm := network.NewPeeringManager(nil, topology, &network.PeeringConfig{
+			ListenAddress: "127.0.0.1:0",
+			MaxPeers:      10,
+			Node:          &types.Node{Position: here, Metadata: types.Metadata{Region: "jakarta"}},
+			Identity:      identity,
+		}, zap.NewNop())
+		require.NoError(t, pm.Start(ctx))
+		t.Cleanup(pm.Stop)
+
+		config := network.DefaultGossipConfig()
+		config.HeartbeatInterval = 50 * time.Millisecond
+		config.StrictSigning = true
+		router := network.NewGossipRouter(pm, config, zap.NewNop())
+		pm.AttachGossip(router)
+		go router.Start(ctx)
+		t.Cleanup(router.Stop)
+		return pm, router
+	}
+
+	topology := newTestTopology(t)
+	a, _ := start(nil)
+	relay, relayRouter := start(nil)
+	c, cRouter := start(topology)
+	require.NoError(t, a.Connect(relay.LocalPeerID(), relay.ListenAddr().String()))
+	require.NoError(t, relay.Connect(c.LocalPeerID(), c.ListenAddr().String()))
+	require.Eventually(t, func() bool {
+		return len(cRouter.MeshPeers(network.TopicTopology)) == 1 &&
+			len(relayRouter.MeshPeers(network.TopicTopology)) == 2
+	}, 5*time.Second, 20*time.Millisecond)
+
+	// A metadata change reaches a node with no session to its origin.
+	a.SetLocalNode(&types.Node{Position: here, Metadata: types.Metadata{Region: "bandung"}})
+	require.Eventually(t, func() bool {
+		node, err := topology.GetNode(a.LocalPeerID())
+		return err == nil && node.Metadata.Region == "bandung"
+	}, 5*time.Second, 20*time.Millisecond)
+
+	// A validly signed record about another node is rejected: only a node
+	// may announce itself.
+	forged, err := json.Marshal(&types.Node{ID: a.LocalPeerID(), Position: here, Metadata: types.Metadata{Region: "forged"}})
+	require.NoError(t, err)
+	_, err = relayRouter.Publish(network.TopicTopology, forged)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return cRouter.PeerScore(relay.LocalPeerID()) < 0
+	}, 5*time.Second, 20*time.Millisecond)
+	node, err := topology.GetNode(a.LocalPeerID())
+	require.NoError(t, err)
+	assert.Equal(t, "bandung", node.Metadata.Region)
+}
@@ -0,0 +1,141 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/network"
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
+)
+
+func TestNegotiateProtocolVersion(t *testing.T) {
+	version, err := network.NegotiateProtocolVersion([]uint32{1, 2, 3}, []uint32{2, 3, 4})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(3), version)
+
+	_, err = network.NegotiateProtocolVersion([]uint32{1}, []uint32{2})
+	assert.Error(t, err)
+}
+
+func TestCheckPositionPlausibility(t *testing.T) {
+	jakarta := types.Position{Latitude: -6.2, Longitude: 106.8}
+	london := types.Position{Latitude: 51.5, Longitude: -0.1}
+
+	minimum := network.MinimumRTT(jakarta, london)
+	assert.Greater(t, minimum, 50*time.Millisecond)
+
+	assert.NoError(t, network.CheckPositionPlausibility(jakarta, london, minimum+time.Millisecond, 0))
+	assert.Error(t, network.CheckPositionPlausibility(jakarta, london, time.Millisecond, 2*time.Millisecond))
+	assert.NoError(t, network.CheckPositionPlausibility(jakarta, jakarta, 0, 0))
+}
+
+func TestPeeringHandshakeMetadata(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	here := types.Position{Latitude: -6.2, Longitude: 106.8}
+	start := func(nodeID string, position types.Position, versions []uint32) *network.PeeringManager {
+		pm := network.NewPeeringManager(nil, nil, &network.PeeringConfig{
+			NodeID:        nodeID,
+			ListenAddress: "127.0.0.1:0",
+			MaxPeers:      10,
+			Node: &types.Node{
+				Position: position,
+				Metadata: types.Metadata{Region: "ap-southeast", Version: "1.0.0", Capabilities: []string{"consensus"}},
+			},
+			ProtocolVersions: versions,
+		}, zap.NewNop())
+		require.NoError(t, pm.Start(ctx))
+		t.Cleanup(pm.Stop)
+		return pm
+	}
+
+	a := start("node-a", here, []uint32{1, 2})
+	b := start("node-b", here, []uint32{1})
+	require.NoError(t, a.Connect("node-b", b.ListenAddr().String()))
+
+	// A peer on the far side of the planet answering over loopback is
+	// faster than light allows, so its position is not trusted.
+	far := start("node-far", types.Position{Latitude: 51.5, Longitude: -0.1}, []uint32{1})
+	require.NoError(t, a.Connect("node-far", far.ListenAddr().String()))
+
+	require.Eventually(t, func() bool {
+		ab, ba := activeSession(a, "node-b"), activeSession(b, "node-a")
+		af := activeSession(a, "node-far")
+		return ab != nil && ba != nil && af != nil && ab.RTT > 0 && ba.RTT > 0 && af.RTT > 0
+	}, 5*time.Second, 20*time.Millisecond)
+
+	ab, ba := activeSession(a, "node-b"), activeSession(b, "node-a")
+	assert.Equal(t, uint32(1), ab.ProtocolVersion)
+	assert.Equal(t, uint32(1), ba.ProtocolVersion)
+	require.NotNil(t, ab.RemoteNode)
+	assert.Equal(t, "node-b", ab.RemoteNode.ID)
+	assert.Equal(t, []string{"consensus"}, ab.RemoteNode.Metadata.Capabilities)
+	assert.True(t, ab.PositionVerified)
+	assert.True(t, ba.PositionVerified)
+	assert.False(t, activeSession(a, "node-far").PositionVerified)
+
+	// No common protocol version: the listener refuses the session.
+	incompatible := start("node-c", here, []uint32{7})
+	require.NoError(t, incompatible.Connect("node-b", b.ListenAddr().String()))
+	time.Sleep(200 * time.Millisecond)
+	assert.Nil(t, activeSession(b, "node-c"))
+}
+
+func TestHandshakeMarksVerified(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	here := types.Position{Latitude: -6.2, Longitude: 106.8}
+	start := func(position types.Position, identity *network.NodeIdentity, nodeID string) (*network.PeeringManager, *network.DiscoveryService) {
+		discovery := network.NewDiscoveryService(nil, zap.NewNop())
+		pm := network.NewPeeringManager(discovery, nil, &network.PeeringConfig{
+			NodeID:        nodeID,
+			ListenAddress: "127.0.0.1:0",
+			MaxPeers:      10,
+			Node:          &types.Node{Position: position},
+			Identity:      identity,
+		}, zap.NewNop())
+		require.NoError(t, pm.Start(ctx))
+		t.Cleanup(pm.Stop)
+		return pm, discovery
+	}
+	withIdentity := func(position types.Position) (*network.PeeringManager, *network.DiscoveryService) {
+		identity, err := network.GenerateIdentity()
+		require.NoError(t, err)
+		return start(position, identity, "")
+	}
+	connected := func(from, to *network.PeeringManager) {
+		require.NoError(t, from.Connect(to.LocalPeerID(), to.ListenAddr().String()))
+		require.Eventually(t, func() bool {
+			session := activeSession(from, to.LocalPeerID())
+			return session != nil && session.RTT > 0
+		}, 5*time.Second, 20*time.Millisecond)
+	}
+
+	a, discovery := withIdentity(here)
+	near, _ := withIdentity(here)
+	far, _ := withIdentity(types.Position{Latitude: 51.5, Longitude: -0.1})
+	connected(a, near)
+	connected(a, far)
+
+	require.Eventually(t, func() bool {
+		peer := discovery.GetPeer(near.LocalPeerID())
+		return peer != nil && peer.Verified
+	}, 5*time.Second, 20*time.Millisecond)
+
+	// An authenticated peer whose position is implausible is not verified.
+	assert.Nil(t, discovery.GetPeer(far.LocalPeerID()))
+
+	// Nor is a plausible peer whose ID no identity backs.
+	open, openDiscovery := start(here, nil, "node-open")
+	claimant, _ := start(here, nil, "node-claimant")
+	connected(open, claimant)
+	assert.True(t, activeSession(open, "node-claimant").PositionVerified)
+	assert.Nil(t, openDiscovery.GetPeer("node-claimant"))
+}
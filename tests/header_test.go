@@ -0,0 +1,61 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/security"
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/relativistic"
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
+)
+
+func TestBlockHeaderLinkage(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+	validator := relativistic.NewHeaderValidator(10 * time.Millisecond)
+
+	genesis := types.NewBlockHeader(nil, &types.Block{
+		Timestamp:    base,
+		ProposedBy:   "tokyo",
+		NodePosition: types.Position{Latitude: 35.68, Longitude: 139.69},
+	}, 0, "set")
+	require.True(t, genesis.IsGenesis())
+	require.NoError(t, validator.ValidateHeader(genesis))
+
+	child := types.NewBlockHeader(genesis, &types.Block{
+		Timestamp:    base.Add(time.Second),
+		ProposedBy:   "london",
+		NodePosition: types.Position{Latitude: 51.51, Longitude: -0.13},
+		Data:         []byte("payload"),
+	}, 0, "set")
+	assert.Equal(t, uint64(1), child.Height)
+	assert.Equal(t, genesis.Hash(), child.ParentHash)
+	require.NoError(t, validator.ValidateChain([]*types.BlockHeader{genesis, child}))
+
+	// The hash covers every field, so editing the parent breaks the link.
+	tampered := *genesis
+	tampered.Round = 1
+	assert.Error(t, validator.ValidateChild(&tampered, child))
+
+	// Tokyo to London is roughly 32ms of light delay: a child stamped 5ms
+	// after its parent is too early even with 10ms of clock tolerance.
+	delay := validator.MinChildDelay(genesis, child)
+	assert.InDelta(t, float64(32*time.Millisecond), float64(delay), float64(2*time.Millisecond))
+	child.Timestamp = base.Add(5 * time.Millisecond)
+	assert.Error(t, validator.ValidateChild(genesis, child))
+	child.Timestamp = base.Add(delay)
+	assert.NoError(t, validator.ValidateChild(genesis, child))
+
+	keyManager := security.NewKeyManager(zap.NewNop())
+	key, err := security.GenerateSigningKey(security.KeyTypeEd25519)
+	require.NoError(t, err)
+	keyManager.SetLocalKey("london", key)
+	require.NoError(t, keyManager.SignHeader(child))
+	require.NoError(t, keyManager.VerifyHeader(child))
+	hash := child.Hash()
+	child.Signature = ""
+	assert.Equal(t, hash, child.Hash(), "signature must not affect the header hash")
+}
@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/relativistic"
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
+)
+
+func TestHybridLogicalClock(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+	physical := base
+	clock := relativistic.NewHybridLogicalClock("local", 100*time.Millisecond)
+	clock.SetPhysicalClock(func() time.Time { return physical })
+
+	first := clock.Now()
+	second := clock.Now()
+	assert.True(t, first.Before(second))
+	assert.Equal(t, first.Wall, second.Wall)
+
+	// A peer 50ms ahead is within the default bound and pulls the clock
+	// forward; the next local event still orders after it.
+	remote := types.HLCTimestamp{Wall: base.Add(50 * time.Millisecond).UnixNano(), Logical: 4}
+	merged, err := clock.Update("peer", remote)
+	require.NoError(t, err)
+	assert.Equal(t, remote.Wall, merged.Wall)
+	assert.Equal(t, uint32(5), merged.Logical)
+	assert.True(t, merged.Before(clock.Now()))
+
+	// With a known 80ms light delay the 100ms tolerance leaves only 20ms of
+	// lead: 15ms ahead is accepted, 100ms ahead is not.
+	clock.SetPeerBounds("far", relativistic.PeerClockBounds{Delay: 80 * time.Millisecond})
+	_, err = clock.Update("far", types.HLCTimestamp{Wall: base.Add(15 * time.Millisecond).UnixNano()})
+	require.NoError(t, err)
+	before := clock.Last()
+	_, err = clock.Update("far", types.HLCTimestamp{Wall: base.Add(100 * time.Millisecond).UnixNano()})
+	assert.Error(t, err)
+	assert.Equal(t, before, clock.Last())
+
+	block := &types.Block{Hash: "b1"}
+	stamped := clock.StampBlock(block)
+	require.NotNil(t, block.HLC)
+	assert.Equal(t, stamped, *block.HLC)
+
+	decoded, err := relativistic.DecodeHLC(relativistic.EncodeHLC(stamped))
+	require.NoError(t, err)
+	assert.Equal(t, stamped, decoded)
+
+	parsed, err := relativistic.ParseHLC(stamped.String())
+	require.NoError(t, err)
+	assert.Equal(t, stamped, parsed)
+}
@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/consensus"
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
+)
+
+func TestBlockIntervalController(t *testing.T) {
+	newController := func() *consensus.BlockIntervalController {
+		config := consensus.DefaultIntervalControllerConfig()
+		config.Cooldown = 0
+		return consensus.NewBlockIntervalController(config, zap.NewNop())
+	}
+
+	t.Run("Hysteresis", func(t *testing.T) {
+		controller := newController()
+		assert.Nil(t, controller.Evaluate(), "no observations should hold the interval")
+
+		// 2.4s propagation targets 4.8s, within 10% of the initial 5s.
+		for i := 0; i < 20; i++ {
+			controller.RecordPropagation(2400 * time.Millisecond)
+		}
+		assert.Nil(t, controller.Evaluate())
+		assert.Equal(t, 5*time.Second, controller.GetInterval())
+	})
+
+	t.Run("StepDownAndBackoff", func(t *testing.T) {
+		controller := newController()
+
+		// Fast propagation lowers the interval, but only by the maximum step.
+		for i := 0; i < 20; i++ {
+			controller.RecordPropagation(500 * time.Millisecond)
+			controller.RecordRound(false)
+		}
+		adjustment := controller.Evaluate()
+		require.NotNil(t, adjustment)
+		assert.Equal(t, 4500*time.Millisecond, adjustment.Interval)
+
+		// Missed rounds back the interval off.
+		for i := 0; i < 5; i++ {
+			controller.RecordRound(true)
+		}
+		adjustment = controller.Evaluate()
+		require.NotNil(t, adjustment)
+		assert.Greater(t, adjustment.Interval, adjustment.Previous)
+		assert.Equal(t, 5, adjustment.Inputs.MissedRounds)
+
+		assert.Len(t, controller.GetHistory(0), 2)
+	})
+
+	t.Run("OneBurstOneBackoff", func(t *testing.T) {
+		controller := newController()
+
+		for i := 0; i < 5; i++ {
+			controller.RecordRound(true)
+		}
+		adjustment := controller.Evaluate()
+		require.NotNil(t, adjustment)
+		interval := controller.GetInterval()
+		assert.Greater(t, interval, 5*time.Second)
+
+		// The burst was consumed by the first backoff; later evaluations
+		// inside the observation window must not back off again.
+		for i := 0; i < 3; i++ {
+			assert.Nil(t, controller.Evaluate())
+		}
+		assert.Equal(t, interval, controller.GetInterval())
+		assert.Len(t, controller.GetHistory(0), 1)
+	})
+}
+
+func TestBlockIntervalInputsFromRounds(t *testing.T) {
+	manager := consensus.NewConsensusManager(nil, zap.NewNop())
+
+	// Two validators propose at height 5; the block committed in round 2
+	// leaves two missed rounds and one orphaned proposal.
+	sentAt := time.Now().UTC().Add(-300 * time.Millisecond)
+	for _, proposal := range []*types.Proposal{
+		{Block: &types.Block{Hash: "block-a", Timestamp: sentAt}, ProposerID: "validator-1", Height: 5, Round: 1, Timestamp: sentAt},
+		{Block: &types.Block{Hash: "block-b", Timestamp: sentAt}, ProposerID: "validator-2", Height: 5, Round: 2, Timestamp: sentAt},
+	} {
+		_, err := manager.IngestProposal(proposal)
+		require.NoError(t, err)
+	}
+	manager.RecordCommit(5, 2, "block-b")
+	manager.RecordCommit(5, 2, "block-b")
+
+	controller := manager.GetBlockIntervalController()
+	controller.Evaluate()
+	inputs := controller.GetLastInputs()
+	require.NotNil(t, inputs)
+	assert.Equal(t, 2, inputs.PropagationSamples)
+	assert.GreaterOrEqual(t, inputs.PropagationP95, 300*time.Millisecond)
+	assert.Equal(t, 3, inputs.Rounds)
+	assert.Equal(t, 2, inputs.MissedRounds)
+	assert.Equal(t, 2, inputs.Blocks)
+	assert.Equal(t, 1, inputs.OrphanedBlocks)
+}
@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/network"
+)
+
+func startPeer(t *testing.T, ctx context.Context, nodeID string, maxPeers int) *network.PeeringManager {
+	pm := network.NewPeeringManager(nil, nil, &network.PeeringConfig{
+		NodeID:        nodeID,
+		ListenAddress: "127.0.0.1:0",
+		MaxPeers:      maxPeers,
+	}, zap.NewNop())
+	require.NoError(t, pm.Start(ctx))
+	t.Cleanup(pm.Stop)
+	return pm
+}
+
+func activeSession(pm *network.PeeringManager, peerID string) *network.PeerConnection {
+	for _, conn := range pm.GetActiveConnections() {
+		if conn.PeerID == peerID {
+			return conn
+		}
+	}
+	return nil
+}
+
+func TestPeeringSimultaneousDial(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a := startPeer(t, ctx, "node-a", 10)
+	b := startPeer(t, ctx, "node-b", 10)
+
+	// Both sides dial at once; they must settle on the session dialled by
+	// the lower ID, node-a.
+	require.NoError(t, a.Connect("node-b", b.ListenAddr().String()))
+	require.NoError(t, b.Connect("node-a", a.ListenAddr().String()))
+
+	require.Eventually(t, func() bool {
+		ab, ba := activeSession(a, "node-b"), activeSession(b, "node-a")
+		return ab != nil && ba != nil && ab.Direction == network.Outbound && ba.Direction == network.Inbound
+	}, 5*time.Second, 20*time.Millisecond)
+
+	assert.Len(t, a.GetActiveConnections(), 1)
+	assert.Len(t, b.GetActiveConnections(), 1)
+	require.NoError(t, a.SendMessage("node-b", []byte("ping")))
+}
+
+func TestPeeringMaxPeers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hub := startPeer(t, ctx, "hub", 1)
+	first := startPeer(t, ctx, "first", 10)
+	second := startPeer(t, ctx, "second", 10)
+
+	require.NoError(t, first.Connect("hub", hub.ListenAddr().String()))
+	require.Eventually(t, func() bool {
+		return activeSession(hub, "first") != nil
+	}, 5*time.Second, 20*time.Millisecond)
+
+	require.NoError(t, second.Connect("hub", hub.ListenAddr().String()))
+	time.Sleep(200 * time.Millisecond)
+	assert.Nil(t, activeSession(hub, "second"))
+	assert.Len(t, hub.GetActiveConnections(), 1)
+}
+
+func TestPeeringMutualTLS(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := filepath.Join(t.TempDir(), "identity.pem")
+	identity, err := network.LoadOrCreateIdentity(path)
+	require.NoError(t, err)
+	reloaded, err := network.LoadOrCreateIdentity(path)
+	require.NoError(t, err)
+	assert.Equal(t, identity.ID, reloaded.ID)
+	assert.Equal(t, network.NodeIDFromPublicKey(identity.PublicKey()), identity.ID)
+
+	other, err := network.GenerateIdentity()
+	require.NoError(t, err)
+
+	start := func(id *network.NodeIdentity) *network.PeeringManager {
+		pm := network.NewPeeringManager(nil, nil, &network.PeeringConfig{
+			ListenAddress: "127.0.0.1:0",
+			MaxPeers:      10,
+			Identity:      id,
+		}, zap.NewNop())
+		require.NoError(t, pm.Start(ctx))
+		t.Cleanup(pm.Stop)
+		return pm
+	}
+	a, b := start(identity), start(other)
+	assert.Equal(t, identity.ID, a.LocalPeerID())
+
+	// Dialling b under a made-up ID fails the TLS pin.
+	require.NoError(t, a.Connect("spoofed", b.ListenAddr().String()))
+	time.Sleep(200 * time.Millisecond)
+	assert.Nil(t, activeSession(a, "spoofed"))
+	assert.Empty(t, b.GetActiveConnections())
+
+	require.NoError(t, a.Connect(other.ID, b.ListenAddr().String()))
+	require.Eventually(t, func() bool {
+		session := activeSession(b, identity.ID)
+		return session != nil && session.Authenticated && activeSession(a, other.ID) != nil
+	}, 5*time.Second, 20*time.Millisecond)
+}
@@ -0,0 +1,110 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/consensus"
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
+)
+
+func TestChainTimeValidator(t *testing.T) {
+	validator := consensus.NewChainTimeValidator(
+		&consensus.ChainTimeConfig{Window: 5, MaxFutureDrift: 10 * time.Second}, zap.NewNop())
+
+	now := time.Unix(1700000000, 0)
+	validator.SetClock(func() time.Time { return now })
+
+	child := func(parent *types.BlockHeader, ts time.Time) *types.BlockHeader {
+		return types.NewBlockHeader(parent, &types.Block{Timestamp: ts, ProposedBy: "proposer"}, 0, "")
+	}
+
+	// Five headers a second apart; the median is the third.
+	var tip *types.BlockHeader
+	for i := 0; i < 5; i++ {
+		header := child(tip, now.Add(time.Duration(i-10)*time.Second))
+		result, err := validator.Accept(header)
+		require.NoError(t, err)
+		require.True(t, result.Valid, result.Reason)
+		tip = header
+	}
+	fork := tip
+	assert.Equal(t, now.Add(-8*time.Second), validator.MedianTimePast())
+
+	result, err := validator.Validate(child(tip, now.Add(-8*time.Second)))
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+
+	result, err = validator.Validate(child(tip, now.Add(11*time.Second)))
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+
+	// Accepting a newer header slides the window forward.
+	next := child(tip, now)
+	result, err = validator.Accept(next)
+	require.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.Equal(t, 5, validator.Len())
+	assert.Equal(t, now.Add(-7*time.Second), validator.MedianTimePast())
+
+	// Replaying an accepted header does not move the median.
+	result, err = validator.Accept(next)
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, now.Add(-7*time.Second), validator.MedianTimePast())
+
+	// A header on a competing branch is judged by its own ancestors.
+	sibling := child(fork, now.Add(-time.Second))
+	result, err = validator.Validate(sibling)
+	require.NoError(t, err)
+	assert.True(t, result.Valid, result.Reason)
+	assert.Equal(t, now.Add(-8*time.Second), result.MedianTimePast)
+
+	// Headers whose parent is unknown have no window to check against.
+	orphan := child(&types.BlockHeader{Height: 41, ParentHash: "missing", ProposerID: "proposer"}, now)
+	result, err = validator.Validate(orphan)
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+}
+
+func TestChainTimeValidatorLightDelay(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	header := func(parent *types.BlockHeader, ts time.Time, position types.Position) *types.BlockHeader {
+		return types.NewBlockHeader(parent, &types.Block{Timestamp: ts, ProposedBy: "proposer", NodePosition: position}, 0, "")
+	}
+
+	tokyo := types.Position{Latitude: 35.6762, Longitude: 139.6503}
+	newYork := types.Position{Latitude: 40.7128, Longitude: -74.0060}
+	genesis := header(nil, now.Add(-time.Second), tokyo)
+
+	// Two validators given the same headers agree on the median, whatever
+	// their own clocks.
+	var medians []time.Time
+	for _, offset := range []time.Duration{0, 5 * time.Second} {
+		local := now.Add(offset)
+		validator := consensus.NewChainTimeValidator(&consensus.ChainTimeConfig{Window: 5, MaxFutureDrift: 10 * time.Second}, zap.NewNop())
+		validator.SetClock(func() time.Time { return local })
+		validator.Append(genesis)
+
+		result, err := validator.Validate(header(genesis, now, newYork))
+		require.NoError(t, err)
+		require.True(t, result.Valid, result.Reason)
+		medians = append(medians, result.MedianTimePast)
+	}
+	assert.Equal(t, medians[0], medians[1])
+
+	// The Tokyo header cannot have reached New York before the light delay,
+	// so a New York timestamp inside it is not after the median.
+	assert.Greater(t, medians[0].Sub(genesis.Timestamp), 30*time.Millisecond)
+
+	validator := consensus.NewChainTimeValidator(nil, zap.NewNop())
+	validator.SetClock(func() time.Time { return now })
+	validator.Append(genesis)
+	result, err := validator.Validate(header(genesis, genesis.Timestamp.Add(10*time.Millisecond), newYork))
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+}
@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/network"
+)
+
+type fixedOffsets map[string]time.Duration
+
+func (f fixedOffsets) ClockOffset(peerID string) (time.Duration, bool) {
+	offset, ok := f[peerID]
+	return offset, ok
+}
+
+func TestLatencyProbes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a := startPeer(t, ctx, "node-a", 10)
+	b := startPeer(t, ctx, "node-b", 10)
+	c := startPeer(t, ctx, "node-c", 10)
+	require.NoError(t, a.Connect("node-b", b.ListenAddr().String()))
+	require.NoError(t, a.Connect("node-c", c.ListenAddr().String()))
+	require.Eventually(t, func() bool {
+		return len(a.GetActiveConnections()) == 2
+	}, 5*time.Second, 20*time.Millisecond)
+
+	config := network.DefaultProbeConfig()
+	config.Interval = 50 * time.Millisecond
+	config.Budget = 1
+	config.ProbesPerLink = 10
+	config.ProbeSpacing = 5 * time.Millisecond
+	monitor := network.NewLatencyMonitor(nil, zap.NewNop())
+	monitor.AttachPeering(a, config)
+	monitor.SetClockOffsetSource(fixedOffsets{"node-b": 0})
+
+	measurement, err := monitor.ProbePeer(ctx, "node-b")
+	require.NoError(t, err)
+	assert.Equal(t, 10, measurement.Measurements)
+	assert.Zero(t, measurement.PacketLoss)
+	assert.Positive(t, measurement.P50)
+	assert.LessOrEqual(t, measurement.P50, measurement.P95)
+	assert.LessOrEqual(t, measurement.P95, measurement.P99)
+
+	total := 0
+	for _, bucket := range measurement.Histogram {
+		total += bucket.Count
+	}
+	assert.Equal(t, 10, total)
+
+	// With a known offset the one-way delays add up to the round trip.
+	require.True(t, measurement.OneWayKnown)
+	assert.InDelta(t, float64(measurement.Average), float64(measurement.ForwardDelay+measurement.ReverseDelay), float64(time.Microsecond))
+
+	_, err = monitor.ProbePeer(ctx, "node-missing")
+	assert.Error(t, err)
+
+	// A budget of one link per round still covers every link over time.
+	go monitor.StartMonitoring(ctx)
+	t.Cleanup(monitor.Stop)
+	require.Eventually(t, func() bool {
+		return monitor.GetMeasurement("node-a", "node-c") != nil
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.False(t, monitor.GetMeasurement("node-a", "node-c").OneWayKnown)
+}
+
+func TestLatencyProbeLinks(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a := startPeer(t, ctx, "node-a", 10)
+	b := startPeer(t, ctx, "node-b", 10)
+	require.NoError(t, a.Connect("node-b", b.ListenAddr().String()))
+	require.Eventually(t, func() bool {
+		return activeSession(a, "node-b") != nil
+	}, 5*time.Second, 20*time.Millisecond)
+
+	config := network.DefaultProbeConfig()
+	config.Interval = 20 * time.Millisecond
+	config.ProbesPerLink = 5
+	config.ProbeSpacing = time.Millisecond
+	config.MaxProcessing = 0
+	monitor := network.NewLatencyMonitor(nil, zap.NewNop())
+	monitor.AttachPeering(a, config)
+	monitor.SetClockOffsetSource(fixedOffsets{"node-b": 0})
+
+	// With no processing time allowed, the peer's claimed processing is
+	// counted as network delay rather than subtracted from the round trip.
+	measurement, err := monitor.ProbePeer(ctx, "node-b")
+	require.NoError(t, err)
+	require.True(t, measurement.OneWayKnown)
+	assert.GreaterOrEqual(t, measurement.Average, measurement.ForwardDelay+measurement.ReverseDelay)
+
+	// Once the peer disconnects its link stops being reported.
+	require.NoError(t, a.Disconnect("node-b"))
+	go monitor.StartMonitoring(ctx)
+	t.Cleanup(monitor.Stop)
+	require.Eventually(t, func() bool {
+		return len(monitor.GetAllMeasurements()) == 0
+	}, 5*time.Second, 20*time.Millisecond)
+}
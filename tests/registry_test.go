@@ -0,0 +1,136 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/consensus"
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/security"
+)
+
+type memoryRecordStore struct {
+	records map[string][]byte
+}
+
+func (s *memoryRecordStore) SaveRecord(key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	s.records[key] = data
+	return nil
+}
+
+func (s *memoryRecordStore) LoadRecord(key string, value interface{}) error {
+	data, exists := s.records[key]
+	if !exists {
+		return fmt.Errorf("record %s not found", key)
+	}
+	return json.Unmarshal(data, value)
+}
+
+func (s *memoryRecordStore) ListRecordKeys(prefix string) ([]string, error) {
+	var keys []string
+	for key := range s.records {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func TestValidatorRegistryEpochs(t *testing.T) {
+	logger := zap.NewNop()
+	store := &memoryRecordStore{records: make(map[string][]byte)}
+	config := consensus.DefaultRegistryConfig()
+	config.EpochLength = 10
+	config.ScheduledPropagation = time.Second
+	config.DefaultBlockInterval = time.Second
+	registry := consensus.NewValidatorRegistry(nil, store, config, logger)
+
+	_, err := registry.Bootstrap([]consensus.ValidatorInfo{{ID: "v2"}, {ID: "v1"}, {ID: "v3"}})
+	require.NoError(t, err)
+
+	// One second of propagation tripled by the safety factor is three blocks,
+	// so a join at height 7 activates at height 10 and misses epoch 1.
+	join, err := registry.RequestJoin(consensus.ValidatorInfo{ID: "v4"}, 7)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(10), join.ActivationHeight)
+	assert.Equal(t, uint64(2), join.EffectiveEpoch)
+
+	// A node whose own propagation estimate differs schedules the same
+	// change for the same epoch.
+	slowConfig := *config
+	slowConfig.FallbackPropagation = 10 * time.Second
+	slow := consensus.NewValidatorRegistry(nil, &memoryRecordStore{records: make(map[string][]byte)}, &slowConfig, logger)
+	_, err = slow.Bootstrap([]consensus.ValidatorInfo{{ID: "v2"}, {ID: "v1"}, {ID: "v3"}})
+	require.NoError(t, err)
+	slowJoin, err := slow.RequestJoin(consensus.ValidatorInfo{ID: "v4"}, 7)
+	require.NoError(t, err)
+	assert.Equal(t, join.EffectiveEpoch, slowJoin.EffectiveEpoch)
+
+	leave, err := registry.RequestLeave("v1", 2)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), leave.EffectiveEpoch)
+
+	_, err = registry.RequestJoin(consensus.ValidatorInfo{ID: "v2"}, 7)
+	assert.Error(t, err)
+
+	created, err := registry.AdvanceHeight(25)
+	require.NoError(t, err)
+	require.Len(t, created, 2)
+	assert.Equal(t, []string{"v2", "v3"}, created[0].ValidatorIDs())
+	assert.Equal(t, []string{"v2", "v3", "v4"}, created[1].ValidatorIDs())
+	assert.Empty(t, registry.GetPendingRequests())
+
+	genesis, err := registry.GetValidatorSet(0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"v1", "v2", "v3"}, genesis.ValidatorIDs())
+
+	atHeight, err := registry.GetValidatorSetAtHeight(15)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), atHeight.Epoch)
+
+	restored := consensus.NewValidatorRegistry(nil, store, config, logger)
+	require.NoError(t, restored.Load())
+	assert.Equal(t, uint64(2), restored.CurrentEpoch())
+	assert.Equal(t, []uint64{0, 1, 2}, restored.ListEpochs())
+}
+
+func TestValidatorKeySync(t *testing.T) {
+	manager := consensus.NewConsensusManager(nil, zap.NewNop())
+	keyManager := security.NewKeyManager(zap.NewNop())
+	manager.AttachKeyManager(keyManager)
+
+	genesisKey, err := security.GenerateSigningKey(security.KeyTypeEd25519)
+	require.NoError(t, err)
+	_, err = manager.BootstrapValidators([]consensus.ValidatorInfo{
+		{ID: "v1", KeyType: string(genesisKey.Type), PublicKey: genesisKey.Public().String()},
+	})
+	require.NoError(t, err)
+	_, err = keyManager.GetValidatorKey("v1")
+	assert.NoError(t, err, "genesis keys are registered")
+
+	// A key that only arrives with a join request is not trusted.
+	joinKey, err := security.GenerateSigningKey(security.KeyTypeEd25519)
+	require.NoError(t, err)
+	_, err = manager.RequestValidatorJoin(consensus.ValidatorInfo{
+		ID: "v2", KeyType: string(joinKey.Type), PublicKey: joinKey.Public().String(),
+	}, 1)
+	require.NoError(t, err)
+	_, err = manager.AdvanceHeight(1000)
+	require.NoError(t, err)
+
+	current, err := manager.GetValidatorRegistry().GetCurrentSet()
+	require.NoError(t, err)
+	require.True(t, current.Contains("v2"))
+	_, err = keyManager.GetValidatorKey("v2")
+	assert.Error(t, err)
+}
@@ -0,0 +1,64 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/consensus"
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
+)
+
+func TestRoundRecorderTimeline(t *testing.T) {
+	logger := zap.NewNop()
+	recorder := consensus.NewRoundRecorder(consensus.NewTimingManager(nil, logger), nil, logger)
+	recorder.SetLocalNode("local")
+	start := time.Now().UTC()
+
+	recorder.RecordProposal(&types.Proposal{
+		ProposerID: "proposer",
+		Height:     7,
+		Round:      1,
+		Timestamp:  start,
+		Block:      &types.Block{Hash: "block7"},
+	}, start.Add(20*time.Millisecond))
+
+	for voter, delay := range map[string]time.Duration{"fast": 30 * time.Millisecond, "slow": 400 * time.Millisecond} {
+		sent := start.Add(50 * time.Millisecond)
+		recorder.RecordVote(&types.Vote{
+			BlockHash: "block7",
+			VoterID:   voter,
+			Height:    7,
+			Round:     1,
+			Timestamp: sent,
+		}, sent.Add(delay))
+	}
+	recorder.RecordCommit(7, 1, "block7", start.Add(500*time.Millisecond))
+
+	timeline, err := recorder.GetTimeline(consensus.RoundID(7, 1))
+	require.NoError(t, err)
+	assert.Equal(t, "proposer", timeline.Proposer)
+	assert.Equal(t, 500*time.Millisecond, timeline.Duration)
+	assert.Equal(t, []string{"slow"}, timeline.Laggards)
+	require.Len(t, timeline.Events, 5)
+	assert.Equal(t, consensus.RoundEventProposalSent, timeline.Events[0].Type)
+	assert.Equal(t, consensus.RoundEventCommit, timeline.Events[4].Type)
+
+	trace, err := recorder.ChromeTrace(timeline.ID)
+	require.NoError(t, err)
+
+	var spans int
+	for _, event := range trace.TraceEvents {
+		if event.Phase == "X" {
+			spans++
+			assert.Greater(t, event.Duration, int64(0))
+		}
+	}
+	assert.Equal(t, 3, spans)
+
+	_, err = recorder.ChromeTrace("unknown")
+	assert.Error(t, err)
+}
@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/security"
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
+)
+
+func TestVoteSignatures(t *testing.T) {
+	for _, keyType := range []security.KeyType{security.KeyTypeEd25519, security.KeyTypeSecp256k1} {
+		t.Run(string(keyType), func(t *testing.T) {
+			key, err := security.GenerateSigningKey(keyType)
+			require.NoError(t, err)
+
+			signer := security.NewKeyManager(zap.NewNop())
+			signer.SetLocalKey("validator1", key)
+
+			verifier := security.NewKeyManager(zap.NewNop())
+			require.NoError(t, verifier.RegisterValidatorKey("validator1", keyType, key.Public().String()))
+
+			vote := &types.Vote{BlockHash: "block", VoterID: "validator1", Height: 7, Timestamp: time.Now().UTC()}
+			require.NoError(t, signer.SignVote(vote))
+			assert.NoError(t, verifier.VerifyVote(vote))
+
+			vote.Round = 1
+			assert.Error(t, verifier.VerifyVote(vote), "signature must cover the round")
+		})
+	}
+}
+
+func TestQuorumCertificate(t *testing.T) {
+	verifier := security.NewKeyManager(zap.NewNop())
+	var validators []string
+	var votes []*types.Vote
+
+	for i := 0; i < 4; i++ {
+		id := fmt.Sprintf("validator%d", i)
+		validators = append(validators, id)
+
+		key, err := security.GenerateSigningKey(security.KeyTypeEd25519)
+		require.NoError(t, err)
+		require.NoError(t, verifier.RegisterValidatorKey(id, key.Type, key.Public().String()))
+
+		signer := security.NewKeyManager(zap.NewNop())
+		signer.SetLocalKey(id, key)
+		vote := &types.Vote{BlockHash: "block", VoterID: id, Height: 3, Timestamp: time.Now().UTC()}
+		require.NoError(t, signer.SignVote(vote))
+		votes = append(votes, vote)
+	}
+
+	// One forged vote still leaves a 3-of-4 quorum.
+	votes[3].Signature = votes[2].Signature
+
+	qc, err := verifier.AggregateVotes(votes, validators, 3)
+	require.NoError(t, err)
+	assert.Len(t, qc.Signatures, 3)
+	assert.NoError(t, verifier.VerifyQuorumCertificate(qc, validators, 3))
+
+	_, err = verifier.AggregateVotes(votes[:2], validators, 3)
+	assert.Error(t, err)
+
+	qc.Signatures[0].Timestamp = qc.Signatures[0].Timestamp.Add(time.Second)
+	assert.Error(t, verifier.VerifyQuorumCertificate(qc, validators, 3))
+}
+
+func TestValidatorKeyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "validator_key.pem")
+	key, err := security.LoadOrCreateSigningKey(path, security.KeyTypeSecp256k1)
+	require.NoError(t, err)
+
+	// An existing file keeps its own key type.
+	reloaded, err := security.LoadOrCreateSigningKey(path, security.KeyTypeEd25519)
+	require.NoError(t, err)
+	assert.Equal(t, security.KeyTypeSecp256k1, reloaded.Type)
+	assert.Equal(t, key.Public().String(), reloaded.Public().String())
+}
+
+func TestValidatorKeyRotation(t *testing.T) {
+	current, err := security.GenerateSigningKey(security.KeyTypeEd25519)
+	require.NoError(t, err)
+	next, err := security.GenerateSigningKey(security.KeyTypeSecp256k1)
+	require.NoError(t, err)
+
+	verifier := security.NewKeyManager(zap.NewNop())
+	require.NoError(t, verifier.RegisterValidatorKey("validator1", current.Type, current.Public().String()))
+	require.NoError(t, verifier.RegisterValidatorKey("validator1", current.Type, current.Public().String()),
+		"registering the same key again is a no-op")
+
+	err = verifier.RegisterValidatorKey("validator1", next.Type, next.Public().String())
+	assert.ErrorIs(t, err, security.ErrKeyConflict)
+
+	// Only the current key can hand the registration over.
+	impostor := security.NewKeyManager(zap.NewNop())
+	impostor.SetLocalKey("validator1", next)
+	forged, err := impostor.SignKeyRotation(next.Public())
+	require.NoError(t, err)
+	err = verifier.RotateValidatorKey("validator1", next.Type, next.Public().String(), forged)
+	assert.ErrorIs(t, err, security.ErrInvalidSignature)
+
+	holder := security.NewKeyManager(zap.NewNop())
+	holder.SetLocalKey("validator1", current)
+	proof, err := holder.SignKeyRotation(next.Public())
+	require.NoError(t, err)
+	require.NoError(t, verifier.RotateValidatorKey("validator1", next.Type, next.Public().String(), proof))
+
+	registered, err := verifier.GetValidatorKey("validator1")
+	require.NoError(t, err)
+	assert.True(t, registered.Equal(next.Public()))
+
+	// The proof names the key it replaces, so it cannot be replayed.
+	assert.Error(t, verifier.RotateValidatorKey("validator1", next.Type, next.Public().String(), proof))
+}
@@ -0,0 +1,209 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/consensus"
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/network"
+)
+
+type syncNode struct {
+	peering   *network.PeeringManager
+	offsets   *consensus.OffsetManager
+	estimator *consensus.OffsetEstimator
+	sync      *consensus.Synchronizer
+}
+
+func startSyncNode(t *testing.T, ctx context.Context, topology *network.TopologyManager) *syncNode {
+	identity, err := network.GenerateIdentity()
+	require.NoError(t, err)
+	pm := network.NewPeeringManager(nil, nil, &network.PeeringConfig{
+		ListenAddress: "127.0.0.1:0",
+		MaxPeers:      10,
+		Identity:      identity,
+	}, zap.NewNop())
+	require.NoError(t, pm.Start(ctx))
+	t.Cleanup(pm.Stop)
+
+	logger := zap.NewNop()
+	timing := consensus.NewTimingManager(topology, logger)
+	offsets := consensus.NewOffsetManager(timing, logger)
+	estimator := consensus.NewOffsetEstimator(pm, timing, logger)
+	offsets.SetEstimator(estimator)
+	synchronizer := consensus.NewSynchronizer(offsets, logger)
+	synchronizer.SetPeering(pm)
+	return &syncNode{peering: pm, offsets: offsets, estimator: estimator, sync: synchronizer}
+}
+
+func connectSyncNodes(t *testing.T, from, to *syncNode) {
+	require.NoError(t, from.peering.Connect(to.peering.LocalPeerID(), to.peering.ListenAddr().String()))
+	require.Eventually(t, func() bool {
+		session := activeSession(to.peering, from.peering.LocalPeerID())
+		return session != nil && session.Authenticated && activeSession(from.peering, to.peering.LocalPeerID()) != nil
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestSyncCorrections(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	validator, target, outsider := startSyncNode(t, ctx, nil), startSyncNode(t, ctx, nil), startSyncNode(t, ctx, nil)
+	connectSyncNodes(t, validator, target)
+	connectSyncNodes(t, outsider, target)
+
+	targetID := target.peering.LocalPeerID()
+	target.sync.SetAuthorizer(func(peerID string) bool {
+		return peerID == validator.peering.LocalPeerID()
+	})
+
+	// Geographic offsets are propagation estimates and are never pushed.
+	err := validator.sync.PushCorrection(&consensus.NodeOffset{
+		NodeID: targetID, Offset: 20 * time.Millisecond, Source: consensus.OffsetSourceGeographic,
+	})
+	assert.Error(t, err)
+
+	// A measured correction from an authorised peer is applied to the
+	// target's clock and acknowledged.
+	require.NoError(t, validator.sync.PushCorrection(&consensus.NodeOffset{
+		NodeID: targetID, Offset: 20 * time.Millisecond, Source: consensus.OffsetSourceMeasured,
+	}))
+	require.Eventually(t, func() bool {
+		status := validator.sync.GetSyncStatus(targetID)
+		return status != nil && status.Status == "applied"
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.Equal(t, 20*time.Millisecond, target.offsets.LocalCorrection())
+	assert.Equal(t, 20*time.Millisecond, target.sync.GetLocalCorrection())
+
+	// An authenticated peer outside the validator set is refused.
+	require.NoError(t, outsider.sync.PushCorrection(&consensus.NodeOffset{
+		NodeID: targetID, Offset: -3 * time.Second, Source: consensus.OffsetSourceMeasured,
+	}))
+	require.Eventually(t, func() bool {
+		status := outsider.sync.GetSyncStatus(targetID)
+		return status != nil && status.Status == "rejected"
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.Equal(t, 20*time.Millisecond, target.offsets.LocalCorrection())
+
+	// So is a correction meant for another node.
+	misaddressed, err := json.Marshal(&consensus.SyncCorrection{
+		CorrectionID: "misaddressed",
+		NodeID:       outsider.peering.LocalPeerID(),
+		Offset:       time.Second,
+		IssuedAt:     time.Now().UTC(),
+	})
+	require.NoError(t, err)
+	require.NoError(t, validator.peering.SendPeerMessage(targetID, network.MessageTypeSyncCorrection, misaddressed))
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, 20*time.Millisecond, target.offsets.LocalCorrection())
+}
+
+func TestSyncConvergence(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	topology := newTestTopology(t)
+	validator := startSyncNode(t, ctx, topology)
+	target, reference := startSyncNode(t, ctx, nil), startSyncNode(t, ctx, nil)
+	connectSyncNodes(t, validator, target)
+	connectSyncNodes(t, validator, reference)
+
+	// All three share a position, so loopback round trips are plausible.
+	for _, node := range []*syncNode{validator, target, reference} {
+		require.NoError(t, topology.AddNode(CreateTestNode(node.peering.LocalPeerID(), 40.7128, -74.0060)))
+	}
+
+	targetID := target.peering.LocalPeerID()
+	target.sync.SetAuthorizer(func(peerID string) bool {
+		return peerID == validator.peering.LocalPeerID()
+	})
+
+	// The target's corrected clock runs 40ms ahead of the others.
+	target.offsets.SetLocalCorrection(40 * time.Millisecond)
+
+	measure := func() {
+		_, err := validator.estimator.MeasurePeer(ctx, targetID)
+		require.NoError(t, err)
+		_, err = validator.estimator.MeasurePeer(ctx, reference.peering.LocalPeerID())
+		require.NoError(t, err)
+	}
+
+	measure()
+	require.NoError(t, validator.sync.SyncNode(targetID))
+	status := validator.sync.GetSyncStatus(targetID)
+	require.NotNil(t, status)
+	assert.False(t, status.Converged)
+	assert.InDelta(t, float64(-40*time.Millisecond), float64(status.LastOffset), float64(5*time.Millisecond))
+
+	// The pushed correction replaces the bad one, and the next measurement
+	// sees the corrected clock.
+	require.Eventually(t, func() bool {
+		status := validator.sync.GetSyncStatus(targetID)
+		return status != nil && status.Status == "applied"
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.InDelta(t, 0, float64(target.offsets.LocalCorrection()), float64(5*time.Millisecond))
+
+	// A node awaiting its correction is not converged yet; once it applies
+	// a correction for a residual within the threshold it is.
+	measure()
+	require.NoError(t, validator.sync.SyncNode(targetID))
+	status = validator.sync.GetSyncStatus(targetID)
+	require.NotNil(t, status)
+	assert.InDelta(t, 0, float64(status.Residual), float64(5*time.Millisecond))
+	require.Eventually(t, func() bool {
+		status := validator.sync.GetSyncStatus(targetID)
+		return status != nil && status.Status == "applied"
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.True(t, validator.sync.GetSyncStatus(targetID).Converged)
+	assert.Equal(t, 1, validator.sync.GetSyncStats().ConvergedNodes)
+}
+
+func TestSyncConvergenceTransitions(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	topology := newTestTopology(t)
+	validator := startSyncNode(t, ctx, topology)
+	target, reference := startSyncNode(t, ctx, nil), startSyncNode(t, ctx, nil)
+	connectSyncNodes(t, validator, target)
+	connectSyncNodes(t, validator, reference)
+	for _, node := range []*syncNode{validator, target, reference} {
+		require.NoError(t, topology.AddNode(CreateTestNode(node.peering.LocalPeerID(), 40.7128, -74.0060)))
+	}
+
+	config := consensus.DefaultSyncConfig()
+	config.AckTimeout = 100 * time.Millisecond
+	validator.sync.SetConfig(config)
+
+	// The target drops corrections without acknowledging them.
+	target.peering.RegisterHandler(network.MessageTypeSyncCorrection, func(*network.PeerConnection, *network.PeerMessage) {})
+	targetID := target.peering.LocalPeerID()
+
+	// Without a measurement the geographic estimate is only recorded.
+	require.NoError(t, validator.sync.SyncNode(targetID))
+	assert.Equal(t, "synced", validator.sync.GetSyncStatus(targetID).Status)
+	assert.Equal(t, 1, validator.sync.GetSyncStats().ConvergedNodes)
+
+	// A measured offset is pushed; the node is pending until it acks.
+	_, err := validator.estimator.MeasurePeer(ctx, targetID)
+	require.NoError(t, err)
+	_, err = validator.estimator.MeasurePeer(ctx, reference.peering.LocalPeerID())
+	require.NoError(t, err)
+	require.NoError(t, validator.sync.SyncNode(targetID))
+	status := validator.sync.GetSyncStatus(targetID)
+	assert.Equal(t, "pending", status.Status)
+	assert.False(t, status.Converged)
+	assert.Equal(t, 0, validator.sync.GetSyncStats().ConvergedNodes)
+
+	time.Sleep(2 * config.AckTimeout)
+	stats := validator.sync.GetSyncStats()
+	assert.Equal(t, 1, stats.UnreachableNodes)
+	assert.Equal(t, 0, stats.ConvergedNodes)
+	assert.False(t, validator.sync.GetSyncStatus(targetID).Converged)
+}
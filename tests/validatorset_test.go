@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/consensus"
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
+)
+
+func TestValidatorSetID(t *testing.T) {
+	id := consensus.ValidatorSetID([]string{"a", "b", "c"})
+
+	assert.Len(t, id, 64)
+	assert.Equal(t, id, consensus.ValidatorSetID([]string{"c", "a", "b"}))
+	assert.Equal(t, id, consensus.ValidatorSetID([]string{"b", "a", "c", "a"}))
+	assert.NotEqual(t, id, consensus.ValidatorSetID([]string{"a", "b"}))
+
+	// Length prefixes keep concatenation from colliding.
+	assert.NotEqual(t, consensus.ValidatorSetID([]string{"ab", "c"}), consensus.ValidatorSetID([]string{"a", "bc"}))
+}
+
+func TestTimingCacheInvalidation(t *testing.T) {
+	topology := newTestTopology(t)
+	require.NoError(t, topology.AddNode(CreateTestNode("node-a", 0, 0)))
+	require.NoError(t, topology.AddNode(CreateTestNode("node-b", 0, 1)))
+
+	tm := consensus.NewTimingManager(topology, zap.NewNop())
+	validators := []string{"node-a", "node-b", "node-c"}
+
+	timing, err := tm.CalculateConsensusTiming(validators)
+	require.NoError(t, err)
+	assert.Equal(t, consensus.ValidatorSetID(validators), timing.ValidatorSetID)
+	assert.Equal(t, 2, timing.ValidatorCount)
+
+	// The same set in any order is served from the cache.
+	cached, err := tm.CalculateConsensusTiming([]string{"node-c", "node-b", "node-a"})
+	require.NoError(t, err)
+	assert.Same(t, timing, cached)
+
+	// A member moving recomputes the timing; in geostationary orbit it is
+	// far enough to raise the propagation delay above its floor.
+	require.NoError(t, topology.UpdateNodePosition("node-b", types.Position{Latitude: 0, Longitude: 1, Altitude: 36e6}))
+	moved, err := tm.CalculateConsensusTiming(validators)
+	require.NoError(t, err)
+	assert.NotSame(t, timing, moved)
+	assert.Greater(t, moved.MaxPropagation, timing.MaxPropagation)
+
+	// So does a member joining the topology, and one leaving it.
+	require.NoError(t, topology.AddNode(CreateTestNode("node-c", -45, -90)))
+	joined, err := tm.CalculateConsensusTiming(validators)
+	require.NoError(t, err)
+	assert.NotSame(t, moved, joined)
+	assert.Equal(t, 3, joined.ValidatorCount)
+
+	require.NoError(t, topology.RemoveNode("node-c"))
+	left, err := tm.CalculateConsensusTiming(validators)
+	require.NoError(t, err)
+	assert.NotSame(t, joined, left)
+	assert.Equal(t, 2, left.ValidatorCount)
+}
+
+func TestTimingCacheEviction(t *testing.T) {
+	topology := newTestTopology(t)
+	require.NoError(t, topology.AddNode(CreateTestNode("node-a", 0, 0)))
+	require.NoError(t, topology.AddNode(CreateTestNode("node-b", 0, 1)))
+
+	tm := consensus.NewTimingManager(topology, zap.NewNop())
+
+	// Absent members still make distinct sets, so each call adds an entry.
+	var first *consensus.ConsensusTiming
+	for i := 0; i < 300; i++ {
+		timing, err := tm.CalculateConsensusTiming([]string{"node-a", "node-b", fmt.Sprintf("absent-%d", i)})
+		require.NoError(t, err)
+		if i == 0 {
+			first = timing
+		}
+	}
+	assert.Equal(t, 256, tm.GetCacheStats()["cache_size"])
+
+	// The oldest entries were the ones dropped.
+	again, err := tm.CalculateConsensusTiming([]string{"node-a", "node-b", "absent-0"})
+	require.NoError(t, err)
+	assert.NotSame(t, first, again)
+
+	last, err := tm.CalculateConsensusTiming([]string{"node-a", "node-b", "absent-299"})
+	require.NoError(t, err)
+	recent, err := tm.CalculateConsensusTiming([]string{"node-a", "node-b", "absent-299"})
+	require.NoError(t, err)
+	assert.Same(t, last, recent)
+}
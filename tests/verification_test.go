@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/network"
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/pkg/types"
+)
+
+type nodeMap map[string]*types.Node
+
+func (m nodeMap) GetNode(nodeID string) (*types.Node, error) {
+	node, exists := m[nodeID]
+	if !exists {
+		return nil, fmt.Errorf("node %s not found", nodeID)
+	}
+	return node, nil
+}
+
+// fiberRTT is a realistic RTT between two points: light in fibre is about
+// a third slower than in vacuum, plus a millisecond of processing.
+func fiberRTT(a, b types.Position) time.Duration {
+	return time.Duration(float64(network.MinimumRTT(a, b))*1.5) + time.Millisecond
+}
+
+func TestPositionVerifier(t *testing.T) {
+	anchors := map[string]types.Position{
+		"anchor-jakarta":   {Latitude: -6.2, Longitude: 106.8},
+		"anchor-singapore": {Latitude: 1.35, Longitude: 103.8},
+		"anchor-sydney":    {Latitude: -33.9, Longitude: 151.2},
+	}
+	bandung := types.Position{Latitude: -6.9, Longitude: 107.6}
+	london := types.Position{Latitude: 51.5, Longitude: -0.1}
+
+	nodes := nodeMap{
+		"honest": {ID: "honest", Position: bandung},
+		// Physically in Bandung, claiming London.
+		"liar":   {ID: "liar", Position: london},
+		"sparse": {ID: "sparse", Position: bandung},
+	}
+	for id, position := range anchors {
+		nodes[id] = &types.Node{ID: id, Position: position}
+	}
+
+	config := network.DefaultPositionVerifierConfig()
+	config.UnverifiedTrust = 0.5
+	verifier := network.NewPositionVerifier(nodes, config, zap.NewNop())
+
+	assert.Error(t, verifier.AddAnchor("missing"))
+	for id := range anchors {
+		require.NoError(t, verifier.AddAnchor(id))
+	}
+	assert.Len(t, verifier.Anchors(), 3)
+	assert.Error(t, verifier.RecordRTT("honest", "liar", time.Millisecond), "only anchors may report")
+
+	for id, position := range anchors {
+		require.NoError(t, verifier.RecordRTT(id, "honest", fiberRTT(position, bandung)))
+		require.NoError(t, verifier.RecordRTT(id, "liar", fiberRTT(position, bandung)))
+	}
+	require.NoError(t, verifier.RecordRTT("anchor-jakarta", "sparse", fiberRTT(anchors["anchor-jakarta"], bandung)))
+
+	// Recording a measurement re-verifies the node without an explicit call.
+	assert.Equal(t, 0.0, verifier.TrustScore("liar"))
+	require.NotNil(t, verifier.GetVerification("honest"))
+	assert.True(t, verifier.GetVerification("honest").Complete)
+
+	honest, err := verifier.Verify("honest")
+	require.NoError(t, err)
+	assert.True(t, honest.Complete)
+	assert.False(t, honest.Flagged)
+	assert.Equal(t, 1.0, honest.TrustScore)
+
+	liar, err := verifier.Verify("liar")
+	require.NoError(t, err)
+	assert.True(t, liar.Flagged)
+	assert.Equal(t, 3, liar.Violations)
+	assert.Equal(t, 0.0, verifier.TrustScore("liar"))
+	assert.Equal(t, []string{"liar"}, verifier.FlaggedNodes())
+
+	// One satisfied anchor out of three required: the two missing ones
+	// count at the unverified score.
+	sparse, err := verifier.Verify("sparse")
+	require.NoError(t, err)
+	assert.False(t, sparse.Complete)
+	assert.InDelta(t, 2.0/3.0, sparse.TrustScore, 1e-9)
+
+	assert.Equal(t, 0.5, verifier.TrustScore("never-measured"))
+
+	// A slower measurement does not loosen the bound; queueing only ever
+	// adds delay, so the fastest RTT is kept.
+	jakartaRTT := fiberRTT(anchors["anchor-jakarta"], bandung)
+	require.NoError(t, verifier.RecordRTT("anchor-jakarta", "liar", 500*time.Millisecond))
+	liar, err = verifier.Verify("liar")
+	require.NoError(t, err)
+	assert.Equal(t, jakartaRTT, liar.Constraints[0].RTT)
+	assert.Equal(t, 3, liar.Violations)
+}
@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ixuxoinzo/relativistic-blockchain-sdk/internal/network"
+)
+
+func TestFrameRoundTrip(t *testing.T) {
+	config := &network.WireConfig{MaxFrameSize: 1024, MaxMessageSize: 64 * 1024}
+	large := bytes.Repeat([]byte("relativistic"), 1000)
+
+	for _, codec := range []network.CodecID{network.CodecJSON, network.CodecBinary} {
+		config.Codec = codec
+		var stream bytes.Buffer
+		writer, err := network.NewFrameWriter(&stream, config)
+		require.NoError(t, err)
+
+		messages := []*network.PeerMessage{
+			{Type: network.MessageTypeData, PeerID: "a", Timestamp: time.Unix(1700000000, 42).UTC(), Payload: large},
+			{Type: network.MessageTypeKeepAlive, PeerID: "a", Timestamp: time.Unix(1700000001, 0).UTC()},
+		}
+		for _, message := range messages {
+			_, err := writer.WriteMessage(message)
+			require.NoError(t, err)
+		}
+
+		// Both messages arrive in one buffer but are read a byte at a time.
+		reader := network.NewFrameReader(iotest.OneByteReader(&stream), config)
+		for _, expected := range messages {
+			message, _, err := reader.ReadMessage()
+			require.NoError(t, err)
+			assert.Equal(t, expected.Type, message.Type)
+			assert.Equal(t, expected.PeerID, message.PeerID)
+			assert.True(t, expected.Timestamp.Equal(message.Timestamp))
+			assert.Equal(t, len(expected.Payload), len(message.Payload))
+		}
+	}
+
+	// The reader enforces its own message limit on streamed messages.
+	var stream bytes.Buffer
+	writer, err := network.NewFrameWriter(&stream, config)
+	require.NoError(t, err)
+	_, err = writer.WriteMessage(&network.PeerMessage{Type: network.MessageTypeData, Payload: large})
+	require.NoError(t, err)
+	small := &network.WireConfig{MaxFrameSize: 1024, MaxMessageSize: 4096}
+	_, _, err = network.NewFrameReader(&stream, small).ReadMessage()
+	assert.True(t, errors.Is(err, network.ErrMessageTooLarge))
+}
+
+func FuzzFrameReader(f *testing.F) {
+	var stream bytes.Buffer
+	writer, _ := network.NewFrameWriter(&stream, nil)
+	writer.WriteMessage(&network.PeerMessage{Type: network.MessageTypeData, PeerID: "peer", Payload: []byte("hello")})
+	f.Add(stream.Bytes())
+	f.Add([]byte{0, 0, 0, 2, 1, 1, 0, '{', '}'})
+	f.Add([]byte{0, 0, 0, 0, 9, 2, 0})
+
+	config := &network.WireConfig{MaxFrameSize: 4096, MaxMessageSize: 16384}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		reader := network.NewFrameReader(bytes.NewReader(data), config)
+		for i := 0; i < 16; i++ {
+			message, n, err := reader.ReadMessage()
+			if n > len(data) {
+				t.Fatalf("reported %d bytes read from %d", n, len(data))
+			}
+			if err != nil && !errors.Is(err, network.ErrMalformedMessage) {
+				return
+			}
+			if err == nil && message == nil {
+				t.Fatal("nil message without error")
+			}
+		}
+	})
+}
+
+func FuzzBinaryCodec(f *testing.F) {
+	codec := network.BinaryCodec{}
+	seed, _ := codec.Marshal(&network.PeerMessage{Type: network.MessageTypeData, PeerID: "peer", Timestamp: time.Unix(1, 0), Payload: []byte{1, 2, 3}})
+	f.Add(seed)
+	f.Add([]byte{0x0a, 0xff})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		message := &network.PeerMessage{}
+		if err := codec.Unmarshal(data, message); err != nil {
+			return
+		}
+		encoded, err := codec.Marshal(message)
+		require.NoError(t, err)
+		again := &network.PeerMessage{}
+		require.NoError(t, codec.Unmarshal(encoded, again))
+		assert.Equal(t, message.Type, again.Type)
+		assert.Equal(t, message.PeerID, again.PeerID)
+		assert.Equal(t, message.Payload, again.Payload)
+	})
+}